@@ -11,21 +11,56 @@ import (
 )
 
 func main() {
+	workDoneProgress := true
+	semanticTokens := true
 	config := &lsp.Options{
+		TextDocumentSync: defines.TextDocumentSyncKindIncremental,
+		DocumentFormattingProvider: &defines.DocumentFormattingOptions{
+			WorkDoneProgressOptions: defines.WorkDoneProgressOptions{WorkDoneProgress: &workDoneProgress},
+		},
+		DocumentRangeFormattingProvider: &defines.DocumentRangeFormattingOptions{
+			WorkDoneProgressOptions: defines.WorkDoneProgressOptions{WorkDoneProgress: &workDoneProgress},
+		},
+		DocumentOnTypeFormattingProvider: &defines.DocumentOnTypeFormattingOptions{
+			FirstTriggerCharacter: "}",
+			MoreTriggerCharacter:  &[]string{";"},
+		},
 		CompletionProvider: &defines.CompletionOptions{
 			TriggerCharacters: &[]string{"."},
 		},
+		CodeActionProvider: &defines.CodeActionOptions{
+			CodeActionKinds: &[]defines.CodeActionKind{defines.CodeActionKindQuickFix, defines.CodeActionKindSourceOrganizeImports},
+		},
+		SemanticTokensProvider: &defines.SemanticTokensOptions{
+			Legend: components.SemanticTokensLegend,
+			Full:   &semanticTokens,
+			Range:  &semanticTokens,
+		},
 	}
 
 	server := lsp.NewServer(config)
 
 	logs.Init(nil)
 	view.Init(server)
+	view.OnDiagnostics(components.Diagnostics)
+	view.OnDiagnostics(components.Imports)
+	view.OnDiagnostics(components.Lint)
+	view.OnDiagnostics(components.Breaking)
 	server.OnDocumentSymbolWithSliceDocumentSymbol(components.ProvideDocumentSymbol)
 	server.OnDefinition(components.JumpDefine)
-	server.OnDocumentFormatting(components.FormatWithRetab)
+	server.OnReferences(components.References)
+	server.OnPrepareRename(components.PrepareRename)
+	server.OnRenameRequest(components.Rename)
+	server.OnWorkspaceSymbol(components.WorkspaceSymbol)
+	server.OnDocumentFormatting(components.Format)
 	server.OnCompletion(components.Completion)
 	server.OnHover(components.Hover)
+	server.OnCodeActionWithSliceCodeAction(components.CodeAction)
+	server.OnSemanticTokensFull(components.SemanticTokensFull)
+	server.OnSemanticTokensRange(components.SemanticTokensRange)
+	server.OnFoldingRanges(components.FoldingRanges)
+	server.OnSelectionRanges(components.SelectionRanges)
 	server.OnDocumentRangeFormatting(components.FormatRange)
+	server.OnDocumentOnTypeFormatting(components.FormatOnType)
 	server.Run()
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/walteh/protobuf-language-server/proto/breaking"
+)
+
+// runBreaking implements the breaking subcommand: it compares the proto files
+// below a directory with a git revision or a descriptor set and prints the
+// breaking changes. The exit code is 1 if there are any and 2 on errors.
+func runBreaking(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("breaking", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	against := flags.String("against", "HEAD", "git revision to compare with")
+	descriptorSet := flags.String("against-descriptor-set", "", "FileDescriptorSet to compare with instead of a git revision, e.g. built by buf build -o")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: %s breaking [flags] [dir]\n\nReports breaking changes of the proto files below dir, the import root, default \".\".\n\n", filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	dir := "."
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		dir = flags.Arg(0)
+	default:
+		flags.Usage()
+		return 2
+	}
+
+	current, err := breaking.LoadDir(dir)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	var base map[string]*breaking.File
+	if *descriptorSet != "" {
+		base, err = breaking.LoadDescriptorSet(*descriptorSet)
+	} else {
+		base, err = breaking.LoadGit(dir, *against)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	problems := breaking.CompareFiles(base, current)
+	for _, problem := range problems {
+		location := filepath.Join(dir, filepath.FromSlash(problem.Path))
+		if problem.Position.Line > 0 {
+			location = fmt.Sprintf("%s:%d:%d", location, problem.Position.Line, problem.Position.Column)
+		}
+		fmt.Fprintf(stdout, "%s: %s (%s)\n", location, problem.Message, problem.Rule)
+	}
+	if len(problems) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"os"
 
 	"github.com/walteh/protobuf-language-server/components"
 	"github.com/walteh/protobuf-language-server/proto/view"
@@ -24,13 +25,37 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "breaking" {
+		os.Exit(runBreaking(os.Args[2:], os.Stdout, os.Stderr))
+	}
 	flag.Parse()
 	logs.Init(logPath)
 
+	workDoneProgress := true
+	semanticTokens := true
 	config := &lsp.Options{
+		TextDocumentSync: defines.TextDocumentSyncKindIncremental,
+		DocumentFormattingProvider: &defines.DocumentFormattingOptions{
+			WorkDoneProgressOptions: defines.WorkDoneProgressOptions{WorkDoneProgress: &workDoneProgress},
+		},
+		DocumentRangeFormattingProvider: &defines.DocumentRangeFormattingOptions{
+			WorkDoneProgressOptions: defines.WorkDoneProgressOptions{WorkDoneProgress: &workDoneProgress},
+		},
+		DocumentOnTypeFormattingProvider: &defines.DocumentOnTypeFormattingOptions{
+			FirstTriggerCharacter: "}",
+			MoreTriggerCharacter:  &[]string{";"},
+		},
 		CompletionProvider: &defines.CompletionOptions{
 			TriggerCharacters: &[]string{"."},
 		},
+		CodeActionProvider: &defines.CodeActionOptions{
+			CodeActionKinds: &[]defines.CodeActionKind{defines.CodeActionKindQuickFix, defines.CodeActionKindSourceOrganizeImports},
+		},
+		SemanticTokensProvider: &defines.SemanticTokensOptions{
+			Legend: components.SemanticTokensLegend,
+			Full:   &semanticTokens,
+			Range:  &semanticTokens,
+		},
 	}
 	if *address != "" {
 		config.Address = *address
@@ -40,11 +65,25 @@ func main() {
 	server := lsp.NewServer(config)
 
 	view.Init(server)
+	view.OnDiagnostics(components.Diagnostics)
+	view.OnDiagnostics(components.Imports)
+	view.OnDiagnostics(components.Lint)
+	view.OnDiagnostics(components.Breaking)
 	server.OnDocumentSymbolWithSliceDocumentSymbol(components.ProvideDocumentSymbol)
 	server.OnDefinition(components.JumpDefine)
+	server.OnReferences(components.References)
+	server.OnPrepareRename(components.PrepareRename)
+	server.OnRenameRequest(components.Rename)
+	server.OnWorkspaceSymbol(components.WorkspaceSymbol)
 	server.OnDocumentFormatting(components.Format)
 	server.OnCompletion(components.Completion)
 	server.OnHover(components.Hover)
+	server.OnCodeActionWithSliceCodeAction(components.CodeAction)
+	server.OnSemanticTokensFull(components.SemanticTokensFull)
+	server.OnSemanticTokensRange(components.SemanticTokensRange)
+	server.OnFoldingRanges(components.FoldingRanges)
+	server.OnSelectionRanges(components.SelectionRanges)
 	server.OnDocumentRangeFormatting(components.FormatRange)
+	server.OnDocumentOnTypeFormatting(components.FormatOnType)
 	server.Run()
 }
@@ -0,0 +1,23 @@
+package components
+
+import (
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/view"
+)
+
+// Breaking reports the changes of a proto file that break clients built
+// against its baseline as errors, the code of a diagnostic is the ID of the
+// violated rule.
+func Breaking(proto_file view.ProtoFile) []defines.Diagnostic {
+	res := []defines.Diagnostic{}
+	for _, problem := range proto_file.View().Breaking(proto_file) {
+		severity := defines.DiagnosticSeverityError
+		res = append(res, defines.Diagnostic{
+			Range:    problemRange(proto_file, problem.Position, problem.Anchor, problem.Text),
+			Severity: &severity,
+			Code:     problem.Rule,
+			Message:  problem.Message,
+		})
+	}
+	return res
+}
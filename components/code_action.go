@@ -0,0 +1,234 @@
+package components
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/walteh/protobuf-language-server/proto/view"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+// CodeAction offers quick fixes for the diagnostics in the requested range of
+// a proto file. An unresolved type can be fixed by importing a file of the
+// workspace, or an embedded well-known type, that declares it, and unused,
+// duplicate and self imports by removing them. The organize imports source
+// action sorts and dedupes the imports of the whole file.
+func CodeAction(ctx context.Context, req *defines.CodeActionParams) (result *[]defines.CodeAction, err error) {
+	res := []defines.CodeAction{}
+	if !view.IsProtoFile(req.TextDocument.Uri) {
+		return &res, nil
+	}
+	v := view.FromContext(ctx)
+	proto_file, err := v.GetFile(req.TextDocument.Uri)
+	if err != nil {
+		return nil, err
+	}
+	if proto_file.Proto() == nil {
+		return &res, nil
+	}
+
+	if codeActionKindRequested(req.Context.Only, defines.CodeActionKindQuickFix) {
+		res = append(res, addImportActions(v, proto_file, req)...)
+		res = append(res, removeImportActions(proto_file, req)...)
+	}
+	if codeActionKindRequested(req.Context.Only, defines.CodeActionKindSourceOrganizeImports) {
+		if edit, ok := organizeImportsEdit(proto_file); ok {
+			kind := defines.CodeActionKindSourceOrganizeImports
+			changes := map[string][]defines.TextEdit{string(proto_file.URI()): {edit}}
+			res = append(res, defines.CodeAction{
+				Title: "Organize imports",
+				Kind:  &kind,
+				Edit:  &defines.WorkspaceEdit{Changes: &changes},
+			})
+		}
+	}
+	return &res, nil
+}
+
+// codeActionKindRequested reports whether kind is one of the kinds in only,
+// or a sub kind of one, e.g. quickfix is part of an empty only.
+func codeActionKindRequested(only *[]defines.CodeActionKind, kind defines.CodeActionKind) bool {
+	if only == nil {
+		return true
+	}
+	for _, requested := range *only {
+		if requested == defines.CodeActionKindEmpty || kind == requested || strings.HasPrefix(string(kind), string(requested)+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// addImportActions returns a quick fix for every file that declares an
+// unresolved type in the requested range. It imports the file in sorted
+// position and qualifies the type if its package differs.
+func addImportActions(v *view.View, proto_file view.ProtoFile, req *defines.CodeActionParams) (res []defines.CodeAction) {
+	symbols := append(v.WorkspaceSymbols(), v.IncludeSymbols()...)
+
+	visitTypeReferences(proto_file, func(typ string, pos scanner.Position, anchor string) {
+		rng, ok := locateWord(proto_file, pos.Line-1, pos.Column-1, anchor, typ)
+		if !ok || !rangesOverlap(rng, req.Range) || len(resolveType(proto_file, typ, pos.Line)) > 0 {
+			return
+		}
+
+		var actions []defines.CodeAction
+		seen := make(map[string]bool)
+		name := strings.TrimPrefix(typ, ".")
+		for _, symbol := range symbols {
+			if symbol.Kind != defines.SymbolKindClass && symbol.Kind != defines.SymbolKindEnum {
+				continue
+			}
+			if symbol.FullName != name && !strings.HasSuffix(symbol.FullName, "."+name) {
+				continue
+			}
+			if symbol.Location.Uri == proto_file.URI() {
+				continue
+			}
+			import_path, ok := v.ImportPath(proto_file.URI(), symbol.Location.Uri)
+			if !ok || seen[import_path+" "+symbol.FullName] {
+				continue
+			}
+			seen[import_path+" "+symbol.FullName] = true
+
+			var edits []defines.TextEdit
+			var titles []string
+			if !importsFile(proto_file, import_path) {
+				edits = append(edits, importEdit(proto_file, import_path))
+				titles = append(titles, fmt.Sprintf("Import %q", import_path))
+			}
+			if !resolvesAfterImport(proto_file, typ, pos.Line, symbol) {
+				edits = append(edits, defines.TextEdit{Range: rng, NewText: symbol.FullName})
+				titles = append(titles, fmt.Sprintf("change %s to %s", typ, symbol.FullName))
+			}
+			if len(edits) == 0 {
+				continue
+			}
+			title := strings.Join(titles, " and ")
+			kind := defines.CodeActionKindQuickFix
+			changes := map[string][]defines.TextEdit{string(proto_file.URI()): edits}
+			actions = append(actions, defines.CodeAction{
+				Title:       strings.ToUpper(title[:1]) + title[1:],
+				Kind:        &kind,
+				Diagnostics: matchingDiagnostics(req.Context.Diagnostics, rng),
+				Edit:        &defines.WorkspaceEdit{Changes: &changes},
+			})
+		}
+		if len(actions) == 1 {
+			preferred := true
+			actions[0].IsPreferred = &preferred
+		}
+		res = append(res, actions...)
+	})
+	return res
+}
+
+// removeImportActions returns a quick fix removing every unused, duplicate
+// or self import in the requested range, see Imports.
+func removeImportActions(proto_file view.ProtoFile, req *defines.CodeActionParams) (res []defines.CodeAction) {
+	for _, problem := range importProblems(proto_file) {
+		rng := importRange(proto_file, problem.Import)
+		if !rangesOverlap(rng, req.Range) {
+			continue
+		}
+		kind := defines.CodeActionKindQuickFix
+		preferred := true
+		changes := map[string][]defines.TextEdit{string(proto_file.URI()): {removeImportEdit(problem.Import)}}
+		res = append(res, defines.CodeAction{
+			Title:       fmt.Sprintf("Remove import %q", problem.Import.Filename),
+			Kind:        &kind,
+			Diagnostics: matchingDiagnostics(req.Context.Diagnostics, rng),
+			IsPreferred: &preferred,
+			Edit:        &defines.WorkspaceEdit{Changes: &changes},
+		})
+	}
+	return res
+}
+
+// resolvesAfterImport reports whether typ, used on the provided line of
+// proto_file, refers to symbol once the file declaring it is imported, see
+// resolveType.
+func resolvesAfterImport(proto_file view.ProtoFile, typ string, line int, symbol view.Symbol) bool {
+	declaring, err := proto_file.View().GetFile(symbol.Location.Uri)
+	if err != nil || declaring.Proto() == nil {
+		return false
+	}
+	files := append(visibleFiles(proto_file), declaring)
+	for _, resolved := range resolveTypeIn(files, typ, proto_file.Proto().GetScopeByLine(line)) {
+		if fullyQualifiedName(resolved) == symbol.FullName {
+			return true
+		}
+	}
+	return false
+}
+
+func importsFile(proto_file view.ProtoFile, import_path string) bool {
+	for _, im := range proto_file.Proto().Imports() {
+		if im.ProtoImport.Filename == import_path {
+			return true
+		}
+	}
+	return false
+}
+
+// importEdit inserts an import of import_path before the first import that
+// sorts after it, or after the last import. Files without imports get it
+// after the package, syntax or edition statement.
+func importEdit(proto_file view.ProtoFile, import_path string) defines.TextEdit {
+	statement := fmt.Sprintf("import %q;\n", import_path)
+	insert := func(line int, text string) defines.TextEdit {
+		pos := defines.Position{Line: uint(max(line, 0))}
+		return defines.TextEdit{Range: defines.Range{Start: pos, End: pos}, NewText: text}
+	}
+
+	imports := proto_file.Proto().Imports()
+	for _, im := range imports {
+		if im.ProtoImport.Filename > import_path {
+			line, _ := importLines(im.ProtoImport)
+			return insert(line-1, statement)
+		}
+	}
+	if len(imports) > 0 {
+		return insert(imports[len(imports)-1].ProtoImport.Position.Line, statement)
+	}
+
+	line := 0
+	for _, e := range proto_file.Proto().Protobuf().Elements {
+		switch v := e.(type) {
+		case *protobuf.Syntax:
+			line = max(line, v.Position.Line)
+		case *protobuf.Edition:
+			line = max(line, v.Position.Line)
+		case *protobuf.Package:
+			line = max(line, v.Position.Line)
+		}
+	}
+	if line == 0 {
+		return insert(0, statement+"\n")
+	}
+	return insert(line, "\n"+statement)
+}
+
+func rangesOverlap(a, b defines.Range) bool {
+	before := func(x, y defines.Position) bool {
+		return x.Line < y.Line || (x.Line == y.Line && x.Character < y.Character)
+	}
+	return !before(a.End, b.Start) && !before(b.End, a.Start)
+}
+
+// matchingDiagnostics returns the diagnostics reported at rng.
+func matchingDiagnostics(diagnostics []defines.Diagnostic, rng defines.Range) *[]defines.Diagnostic {
+	var res []defines.Diagnostic
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Range == rng {
+			res = append(res, diagnostic)
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return &res
+}
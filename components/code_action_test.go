@@ -0,0 +1,139 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+func codeActions(t *testing.T, document_uri defines.DocumentUri, line uint) []defines.CodeAction {
+	t.Helper()
+	res, err := CodeAction(context.Background(), &defines.CodeActionParams{
+		TextDocument: defines.TextDocumentIdentifier{Uri: document_uri},
+		Range: defines.Range{
+			Start: defines.Position{Line: line},
+			End:   defines.Position{Line: line, Character: 100},
+		},
+	})
+	require.NoError(t, err)
+	return *res
+}
+
+func TestCodeActionAddImport(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"common/user.proto": `syntax = "proto3";
+package common;
+
+message User {
+	string name = 1;
+	enum Status {
+		STATUS_UNSPECIFIED = 0;
+	}
+}
+`,
+		"api.proto": `syntax = "proto3";
+package api;
+
+import "b.proto";
+import "z.proto";
+
+message GetUserResponse {
+	User user = 1;
+	common.User.Status status = 2;
+	Duration timeout = 3;
+	.google.protobuf.Timestamp created = 4;
+}
+`,
+		"b.proto": `syntax = "proto3";
+`,
+		"z.proto": `syntax = "proto3";
+`,
+	})
+	api := uris["api.proto"]
+	at := func(line, from, to uint) defines.Range {
+		return defines.Range{Start: defines.Position{Line: line, Character: from}, End: defines.Position{Line: line, Character: to}}
+	}
+	importAt := func(line uint, import_path string) defines.TextEdit {
+		return defines.TextEdit{Range: at(line, 0, 0), NewText: "import \"" + import_path + "\";\n"}
+	}
+	edits := func(action defines.CodeAction) []defines.TextEdit {
+		return (*action.Edit.Changes)[string(api)]
+	}
+
+	actions := codeActions(t, api, 7)
+	require.Len(t, actions, 1)
+	require.Equal(t, `Import "common/user.proto" and change User to common.User`, actions[0].Title)
+	require.Equal(t, defines.CodeActionKindQuickFix, *actions[0].Kind)
+	require.True(t, *actions[0].IsPreferred)
+	require.Equal(t, []defines.TextEdit{
+		importAt(4, "common/user.proto"),
+		{Range: at(7, 1, 5), NewText: "common.User"},
+	}, edits(actions[0]))
+
+	// already qualified
+	actions = codeActions(t, api, 8)
+	require.Len(t, actions, 1)
+	require.Equal(t, `Import "common/user.proto"`, actions[0].Title)
+	require.Equal(t, []defines.TextEdit{importAt(4, "common/user.proto")}, edits(actions[0]))
+
+	// embedded well-known types
+	actions = codeActions(t, api, 9)
+	require.Len(t, actions, 1)
+	require.Equal(t, []defines.TextEdit{
+		importAt(4, "google/protobuf/duration.proto"),
+		{Range: at(9, 1, 9), NewText: "google.protobuf.Duration"},
+	}, edits(actions[0]))
+	actions = codeActions(t, api, 10)
+	require.Len(t, actions, 1)
+	require.Equal(t, []defines.TextEdit{importAt(4, "google/protobuf/timestamp.proto")}, edits(actions[0]))
+
+	// the diagnostic the fix resolves is attached
+	severity := defines.DiagnosticSeverityError
+	diagnostic := defines.Diagnostic{Range: at(7, 1, 5), Severity: &severity, Message: "unresolved type User"}
+	res, err := CodeAction(context.Background(), &defines.CodeActionParams{
+		TextDocument: defines.TextDocumentIdentifier{Uri: api},
+		Range:        at(7, 2, 2),
+		Context:      defines.CodeActionContext{Diagnostics: []defines.Diagnostic{diagnostic}},
+	})
+	require.NoError(t, err)
+	require.Len(t, *res, 1)
+	require.Equal(t, []defines.Diagnostic{diagnostic}, *(*res)[0].Diagnostics)
+
+	// resolved types and other kinds need no fix
+	require.Empty(t, codeActions(t, uris["common/user.proto"], 4))
+	only := []defines.CodeActionKind{defines.CodeActionKindRefactor}
+	res, err = CodeAction(context.Background(), &defines.CodeActionParams{
+		TextDocument: defines.TextDocumentIdentifier{Uri: api},
+		Range:        at(7, 0, 100),
+		Context:      defines.CodeActionContext{Only: &only},
+	})
+	require.NoError(t, err)
+	require.Empty(t, *res)
+}
+
+func TestCodeActionAddFirstImport(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"common.proto": `syntax = "proto3";
+package common;
+
+message User {}
+`,
+		"api.proto": `syntax = "proto3";
+package common;
+
+message Request {
+	User user = 1;
+}
+`,
+	})
+	actions := codeActions(t, uris["api.proto"], 4)
+	require.Len(t, actions, 1)
+	require.Equal(t, `Import "common.proto"`, actions[0].Title)
+	require.Equal(t, []defines.TextEdit{{
+		Range:   defines.Range{Start: defines.Position{Line: 2}, End: defines.Position{Line: 2}},
+		NewText: "\nimport \"common.proto\";\n",
+	}}, (*actions[0].Edit.Changes)[string(uris["api.proto"])])
+}
@@ -2,9 +2,12 @@ package components
 
 import (
 	"context"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/walteh/protobuf-language-server/proto/parser"
 	"github.com/walteh/protobuf-language-server/proto/view"
 
 	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
@@ -17,15 +20,23 @@ var (
 	kindModule  = defines.CompletionItemKindModule
 	kindClass   = defines.CompletionItemKindClass
 	kindEnum    = defines.CompletionItemKindEnum
+	kindField   = defines.CompletionItemKindField
+
+	kindEnumMember = defines.CompletionItemKindEnumMember
 
 	defaultCompletionTimeout = time.Millisecond * 500
+
+	// optionNamePrefix matches the text before the cursor in the parentheses
+	// of a custom option name, the group is the part of the extension name
+	// typed so far.
+	optionNamePrefix = regexp.MustCompile(`(?:\boption\s*|[\[,]\s*)\(\s*([\w.]*)$`)
 )
 
 func init() {
 	for _, keyword := range []string{"string", "bytes", "double", "float", "int32", "int64",
 		"uint32", "uint64", "sint32", "sint64", "fixed32", "fixed64", "sfixed32", "sfixed64", "bool",
 		"message", "enum", "service", "rpc", "optional", "repeated", "required",
-		"option", "default", "syntax", "package", "import", "extend", "oneof", "map", "reserved",
+		"option", "default", "syntax", "edition", "package", "import", "extend", "oneof", "map", "reserved",
 	} {
 		insertText := keyword
 		protoKeywordCompletionItems = append(protoKeywordCompletionItems, defines.CompletionItem{
@@ -43,12 +54,23 @@ func Completion(ctx context.Context, req *defines.CompletionParams) (*[]defines.
 	ctx, cancel := context.WithTimeout(ctx, defaultCompletionTimeout)
 	defer cancel()
 
-	proto_file, err := view.ViewManager.GetFile(req.TextDocument.Uri)
+	proto_file, err := view.FromContext(ctx).GetFile(req.TextDocument.Uri)
 	if err != nil || proto_file.Proto() == nil {
 		return nil, nil
 	}
 	line_str := proto_file.ReadLine(int(req.Position.Line))
-	wordWithDot := getWord(line_str, int(req.Position.Character-1), true)
+	cursor := parser.UTF16Offset(line_str, req.Position.Character)
+	wordWithDot := getWord(line_str, cursor-1, true)
+
+	before := line_str[:cursor]
+	// only extensions are named in the parentheses of a custom option
+	if match := optionNamePrefix.FindStringSubmatch(before); match != nil {
+		res := CompletionInOptionName(ctx, proto_file, match[1])
+		return &res, nil
+	}
+	if res, ok := CompletionInFeatureOption(proto_file, before); ok {
+		return &res, nil
+	}
 
 	var res []defines.CompletionItem
 
@@ -63,17 +85,35 @@ func Completion(ctx context.Context, req *defines.CompletionParams) (*[]defines.
 	}
 
 	if req.Context.TriggerKind != defines.CompletionTriggerKindTriggerCharacter {
-		res = append(res, protoKeywordCompletionItems...)
+		res = append(res, keywordCompletionItems(proto_file)...)
 		res = append(res, CompletionInThisFile(ctx, proto_file)...)
 		return &res, err
 	}
 
 	packageName := strings.TrimSuffix(wordWithDot, ".")
-	res = append(res, CompletionInPackage(ctx, proto_file, packageName)...)
+	res = append(res, CompletionInPackage(ctx, proto_file, packageName, int(req.Position.Line+1))...)
 
 	return &res, nil
 }
 
+// editionsReplacedKeywords are the keywords files using editions replace by
+// features or the edition declaration.
+var editionsReplacedKeywords = []string{"optional", "required", "syntax"}
+
+// keywordCompletionItems returns the keywords that can be used in file.
+func keywordCompletionItems(file view.ProtoFile) []defines.CompletionItem {
+	if file.Proto().Syntax() != "editions" {
+		return protoKeywordCompletionItems
+	}
+	res := make([]defines.CompletionItem, 0, len(protoKeywordCompletionItems))
+	for _, item := range protoKeywordCompletionItems {
+		if !slices.Contains(editionsReplacedKeywords, item.Label) {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
 func GetImportedPackages(ctx context.Context, proto_file view.ProtoFile) (res []defines.CompletionItem) {
 	unique := make(map[string]struct{})
 	for _, im := range proto_file.Proto().Imports() {
@@ -83,12 +123,12 @@ func GetImportedPackages(ctx context.Context, proto_file view.ProtoFile) (res []
 		default:
 		}
 
-		import_uri, err := view.ViewManager.GetDocumentUriFromImportPath(proto_file.URI(), im.ProtoImport.Filename)
+		import_uri, err := proto_file.View().GetDocumentUriFromImportPath(proto_file.URI(), im.ProtoImport.Filename)
 		if err != nil {
 			continue
 		}
 
-		file, err := view.ViewManager.GetFile(import_uri)
+		file, err := proto_file.View().GetFile(import_uri)
 		if err != nil {
 			continue
 		}
@@ -115,33 +155,26 @@ func GetImportedPackages(ctx context.Context, proto_file view.ProtoFile) (res []
 	return res
 }
 
-func CompletionInPackage(ctx context.Context, file view.ProtoFile, packageName string) (res []defines.CompletionItem) {
-	for _, im := range file.Proto().Imports() {
+// CompletionInPackage returns the types of the files visible from file whose
+// package is packageName, which is resolved like a type name used on the
+// provided line (1-based), so it may be partially qualified or relative to
+// the package of file.
+func CompletionInPackage(ctx context.Context, file view.ProtoFile, packageName string, line int) (res []defines.CompletionItem) {
+	files := visibleFiles(file)
+	pkg, ok := newResolver(files).ResolvePackage(packageName, file.Proto().GetScopeByLine(line))
+	if !ok {
+		return nil
+	}
+	for _, visible := range files {
 		select {
 		case <-ctx.Done():
 			return
 		default:
 		}
 
-		import_uri, err := view.ViewManager.GetDocumentUriFromImportPath(file.URI(), im.ProtoImport.Filename)
-		if err != nil {
-			continue
-		}
-
-		imported_file, err := view.ViewManager.GetFile(import_uri)
-		if err != nil {
-			continue
+		if packages := visible.Proto().Packages(); len(packages) > 0 && packages[0].ProtoPackage.Name == pkg {
+			res = append(res, CompletionInThisFile(ctx, visible)...)
 		}
-
-		if len(imported_file.Proto().Packages()) == 0 {
-			continue
-		}
-
-		importedPackage := imported_file.Proto().Packages()[0].ProtoPackage.Name
-		if importedPackage == packageName {
-			res = append(res, CompletionInThisFile(ctx, imported_file)...)
-		}
-
 	}
 	return res
 }
@@ -190,3 +223,40 @@ func CompletionInThisFile(ctx context.Context, file view.ProtoFile) (res []defin
 	}
 	return res
 }
+
+// CompletionInOptionName suggests the extensions visible in file whose fully
+// qualified name starts with prefix, the name typed so far in the parentheses
+// of a custom option. The part of prefix up to its last dot is not inserted
+// again.
+func CompletionInOptionName(ctx context.Context, file view.ProtoFile, prefix string) (res []defines.CompletionItem) {
+	prefix = strings.TrimPrefix(prefix, ".")
+	typed := prefix[:strings.LastIndex(prefix, ".")+1]
+	for _, visible := range visibleFiles(file) {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		for _, extend := range visible.Proto().Extends() {
+			for _, f := range extend.Fields() {
+				if !strings.HasPrefix(f.FullyQualifiedName, prefix) {
+					continue
+				}
+				name := strings.TrimPrefix(f.FullyQualifiedName, typed)
+				detail := f.FullyQualifiedName
+				res = append(res, defines.CompletionItem{
+					Label:      name,
+					Kind:       &kindField,
+					Detail:     &detail,
+					InsertText: &name,
+					Documentation: defines.MarkupContent{
+						Kind:  defines.MarkupKindMarkdown,
+						Value: formatHover(SymbolDefinition{Type: DefinitionTypeExtension, Extension: &parser.Extension{Extend: extend, Field: f}}),
+					},
+				})
+			}
+		}
+	}
+	return res
+}
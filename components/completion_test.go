@@ -0,0 +1,55 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+func TestCompletionInPackage(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"common.proto": `syntax = "proto3";
+package acme.common;
+
+message Status {}
+`,
+		"api.proto": `syntax = "proto3";
+package acme.api;
+
+import "common.proto";
+
+message User {
+	common.
+	api.
+	acme.common.
+	.acme.common.
+	User.
+}
+`,
+	})
+	complete := func(line, character uint) (res []string) {
+		items, err := Completion(context.Background(), &defines.CompletionParams{
+			TextDocumentPositionParams: defines.TextDocumentPositionParams{
+				TextDocument: defines.TextDocumentIdentifier{Uri: uris["api.proto"]},
+				Position:     defines.Position{Line: line, Character: character},
+			},
+			Context: &defines.CompletionContext{TriggerKind: defines.CompletionTriggerKindTriggerCharacter},
+		})
+		require.NoError(t, err)
+		for _, item := range *items {
+			res = append(res, item.Label)
+		}
+		return res
+	}
+	// relative to the package of the file
+	require.Equal(t, []string{"Status"}, complete(6, 8))
+	require.Equal(t, []string{"User"}, complete(7, 5))
+	// partially and fully qualified
+	require.Equal(t, []string{"Status"}, complete(8, 13))
+	require.Equal(t, []string{"Status"}, complete(9, 14))
+	// a message is not a package
+	require.Empty(t, complete(10, 6))
+}
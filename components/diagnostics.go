@@ -0,0 +1,239 @@
+package components
+
+import (
+	"fmt"
+	"strconv"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/walteh/protobuf-language-server/proto/view"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+const (
+	// maxFieldNumber is the largest field number, written as max in reserved
+	// ranges.
+	maxFieldNumber = 536870911
+
+	firstImplementationReservedNumber = 19000
+	lastImplementationReservedNumber  = 19999
+)
+
+// Diagnostics validates the semantics of a parsed proto file: type references
+// and the extensions of custom options must resolve, field numbers and names
+// must be unique and outside reserved ranges, extensions must use a number of
+// an extension range of the extended message, features must be valid, open
+// enums must start with zero and enum values may only repeat with
+// allow_alias.
+func Diagnostics(proto_file view.ProtoFile) []defines.Diagnostic {
+	if proto_file.Proto() == nil {
+		return nil
+	}
+	d := &diagnoser{file: proto_file}
+	d.checkTypeReferences()
+	d.checkOptionExtensions()
+	d.checkExtends()
+	d.checkFeatures()
+	d.checkElements(proto_file.Proto().Protobuf().Elements)
+	return d.diagnostics
+}
+
+type diagnoser struct {
+	file        view.ProtoFile
+	diagnostics []defines.Diagnostic
+}
+
+// add reports message at text, which is searched after anchor starting at pos,
+// see locateWord.
+func (d *diagnoser) add(pos scanner.Position, anchor, text, message string) {
+	rng, ok := locateWord(d.file, pos.Line-1, pos.Column-1, anchor, text)
+	if !ok {
+		rng = defines.Range{Start: positionOf(d.file, pos), End: positionOf(d.file, pos)}
+	}
+	d.report(rng, message)
+}
+
+// addName reports message at the name of the element e, which is declared at
+// pos.
+func (d *diagnoser) addName(e protobuf.Visitee, pos scanner.Position, name, message string) {
+	name_range, _ := declarationRanges(d.file, e, pos, name)
+	d.report(name_range, message)
+}
+
+func (d *diagnoser) report(rng defines.Range, message string) {
+	severity := defines.DiagnosticSeverityError
+	d.diagnostics = append(d.diagnostics, defines.Diagnostic{
+		Range:    rng,
+		Severity: &severity,
+		Message:  message,
+	})
+}
+
+func (d *diagnoser) checkTypeReferences() {
+	visitTypeReferences(d.file, func(typ string, pos scanner.Position, anchor string) {
+		if len(resolveType(d.file, typ, pos.Line)) == 0 {
+			d.add(pos, anchor, typ, fmt.Sprintf("unresolved type %s", typ))
+		}
+	})
+}
+
+func (d *diagnoser) checkOptionExtensions() {
+	for _, extension := range optionExtensionsInFile(d.file) {
+		if len(resolveExtension(d.file, extension.Name, extension.Option.Position.Line)) == 0 {
+			d.report(extension.Range, fmt.Sprintf("unresolved extension %s", extension.Name))
+		}
+	}
+}
+
+// checkExtends reports extensions whose number is outside the extension
+// ranges of the message they extend. Unresolved extended messages are
+// reported by checkTypeReferences.
+func (d *diagnoser) checkExtends() {
+	for _, extend := range d.file.Proto().Extends() {
+		symbols := resolveType(d.file, extend.Extendee(), extend.Protobuf().Position.Line)
+		if len(symbols) == 0 || symbols[0].Type != DefinitionTypeMessage {
+			continue
+		}
+		extendee := symbols[0].Message
+	fields:
+		for _, f := range extend.Fields() {
+			for _, extensions := range extendee.Extensions() {
+				if extensions.Contains(f.ProtoField.Sequence) {
+					continue fields
+				}
+			}
+			d.add(f.ProtoField.Position, "=", strconv.Itoa(f.ProtoField.Sequence), fmt.Sprintf("field number %d is not in an extension range of message %s", f.ProtoField.Sequence, extendee.FullyQualifiedName()))
+		}
+	}
+}
+
+func (d *diagnoser) checkElements(elements []protobuf.Visitee) {
+	for _, e := range elements {
+		switch v := e.(type) {
+		case *protobuf.Message:
+			if !v.IsExtend {
+				d.checkMessage(v.Name, v.Elements)
+			}
+			d.checkElements(v.Elements)
+		case *protobuf.Group:
+			d.checkMessage(v.Name, v.Elements)
+			d.checkElements(v.Elements)
+		case *protobuf.Enum:
+			d.checkEnum(v)
+		}
+	}
+}
+
+// messageField is a field of any kind declared in a message.
+type messageField struct {
+	Name     string
+	Number   int
+	Position scanner.Position
+	Element  protobuf.Visitee
+}
+
+func messageFields(elements []protobuf.Visitee) (fields []messageField) {
+	for _, e := range elements {
+		switch v := e.(type) {
+		case *protobuf.NormalField:
+			fields = append(fields, messageField{v.Name, v.Sequence, v.Position, v})
+		case *protobuf.MapField:
+			fields = append(fields, messageField{v.Name, v.Sequence, v.Position, v})
+		case *protobuf.OneOfField:
+			fields = append(fields, messageField{v.Name, v.Sequence, v.Position, v})
+		case *protobuf.Group:
+			fields = append(fields, messageField{v.Name, v.Sequence, v.Position, v})
+		case *protobuf.Oneof:
+			fields = append(fields, messageFields(v.Elements)...)
+		}
+	}
+	return fields
+}
+
+func (d *diagnoser) checkMessage(name string, elements []protobuf.Visitee) {
+	var reserved []*protobuf.Reserved
+	for _, e := range elements {
+		if r, ok := e.(*protobuf.Reserved); ok {
+			reserved = append(reserved, r)
+		}
+	}
+
+	numbers := make(map[int]string)
+	names := make(map[string]bool)
+	for _, f := range messageFields(elements) {
+		number := strconv.Itoa(f.Number)
+		if names[f.Name] {
+			d.addName(f.Element, f.Position, f.Name, fmt.Sprintf("field %s is already defined in message %s", f.Name, name))
+		}
+		names[f.Name] = true
+
+		if other, ok := numbers[f.Number]; ok {
+			d.add(f.Position, "=", number, fmt.Sprintf("field number %d is already used by %s in message %s", f.Number, other, name))
+		} else {
+			numbers[f.Number] = f.Name
+		}
+		if f.Number < 1 || f.Number > maxFieldNumber {
+			d.add(f.Position, "=", number, fmt.Sprintf("field number %d is out of range 1 to %d", f.Number, maxFieldNumber))
+		}
+		if f.Number >= firstImplementationReservedNumber && f.Number <= lastImplementationReservedNumber {
+			d.add(f.Position, "=", number, fmt.Sprintf("field numbers %d to %d are reserved for the protocol buffer implementation", firstImplementationReservedNumber, lastImplementationReservedNumber))
+		}
+
+		for _, r := range reserved {
+			for _, rng := range r.Ranges {
+				to := rng.To
+				if rng.Max {
+					to = maxFieldNumber
+				}
+				if f.Number >= rng.From && f.Number <= to {
+					d.add(f.Position, "=", number, fmt.Sprintf("field number %d is reserved by %q in message %s", f.Number, rng.SourceRepresentation(), name))
+				}
+			}
+			for _, reserved_name := range r.FieldNames {
+				if f.Name == reserved_name {
+					d.addName(f.Element, f.Position, f.Name, fmt.Sprintf("field name %s is reserved in message %s", f.Name, name))
+				}
+			}
+		}
+	}
+}
+
+func (d *diagnoser) checkEnum(enum *protobuf.Enum) {
+	allow_alias := false
+	var values []*protobuf.EnumField
+	for _, e := range enum.Elements {
+		switch v := e.(type) {
+		case *protobuf.Option:
+			if v.Name == "allow_alias" && v.Constant.Source == "true" {
+				allow_alias = true
+			}
+		case *protobuf.EnumField:
+			values = append(values, v)
+		}
+	}
+
+	// open enums need a zero default value
+	if features, ok := d.file.Proto().Features(enum); ok && features.Values["enum_type"] == "OPEN" && len(values) > 0 && values[0].Integer != 0 {
+		message := fmt.Sprintf("the first value of open enum %s must be zero", enum.Name)
+		if d.file.Proto().Syntax() == "proto3" {
+			message = fmt.Sprintf("the first value of enum %s must be zero in proto3", enum.Name)
+		}
+		d.add(values[0].Position, "=", strconv.Itoa(values[0].Integer), message)
+	}
+
+	numbers := make(map[int]string)
+	names := make(map[string]bool)
+	for _, v := range values {
+		if names[v.Name] {
+			d.addName(v, v.Position, v.Name, fmt.Sprintf("enum value %s is already defined in enum %s", v.Name, enum.Name))
+		}
+		names[v.Name] = true
+
+		if other, ok := numbers[v.Integer]; ok && !allow_alias {
+			d.add(v.Position, "=", strconv.Itoa(v.Integer), fmt.Sprintf("enum value %d is already used by %s in enum %s, set option allow_alias = true to alias it", v.Integer, other, enum.Name))
+		} else if !ok {
+			numbers[v.Integer] = v.Name
+		}
+	}
+}
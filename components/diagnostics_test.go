@@ -0,0 +1,69 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/view"
+)
+
+func TestDiagnostics(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"api.proto": `syntax = "proto3";
+package api;
+
+message User {
+	reserved 5 to 10;
+	reserved "legacy";
+	string name = 1;
+	string name = 2;
+	int32 age = 2;
+	Missing missing = 7;
+	string legacy = 19500;
+	map<string, common.Group> groups = 11;
+}
+
+enum Status {
+	ACTIVE = 1;
+	BANNED = 1;
+}
+
+enum Alias {
+	option allow_alias = true;
+	UNKNOWN = 0;
+	DEFAULT = 0;
+}
+
+service Users {
+	rpc Watch(stream .api.User) returns (stream .api.Gone);
+}
+`,
+	})
+	proto_file, err := view.FromContext(context.Background()).GetFile(uris["api.proto"])
+	require.NoError(t, err)
+
+	type diagnostic struct {
+		Line, Start, End uint
+		Message          string
+	}
+	var got []diagnostic
+	for _, d := range Diagnostics(proto_file) {
+		got = append(got, diagnostic{d.Range.Start.Line, d.Range.Start.Character, d.Range.End.Character, d.Message})
+		require.Equal(t, defines.DiagnosticSeverityError, *d.Severity)
+	}
+	require.Equal(t, []diagnostic{
+		{9, 1, 8, "unresolved type Missing"},
+		{11, 13, 25, "unresolved type common.Group"},
+		{26, 45, 54, "unresolved type .api.Gone"},
+		{7, 8, 12, "field name is already defined in message User"},
+		{8, 13, 14, "field number 2 is already used by name in message User"},
+		{9, 19, 20, `field number 7 is reserved by "5 to 10" in message User`},
+		{10, 17, 22, "field numbers 19000 to 19999 are reserved for the protocol buffer implementation"},
+		{10, 8, 14, "field name legacy is reserved in message User"},
+		{15, 10, 11, "the first value of enum Status must be zero in proto3"},
+		{16, 10, 11, "enum value 1 is already used by ACTIVE in enum Status, set option allow_alias = true to alias it"},
+	}, got)
+}
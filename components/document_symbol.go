@@ -2,7 +2,9 @@ package components
 
 import (
 	"context"
+	"text/scanner"
 
+	protobuf "github.com/emicklei/proto"
 	"github.com/walteh/protobuf-language-server/proto/view"
 
 	"github.com/walteh/protobuf-language-server/go-lsp/logs"
@@ -13,107 +15,48 @@ func ProvideDocumentSymbol(ctx context.Context, req *defines.DocumentSymbolParam
 	if !view.IsProtoFile(req.TextDocument.Uri) {
 		return nil, nil
 	}
-	file, err := view.ViewManager.GetFile(req.TextDocument.Uri)
+	file, err := view.FromContext(ctx).GetFile(req.TextDocument.Uri)
 	res := []defines.DocumentSymbol{}
 	if err != nil {
 		logs.Printf("GetFile err: %v", err)
 		return &res, nil
 	}
+	if file.Proto() == nil {
+		return &res, nil
+	}
 	for _, pack := range file.Proto().Packages() {
-		res = append(res, defines.DocumentSymbol{
-			Name: pack.ProtoPackage.Name,
-			Kind: defines.SymbolKindPackage,
-			SelectionRange: defines.Range{
-				Start: defines.Position{Line: uint(pack.ProtoPackage.Position.Line - 1)},
-				End:   defines.Position{Line: uint(pack.ProtoPackage.Position.Line - 1)},
-			},
-			Range: defines.Range{
-				Start: defines.Position{Line: uint(pack.ProtoPackage.Position.Line - 1)},
-				End:   defines.Position{Line: uint(pack.ProtoPackage.Position.Line - 1)},
-			},
-		})
+		res = append(res, documentSymbol(file, pack.ProtoPackage, pack.ProtoPackage.Position, pack.ProtoPackage.Name, defines.SymbolKindPackage))
 	}
 	for _, imp := range file.Proto().Imports() {
-		res = append(res, defines.DocumentSymbol{
-			Name: imp.ProtoImport.Filename,
-			Kind: defines.SymbolKindFile,
-			SelectionRange: defines.Range{
-				Start: defines.Position{Line: uint(imp.ProtoImport.Position.Line - 1)},
-				End:   defines.Position{Line: uint(imp.ProtoImport.Position.Line - 1)},
-			},
-			Range: defines.Range{
-				Start: defines.Position{Line: uint(imp.ProtoImport.Position.Line - 1)},
-				End:   defines.Position{Line: uint(imp.ProtoImport.Position.Line - 1)},
-			},
-		})
-
+		res = append(res, documentSymbol(file, imp.ProtoImport, imp.ProtoImport.Position, imp.ProtoImport.Filename, defines.SymbolKindFile))
 	}
 	for _, enums := range file.Proto().Enums() {
-		res = append(res, defines.DocumentSymbol{
-			Name: enums.Protobuf().Name,
-			Kind: defines.SymbolKindEnum,
-			SelectionRange: defines.Range{
-				Start: defines.Position{Line: uint(enums.Protobuf().Position.Line - 1)},
-				End:   defines.Position{Line: uint(enums.Protobuf().Position.Line - 1)},
-			},
-			Range: defines.Range{
-				Start: defines.Position{Line: uint(enums.Protobuf().Position.Line - 1)},
-				End:   defines.Position{Line: uint(enums.Protobuf().Position.Line - 1)},
-			},
-		})
+		res = append(res, documentSymbol(file, enums.Protobuf(), enums.Protobuf().Position, enums.Protobuf().Name, defines.SymbolKindEnum))
 	}
 	for _, message := range file.Proto().Messages() {
 		message_proto := message.Protobuf()
-		res = append(res, defines.DocumentSymbol{
-			Name: message_proto.Name,
-			Kind: defines.SymbolKindClass,
-			SelectionRange: defines.Range{
-				Start: defines.Position{Line: uint(message_proto.Position.Line - 1)},
-				End:   defines.Position{Line: uint(message_proto.Position.Line - 1)},
-			},
-			Range: defines.Range{
-				Start: defines.Position{Line: uint(message_proto.Position.Line - 1)},
-				End:   defines.Position{Line: uint(message_proto.Position.Line - 1)},
-			},
-		})
+		res = append(res, documentSymbol(file, message_proto, message_proto.Position, message_proto.Name, defines.SymbolKindClass))
 	}
 	for _, service := range file.Proto().Services() {
-		service_sym := defines.DocumentSymbol{
-			Name: service.Protobuf().Name,
-			Kind: defines.SymbolKindNamespace,
-			SelectionRange: defines.Range{
-				Start: defines.Position{Line: uint(service.Protobuf().Position.Line - 1)},
-				End:   defines.Position{Line: uint(service.Protobuf().Position.Line - 1)},
-			},
-			Range: defines.Range{
-				Start: defines.Position{Line: uint(service.Protobuf().Position.Line - 1)},
-				End:   defines.Position{Line: uint(service.Protobuf().Position.Line - 1)},
-			},
-
-			Children: &[]defines.DocumentSymbol{},
-		}
+		service_sym := documentSymbol(file, service.Protobuf(), service.Protobuf().Position, service.Protobuf().Name, defines.SymbolKindNamespace)
 		child := []defines.DocumentSymbol{}
 		for _, rpc := range service.RPCs() {
-			rpc := defines.DocumentSymbol{
-				Name: rpc.ProtoRPC.Name,
-				Kind: defines.SymbolKindMethod,
-				SelectionRange: defines.Range{
-					Start: defines.Position{Line: uint(rpc.ProtoRPC.Position.Line - 1)},
-					End:   defines.Position{Line: uint(rpc.ProtoRPC.Position.Line - 1)},
-				},
-				Range: defines.Range{
-					Start: defines.Position{Line: uint(rpc.ProtoRPC.Position.Line - 1)},
-					End:   defines.Position{Line: uint(rpc.ProtoRPC.Position.Line - 1)},
-				},
-			}
-			child = append(child, rpc)
+			child = append(child, documentSymbol(file, rpc.ProtoRPC, rpc.ProtoRPC.Position, rpc.ProtoRPC.Name, defines.SymbolKindMethod))
 		}
 		service_sym.Children = &child
 		res = append(res, service_sym)
 	}
-	// for i := 0; i < len(res); i++ {
-	//     res[i].Range = res[i].SelectionRange
-	// }
-	// logs.Printf("dddddddd %+v", res)
 	return &res, nil
 }
+
+// documentSymbol returns the symbol of the element v declared at pos. Its
+// range is the whole declaration and its selection range the name.
+func documentSymbol(file view.ProtoFile, v protobuf.Visitee, pos scanner.Position, name string, kind defines.SymbolKind) defines.DocumentSymbol {
+	name_range, rng := declarationRanges(file, v, pos, name)
+	return defines.DocumentSymbol{
+		Name:           name,
+		Kind:           kind,
+		Range:          rng,
+		SelectionRange: name_range,
+	}
+}
@@ -0,0 +1,63 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+func TestProvideDocumentSymbol(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"api.proto": `syntax = "proto3";
+package api;
+
+message Message { Message Message = 1; }
+
+message
+	User
+{
+	string name = 1;
+}
+
+service Users {
+	rpc Get(User) returns (User) {
+		option deprecated = true;
+	}
+}
+`,
+	})
+	res, err := ProvideDocumentSymbol(context.Background(), &defines.DocumentSymbolParams{
+		TextDocument: defines.TextDocumentIdentifier{Uri: uris["api.proto"]},
+	})
+	require.NoError(t, err)
+
+	rng := func(start_line, start_character, end_line, end_character uint) defines.Range {
+		return defines.Range{
+			Start: defines.Position{Line: start_line, Character: start_character},
+			End:   defines.Position{Line: end_line, Character: end_character},
+		}
+	}
+	type symbol struct {
+		Name                  string
+		Range, SelectionRange defines.Range
+	}
+	var got []symbol
+	for _, s := range *res {
+		got = append(got, symbol{s.Name, s.Range, s.SelectionRange})
+		if s.Children != nil {
+			for _, c := range *s.Children {
+				got = append(got, symbol{c.Name, c.Range, c.SelectionRange})
+			}
+		}
+	}
+	require.Equal(t, []symbol{
+		{"api", rng(1, 0, 1, 12), rng(1, 8, 1, 11)},
+		{"Message", rng(3, 0, 3, 40), rng(3, 8, 3, 15)},
+		{"User", rng(5, 0, 9, 1), rng(6, 1, 6, 5)},
+		{"Users", rng(11, 0, 15, 1), rng(11, 8, 11, 13)},
+		{"Get", rng(12, 1, 14, 2), rng(12, 5, 12, 8)},
+	}, got)
+}
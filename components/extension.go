@@ -0,0 +1,113 @@
+package components
+
+import (
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/walteh/protobuf-language-server/proto/parser"
+	"github.com/walteh/protobuf-language-server/proto/view"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+// optionExtension is the extension set by a custom option, written in
+// parentheses as the first part of the option name, e.g. google.api.http for
+// option (google.api.http).get = "/".
+type optionExtension struct {
+	Option *protobuf.Option
+	// Name is the name of the extension as written, without the parentheses.
+	Name  string
+	Range defines.Range
+}
+
+// optionExtensionsInFile returns the extensions of the custom options set in
+// proto_file, also inline ones of fields and enum values.
+func optionExtensionsInFile(proto_file view.ProtoFile) (res []optionExtension) {
+	walkElements(proto_file.Proto().Protobuf().Elements, func(e protobuf.Visitee) {
+		if o, ok := e.(*protobuf.Option); ok {
+			if extension, ok := optionExtensionOf(proto_file, o); ok {
+				res = append(res, extension)
+			}
+		}
+	})
+	return res
+}
+
+// optionExtensionAt returns the extension of the custom option whose name
+// is under pos.
+func optionExtensionAt(proto_file view.ProtoFile, pos defines.Position) (optionExtension, bool) {
+	at := defines.Range{Start: pos, End: pos}
+	for _, extension := range optionExtensionsInFile(proto_file) {
+		if rangeContains(extension.Range, at) {
+			return extension, true
+		}
+	}
+	return optionExtension{}, false
+}
+
+// optionExtensionOf returns the extension of o if it is a custom option.
+func optionExtensionOf(proto_file view.ProtoFile, o *protobuf.Option) (optionExtension, bool) {
+	if !strings.HasPrefix(o.Name, "(") {
+		return optionExtension{}, false
+	}
+	name_range, ok := proto_file.Proto().NameRange(o)
+	if !ok {
+		return optionExtension{}, false
+	}
+	line_str := proto_file.ReadLine(int(name_range.Start.Line))
+	start := parser.UTF16Offset(line_str, name_range.Start.Character)
+	if start >= len(line_str) || line_str[start] != '(' {
+		return optionExtension{}, false
+	}
+	end := strings.IndexByte(line_str[start:], ')')
+	if end < 0 {
+		return optionExtension{}, false
+	}
+	inner := line_str[start+1 : start+end]
+	name := strings.TrimSpace(inner)
+	if name == "" {
+		return optionExtension{}, false
+	}
+	from := start + 1 + len(inner) - len(strings.TrimLeft(inner, " \t"))
+	return optionExtension{
+		Option: o,
+		Name:   name,
+		Range: defines.Range{
+			Start: defines.Position{Line: name_range.Start.Line, Character: parser.UTF16Character(line_str, from)},
+			End:   defines.Position{Line: name_range.Start.Line, Character: parser.UTF16Character(line_str, from+len(name))},
+		},
+	}, true
+}
+
+// resolveExtension resolves the possibly qualified name of an extension that
+// is used on the provided line (1-based) of proto_file to the field of the
+// extend block declaring it, see parser.Resolver.ResolveExtension.
+func resolveExtension(proto_file view.ProtoFile, name string, line int) []SymbolDefinition {
+	if proto_file.Proto() == nil {
+		return nil
+	}
+	files := visibleFiles(proto_file)
+	t, ok := newResolver(files).ResolveExtension(name, proto_file.Proto().GetScopeByLine(line))
+	if !ok {
+		return nil
+	}
+	for _, file := range files {
+		if file.Proto() == t.Proto {
+			return []SymbolDefinition{extensionSymbolDefinition(file, t.Extension)}
+		}
+	}
+	return nil
+}
+
+func extensionSymbolDefinition(proto_file view.ProtoFile, extension *parser.Extension) SymbolDefinition {
+	field := extension.Field.ProtoField
+	name_range, rng := declarationRanges(proto_file, field, field.Position, field.Name)
+	return SymbolDefinition{
+		Filename:  string(proto_file.URI()),
+		Position:  name_range.Start,
+		NameRange: name_range,
+		Range:     rng,
+		Type:      DefinitionTypeExtension,
+		Extension: extension,
+	}
+}
@@ -0,0 +1,119 @@
+package components
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.lsp.dev/uri"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/view"
+)
+
+func TestOptionExtensions(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"options.proto": `syntax = "proto2";
+package acme.options;
+
+message Options {
+	extensions 100 to 199;
+}
+
+extend Options {
+	// Who owns the file.
+	optional string owner = 100;
+	optional bool sensitive = 200;
+}
+
+message Rules {
+	extend Options {
+		optional Rules rules = 101;
+	}
+	optional int32 min_len = 1;
+}
+`,
+		"api.proto": `syntax = "proto2";
+package acme.api;
+
+import "options.proto";
+
+option (acme.options.owner) = "team";
+option (.acme.options.Rules.rules).min_len = 1;
+
+message User {
+	optional string name = 1 [(options.owner) = "users", ( missing ) = true];
+}
+`,
+	})
+
+	jump := func(line, character uint) string {
+		result, err := JumpDefine(context.Background(), &defines.DefinitionParams{
+			TextDocumentPositionParams: defines.TextDocumentPositionParams{
+				TextDocument: defines.TextDocumentIdentifier{Uri: uris["api.proto"]},
+				Position:     defines.Position{Line: line, Character: character},
+			},
+		})
+		require.NoError(t, err)
+		if len(*result) == 0 {
+			return ""
+		}
+		link := (*result)[0]
+		return fmt.Sprintf("%s:%d:%d", filepath.Base(uri.URI(link.TargetUri).Filename()), link.TargetSelectionRange.Start.Line, link.TargetSelectionRange.Start.Character)
+	}
+	require.Equal(t, "options.proto:9:17", jump(5, 22))
+	require.Equal(t, "options.proto:15:17", jump(6, 30))
+	// relative to the scope of the option
+	require.Equal(t, "options.proto:9:17", jump(9, 38))
+	require.Equal(t, "", jump(9, 60))
+
+	hover, err := Hover(context.Background(), &defines.HoverParams{
+		TextDocumentPositionParams: defines.TextDocumentPositionParams{
+			TextDocument: defines.TextDocumentIdentifier{Uri: uris["api.proto"]},
+			Position:     defines.Position{Line: 5, Character: 22},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "```proto\nextend Options {\n\t// Who owns the file.\n\toptional string owner = 100;\n}\n```", hover.Contents.(defines.MarkupContent).Value)
+
+	complete := func(line uint, character uint) (res []string) {
+		items, err := Completion(context.Background(), &defines.CompletionParams{
+			TextDocumentPositionParams: defines.TextDocumentPositionParams{
+				TextDocument: defines.TextDocumentIdentifier{Uri: uris["api.proto"]},
+				Position:     defines.Position{Line: line, Character: character},
+			},
+			Context: &defines.CompletionContext{TriggerKind: defines.CompletionTriggerKindInvoked},
+		})
+		require.NoError(t, err)
+		for _, item := range *items {
+			res = append(res, item.Label)
+		}
+		return res
+	}
+	require.Equal(t, []string{"acme.options.owner", "acme.options.sensitive", "acme.options.Rules.rules"}, complete(5, 8))
+	require.Equal(t, []string{"owner", "sensitive", "Rules.rules"}, complete(5, 21))
+	require.Equal(t, []string{"owner"}, complete(5, 22))
+
+	api, err := view.FromContext(context.Background()).GetFile(uris["api.proto"])
+	require.NoError(t, err)
+	type diagnostic struct {
+		Line, Start, End uint
+		Message          string
+	}
+	var got []diagnostic
+	for _, d := range Diagnostics(api) {
+		got = append(got, diagnostic{d.Range.Start.Line, d.Range.Start.Character, d.Range.End.Character, d.Message})
+	}
+	require.Equal(t, []diagnostic{{9, 56, 63, "unresolved extension missing"}}, got)
+	require.Empty(t, Imports(api))
+
+	options, err := view.FromContext(context.Background()).GetFile(uris["options.proto"])
+	require.NoError(t, err)
+	got = nil
+	for _, d := range Diagnostics(options) {
+		got = append(got, diagnostic{d.Range.Start.Line, d.Range.Start.Character, d.Range.End.Character, d.Message})
+	}
+	require.Equal(t, []diagnostic{{10, 27, 30, "field number 200 is not in an extension range of message acme.options.Options"}}, got)
+}
@@ -0,0 +1,220 @@
+package components
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/walteh/protobuf-language-server/proto/parser"
+	"github.com/walteh/protobuf-language-server/proto/view"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+var (
+	// featureNamePrefix matches the text before the cursor in the name of a
+	// feature option, the group is the part of the name typed so far.
+	featureNamePrefix = regexp.MustCompile(`(?:\boption\s+|[\[,]\s*)features\.(\w*)$`)
+	// featureValuePrefix matches the text before the cursor in the value of a
+	// feature option, the groups are the feature and the value typed so far.
+	featureValuePrefix = regexp.MustCompile(`(?:\boption\s+|[\[,]\s*)features\.(\w+)\s*=\s*(\w*)$`)
+)
+
+// featureTargetLabels name the kinds of elements in messages.
+var featureTargetLabels = map[parser.FeatureTarget]string{
+	parser.FeatureTargetFile:      "a file",
+	parser.FeatureTargetMessage:   "a message",
+	parser.FeatureTargetField:     "a field",
+	parser.FeatureTargetOneof:     "a oneof",
+	parser.FeatureTargetEnum:      "an enum",
+	parser.FeatureTargetEnumValue: "an enum value",
+	parser.FeatureTargetService:   "a service",
+	parser.FeatureTargetMethod:    "an rpc",
+}
+
+// checkFeatures reports feature options outside of editions, unknown
+// features and values, features set on elements they do not apply to and the
+// proto2 and proto3 constructs editions replace by features.
+func (d *diagnoser) checkFeatures() {
+	proto := d.file.Proto()
+	editions := proto.Syntax() == "editions"
+
+	checkOptions := func(target parser.FeatureTarget, options []*protobuf.Option) {
+		for _, o := range options {
+			if !parser.IsFeatureOption(o) {
+				continue
+			}
+			name_range, _ := declarationRanges(d.file, o, o.Position, o.Name)
+			if !editions {
+				d.report(name_range, "features are only available in editions")
+				continue
+			}
+			for _, value := range parser.FeatureOptionValues(o) {
+				feature, ok := parser.GetFeatureByName(value.Name)
+				switch {
+				case !ok:
+					d.report(name_range, fmt.Sprintf("unknown feature %s", value.Name))
+				case !feature.Available(proto.Edition()):
+					d.report(name_range, fmt.Sprintf("feature %s is not available before edition %s", feature.Name, feature.Since))
+				case !feature.AppliesTo(target):
+					d.report(name_range, fmt.Sprintf("feature %s cannot be set on %s", feature.Name, featureTargetLabels[target]))
+				case !slices.Contains(feature.Values, value.Value):
+					d.report(name_range, fmt.Sprintf("invalid value %s for feature %s, expected one of %s", value.Value, feature.Name, strings.Join(feature.Values, ", ")))
+				}
+			}
+		}
+	}
+
+	checkField := func(e protobuf.Visitee, field *protobuf.Field, in_oneof bool) {
+		checkOptions(parser.FeatureTargetField, field.Options)
+		if !editions {
+			return
+		}
+		for _, o := range field.Options {
+			switch {
+			case o.Name == "packed":
+				name_range, _ := declarationRanges(d.file, o, o.Position, o.Name)
+				d.report(name_range, "option packed is not allowed in editions, use features.repeated_field_encoding")
+			case o.Name == "default":
+				if features, ok := proto.Features(e); ok && features.Values["field_presence"] == "IMPLICIT" {
+					name_range, _ := declarationRanges(d.file, o, o.Position, o.Name)
+					d.report(name_range, "default values are not allowed for fields with implicit presence")
+				}
+			}
+			for _, value := range parser.FeatureOptionValues(o) {
+				if value.Name != "field_presence" {
+					continue
+				}
+				name_range, _ := declarationRanges(d.file, o, o.Position, o.Name)
+				if in_oneof {
+					d.report(name_range, "feature field_presence cannot be set on oneof fields")
+				} else if value.Value == "IMPLICIT" && isMessageType(d.file, field.Type, field.Position.Line) {
+					d.report(name_range, "message fields cannot have implicit presence")
+				}
+			}
+		}
+	}
+
+	var visit func(target parser.FeatureTarget, elements []protobuf.Visitee)
+	visit = func(target parser.FeatureTarget, elements []protobuf.Visitee) {
+		for _, e := range elements {
+			switch v := e.(type) {
+			case *protobuf.Option:
+				checkOptions(target, []*protobuf.Option{v})
+			case *protobuf.Message:
+				if v.IsExtend {
+					visit("", v.Elements)
+				} else {
+					visit(parser.FeatureTargetMessage, v.Elements)
+				}
+			case *protobuf.Group:
+				if editions {
+					d.addName(v, v.Position, v.Name, "groups are not allowed in editions, use a message field with features.message_encoding = DELIMITED")
+				}
+				visit(parser.FeatureTargetMessage, v.Elements)
+			case *protobuf.Enum:
+				visit(parser.FeatureTargetEnum, v.Elements)
+			case *protobuf.EnumField:
+				visit(parser.FeatureTargetEnumValue, v.Elements)
+			case *protobuf.Oneof:
+				visit(parser.FeatureTargetOneof, v.Elements)
+			case *protobuf.Service:
+				visit(parser.FeatureTargetService, v.Elements)
+			case *protobuf.RPC:
+				visit(parser.FeatureTargetMethod, v.Elements)
+			case *protobuf.NormalField:
+				// the position of a field is the one of its type, the label
+				// is before it
+				label := v.Position
+				label.Column = 1
+				if editions && v.Optional {
+					d.add(label, "", "optional", "label optional is not allowed in editions, fields have explicit presence by default")
+				}
+				if editions && v.Required {
+					d.add(label, "", "required", "label required is not allowed in editions, use features.field_presence = LEGACY_REQUIRED")
+				}
+				checkField(v, v.Field, false)
+			case *protobuf.MapField:
+				checkField(v, v.Field, false)
+			case *protobuf.OneOfField:
+				checkField(v, v.Field, true)
+			}
+		}
+	}
+	visit(parser.FeatureTargetFile, proto.Protobuf().Elements)
+}
+
+// isMessageType reports whether typ, used on the provided line (1-based) of
+// proto_file, is a message.
+func isMessageType(proto_file view.ProtoFile, typ string, line int) bool {
+	symbols := resolveType(proto_file, typ, line)
+	return len(symbols) > 0 && symbols[0].Type == DefinitionTypeMessage
+}
+
+// CompletionInFeatureOption suggests the names of features or, once the
+// feature is named, its values, if the text before the cursor on line_str
+// is in a feature option. ok is false outside of feature options and files
+// using editions.
+func CompletionInFeatureOption(file view.ProtoFile, line_str string) (res []defines.CompletionItem, ok bool) {
+	if file.Proto().Syntax() != "editions" {
+		return nil, false
+	}
+	if match := featureValuePrefix.FindStringSubmatch(line_str); match != nil {
+		feature, found := parser.GetFeatureByName(match[1])
+		if !found {
+			return nil, true
+		}
+		for _, value := range feature.Values {
+			if !strings.HasPrefix(value, match[2]) {
+				continue
+			}
+			value := value
+			res = append(res, defines.CompletionItem{
+				Label:      value,
+				Kind:       &kindEnumMember,
+				InsertText: &value,
+			})
+		}
+		return res, true
+	}
+	if match := featureNamePrefix.FindStringSubmatch(line_str); match != nil {
+		for _, feature := range parser.Features {
+			if !feature.Available(file.Proto().Edition()) || !strings.HasPrefix(feature.Name, match[1]) {
+				continue
+			}
+			name, detail := feature.Name, strings.Join(feature.Values, " | ")
+			res = append(res, defines.CompletionItem{
+				Label:      name,
+				Kind:       &kindField,
+				Detail:     &detail,
+				InsertText: &name,
+				Documentation: defines.MarkupContent{
+					Kind:  defines.MarkupKindMarkdown,
+					Value: feature.Doc,
+				},
+			})
+		}
+		return res, true
+	}
+	return nil, false
+}
+
+// formatFeatures lists the resolved features of an element of kind target,
+// only the ones that can be set on it. It is empty outside of editions.
+func formatFeatures(features parser.FeatureSet, target parser.FeatureTarget) string {
+	if features.Edition == "" {
+		return ""
+	}
+	var lines []string
+	for _, feature := range parser.Features {
+		if feature.Available(features.Edition) && feature.AppliesTo(target) {
+			lines = append(lines, fmt.Sprintf("- `%s`: `%s`", feature.Name, features.Values[feature.Name]))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Features (edition %s):\n\n%s", features.Edition, strings.Join(lines, "\n"))
+}
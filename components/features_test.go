@@ -0,0 +1,110 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/view"
+)
+
+func TestFeatures(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"editions.proto": `edition = "2023";
+package api;
+
+option features.enum_type = CLOSED;
+option features.enforce_naming_style = STYLE2024;
+
+message User {
+	option features.json_format = LEGACY_BEST_EFFORT;
+	option features.field_presence = IMPLICIT;
+
+	optional string name = 1;
+	repeated int32 ids = 2 [packed = true, features.utf8_validation = SOMETIMES];
+	User parent = 3 [features.field_presence = IMPLICIT];
+	oneof contact {
+		string email = 4 [features.field_presence = EXPLICIT];
+	}
+	int32 age = 5 [features.field_presence = IMPLICIT, default = 1];
+}
+
+enum Status {
+	option features.enum_type = OPEN;
+	ACTIVE = 1;
+}
+
+enum Legacy {
+	ACTIVE_LEGACY = 1;
+}
+`,
+		"proto3.proto": `syntax = "proto3";
+package api;
+
+option features.field_presence = IMPLICIT;
+`,
+	})
+
+	type diagnostic struct {
+		Line, Start, End uint
+		Message          string
+	}
+	diagnostics := func(name string) (got []diagnostic) {
+		proto_file, err := view.FromContext(context.Background()).GetFile(uris[name])
+		require.NoError(t, err)
+		for _, d := range Diagnostics(proto_file) {
+			got = append(got, diagnostic{d.Range.Start.Line, d.Range.Start.Character, d.Range.End.Character, d.Message})
+		}
+		return got
+	}
+	require.Equal(t, []diagnostic{
+		{4, 7, 36, "feature enforce_naming_style is not available before edition 2024"},
+		{8, 8, 31, "feature field_presence cannot be set on a message"},
+		{10, 1, 9, "label optional is not allowed in editions, fields have explicit presence by default"},
+		{11, 40, 64, "invalid value SOMETIMES for feature utf8_validation, expected one of VERIFY, NONE"},
+		{11, 25, 31, "option packed is not allowed in editions, use features.repeated_field_encoding"},
+		{12, 18, 41, "message fields cannot have implicit presence"},
+		{14, 20, 43, "feature field_presence cannot be set on oneof fields"},
+		{16, 52, 59, "default values are not allowed for fields with implicit presence"},
+		{21, 10, 11, "the first value of open enum Status must be zero"},
+	}, diagnostics("editions.proto"))
+	require.Equal(t, []diagnostic{
+		{3, 7, 30, "features are only available in editions"},
+	}, diagnostics("proto3.proto"))
+
+	complete := func(line_str string) (res []string) {
+		proto_file, err := view.FromContext(context.Background()).GetFile(uris["editions.proto"])
+		require.NoError(t, err)
+		items, ok := CompletionInFeatureOption(proto_file, line_str)
+		require.True(t, ok)
+		for _, item := range items {
+			res = append(res, item.Label)
+		}
+		return res
+	}
+	require.Equal(t, []string{"field_presence", "enum_type", "repeated_field_encoding", "utf8_validation", "message_encoding", "json_format"}, complete("option features."))
+	require.Equal(t, []string{"message_encoding"}, complete("\tint32 id = 1 [deprecated = true, features.mes"))
+	require.Equal(t, []string{"EXPLICIT", "IMPLICIT", "LEGACY_REQUIRED"}, complete("option features.field_presence = "))
+	require.Equal(t, []string{"CLOSED"}, complete("option features.enum_type = C"))
+
+	hover, err := Hover(context.Background(), &defines.HoverParams{
+		TextDocumentPositionParams: defines.TextDocumentPositionParams{
+			TextDocument: defines.TextDocumentIdentifier{Uri: uris["editions.proto"]},
+			Position:     defines.Position{Line: 19, Character: 6},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "```proto\nenum Status {\n\tACTIVE = 1;\n}\n```\n\nFeatures (edition 2023):\n\n- `enum_type`: `OPEN`\n- `json_format`: `ALLOW`", hover.Contents.(defines.MarkupContent).Value)
+
+	hover, err = Hover(context.Background(), &defines.HoverParams{
+		TextDocumentPositionParams: defines.TextDocumentPositionParams{
+			TextDocument: defines.TextDocumentIdentifier{Uri: uris["editions.proto"]},
+			Position:     defines.Position{Line: 3, Character: 20},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "```proto\noption features.enum_type = CLOSED;\n```\n\n- Option: `google.protobuf.FileOptions.features`\n\n"+
+		"`enum_type`: whether unknown values are kept in enum fields, one of `OPEN`, `CLOSED`", hover.Contents.(defines.MarkupContent).Value)
+}
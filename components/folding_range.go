@@ -0,0 +1,148 @@
+package components
+
+import (
+	"context"
+	"sort"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/walteh/protobuf-language-server/proto/view"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+// FoldingRanges folds the blocks of messages, enums, services, rpcs, oneofs
+// and aggregate options up to the line before their closing brace, comments
+// spanning several lines and the imports of a proto file.
+func FoldingRanges(ctx context.Context, req *defines.FoldingRangeParams) (result *[]defines.FoldingRange, err error) {
+	if !view.IsProtoFile(req.TextDocument.Uri) {
+		return nil, nil
+	}
+	proto_file, err := view.FromContext(ctx).GetFile(req.TextDocument.Uri)
+	if err != nil {
+		return nil, err
+	}
+	res := []defines.FoldingRange{}
+	if proto_file.Proto() == nil {
+		return &res, nil
+	}
+
+	// add folds the 1-based lines from start to end
+	add := func(start, end int, kind defines.FoldingRangeKind) {
+		if end <= start || start < 1 {
+			return
+		}
+		folding := defines.FoldingRange{StartLine: uint(start - 1), EndLine: uint(end - 1)}
+		if kind != "" {
+			k := string(kind)
+			folding.Kind = &k
+		}
+		res = append(res, folding)
+	}
+	addComment := func(comment *protobuf.Comment) {
+		if comment != nil {
+			add(comment.Position.Line, comment.Position.Line+len(comment.Lines)-1, defines.FoldingRangeKindComment)
+		}
+	}
+
+	proto := proto_file.Proto()
+	walkElements(proto.Protobuf().Elements, func(e protobuf.Visitee) {
+		if comment, ok := e.(*protobuf.Comment); ok {
+			addComment(comment)
+			return
+		}
+		if documented, ok := e.(protobuf.Documented); ok {
+			addComment(documented.Doc())
+		}
+		if end, ok := proto.BlockEnd(e); ok {
+			pos, _ := elementPosition(e)
+			add(pos.Line, end.Line-1, "")
+		}
+	})
+	if imports := proto.Imports(); len(imports) > 0 {
+		add(imports[0].ProtoImport.Position.Line, imports[len(imports)-1].ProtoImport.Position.Line, defines.FoldingRangeKindImports)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].StartLine < res[j].StartLine
+	})
+	return &res, nil
+}
+
+// walkElements calls f for every element and, after it, its nested elements,
+// including the inline options of fields and enum values.
+func walkElements(elements []protobuf.Visitee, f func(protobuf.Visitee)) {
+	for _, e := range elements {
+		f(e)
+		switch v := e.(type) {
+		case *protobuf.Message:
+			walkElements(v.Elements, f)
+		case *protobuf.Group:
+			walkElements(v.Elements, f)
+		case *protobuf.Enum:
+			walkElements(v.Elements, f)
+		case *protobuf.EnumField:
+			walkElements(v.Elements, f)
+		case *protobuf.Oneof:
+			walkElements(v.Elements, f)
+		case *protobuf.Service:
+			walkElements(v.Elements, f)
+		case *protobuf.RPC:
+			walkElements(v.Elements, f)
+		case *protobuf.NormalField:
+			walkOptions(v.Options, f)
+		case *protobuf.MapField:
+			walkOptions(v.Options, f)
+		case *protobuf.OneOfField:
+			walkOptions(v.Options, f)
+		}
+	}
+}
+
+func walkOptions(options []*protobuf.Option, f func(protobuf.Visitee)) {
+	for _, o := range options {
+		f(o)
+	}
+}
+
+// elementPosition returns where an element starts, for inline options the
+// bracket or comma before them.
+func elementPosition(e protobuf.Visitee) (scanner.Position, bool) {
+	switch v := e.(type) {
+	case *protobuf.Syntax:
+		return v.Position, true
+	case *protobuf.Edition:
+		return v.Position, true
+	case *protobuf.Package:
+		return v.Position, true
+	case *protobuf.Import:
+		return v.Position, true
+	case *protobuf.Option:
+		return v.Position, true
+	case *protobuf.Message:
+		return v.Position, true
+	case *protobuf.Group:
+		return v.Position, true
+	case *protobuf.Enum:
+		return v.Position, true
+	case *protobuf.EnumField:
+		return v.Position, true
+	case *protobuf.Oneof:
+		return v.Position, true
+	case *protobuf.NormalField:
+		return v.Position, true
+	case *protobuf.MapField:
+		return v.Position, true
+	case *protobuf.OneOfField:
+		return v.Position, true
+	case *protobuf.Service:
+		return v.Position, true
+	case *protobuf.RPC:
+		return v.Position, true
+	case *protobuf.Reserved:
+		return v.Position, true
+	case *protobuf.Extensions:
+		return v.Position, true
+	}
+	return scanner.Position{}, false
+}
@@ -0,0 +1,72 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+func TestFoldingRanges(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"a.proto": "syntax = \"proto3\";\nmessage A {}\n",
+		"b.proto": "syntax = \"proto3\";\nmessage B {}\n",
+		"api.proto": `syntax = "proto3";
+
+import "a.proto";
+import "b.proto";
+
+/*
+ * The user.
+ */
+message User {
+	option (meta) = {
+		name: "user"
+	};
+	// how to reach
+	// the user
+	oneof contact {
+		string email = 1;
+	}
+	message Empty {}
+}
+
+enum Status { ACTIVE = 0; }
+
+service Users {
+	rpc Get(A) returns (B) {
+		option deprecated = true;
+	}
+}
+`,
+	})
+
+	res, err := FoldingRanges(context.Background(), &defines.FoldingRangeParams{
+		TextDocument: defines.TextDocumentIdentifier{Uri: uris["api.proto"]},
+	})
+	require.NoError(t, err)
+	type folding struct {
+		Start, End uint
+		Kind       string
+	}
+	var got []folding
+	for _, r := range *res {
+		f := folding{Start: r.StartLine, End: r.EndLine}
+		if r.Kind != nil {
+			f.Kind = *r.Kind
+		}
+		got = append(got, f)
+	}
+	require.Equal(t, []folding{
+		{2, 3, "imports"},
+		{5, 7, "comment"},
+		{8, 17, ""},
+		{9, 10, ""},
+		{12, 13, "comment"},
+		{14, 15, ""},
+		{22, 25, ""},
+		{23, 24, ""},
+	}, got)
+}
@@ -2,122 +2,276 @@ package components
 
 import (
 	"context"
-	"fmt"
-	"io"
-	"log"
-	"math"
-	"os/exec"
-	"path/filepath"
 	"strings"
 
-	"github.com/walteh/protobuf-language-server/proto/view"
-	"github.com/walteh/retab/v2/pkg/format"
-	"github.com/walteh/retab/v2/pkg/format/editorconfig"
-	"github.com/walteh/retab/v2/pkg/format/protofmt"
-
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
 	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/format"
+	"github.com/walteh/protobuf-language-server/proto/parser"
+	"github.com/walteh/protobuf-language-server/proto/view"
 )
 
-func FormatWithRetab(ctx context.Context, req *defines.DocumentFormattingParams) (result *[]defines.TextEdit, err error) {
-	formatter := protofmt.NewFormatter()
-	proto_file, err := view.ViewManager.GetFile(req.TextDocument.Uri)
-	if err != nil {
-		return nil, err
+func Format(ctx context.Context, req *defines.DocumentFormattingParams) (result *[]defines.TextEdit, err error) {
+	if !view.IsProtoFile(req.TextDocument.Uri) {
+		return nil, nil
 	}
-	text, _, _ := proto_file.Read(ctx)
-	reader := strings.NewReader(string(text))
+	progress := view.FromContext(ctx).Server.BeginWorkDoneProgress(ctx, req.WorkDoneToken, "Formatting")
+	defer progress.End("")
 
-	cfgProvider, err := editorconfig.NewDynamicConfigurationProvider(ctx, "")
+	edits, err := formatEdits(ctx, req.TextDocument.Uri, req.Options)
 	if err != nil {
-		return nil, fmt.Errorf("creating configuration provider: %w", err)
+		return nil, err
 	}
+	return &edits, nil
+}
 
-	r, err := format.Format(ctx, formatter, cfgProvider, string(req.TextDocument.Uri), reader)
-	if err != nil {
-		return nil, fmt.Errorf("formatting content: %w", err)
+// FormatRange formats the whole file but only returns the edits touching the
+// lines of the range.
+func FormatRange(ctx context.Context, req *defines.DocumentRangeFormattingParams) (result *[]defines.TextEdit, err error) {
+	if !view.IsProtoFile(req.TextDocument.Uri) {
+		return nil, nil
 	}
+	progress := view.FromContext(ctx).Server.BeginWorkDoneProgress(ctx, req.WorkDoneToken, "Formatting")
+	defer progress.End("")
 
-	out, err := io.ReadAll(r)
+	edits, err := formatEdits(ctx, req.TextDocument.Uri, req.Options)
 	if err != nil {
-		return nil, fmt.Errorf("reading formatted content: %w", err)
-	}
-
-	return &[]defines.TextEdit{
-		{
-			Range: defines.Range{
-				Start: defines.Position{Line: 0, Character: 0},
-				End:   defines.Position{Line: math.MaxInt32, Character: math.MaxInt32},
-			},
-			NewText: string(out),
-		},
-	}, nil
+		return nil, err
+	}
+	edits = editsInLines(edits, int(req.Range.Start.Line), int(req.Range.End.Line))
+	return &edits, nil
 }
 
-func Format(ctx context.Context, req *defines.DocumentFormattingParams) (result *[]defines.TextEdit, err error) {
+// FormatOnType formats the block closed by a typed '}' or the line of a
+// typed ';'.
+func FormatOnType(ctx context.Context, req *defines.DocumentOnTypeFormattingParams) (result *[]defines.TextEdit, err error) {
 	if !view.IsProtoFile(req.TextDocument.Uri) {
 		return nil, nil
 	}
-	format := exec.Command("clang-format", fmt.Sprintf("--assume-filename=%v", filepath.Base(string(req.TextDocument.Uri))))
-	in, err := format.StdinPipe()
+	proto_file, err := view.FromContext(ctx).GetFile(req.TextDocument.Uri)
+	if err != nil {
+		return nil, err
+	}
+	data, _, _ := proto_file.Read(ctx)
+
+	line := int(req.Position.Line)
+	start := line
+	if req.Ch == "}" {
+		start = blockStart(strings.Split(string(data), "\n"), line)
+	}
+	edits, err := formatEdits(ctx, req.TextDocument.Uri, req.Options)
 	if err != nil {
 		return nil, err
 	}
+	edits = editsInLines(edits, start, line)
+	return &edits, nil
+}
 
-	proto_file, err := view.ViewManager.GetFile(req.TextDocument.Uri)
+// formatEdits returns the edits that format the file, none if it can't be
+// formatted, e.g. while it has syntax errors.
+func formatEdits(ctx context.Context, document_uri defines.DocumentUri, formatting_options defines.FormattingOptions) ([]defines.TextEdit, error) {
+	proto_file, err := view.FromContext(ctx).GetFile(document_uri)
 	if err != nil {
 		return nil, err
 	}
 	data, _, _ := proto_file.Read(ctx)
-	go func() {
-		io.WriteString(in, string(data))
-		defer in.Close()
-	}()
 
-	res, err := format.CombinedOutput()
+	options := format.Options{Indent: "\t"}
+	if formatting_options.InsertSpaces {
+		tab_size := formatting_options.TabSize
+		if tab_size == 0 {
+			tab_size = 2
+		}
+		options.Indent = strings.Repeat(" ", int(tab_size))
+	}
+	options = view.FromContext(ctx).EditorConfig(document_uri, options)
+
+	formatted, err := format.Source(data, options)
 	if err != nil {
-		log.Fatal(err)
-	}
-	return &[]defines.TextEdit{
-		{
-			Range: defines.Range{
-				Start: defines.Position{Line: 0, Character: 0},
-				End:   defines.Position{Line: math.MaxInt32, Character: math.MaxInt32},
-			},
-			NewText: string(res),
-		},
-	}, nil
+		logs.Printf("format %v err:%v", document_uri, err)
+		return []defines.TextEdit{}, nil
+	}
+	return lineEdits(string(data), string(formatted)), nil
 }
 
-func FormatRange(ctx context.Context, req *defines.DocumentRangeFormattingParams) (result *[]defines.TextEdit, err error) {
-	if !view.IsProtoFile(req.TextDocument.Uri) {
-		return nil, nil
+// lineEdits returns the edits turning before into after, every edit replaces
+// a run of whole lines that differ. Runs replaced by as many lines, e.g. when
+// only the indentation changes, are split into an edit per line.
+func lineEdits(before, after string) []defines.TextEdit {
+	a, b := splitLines(before), splitLines(after)
+
+	var hunks []hunk
+	for _, h := range diffLines(a, b) {
+		if h.a_end-h.a_start != h.b_end-h.b_start {
+			hunks = append(hunks, h)
+			continue
+		}
+		for i := 0; i < h.a_end-h.a_start; i++ {
+			if a[h.a_start+i] != b[h.b_start+i] {
+				hunks = append(hunks, hunk{h.a_start + i, h.a_start + i + 1, h.b_start + i, h.b_start + i + 1})
+			}
+		}
 	}
-	format := exec.Command("clang-format", fmt.Sprintf("--assume-filename=%v", filepath.Base(string(req.TextDocument.Uri))), fmt.Sprintf("--lines=%v:%v", req.Range.Start.Line+1, req.Range.End.Line+1))
-	in, err := format.StdinPipe()
-	if err != nil {
-		return nil, err
+
+	edits := []defines.TextEdit{}
+	for _, h := range hunks {
+		start := defines.Position{Line: uint(h.a_start)}
+		end := defines.Position{Line: uint(h.a_end)}
+		if h.a_end == len(a) && h.a_end > 0 && !strings.HasSuffix(a[h.a_end-1], "\n") {
+			// the last line has no newline, end the edit on it
+			end = defines.Position{Line: uint(h.a_end - 1), Character: parser.UTF16Character(a[h.a_end-1], len(a[h.a_end-1]))}
+		}
+		edits = append(edits, defines.TextEdit{
+			Range:   defines.Range{Start: start, End: end},
+			NewText: strings.Join(b[h.b_start:h.b_end], ""),
+		})
 	}
-	proto_file, err := view.ViewManager.GetFile(req.TextDocument.Uri)
-	if err != nil {
-		return nil, err
+	return edits
+}
+
+// splitLines splits s after each newline.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
 	}
-	data, _, _ := proto_file.Read(ctx)
-	go func() {
-		io.WriteString(in, string(data))
-		defer in.Close()
-	}()
+	return lines
+}
 
-	res, err := format.CombinedOutput()
-	if err != nil {
-		log.Fatal(err)
-	}
-	return &[]defines.TextEdit{
-		{
-			Range: defines.Range{
-				Start: defines.Position{Line: 0, Character: 0},
-				End:   defines.Position{Line: math.MaxInt32, Character: math.MaxInt32},
-			},
-			NewText: string(res),
-		},
-	}, nil
+// editsInLines keeps the edits that touch a line in [start, end].
+func editsInLines(edits []defines.TextEdit, start, end int) []defines.TextEdit {
+	res := []defines.TextEdit{}
+	for _, edit := range edits {
+		first, last := int(edit.Range.Start.Line), int(edit.Range.End.Line)
+		if edit.Range.End.Character == 0 && last > first {
+			last--
+		}
+		if first <= end && last >= start {
+			res = append(res, edit)
+		}
+	}
+	return res
+}
+
+// blockStart returns the line of the '{' matching the '}' on line.
+func blockStart(lines []string, line int) int {
+	depth := 0
+	for i := min(line, len(lines)-1); i >= 0; i-- {
+		text := lines[i]
+		if idx := strings.Index(text, "//"); idx >= 0 {
+			text = text[:idx]
+		}
+		for j := len(text) - 1; j >= 0; j-- {
+			switch text[j] {
+			case '}':
+				depth++
+			case '{':
+				depth--
+				if depth <= 0 {
+					return i
+				}
+			}
+		}
+	}
+	return line
+}
+
+type hunk struct {
+	a_start, a_end int
+	b_start, b_end int
+}
+
+// maxDiffCost bounds the work of diffLines, if more lines differ they are
+// replaced as a whole.
+const maxDiffCost = 1000
+
+// diffLines returns the runs of lines that differ between a and b, computed
+// with the Myers algorithm.
+func diffLines(a, b []string) []hunk {
+	// the common prefix and suffix are left out of the search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a_mid, b_mid := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(a_mid) == 0 && len(b_mid) == 0 {
+		return nil
+	}
+
+	n, m := len(a_mid), len(b_mid)
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	var trace [][]int
+	found := false
+	for d := 0; d <= n+m && d <= maxDiffCost && !found; d++ {
+		// only the diagonals in [-d, d] are read back
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a_mid[x] == b_mid[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return []hunk{{prefix, prefix + n, prefix, prefix + m}}
+	}
+
+	// walk back through the trace, collecting the matching lines
+	type match struct{ x, y int }
+	var matches []match
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prev_k int
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prev_k = k + 1
+		} else {
+			prev_k = k - 1
+		}
+		prev_x := v[d+prev_k]
+		prev_y := prev_x - prev_k
+		for x > prev_x && y > prev_y {
+			x--
+			y--
+			matches = append(matches, match{x, y})
+		}
+		x, y = prev_x, prev_y
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		matches = append(matches, match{x, y})
+	}
+
+	var res []hunk
+	x, y = 0, 0
+	for i := len(matches) - 1; i >= -1; i-- {
+		next := match{n, m}
+		if i >= 0 {
+			next = matches[i]
+		}
+		if next.x > x || next.y > y {
+			res = append(res, hunk{prefix + x, prefix + next.x, prefix + y, prefix + next.y})
+		}
+		x, y = next.x+1, next.y+1
+	}
+	return res
 }
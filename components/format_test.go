@@ -0,0 +1,118 @@
+package components
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+// applyEdits applies line edits as returned by lineEdits.
+func applyEdits(t *testing.T, text string, edits []defines.TextEdit) string {
+	t.Helper()
+	lines := splitLines(text)
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+		start, end := int(edit.Range.Start.Line), int(edit.Range.End.Line)
+		if edit.Range.End.Character > 0 {
+			end++
+		}
+		require.Zero(t, edit.Range.Start.Character)
+		lines = append(lines[:start], append([]string{edit.NewText}, lines[end:]...)...)
+	}
+	return strings.Join(lines, "")
+}
+
+func TestFormat(t *testing.T) {
+	content := `syntax = "proto3";
+package api;
+
+message User {
+	string name = 1;
+      int32   age = 2;
+	message Address {
+	string street = 1;
+   }
+}
+
+enum Status {
+  STATUS_UNSPECIFIED  =  0;
+}
+`
+	want := `syntax = "proto3";
+package api;
+
+message User {
+  string name = 1;
+  int32 age = 2;
+  message Address {
+    string street = 1;
+  }
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+}
+`
+	uris := setupWorkspace(t, map[string]string{"api.proto": content})
+	options := defines.FormattingOptions{TabSize: 2, InsertSpaces: true}
+	document := defines.TextDocumentIdentifier{Uri: uris["api.proto"]}
+
+	edits, err := Format(context.Background(), &defines.DocumentFormattingParams{TextDocument: document, Options: options})
+	require.NoError(t, err)
+	require.Equal(t, want, applyEdits(t, content, *edits))
+	// the unchanged header is not part of any edit
+	for _, edit := range *edits {
+		require.GreaterOrEqual(t, edit.Range.Start.Line, uint(4))
+	}
+
+	edits, err = FormatRange(context.Background(), &defines.DocumentRangeFormattingParams{
+		TextDocument: document,
+		Range:        defines.Range{Start: defines.Position{Line: 12}, End: defines.Position{Line: 12, Character: 5}},
+		Options:      options,
+	})
+	require.NoError(t, err)
+	require.Equal(t, strings.Replace(content, "STATUS_UNSPECIFIED  =  0", "STATUS_UNSPECIFIED = 0", 1), applyEdits(t, content, *edits))
+
+	onType := func(line uint, ch string) string {
+		edits, err := FormatOnType(context.Background(), &defines.DocumentOnTypeFormattingParams{
+			TextDocument: document,
+			Position:     defines.Position{Line: line, Character: 4},
+			Ch:           ch,
+			Options:      options,
+		})
+		require.NoError(t, err)
+		return applyEdits(t, content, *edits)
+	}
+	require.Equal(t, strings.Replace(content, "      int32   age = 2;", "  int32 age = 2;", 1), onType(5, ";"))
+	require.Equal(t, strings.Replace(content, "\tmessage Address {\n\tstring street = 1;\n   }", "  message Address {\n    string street = 1;\n  }", 1), onType(8, "}"))
+}
+
+func TestFormatSyntaxError(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{"api.proto": "message User {\n"})
+	edits, err := Format(context.Background(), &defines.DocumentFormattingParams{
+		TextDocument: defines.TextDocumentIdentifier{Uri: uris["api.proto"]},
+	})
+	require.NoError(t, err)
+	require.Empty(t, *edits)
+}
+
+func TestLineEdits(t *testing.T) {
+	tests := []struct {
+		before, after string
+	}{
+		{"a\nb\nc\n", "a\nB\nc\n"},
+		{"a\nb\nc\n", "a\nc\n"},
+		{"a\nc\n", "a\nb\nc\n"},
+		{"a\nb", "a\nb\n"},
+		{"", "a\n"},
+		{"x\ny\nz\n", "1\n2\n"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.after, applyEdits(t, tt.before, lineEdits(tt.before, tt.after)), "%q -> %q", tt.before, tt.after)
+	}
+	require.Empty(t, lineEdits("a\nb\n", "a\nb\n"))
+}
@@ -24,6 +24,9 @@ func init() {
 	template.Must(hoverTmpl.Parse(messageTemplate))
 	template.Must(hoverTmpl.Parse(oneofTemplate))
 	template.Must(hoverTmpl.Parse(enumTemplate))
+	template.Must(hoverTmpl.Parse(extendTemplate))
+	template.Must(hoverTmpl.Parse(declarationTemplate))
+	template.Must(hoverTmpl.Parse(serviceTemplate))
 }
 
 func Hover(ctx context.Context, req *defines.HoverParams) (result *defines.Hover, err error) {
@@ -35,14 +38,17 @@ func Hover(ctx context.Context, req *defines.HoverParams) (result *defines.Hover
 		return nil, err
 	}
 
-	if len(symbols) == 0 {
-		return nil, ErrSymbolNotFound
+	var value string
+	if len(symbols) > 0 {
+		value = formatHover(symbols[0])
+	} else if value, err = hoverDeclaration(ctx, &req.TextDocumentPositionParams); err != nil {
+		return nil, err
 	}
 
 	result = &defines.Hover{
 		Contents: defines.MarkupContent{
 			Kind:  defines.MarkupKindMarkdown,
-			Value: formatHover(symbols[0]),
+			Value: value,
 		},
 	}
 
@@ -52,23 +58,22 @@ func Hover(ctx context.Context, req *defines.HoverParams) (result *defines.Hover
 func formatHover(symbol SymbolDefinition) string {
 
 	var hoverData hoverData
+	var features string
 
 	switch symbol.Type {
 	case DefinitionTypeEnum:
 		hoverData.Enum = prepareEnumData(symbol.Enum)
+		features = formatFeatures(symbol.Enum.Features(), parser.FeatureTargetEnum)
 	case DefinitionTypeMessage:
 		hoverData.Message = prepareMessageData(symbol.Message)
+		features = formatFeatures(symbol.Message.Features(), parser.FeatureTargetMessage)
+	case DefinitionTypeExtension:
+		hoverData.Extend = prepareExtendData(symbol.Extension)
 	default:
 		return ""
 	}
 
-	buffer := bytes.NewBuffer(nil)
-	err := hoverTmpl.Execute(buffer, hoverData)
-	if err != nil {
-		return err.Error()
-	}
-
-	return buffer.String()
+	return formatDeclarationHover(hoverData, nil, features)
 }
 
 const hoverTemplate = "```proto" + `
@@ -78,11 +83,23 @@ const hoverTemplate = "```proto" + `
 {{- if .Enum }}
 {{- templateWithIndent "enum" .Enum 0 }}
 {{- end }}
+{{- if .Extend }}
+{{- templateWithIndent "extend" .Extend 0 }}
+{{- end }}
+{{- if .Declaration }}
+{{- templateWithIndent "declaration" .Declaration 0 }}
+{{- end }}
+{{- if .Service }}
+{{- templateWithIndent "service" .Service 0 }}
+{{- end }}
 ` + "```"
 
 type hoverData struct {
-	Message *messageData
-	Enum    *enumData
+	Message     *messageData
+	Enum        *enumData
+	Extend      *extendData
+	Declaration *declarationData
+	Service     *serviceData
 }
 
 const enumTemplate = `{{- define "enum" }}
@@ -252,28 +269,7 @@ func prepareMessageData(message parser.Message) *messageData {
 	}
 
 	for _, item := range message.Fields() {
-
-		var field field
-
-		if item.ProtoField.Comment != nil {
-			field.Comments = formatComments(item.ProtoField.Comment.Lines)
-		}
-
-		if item.ProtoField.Optional {
-			field.Optional = "optional "
-		}
-		if item.ProtoField.Repeated {
-			field.Repeated = "repeated "
-		}
-
-		field.Type = item.ProtoField.Type
-		field.Name = item.ProtoField.Name
-		field.ProtoSequence = item.ProtoField.Sequence
-
-		if item.ProtoField.InlineComment != nil {
-			field.InlineComment = formatComments(item.ProtoField.InlineComment.Lines[0:1])[0]
-		}
-		data.Fields = append(data.Fields, field)
+		data.Fields = append(data.Fields, prepareField(item))
 	}
 
 	data.Oneofs = prepareOneofFields(message.Oneofs())
@@ -300,6 +296,103 @@ func prepareMessageData(message parser.Message) *messageData {
 	return &data
 }
 
+func prepareField(item *parser.MessageField) field {
+
+	var field field
+
+	if item.ProtoField.Comment != nil {
+		field.Comments = formatComments(item.ProtoField.Comment.Lines)
+	}
+
+	if item.ProtoField.Optional {
+		field.Optional = "optional "
+	}
+	if item.ProtoField.Repeated {
+		field.Repeated = "repeated "
+	}
+
+	field.Type = item.ProtoField.Type
+	field.Name = item.ProtoField.Name
+	field.ProtoSequence = item.ProtoField.Sequence
+
+	if item.ProtoField.InlineComment != nil {
+		field.InlineComment = formatComments(item.ProtoField.InlineComment.Lines[0:1])[0]
+	}
+	return field
+}
+
+const extendTemplate = `{{- define "extend" }}
+extend {{ .Extendee }} {
+	{{- range .Field.Comments }}
+	{{ . }}
+	{{- end }}
+	{{.Field.Optional}}{{.Field.Repeated}}{{.Field.Type}} {{.Field.Name}} = {{.Field.ProtoSequence}};{{ if .Field.InlineComment }} {{.Field.InlineComment }}{{ end }}
+}
+{{- end }}`
+
+// extendData is an extension shown in the extend block declaring it.
+type extendData struct {
+	Extendee string
+	Field    field
+}
+
+func prepareExtendData(extension *parser.Extension) *extendData {
+	return &extendData{
+		Extendee: extension.Extend.Extendee(),
+		Field:    prepareField(extension.Field),
+	}
+}
+
+const declarationTemplate = `{{- define "declaration" }}
+{{- range .Comments }}
+{{ . }}
+{{- end }}
+{{ .Source }}{{ if .InlineComment }} {{ .InlineComment }}{{ end }}
+{{- end }}`
+
+// declarationData is a declaration of a single line, e.g. of a field, enum
+// value, rpc or package.
+type declarationData struct {
+	Comments      []string
+	Source        string
+	InlineComment string
+}
+
+func prepareDeclarationData(source string, comment, inline_comment *proto.Comment) *declarationData {
+	data := declarationData{
+		Source: source,
+	}
+
+	if comment != nil {
+		data.Comments = formatComments(comment.Lines)
+	}
+
+	if inline_comment != nil && len(inline_comment.Lines) != 0 {
+		data.InlineComment = formatComments(inline_comment.Lines[0:1])[0]
+	}
+	return &data
+}
+
+const serviceTemplate = `{{- define "service" }}
+{{- range .Comments }}
+{{ . }}
+{{- end }}
+service {{ .Name }} {
+	{{- range .RPCs }}
+	{{- range .Comments }}
+	{{ . }}
+	{{- end }}
+	{{ .Source }}{{ if .InlineComment }} {{ .InlineComment }}{{ end }}
+	{{- end }}
+}
+{{- end }}`
+
+type serviceData struct {
+	Comments []string
+	Name     string
+	RPCs     []*declarationData
+}
+
 type oneOfFieldVisitor struct {
 	proto.NoopVisitor
 	visitFunc func(*proto.OneOfField)
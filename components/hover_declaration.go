@@ -0,0 +1,604 @@
+package components
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	protobuf "github.com/emicklei/proto"
+	"go.lsp.dev/uri"
+
+	"github.com/walteh/protobuf-language-server/proto/parser"
+	"github.com/walteh/protobuf-language-server/proto/types"
+	"github.com/walteh/protobuf-language-server/proto/view"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+// hoverDetail is a line of the list shown below a declaration on hover.
+type hoverDetail struct {
+	Name  string
+	Value string
+}
+
+// hoverDeclaration formats the hover of the field, enum value, rpc, service,
+// package or built-in option declared under the cursor, or of the scalar
+// type under it. They are no types, so findSymbolDefinition does not find
+// them.
+func hoverDeclaration(ctx context.Context, position *defines.TextDocumentPositionParams) (string, error) {
+	proto_file, err := view.FromContext(ctx).GetFile(position.TextDocument.Uri)
+	if err != nil {
+		return "", err
+	}
+	if proto_file.Proto() == nil {
+		return "", ErrSymbolNotFound
+	}
+
+	if member, ok := findMemberDefinition(ctx, position); ok {
+		return formatMemberHover(proto_file, member), nil
+	}
+
+	at := defines.Range{Start: position.Position, End: position.Position}
+	for _, service := range proto_file.Proto().Services() {
+		if name_range, _ := declarationRanges(proto_file, service.Protobuf(), service.Protobuf().Position, service.Protobuf().Name); rangeContains(name_range, at) {
+			return formatServiceHover(proto_file, service), nil
+		}
+	}
+	for _, pkg := range proto_file.Proto().Packages() {
+		if name_range, ok := proto_file.Proto().NameRange(pkg.ProtoPackage); ok && rangeContains(name_range, at) {
+			return formatPackageHover(proto_file, pkg), nil
+		}
+	}
+	if o, target, ok := optionAt(proto_file, at); ok {
+		if value := formatOptionHover(o, target); value != "" {
+			return value, nil
+		}
+	}
+
+	line_str := proto_file.ReadLine(int(position.Position.Line))
+	cursor := parser.UTF16Offset(line_str, position.Position.Character)
+	if comment := strings.Index(line_str, "//"); comment < 0 || cursor < comment {
+		word := getWord(line_str, cursor, false)
+		if scalar, ok := types.ScalarTypes[types.ProtoType(word)]; ok {
+			return formatScalarHover(word, scalar), nil
+		}
+	}
+	return "", ErrSymbolNotFound
+}
+
+// formatDeclarationHover renders data followed by the list of details and
+// the sections, e.g. features, that are not empty.
+func formatDeclarationHover(data hoverData, details []hoverDetail, sections ...string) string {
+	buffer := bytes.NewBuffer(nil)
+	if err := hoverTmpl.Execute(buffer, data); err != nil {
+		return err.Error()
+	}
+
+	var lines []string
+	for _, detail := range details {
+		lines = append(lines, fmt.Sprintf("- %s: %s", detail.Name, detail.Value))
+	}
+	for _, section := range append([]string{strings.Join(lines, "\n")}, sections...) {
+		if section != "" {
+			buffer.WriteString("\n\n" + section)
+		}
+	}
+	return buffer.String()
+}
+
+func formatMemberHover(proto_file view.ProtoFile, member memberDefinition) string {
+	switch v := member.Element.(type) {
+	case *protobuf.EnumField:
+		return formatEnumValueHover(proto_file, v)
+	case *protobuf.RPC:
+		return formatRPCHover(proto_file, v, member.Parent)
+	}
+	return formatFieldHover(proto_file, member.Element)
+}
+
+// formatFieldHover shows a field with its number, cardinality, resolved type
+// and JSON name.
+func formatFieldHover(proto_file view.ProtoFile, e protobuf.Visitee) string {
+	var (
+		field       *protobuf.Field
+		source      string
+		cardinality = "singular"
+		typ         string
+	)
+	switch v := e.(type) {
+	case *protobuf.NormalField:
+		field = v.Field
+		switch {
+		case v.Repeated:
+			cardinality = "repeated"
+		case v.Optional:
+			cardinality = "optional"
+		case v.Required:
+			cardinality = "required"
+		}
+		label := ""
+		if cardinality != "singular" {
+			label = cardinality + " "
+		}
+		source = fmt.Sprintf("%s%s %s = %d%s;", label, v.Type, v.Name, v.Sequence, formatInlineOptions(v.Options))
+		typ = resolvedTypeName(proto_file, v.Type, v.Position.Line)
+	case *protobuf.MapField:
+		field = v.Field
+		cardinality = "map"
+		source = fmt.Sprintf("map<%s, %s> %s = %d%s;", v.KeyType, v.Type, v.Name, v.Sequence, formatInlineOptions(v.Options))
+		typ = fmt.Sprintf("map<%s, %s>", v.KeyType, resolvedTypeName(proto_file, v.Type, v.Position.Line))
+	case *protobuf.OneOfField:
+		field = v.Field
+		if oneof, ok := v.Parent.(*protobuf.Oneof); ok {
+			cardinality = "oneof " + oneof.Name
+		}
+		source = fmt.Sprintf("%s %s = %d%s;", v.Type, v.Name, v.Sequence, formatInlineOptions(v.Options))
+		typ = resolvedTypeName(proto_file, v.Type, v.Position.Line)
+	default:
+		return ""
+	}
+
+	details := []hoverDetail{
+		{"Full name", code(qualify(proto_file.Proto().GetScopeByLine(field.Position.Line), field.Name))},
+		{"Number", code(strconv.Itoa(field.Sequence))},
+		{"Cardinality", code(cardinality)},
+		{"Type", code(typ)},
+		{"JSON name", code(jsonName(field))},
+	}
+	if isDeprecated(field.Options) {
+		details = append(details, hoverDetail{"Deprecated", code("true")})
+	}
+	var features string
+	if set, ok := proto_file.Proto().Features(e); ok {
+		features = formatFeatures(set, parser.FeatureTargetField)
+	}
+	return formatDeclarationHover(hoverData{
+		Declaration: prepareDeclarationData(source, field.Comment, field.InlineComment),
+	}, details, features)
+}
+
+func formatEnumValueHover(proto_file view.ProtoFile, v *protobuf.EnumField) string {
+	options := elementOptions(v.Elements)
+	source := fmt.Sprintf("%s = %d%s;", v.Name, v.Integer, formatInlineOptions(options))
+
+	var details []hoverDetail
+	if enum, ok := v.Parent.(*protobuf.Enum); ok {
+		details = append(details, hoverDetail{"Enum", code(qualify(proto_file.Proto().GetScopeByLine(enum.Position.Line), enum.Name))})
+	}
+	details = append(details, hoverDetail{"Number", code(strconv.Itoa(v.Integer))})
+	if isDeprecated(options) {
+		details = append(details, hoverDetail{"Deprecated", code("true")})
+	}
+	var features string
+	if set, ok := proto_file.Proto().Features(v); ok {
+		features = formatFeatures(set, parser.FeatureTargetEnumValue)
+	}
+	return formatDeclarationHover(hoverData{
+		Declaration: prepareDeclarationData(source, v.Comment, v.InlineComment),
+	}, details, features)
+}
+
+// formatRPCHover shows an rpc with its resolved request and response types,
+// its streaming mode and its HTTP rules.
+func formatRPCHover(proto_file view.ProtoFile, v *protobuf.RPC, parent protobuf.Visitee) string {
+	var details []hoverDetail
+	if service, ok := parent.(*protobuf.Service); ok {
+		details = append(details, hoverDetail{"Full name", code(qualify(qualify(packageName(proto_file), service.Name), v.Name))})
+	}
+	details = append(details,
+		hoverDetail{"Request", code(resolvedTypeName(proto_file, v.RequestType, v.Position.Line))},
+		hoverDetail{"Response", code(resolvedTypeName(proto_file, v.ReturnsType, v.Position.Line))},
+		hoverDetail{"Streaming", code(streamingMode(v))},
+	)
+	options := elementOptions(v.Elements)
+	for _, rule := range httpRules(options) {
+		details = append(details, hoverDetail{"HTTP", rule})
+	}
+	if isDeprecated(options) {
+		details = append(details, hoverDetail{"Deprecated", code("true")})
+	}
+	var features string
+	if set, ok := proto_file.Proto().Features(v); ok {
+		features = formatFeatures(set, parser.FeatureTargetMethod)
+	}
+	return formatDeclarationHover(hoverData{
+		Declaration: prepareDeclarationData(rpcSignature(v)+";", v.Comment, v.InlineComment),
+	}, details, features)
+}
+
+// rpcSignature returns the declaration of v without its options, e.g.
+// rpc Get(GetRequest) returns (stream GetResponse).
+func rpcSignature(v *protobuf.RPC) string {
+	stream := func(streams bool) string {
+		if streams {
+			return "stream "
+		}
+		return ""
+	}
+	return fmt.Sprintf("rpc %s(%s%s) returns (%s%s)", v.Name, stream(v.StreamsRequest), v.RequestType, stream(v.StreamsReturns), v.ReturnsType)
+}
+
+func streamingMode(v *protobuf.RPC) string {
+	switch {
+	case v.StreamsRequest && v.StreamsReturns:
+		return "bidirectional streaming"
+	case v.StreamsRequest:
+		return "client streaming"
+	case v.StreamsReturns:
+		return "server streaming"
+	}
+	return "unary"
+}
+
+// httpMethods are the fields of google.api.HttpRule naming the HTTP method.
+var httpMethods = []string{"get", "put", "post", "delete", "patch"}
+
+// httpRules returns the rules of the google.api.http options, e.g.
+// `GET /v1/users/{id}`, followed by the ones of their additional bindings.
+func httpRules(options []*protobuf.Option) (res []string) {
+	for _, o := range options {
+		name, ok := strings.CutPrefix(o.Name, "(google.api.http)")
+		if !ok {
+			continue
+		}
+		if method := strings.TrimPrefix(name, "."); slices.Contains(httpMethods, method) {
+			res = append(res, code(strings.ToUpper(method)+" "+o.Constant.Source))
+		} else if name == "" {
+			res = append(res, httpRulesOf(&o.Constant)...)
+		}
+	}
+	return res
+}
+
+func httpRulesOf(rule *protobuf.Literal) (res []string) {
+	var pattern, body string
+	var bindings []string
+	for _, named := range rule.OrderedMap {
+		if named.Literal == nil {
+			continue
+		}
+		switch {
+		case slices.Contains(httpMethods, named.Name):
+			pattern = strings.ToUpper(named.Name) + " " + named.Source
+		case named.Name == "custom":
+			var kind, path string
+			for _, custom := range named.OrderedMap {
+				switch {
+				case custom.Literal == nil:
+				case custom.Name == "kind":
+					kind = custom.Source
+				case custom.Name == "path":
+					path = custom.Source
+				}
+			}
+			pattern = kind + " " + path
+		case named.Name == "body":
+			body = named.Source
+		case named.Name == "additional_bindings":
+			if len(named.Array) == 0 {
+				bindings = append(bindings, httpRulesOf(named.Literal)...)
+			}
+			for _, binding := range named.Array {
+				bindings = append(bindings, httpRulesOf(binding)...)
+			}
+		}
+	}
+	if pattern != "" {
+		rule := code(pattern)
+		if body != "" {
+			rule += " with body " + code(body)
+		}
+		res = append(res, rule)
+	}
+	return append(res, bindings...)
+}
+
+func formatServiceHover(proto_file view.ProtoFile, service parser.Service) string {
+	data := serviceData{
+		Name: service.Protobuf().Name,
+	}
+	if service.Protobuf().Comment != nil {
+		data.Comments = formatComments(service.Protobuf().Comment.Lines)
+	}
+	for _, rpc := range service.RPCs() {
+		data.RPCs = append(data.RPCs, prepareDeclarationData(rpcSignature(rpc.ProtoRPC)+";", rpc.ProtoRPC.Comment, rpc.ProtoRPC.InlineComment))
+	}
+
+	details := []hoverDetail{{"Full name", code(service.FullyQualifiedName())}}
+	if isDeprecated(elementOptions(service.Protobuf().Elements)) {
+		details = append(details, hoverDetail{"Deprecated", code("true")})
+	}
+	var features string
+	if set, ok := proto_file.Proto().Features(service.Protobuf()); ok {
+		features = formatFeatures(set, parser.FeatureTargetService)
+	}
+	return formatDeclarationHover(hoverData{Service: &data}, details, features)
+}
+
+// formatPackageHover shows a package with the syntax of the file and the
+// loaded files declaring the package.
+func formatPackageHover(proto_file view.ProtoFile, pkg *parser.Package) string {
+	name := pkg.ProtoPackage.Name
+
+	details := []hoverDetail{{"Syntax", code(proto_file.Proto().Syntax())}}
+	if edition := proto_file.Proto().Edition(); edition != "" {
+		details = append(details, hoverDetail{"Edition", code(edition)})
+	}
+	var files []string
+	for _, file := range proto_file.View().GetFiles() {
+		if file.Proto() != nil && packageName(file) == name {
+			files = append(files, code(filepath.Base(uri.URI(file.URI()).Filename())))
+		}
+	}
+	sort.Strings(files)
+	if len(files) > 0 {
+		details = append(details, hoverDetail{"Files", strings.Join(files, ", ")})
+	}
+	return formatDeclarationHover(hoverData{
+		Declaration: prepareDeclarationData("package "+name+";", pkg.ProtoPackage.Comment, pkg.ProtoPackage.InlineComment),
+	}, details)
+}
+
+// optionAt returns the option whose name is under at and the kind of element
+// it is set on.
+func optionAt(proto_file view.ProtoFile, at defines.Range) (res *protobuf.Option, res_target parser.FeatureTarget, ok bool) {
+	check := func(target parser.FeatureTarget, options []*protobuf.Option) {
+		for _, o := range options {
+			if name_range, found := proto_file.Proto().NameRange(o); !ok && found && rangeContains(name_range, at) {
+				res, res_target, ok = o, target, true
+			}
+		}
+	}
+	var visit func(target parser.FeatureTarget, elements []protobuf.Visitee)
+	visit = func(target parser.FeatureTarget, elements []protobuf.Visitee) {
+		for _, e := range elements {
+			switch v := e.(type) {
+			case *protobuf.Option:
+				check(target, []*protobuf.Option{v})
+			case *protobuf.Message:
+				visit(parser.FeatureTargetMessage, v.Elements)
+			case *protobuf.Group:
+				visit(parser.FeatureTargetMessage, v.Elements)
+			case *protobuf.Enum:
+				visit(parser.FeatureTargetEnum, v.Elements)
+			case *protobuf.EnumField:
+				visit(parser.FeatureTargetEnumValue, v.Elements)
+			case *protobuf.Oneof:
+				visit(parser.FeatureTargetOneof, v.Elements)
+			case *protobuf.Service:
+				visit(parser.FeatureTargetService, v.Elements)
+			case *protobuf.RPC:
+				visit(parser.FeatureTargetMethod, v.Elements)
+			case *protobuf.NormalField:
+				check(parser.FeatureTargetField, v.Options)
+			case *protobuf.MapField:
+				check(parser.FeatureTargetField, v.Options)
+			case *protobuf.OneOfField:
+				check(parser.FeatureTargetField, v.Options)
+			}
+		}
+	}
+	visit(parser.FeatureTargetFile, proto_file.Proto().Protobuf().Elements)
+	return res, res_target, ok
+}
+
+// optionsMessages are the messages of google/protobuf/descriptor.proto
+// declaring the options of each kind of element.
+var optionsMessages = map[parser.FeatureTarget]string{
+	parser.FeatureTargetFile:      "google.protobuf.FileOptions",
+	parser.FeatureTargetMessage:   "google.protobuf.MessageOptions",
+	parser.FeatureTargetField:     "google.protobuf.FieldOptions",
+	parser.FeatureTargetOneof:     "google.protobuf.OneofOptions",
+	parser.FeatureTargetEnum:      "google.protobuf.EnumOptions",
+	parser.FeatureTargetEnumValue: "google.protobuf.EnumValueOptions",
+	parser.FeatureTargetService:   "google.protobuf.ServiceOptions",
+	parser.FeatureTargetMethod:    "google.protobuf.MethodOptions",
+}
+
+// formatOptionHover shows the documentation of a built-in option or of the
+// features an option sets. It is empty for custom and unknown options.
+func formatOptionHover(o *protobuf.Option, target parser.FeatureTarget) string {
+	source := fmt.Sprintf("option %s = %s;", o.Name, optionValue(o))
+	if target == parser.FeatureTargetField || target == parser.FeatureTargetEnumValue {
+		source = fmt.Sprintf("[%s = %s]", o.Name, optionValue(o))
+	}
+	data := hoverData{
+		Declaration: prepareDeclarationData(source, o.Comment, o.InlineComment),
+	}
+
+	if parser.IsFeatureOption(o) {
+		var sections []string
+		for _, value := range parser.FeatureOptionValues(o) {
+			if feature, ok := parser.GetFeatureByName(value.Name); ok {
+				values := make([]string, len(feature.Values))
+				for i, v := range feature.Values {
+					values[i] = code(v)
+				}
+				sections = append(sections, fmt.Sprintf("%s: %s, one of %s", code(feature.Name), feature.Doc, strings.Join(values, ", ")))
+			}
+		}
+		return formatDeclarationHover(data, []hoverDetail{{"Option", code(optionsMessages[target] + ".features")}}, strings.Join(sections, "\n\n"))
+	}
+
+	option, ok := getBuiltinOption(o.Name, target)
+	if !ok {
+		return ""
+	}
+	full_name := option.FullName
+	if full_name == "" {
+		full_name = optionsMessages[target] + "." + option.Name
+	}
+	return formatDeclarationHover(data, []hoverDetail{{"Option", code(full_name)}}, option.Doc)
+}
+
+// builtinOption is an option declared in google/protobuf/descriptor.proto.
+type builtinOption struct {
+	Name    string
+	Targets []parser.FeatureTarget
+	// FullName is set for options that are fields of the descriptor instead
+	// of its options message, e.g. json_name.
+	FullName string
+	Doc      string
+}
+
+var builtinOptions = []builtinOption{
+	{
+		Name: "deprecated",
+		Targets: []parser.FeatureTarget{
+			parser.FeatureTargetFile, parser.FeatureTargetMessage, parser.FeatureTargetField, parser.FeatureTargetEnum,
+			parser.FeatureTargetEnumValue, parser.FeatureTargetService, parser.FeatureTargetMethod,
+		},
+		Doc: "Marks the element as deprecated, generated code may annotate it as such.",
+	},
+	{Name: "java_package", Targets: []parser.FeatureTarget{parser.FeatureTargetFile}, Doc: "The package of the generated Java classes, by default the proto package."},
+	{Name: "java_outer_classname", Targets: []parser.FeatureTarget{parser.FeatureTargetFile}, Doc: "The name of the Java class wrapping the generated classes, by default the file name in camel case."},
+	{Name: "java_multiple_files", Targets: []parser.FeatureTarget{parser.FeatureTargetFile}, Doc: "Whether a separate Java file is generated for each top-level message, enum and service."},
+	{Name: "go_package", Targets: []parser.FeatureTarget{parser.FeatureTargetFile}, Doc: "The import path of the generated Go package, optionally followed by a semicolon and the package name."},
+	{Name: "optimize_for", Targets: []parser.FeatureTarget{parser.FeatureTargetFile}, Doc: "Whether generated code is optimized for SPEED, CODE_SIZE or LITE_RUNTIME."},
+	{Name: "cc_enable_arenas", Targets: []parser.FeatureTarget{parser.FeatureTargetFile}, Doc: "Whether arena allocation is enabled for the generated C++ code."},
+	{Name: "objc_class_prefix", Targets: []parser.FeatureTarget{parser.FeatureTargetFile}, Doc: "The prefix of the generated Objective-C classes."},
+	{Name: "csharp_namespace", Targets: []parser.FeatureTarget{parser.FeatureTargetFile}, Doc: "The namespace of the generated C# classes."},
+	{Name: "swift_prefix", Targets: []parser.FeatureTarget{parser.FeatureTargetFile}, Doc: "The prefix of the generated Swift types."},
+	{Name: "php_namespace", Targets: []parser.FeatureTarget{parser.FeatureTargetFile}, Doc: "The namespace of the generated PHP classes."},
+	{Name: "ruby_package", Targets: []parser.FeatureTarget{parser.FeatureTargetFile}, Doc: "The module of the generated Ruby classes."},
+	{Name: "message_set_wire_format", Targets: []parser.FeatureTarget{parser.FeatureTargetMessage}, Doc: "Whether the message uses the legacy MessageSet wire format."},
+	{Name: "map_entry", Targets: []parser.FeatureTarget{parser.FeatureTargetMessage}, Doc: "Set by the compiler on the entry messages of map fields, it must not be set by hand."},
+	{Name: "packed", Targets: []parser.FeatureTarget{parser.FeatureTargetField}, Doc: "Whether a repeated scalar field is encoded packed, the default in proto3."},
+	{Name: "lazy", Targets: []parser.FeatureTarget{parser.FeatureTargetField}, Doc: "Whether a message field is parsed lazily when it is first accessed."},
+	{Name: "ctype", Targets: []parser.FeatureTarget{parser.FeatureTargetField}, Doc: "The C++ type of a string or bytes field: STRING, CORD or STRING_PIECE."},
+	{Name: "jstype", Targets: []parser.FeatureTarget{parser.FeatureTargetField}, Doc: "The JavaScript type of a 64-bit integer field: JS_NORMAL, JS_STRING or JS_NUMBER."},
+	{
+		Name: "json_name", Targets: []parser.FeatureTarget{parser.FeatureTargetField},
+		FullName: "google.protobuf.FieldDescriptorProto.json_name",
+		Doc:      "The name of the field in JSON, by default the field name in lower camel case.",
+	},
+	{
+		Name: "default", Targets: []parser.FeatureTarget{parser.FeatureTargetField},
+		FullName: "google.protobuf.FieldDescriptorProto.default_value",
+		Doc:      "The value of the field when it is not set, only for fields with explicit presence.",
+	},
+	{Name: "allow_alias", Targets: []parser.FeatureTarget{parser.FeatureTargetEnum}, Doc: "Whether values of the enum may share numbers."},
+	{Name: "idempotency_level", Targets: []parser.FeatureTarget{parser.FeatureTargetMethod}, Doc: "Whether the method has no side effects, NO_SIDE_EFFECTS, or is idempotent, IDEMPOTENT."},
+}
+
+// getBuiltinOption gets the built-in option by provided name that can be set
+// on target.
+func getBuiltinOption(name string, target parser.FeatureTarget) (builtinOption, bool) {
+	for _, option := range builtinOptions {
+		if option.Name == name && slices.Contains(option.Targets, target) {
+			return option, true
+		}
+	}
+	return builtinOption{}, false
+}
+
+// formatScalarHover shows the encoding and language mappings of a scalar
+// type.
+func formatScalarHover(name string, scalar types.ScalarType) string {
+	return formatDeclarationHover(hoverData{
+		Declaration: &declarationData{Source: name},
+	}, []hoverDetail{
+		{"Wire type", code(scalar.WireType)},
+		{"Range", scalar.Range},
+		{"Go", code(scalar.Go)},
+		{"Java", code(scalar.Java)},
+		{"C++", code(scalar.Cpp)},
+		{"Python", code(scalar.Python)},
+	}, scalar.Notes)
+}
+
+// resolvedTypeName returns the fully qualified name of the message or enum
+// typ, used on the provided line (1-based), or typ itself for scalar and
+// unresolved types.
+func resolvedTypeName(proto_file view.ProtoFile, typ string, line int) string {
+	if _, ok := types.ScalarTypes[types.ProtoType(typ)]; ok {
+		return typ
+	}
+	if symbols := resolveType(proto_file, typ, line); len(symbols) > 0 {
+		return fullyQualifiedName(symbols[0])
+	}
+	return typ
+}
+
+// jsonName returns the json_name of field or, without it, the name protoc
+// derives: underscores are dropped and the letters following them
+// capitalized.
+func jsonName(field *protobuf.Field) string {
+	for _, o := range field.Options {
+		if o.Name == "json_name" {
+			return o.Constant.Source
+		}
+	}
+	var b strings.Builder
+	upper := false
+	for _, r := range field.Name {
+		switch {
+		case r == '_':
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isDeprecated(options []*protobuf.Option) bool {
+	for _, o := range options {
+		if o.Name == "deprecated" && o.Constant.Source == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// formatInlineOptions formats options as written after a field or enum
+// value, e.g. [deprecated = true], with a leading space.
+func formatInlineOptions(options []*protobuf.Option) string {
+	if len(options) == 0 {
+		return ""
+	}
+	parts := make([]string, len(options))
+	for i, o := range options {
+		parts[i] = o.Name + " = " + optionValue(o)
+	}
+	return " [" + strings.Join(parts, ", ") + "]"
+}
+
+// optionValue returns the value of o as written, aggregate values are
+// abbreviated.
+func optionValue(o *protobuf.Option) string {
+	if len(o.Constant.OrderedMap) > 0 {
+		return "{ ... }"
+	}
+	return o.Constant.SourceRepresentation()
+}
+
+// elementOptions returns the options among elements.
+func elementOptions(elements []protobuf.Visitee) (res []*protobuf.Option) {
+	for _, e := range elements {
+		if o, ok := e.(*protobuf.Option); ok {
+			res = append(res, o)
+		}
+	}
+	return res
+}
+
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func code(s string) string {
+	return "`" + s + "`"
+}
@@ -0,0 +1,75 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+func TestHoverDeclaration(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"users.proto": `syntax = "proto3";
+// Users of the api.
+package acme.api;
+
+option go_package = "acme/api";
+
+message User {
+	// Name of the user.
+	optional string display_name = 1 [deprecated = true]; // shown in lists
+	repeated User friends = 2;
+	map<string, Status> statuses = 3;
+	oneof contact {
+		string email = 4 [json_name = "mail"];
+	}
+	enum Status {
+		ACTIVE = 0;
+	}
+}
+
+// Manages users.
+service Users {
+	// Gets a user.
+	rpc Get(User) returns (stream User) {
+		option (google.api.http) = {
+			get: "/v1/users/{display_name}"
+			additional_bindings { post: "/v1/users:get" body: "*" }
+		};
+	}
+}
+`,
+	})
+
+	hover := func(line, character uint) string {
+		result, err := Hover(context.Background(), &defines.HoverParams{
+			TextDocumentPositionParams: defines.TextDocumentPositionParams{
+				TextDocument: defines.TextDocumentIdentifier{Uri: uris["users.proto"]},
+				Position:     defines.Position{Line: line, Character: character},
+			},
+		})
+		if err != nil {
+			return err.Error()
+		}
+		return result.Contents.(defines.MarkupContent).Value
+	}
+	require.Equal(t, "```proto\n// Name of the user.\noptional string display_name = 1 [deprecated = true]; // shown in lists\n```\n\n"+
+		"- Full name: `acme.api.User.display_name`\n- Number: `1`\n- Cardinality: `optional`\n- Type: `string`\n- JSON name: `displayName`\n- Deprecated: `true`", hover(8, 20))
+	require.Equal(t, "```proto\nmap<string, Status> statuses = 3;\n```\n\n"+
+		"- Full name: `acme.api.User.statuses`\n- Number: `3`\n- Cardinality: `map`\n- Type: `map<string, acme.api.User.Status>`\n- JSON name: `statuses`", hover(10, 25))
+	require.Contains(t, hover(12, 10), "- Cardinality: `oneof contact`\n- Type: `string`\n- JSON name: `mail`")
+	require.Equal(t, "```proto\nACTIVE = 0;\n```\n\n- Enum: `acme.api.User.Status`\n- Number: `0`", hover(15, 3))
+	require.Equal(t, "```proto\n// Manages users.\nservice Users {\n\t// Gets a user.\n\trpc Get(User) returns (stream User);\n}\n```\n\n- Full name: `acme.api.Users`", hover(20, 10))
+	require.Equal(t, "```proto\n// Gets a user.\nrpc Get(User) returns (stream User);\n```\n\n"+
+		"- Full name: `acme.api.Users.Get`\n- Request: `acme.api.User`\n- Response: `acme.api.User`\n- Streaming: `server streaming`\n"+
+		"- HTTP: `GET /v1/users/{display_name}`\n- HTTP: `POST /v1/users:get` with body `*`", hover(22, 7))
+	require.Equal(t, "```proto\n// Users of the api.\npackage acme.api;\n```\n\n- Syntax: `proto3`\n- Files: `users.proto`", hover(2, 10))
+	require.Contains(t, hover(4, 9), "- Option: `google.protobuf.FileOptions.go_package`")
+	require.Contains(t, hover(8, 45), "- Option: `google.protobuf.FieldOptions.deprecated`")
+	require.Contains(t, hover(8, 11), "```proto\nstring\n```\n\n- Wire type: `LEN`")
+	// the type of a message field is the message
+	require.Contains(t, hover(9, 12), "message User {")
+	require.Equal(t, ErrSymbolNotFound.Error(), hover(7, 10))
+}
@@ -0,0 +1,242 @@
+package components
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+	"go.lsp.dev/uri"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/parser"
+	"github.com/walteh/protobuf-language-server/proto/view"
+)
+
+// importProblem is an import that should be removed.
+type importProblem struct {
+	Import   *protobuf.Import
+	Severity defines.DiagnosticSeverity
+	Message  string
+	// Unused is set if nothing of the imported file is referenced.
+	Unused bool
+}
+
+// Imports reports imports that are listed twice, that import the file itself
+// and, as unnecessary code, imports whose types, extensions and public
+// imports are never referenced. Public imports are kept for the files
+// importing this one and never unused.
+func Imports(proto_file view.ProtoFile) []defines.Diagnostic {
+	res := []defines.Diagnostic{}
+	for _, problem := range importProblems(proto_file) {
+		severity := problem.Severity
+		diagnostic := defines.Diagnostic{
+			Range:    importRange(proto_file, problem.Import),
+			Severity: &severity,
+			Message:  problem.Message,
+		}
+		if problem.Unused {
+			diagnostic.Tags = &[]defines.DiagnosticTag{defines.DiagnosticTagUnnecessary}
+		}
+		res = append(res, diagnostic)
+	}
+	return res
+}
+
+func importProblems(proto_file view.ProtoFile) (res []importProblem) {
+	if proto_file.Proto() == nil {
+		return nil
+	}
+	v := proto_file.View()
+	self := uri.URI(proto_file.URI()).Filename()
+
+	// the files declaring the referenced types and the extensions of custom
+	// options
+	used := make(map[string]bool)
+	visitTypeReferences(proto_file, func(typ string, pos scanner.Position, anchor string) {
+		for _, symbol := range resolveType(proto_file, typ, pos.Line) {
+			used[uri.URI(symbol.Filename).Filename()] = true
+		}
+	})
+	for _, extension := range optionExtensionsInFile(proto_file) {
+		for _, symbol := range resolveExtension(proto_file, extension.Name, extension.Option.Position.Line) {
+			used[uri.URI(symbol.Filename).Filename()] = true
+		}
+	}
+
+	lines := make(map[string]int)
+	for _, im := range proto_file.Proto().Imports() {
+		i := im.ProtoImport
+		if line, ok := lines[i.Filename]; ok {
+			res = append(res, importProblem{
+				Import:   i,
+				Severity: defines.DiagnosticSeverityError,
+				Message:  fmt.Sprintf("import %q is already imported on line %d", i.Filename, line),
+			})
+			continue
+		}
+		lines[i.Filename] = i.Position.Line
+
+		import_uri, err := v.GetDocumentUriFromImportPath(proto_file.URI(), i.Filename)
+		if err != nil {
+			continue
+		}
+		if uri.URI(import_uri).Filename() == self {
+			res = append(res, importProblem{
+				Import:   i,
+				Severity: defines.DiagnosticSeverityError,
+				Message:  fmt.Sprintf("import %q imports the file itself", i.Filename),
+			})
+			continue
+		}
+		if i.Kind == "public" {
+			continue
+		}
+		if unused, known := importUnused(v, import_uri, used, make(map[string]bool)); unused && known {
+			res = append(res, importProblem{
+				Import:   i,
+				Severity: defines.DiagnosticSeverityWarning,
+				Message:  fmt.Sprintf("import %q is not used", i.Filename),
+				Unused:   true,
+			})
+		}
+	}
+	return res
+}
+
+// importUnused reports whether neither the file import_uri nor the files it
+// imports publicly are in used, the files declaring a used type or extension.
+// known is false if one of the files could not be loaded.
+func importUnused(v *view.View, import_uri defines.DocumentUri, used, visited map[string]bool) (unused, known bool) {
+	filename := uri.URI(import_uri).Filename()
+	if visited[filename] {
+		return true, true
+	}
+	visited[filename] = true
+	if used[filename] {
+		return false, true
+	}
+
+	import_file, err := v.GetFile(import_uri)
+	if err != nil || import_file.Proto() == nil {
+		return true, false
+	}
+
+	known = true
+	for _, im := range import_file.Proto().Imports() {
+		if im.ProtoImport.Kind != "public" {
+			continue
+		}
+		public_uri, err := v.GetDocumentUriFromImportPath(import_file.URI(), im.ProtoImport.Filename)
+		if err != nil {
+			continue
+		}
+		public_unused, public_known := importUnused(v, public_uri, used, visited)
+		if !public_unused {
+			return false, true
+		}
+		known = known && public_known
+	}
+	return true, known
+}
+
+// importRange returns the range of the import statement.
+func importRange(proto_file view.ProtoFile, i *protobuf.Import) defines.Range {
+	line_str := proto_file.ReadLine(i.Position.Line - 1)
+	start := parser.ColumnOffset(line_str, i.Position.Column-1)
+	end := len(line_str)
+	if idx := strings.Index(line_str[start:], ";"); idx >= 0 {
+		end = start + idx + 1
+	}
+	return defines.Range{
+		Start: defines.Position{Line: uint(i.Position.Line - 1), Character: parser.UTF16Character(line_str, start)},
+		End:   defines.Position{Line: uint(i.Position.Line - 1), Character: parser.UTF16Character(line_str, end)},
+	}
+}
+
+// importLines returns the first and last line, 1-based, of an import
+// together with the comment above it.
+func importLines(i *protobuf.Import) (first, last int) {
+	first = i.Position.Line
+	if i.Comment != nil {
+		first = min(first, i.Comment.Position.Line)
+	}
+	return first, i.Position.Line
+}
+
+// removeImportEdit deletes the lines of an import.
+func removeImportEdit(i *protobuf.Import) defines.TextEdit {
+	first, last := importLines(i)
+	return defines.TextEdit{Range: defines.Range{
+		Start: defines.Position{Line: uint(first - 1)},
+		End:   defines.Position{Line: uint(last)},
+	}}
+}
+
+// organizeImportsEdit sorts the imports by path and drops duplicates, a
+// public import is kept over a plain one. Blank lines between the imports are
+// removed and other statements among them are moved below. It returns false
+// if the imports are already organized.
+func organizeImportsEdit(proto_file view.ProtoFile) (defines.TextEdit, bool) {
+	imports := proto_file.Proto().Imports()
+	if len(imports) == 0 {
+		return defines.TextEdit{}, false
+	}
+	type block struct {
+		i     *protobuf.Import
+		lines []string
+	}
+	var blocks []block
+	first, last := importLines(imports[0].ProtoImport)
+	claimed := make(map[int]bool)
+	for _, im := range imports {
+		from, to := importLines(im.ProtoImport)
+		b := block{i: im.ProtoImport}
+		for line := from; line <= to; line++ {
+			b.lines = append(b.lines, proto_file.ReadLine(line-1))
+			claimed[line] = true
+		}
+		blocks = append(blocks, b)
+		first, last = min(first, from), max(last, to)
+	}
+
+	var original, others []string
+	for line := first; line <= last; line++ {
+		line_str := proto_file.ReadLine(line - 1)
+		original = append(original, line_str)
+		if !claimed[line] && strings.TrimSpace(line_str) != "" {
+			others = append(others, line_str)
+		}
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool {
+		a, b := blocks[i].i, blocks[j].i
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Kind == "public" && b.Kind != "public"
+	})
+	var organized []string
+	for i, b := range blocks {
+		if i > 0 && blocks[i-1].i.Filename == b.i.Filename {
+			continue
+		}
+		organized = append(organized, b.lines...)
+	}
+	if len(others) > 0 {
+		organized = append(append(organized, ""), others...)
+	}
+
+	new_text := strings.Join(organized, "\n") + "\n"
+	if new_text == strings.Join(original, "\n")+"\n" {
+		return defines.TextEdit{}, false
+	}
+	return defines.TextEdit{
+		Range: defines.Range{
+			Start: defines.Position{Line: uint(first - 1)},
+			End:   defines.Position{Line: uint(last)},
+		},
+		NewText: new_text,
+	}, true
+}
@@ -0,0 +1,156 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/view"
+)
+
+func TestImports(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"common.proto": `syntax = "proto3";
+package common;
+
+message User {}
+`,
+		"options.proto": `syntax = "proto3";
+package options;
+
+import "google/protobuf/descriptor.proto";
+
+extend google.protobuf.FieldOptions {
+	string label = 50000;
+}
+`,
+		"reexport.proto": `syntax = "proto3";
+package reexport;
+
+import public "common.proto";
+`,
+		"unused.proto": `syntax = "proto3";
+package unused;
+
+message Unused {}
+`,
+		"api.proto": `syntax = "proto3";
+package api;
+
+import "reexport.proto";
+import "options.proto";
+import "unused.proto";
+import "api.proto";
+import "options.proto";
+import "google/protobuf/empty.proto";
+
+message Request {
+	common.User user = 1 [(options.label) = "user"];
+}
+`,
+	})
+	proto_file, err := view.FromContext(context.Background()).GetFile(uris["api.proto"])
+	require.NoError(t, err)
+
+	type problem struct {
+		Line     uint
+		Severity defines.DiagnosticSeverity
+		Message  string
+		Unused   bool
+	}
+	var got []problem
+	for _, d := range Imports(proto_file) {
+		got = append(got, problem{d.Range.Start.Line, *d.Severity, d.Message, d.Tags != nil})
+	}
+	require.Equal(t, []problem{
+		{5, defines.DiagnosticSeverityWarning, `import "unused.proto" is not used`, true},
+		{6, defines.DiagnosticSeverityError, `import "api.proto" imports the file itself`, false},
+		{7, defines.DiagnosticSeverityError, `import "options.proto" is already imported on line 5`, false},
+		{8, defines.DiagnosticSeverityWarning, `import "google/protobuf/empty.proto" is not used`, true},
+	}, got)
+	require.Equal(t, defines.Range{
+		Start: defines.Position{Line: 5, Character: 0},
+		End:   defines.Position{Line: 5, Character: 22},
+	}, Imports(proto_file)[0].Range)
+
+	// the extend block uses descriptor.proto
+	proto_file, err = view.FromContext(context.Background()).GetFile(uris["options.proto"])
+	require.NoError(t, err)
+	require.Empty(t, Imports(proto_file))
+}
+
+func TestCodeActionRemoveImport(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"unused.proto": `syntax = "proto3";
+package unused;
+`,
+		"api.proto": `syntax = "proto3";
+package api;
+
+// needed for nothing
+import "unused.proto";
+`,
+	})
+	actions := codeActions(t, uris["api.proto"], 4)
+	require.Len(t, actions, 1)
+	require.Equal(t, `Remove import "unused.proto"`, actions[0].Title)
+	require.Equal(t, []defines.TextEdit{{
+		Range: defines.Range{Start: defines.Position{Line: 3}, End: defines.Position{Line: 5}},
+	}}, (*actions[0].Edit.Changes)[string(uris["api.proto"])])
+	require.Empty(t, codeActions(t, uris["api.proto"], 1))
+}
+
+func TestCodeActionOrganizeImports(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"a.proto": "syntax = \"proto3\";\nmessage A {}\n",
+		"b.proto": "syntax = \"proto3\";\nmessage B {}\n",
+		"c.proto": "syntax = \"proto3\";\nmessage C {}\n",
+		"api.proto": `syntax = "proto3";
+
+import "c.proto";
+// b is for B
+import "b.proto";
+
+option go_package = "example.com/api";
+import "a.proto";
+import public "b.proto";
+
+message Request {
+	A a = 1;
+	B b = 2;
+	C c = 3;
+}
+`,
+		"sorted.proto": `syntax = "proto3";
+
+import "a.proto";
+import "b.proto";
+`,
+	})
+	organize := func(document_uri defines.DocumentUri) []defines.CodeAction {
+		only := []defines.CodeActionKind{defines.CodeActionKindSourceOrganizeImports}
+		res, err := CodeAction(context.Background(), &defines.CodeActionParams{
+			TextDocument: defines.TextDocumentIdentifier{Uri: document_uri},
+			Context:      defines.CodeActionContext{Only: &only},
+		})
+		require.NoError(t, err)
+		return *res
+	}
+
+	actions := organize(uris["api.proto"])
+	require.Len(t, actions, 1)
+	require.Equal(t, defines.CodeActionKindSourceOrganizeImports, *actions[0].Kind)
+	require.Equal(t, []defines.TextEdit{{
+		Range: defines.Range{Start: defines.Position{Line: 2}, End: defines.Position{Line: 9}},
+		NewText: `import "a.proto";
+import public "b.proto";
+import "c.proto";
+
+option go_package = "example.com/api";
+`,
+	}}, (*actions[0].Edit.Changes)[string(uris["api.proto"])])
+
+	require.Empty(t, organize(uris["sorted.proto"]))
+}
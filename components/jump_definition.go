@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"text/scanner"
 
+	protobuf "github.com/emicklei/proto"
 	"github.com/walteh/protobuf-language-server/proto/parser"
 	"github.com/walteh/protobuf-language-server/proto/view"
 
@@ -15,18 +17,28 @@ import (
 )
 
 type SymbolDefinition struct {
-	Filename  string
-	Position  defines.Position
+	Filename string
+	// Position is the start of the name of the declaration.
+	Position defines.Position
+	// NameRange is the range of the name and Range the range of the whole
+	// declaration, including its body.
+	NameRange defines.Range
+	Range     defines.Range
 	Type      string
 	Enum      parser.Enum
 	Message   parser.Message
+	Extension *parser.Extension
 	ImportUri string
 }
 
 const (
-	DefinitionTypeImport  = "import"
-	DefinitionTypeMessage = "message"
-	DefinitionTypeEnum    = "enum"
+	DefinitionTypeImport    = "import"
+	DefinitionTypeMessage   = "message"
+	DefinitionTypeEnum      = "enum"
+	DefinitionTypeField     = "field"
+	DefinitionTypeEnumValue = "enum_value"
+	DefinitionTypeRPC       = "rpc"
+	DefinitionTypeExtension = "extension"
 )
 
 var ErrSymbolNotFound = errors.New("symbol not found")
@@ -50,35 +62,11 @@ func locationFromSymbols(symbols []SymbolDefinition) (result []defines.LocationL
 			result = append(result, defines.LocationLink{
 				TargetUri: defines.DocumentUri(symbol.ImportUri),
 			})
-		case DefinitionTypeEnum:
-			proto := symbol.Enum.Protobuf()
+		case DefinitionTypeEnum, DefinitionTypeMessage, DefinitionTypeExtension:
 			result = append(result, defines.LocationLink{
-				TargetUri: defines.DocumentUri(proto.Position.Filename),
-				TargetSelectionRange: defines.Range{
-					Start: defines.Position{
-						Line:      symbol.Position.Line,
-						Character: symbol.Position.Character,
-					},
-					End: defines.Position{
-						Line:      symbol.Position.Line,
-						Character: symbol.Position.Character + uint(len(proto.Name)),
-					},
-				},
-			})
-		case DefinitionTypeMessage:
-			proto := symbol.Message.Protobuf()
-			result = append(result, defines.LocationLink{
-				TargetUri: defines.DocumentUri(proto.Position.Filename),
-				TargetSelectionRange: defines.Range{
-					Start: defines.Position{
-						Line:      symbol.Position.Line,
-						Character: symbol.Position.Character,
-					},
-					End: defines.Position{
-						Line:      symbol.Position.Line,
-						Character: symbol.Position.Character + uint(len(proto.Name)),
-					},
-				},
+				TargetUri:            defines.DocumentUri(symbol.Filename),
+				TargetRange:          symbol.Range,
+				TargetSelectionRange: symbol.NameRange,
 			})
 		}
 	}
@@ -103,32 +91,34 @@ func findSymbolDefinition(ctx context.Context, position *defines.TextDocumentPos
 func JumpPbHeaderDefine(ctx context.Context, req *defines.TextDocumentPositionParams) (result []SymbolDefinition, err error) {
 	proto_uri := strings.ReplaceAll(string(req.TextDocument.Uri), "bazel-out/local_linux-fastbuild/genfiles/", "")
 	proto_uri = strings.ReplaceAll(proto_uri, ".pb.h", ".proto")
-	proto_file, err := view.ViewManager.GetFile(defines.DocumentUri(proto_uri))
+	v := view.FromContext(ctx)
+	proto_file, err := v.GetFile(defines.DocumentUri(proto_uri))
 	if err != nil {
 		return nil, err
 	}
-	line := view.ViewManager.GetPbHeaderLine(req.TextDocument.Uri, int(req.Position.Line))
-	word := getWord(line, int(req.Position.Character), false)
+	line := v.GetPbHeaderLine(req.TextDocument.Uri, int(req.Position.Line))
+	word := getWord(line, parser.UTF16Offset(line, req.Position.Character), false)
 	logs.Printf("line %v, word %v", line, word)
-	res, err := searchType(proto_file, word)
+	scope := packageName(proto_file)
+	res := resolveTypeIn([]view.ProtoFile{proto_file}, word, scope)
 	// better than nothing
-	if (res == nil || len(res) == 0) && strings.Contains(word, "_") {
+	if len(res) == 0 && strings.Contains(word, "_") {
 		split_res := strings.Split(word, "_")
 		if len(split_res) > 0 {
-			res, err = searchType(proto_file, split_res[0])
+			res = resolveTypeIn([]view.ProtoFile{proto_file}, split_res[0], scope)
 		}
 	}
-	return res, err
+	return res, nil
 }
 
 func JumpProtoDefine(ctx context.Context, position *defines.TextDocumentPositionParams) (result []SymbolDefinition, err error) {
-	proto_file, err := view.ViewManager.GetFile(position.TextDocument.Uri)
+	proto_file, err := view.FromContext(ctx).GetFile(position.TextDocument.Uri)
 
 	if err != nil {
 		return nil, err
 	}
 	line_str := proto_file.ReadLine(int(position.Position.Line))
-	if len(line_str) < int(position.Position.Character) {
+	if parser.UTF16Character(line_str, len(line_str)) < position.Position.Character {
 		return nil, fmt.Errorf("pos %v line_str %v", position.Position, line_str)
 	}
 
@@ -137,98 +127,91 @@ func JumpProtoDefine(ctx context.Context, position *defines.TextDocumentPosition
 		return jumpImport(ctx, position, line_str)
 	}
 
-	// type define
-	package_and_word := getWord(line_str, int(position.Position.Character), true)
-	pos := strings.LastIndexAny(package_and_word, ".")
-
-	my_package := ""
-	if len(proto_file.Proto().Packages()) > 0 {
-		my_package = proto_file.Proto().Packages()[0].ProtoPackage.Name
-	}
-
-	var package_name, word string
-	word_only := true
-	if pos == -1 {
-		package_name = my_package
-		word = package_and_word
-	} else {
-		package_name, word = package_and_word[0:pos], package_and_word[pos+1:]
-		word_only = false
+	// the extension of a custom option
+	if extension, ok := optionExtensionAt(proto_file, position.Position); ok {
+		return resolveExtension(proto_file, extension.Name, extension.Option.Position.Line), nil
 	}
 
-	if word_only {
-		res, err := searchTypeNested(proto_file, word, int(position.Position.Line+1))
-		if err == nil && len(res) > 0 {
-			return res, nil
-		}
-	}
-	if my_package == package_name {
-		res, err := searchType(proto_file, word)
-		if err == nil && len(res) > 0 {
-			return res, nil
-		}
-	}
-
-	res, err := searchImport(proto_file, package_name, my_package, word, "")
-	if err == nil && len(res) > 0 {
+	// the name of a message or enum declaration
+	if res := searchDeclaration(proto_file, position.Position); len(res) > 0 {
 		return res, nil
 	}
+	// the name of a field, enum value or rpc declaration is not a type
+	if _, ok := findMemberDefinition(ctx, position); ok {
+		return nil, nil
+	}
 
-	return nil, nil
+	// type define
+	package_and_word := getWord(line_str, parser.UTF16Offset(line_str, position.Position.Character), true)
+	return resolveType(proto_file, package_and_word, int(position.Position.Line+1)), nil
 }
 
-func searchImport(proto view.ProtoFile, package_name, my_package, word, kind string) (result []SymbolDefinition, err error) {
-	for _, im := range proto.Proto().Imports() {
-
-		if kind != "" && im.ProtoImport.Kind != kind {
-			continue
-		}
-
-		import_uri, err := view.ViewManager.GetDocumentUriFromImportPath(proto.URI(), im.ProtoImport.Filename)
-		if err != nil {
-			continue
-		}
+// resolveType resolves a possibly qualified type name that is used on the
+// provided line (1-based) of proto_file to the message or enum it refers to,
+// following the scoping rules of protobuf, see parser.Resolver.
+func resolveType(proto_file view.ProtoFile, package_and_word string, line int) []SymbolDefinition {
+	if proto_file.Proto() == nil {
+		return nil
+	}
+	return resolveTypeIn(visibleFiles(proto_file), package_and_word, proto_file.Proto().GetScopeByLine(line))
+}
 
-		import_file, err := view.ViewManager.GetFile(import_uri)
-		if err != nil {
+// resolveTypeIn resolves typ, used in scope, against the types of files.
+func resolveTypeIn(files []view.ProtoFile, typ string, scope string) []SymbolDefinition {
+	t, ok := newResolver(files).Resolve(typ, scope)
+	if !ok {
+		return nil
+	}
+	for _, file := range files {
+		if file.Proto() != t.Proto {
 			continue
 		}
-
-		packages := import_file.Proto().Packages()
-		if len(packages) > 0 {
-			if qualifierReferencesPackage(package_name, packages[0].ProtoPackage.Name, my_package) {
-				// same packages_name in different file
-				res, err := searchType(import_file, word)
-				if err == nil && len(res) > 0 {
-					return res, nil
-				}
-			}
-		}
-		res, err := searchImport(import_file, package_name, my_package, word, "public")
-		if res != nil && len(res) > 0 {
-			return res, err
+		if t.Message != nil {
+			t.Message.Protobuf().Position.Filename = string(file.URI())
+			return []SymbolDefinition{messageSymbolDefinition(file, t.Message)}
 		}
+		t.Enum.Protobuf().Position.Filename = string(file.URI())
+		return []SymbolDefinition{enumSymbolDefinition(file, t.Enum)}
 	}
-
-	return nil, nil
-
+	return nil
 }
 
-func qualifierReferencesPackage(query_pkg string, candidate_pkg string, current_pkg string) bool {
-	if query_pkg == candidate_pkg { // fully qualified name
-		return true
+// newResolver returns a parser.Resolver for the declarations of files.
+func newResolver(files []view.ProtoFile) *parser.Resolver {
+	protos := make([]parser.Proto, len(files))
+	for i, file := range files {
+		protos[i] = file.Proto()
 	}
+	return parser.NewResolver(protos...)
+}
 
-	// If the current package and the candidate package are within the
-	// same package, then the query need not include this package prefix.
-	// Example:
-	//   query_pkg = "some.dependency"
-	//   candidate_pkg = "common.some.dependency"
-	//   current_pkg = "common.user"
-
-	prefix := strings.TrimSuffix(candidate_pkg, "."+query_pkg)
-
-	return current_pkg == prefix || strings.HasPrefix(current_pkg, prefix+".")
+// visibleFiles returns proto_file followed by the files whose types it can
+// use: the files it imports and, transitively, the files those import
+// publicly.
+func visibleFiles(proto_file view.ProtoFile) []view.ProtoFile {
+	res := []view.ProtoFile{proto_file}
+	visited := map[defines.DocumentUri]bool{proto_file.URI(): true}
+	var visit func(file view.ProtoFile, public_only bool)
+	visit = func(file view.ProtoFile, public_only bool) {
+		for _, im := range file.Proto().Imports() {
+			if public_only && im.ProtoImport.Kind != "public" {
+				continue
+			}
+			import_uri, err := file.View().GetDocumentUriFromImportPath(file.URI(), im.ProtoImport.Filename)
+			if err != nil || visited[import_uri] {
+				continue
+			}
+			visited[import_uri] = true
+			import_file, err := file.View().GetFile(import_uri)
+			if err != nil || import_file.Proto() == nil {
+				continue
+			}
+			res = append(res, import_file)
+			visit(import_file, true)
+		}
+	}
+	visit(proto_file, false)
+	return res
 }
 
 func jumpImport(ctx context.Context, position *defines.TextDocumentPositionParams, line_str string) (result []SymbolDefinition, err error) {
@@ -237,7 +220,7 @@ func jumpImport(ctx context.Context, position *defines.TextDocumentPositionParam
 	if pos == nil {
 		return nil, fmt.Errorf("import match failed")
 	}
-	import_uri, err := view.ViewManager.GetDocumentUriFromImportPath(position.TextDocument.Uri, line_str[pos[0]+1:pos[1]-1])
+	import_uri, err := view.FromContext(ctx).GetDocumentUriFromImportPath(position.TextDocument.Uri, line_str[pos[0]+1:pos[1]-1])
 	if err != nil {
 		return nil, err
 	}
@@ -247,83 +230,98 @@ func jumpImport(ctx context.Context, position *defines.TextDocumentPositionParam
 	}}, nil
 }
 
-func searchTypeNested(proto_file view.ProtoFile, word string, line int) (result []SymbolDefinition, err error) {
-	// search message
-	for _, message := range proto_file.Proto().GetAllParentMessage(line) {
-		if message.Protobuf().Name == word {
-			message.Protobuf().Position.Filename = string(proto_file.URI())
-			result = append(result, messageSymbolDefinition(proto_file, message))
+// searchDeclaration finds the messages and enums, including nested ones,
+// whose name is declared at pos.
+func searchDeclaration(proto_file view.ProtoFile, pos defines.Position) (result []SymbolDefinition) {
+	at := defines.Range{Start: pos, End: pos}
+	searchEnums := func(enums []parser.Enum) {
+		for _, enum := range enums {
+			if symbol := enumSymbolDefinition(proto_file, enum); rangeContains(symbol.NameRange, at) {
+				enum.Protobuf().Position.Filename = string(proto_file.URI())
+				result = append(result, symbol)
+			}
 		}
 	}
-	// search enum
-	for _, enum := range proto_file.Proto().GetAllParentEnum(line) {
-		if enum.Protobuf().Name == word {
-			enum.Protobuf().Position.Filename = string(proto_file.URI())
-			result = append(result, enumSymbolDefinition(proto_file, enum))
+	var searchMessages func(messages []parser.Message)
+	searchMessages = func(messages []parser.Message) {
+		for _, message := range messages {
+			if message.Protobuf().IsExtend {
+				continue
+			}
+			if symbol := messageSymbolDefinition(proto_file, message); rangeContains(symbol.NameRange, at) {
+				message.Protobuf().Position.Filename = string(proto_file.URI())
+				result = append(result, symbol)
+			}
+			searchEnums(message.NestedEnums())
+			searchMessages(message.NestedMessages())
 		}
 	}
-
-	if len(result) == 0 {
-		return nil, fmt.Errorf("%w: %s", ErrSymbolNotFound, word)
-	}
-
-	return result, nil
+	searchEnums(proto_file.Proto().Enums())
+	searchMessages(proto_file.Proto().Messages())
+	return result
 }
 
-func searchType(proto_file view.ProtoFile, word string) (result []SymbolDefinition, err error) {
-	// search message
-	for _, message := range proto_file.Proto().Messages() {
-		if message.Protobuf().Name == word {
-			message.Protobuf().Position.Filename = string(proto_file.URI())
-			result = append(result, messageSymbolDefinition(proto_file, message))
-		}
-	}
-	// search enum
-	for _, enum := range proto_file.Proto().Enums() {
-		if enum.Protobuf().Name == word {
-			enum.Protobuf().Position.Filename = string(proto_file.URI())
-			result = append(result, enumSymbolDefinition(proto_file, enum))
-		}
-	}
-
-	if len(result) == 0 {
-		return nil, fmt.Errorf("%w: %s", ErrSymbolNotFound, word)
-	}
-
-	return result, nil
+// fullyQualifiedName returns the fully qualified name of a message, enum or
+// extension.
+func fullyQualifiedName(symbol SymbolDefinition) string {
+	switch symbol.Type {
+	case DefinitionTypeMessage:
+		return symbol.Message.FullyQualifiedName()
+	case DefinitionTypeEnum:
+		return symbol.Enum.FullyQualifiedName()
+	case DefinitionTypeExtension:
+		return symbol.Extension.Field.FullyQualifiedName
+	}
+	return ""
 }
 
 func messageSymbolDefinition(proto_file view.ProtoFile, message parser.Message) SymbolDefinition {
-	line := proto_file.ReadLine(message.Protobuf().Position.Line - 1)
-	symbolStart := strings.Index(line, message.Protobuf().Name)
+	name_range, rng := declarationRanges(proto_file, message.Protobuf(), message.Protobuf().Position, message.Protobuf().Name)
 	return SymbolDefinition{
-		Filename: string(proto_file.URI()),
-		Position: defines.Position{
-			Line:      uint(message.Protobuf().Position.Line - 1),
-			Character: uint(symbolStart),
-		},
-		Type:    DefinitionTypeMessage,
-		Message: message,
+		Filename:  string(proto_file.URI()),
+		Position:  name_range.Start,
+		NameRange: name_range,
+		Range:     rng,
+		Type:      DefinitionTypeMessage,
+		Message:   message,
 	}
 }
 
 func enumSymbolDefinition(proto_file view.ProtoFile, enum parser.Enum) SymbolDefinition {
-	line := proto_file.ReadLine(enum.Protobuf().Position.Line - 1)
-	symbolStart := strings.Index(line, enum.Protobuf().Name)
+	name_range, rng := declarationRanges(proto_file, enum.Protobuf(), enum.Protobuf().Position, enum.Protobuf().Name)
 	return SymbolDefinition{
-		Filename: string(proto_file.URI()),
-		Position: defines.Position{
-			Line:      uint(enum.Protobuf().Position.Line - 1),
-			Character: uint(symbolStart),
-		},
-		Type: DefinitionTypeEnum,
-		Enum: enum,
+		Filename:  string(proto_file.URI()),
+		Position:  name_range.Start,
+		NameRange: name_range,
+		Range:     rng,
+		Type:      DefinitionTypeEnum,
+		Enum:      enum,
+	}
+}
+
+// declarationRanges returns the range of the name and the whole range of the
+// element v declared at pos, see parser.Proto.NameRange. Without them the
+// name is searched from pos on.
+func declarationRanges(proto_file view.ProtoFile, v protobuf.Visitee, pos scanner.Position, name string) (name_range, rng defines.Range) {
+	name_range, ok := proto_file.Proto().NameRange(v)
+	if !ok {
+		name_range = problemRange(proto_file, pos, "", name)
 	}
+	if rng, ok = proto_file.Proto().Range(v); !ok {
+		rng = name_range
+	}
+	return name_range, rng
 }
 
 func getWord(line string, idx int, includeDot bool) string {
+	l, r := getWordRange(line, idx, includeDot)
+	return line[l:r]
+}
+
+// getWordRange returns the start and end index of the word around idx.
+func getWordRange(line string, idx int, includeDot bool) (int, int) {
 	if len(line) == 0 {
-		return ""
+		return 0, 0
 	}
 	if idx < 0 {
 		idx = 0
@@ -347,5 +345,5 @@ func getWord(line string, idx int, includeDot bool) string {
 	for r < len(line) && isWordChar(line[r]) {
 		r++
 	}
-	return line[l:r]
+	return l, r
 }
@@ -1,8 +1,17 @@
 package components
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.lsp.dev/uri"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/view"
 )
 
 func Test_getWord(t *testing.T) {
@@ -69,3 +78,140 @@ func Test_getWord(t *testing.T) {
 		})
 	}
 }
+
+func TestJumpDefineWellKnownType(t *testing.T) {
+	// the embedded protos are extracted to the user cache
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	uris := setupWorkspace(t, map[string]string{
+		"api.proto": `syntax = "proto3";
+package api;
+
+import "google/protobuf/timestamp.proto";
+
+message Event {
+	google.protobuf.Timestamp created_at = 1;
+}
+`,
+	})
+
+	proto_file, err := view.FromContext(context.Background()).GetFile(uris["api.proto"])
+	require.NoError(t, err)
+	require.Empty(t, Diagnostics(proto_file))
+
+	result, err := JumpDefine(context.Background(), &defines.DefinitionParams{
+		TextDocumentPositionParams: defines.TextDocumentPositionParams{
+			TextDocument: defines.TextDocumentIdentifier{Uri: uris["api.proto"]},
+			Position:     defines.Position{Line: 6, Character: 20},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, *result, 1)
+	target := uri.URI((*result)[0].TargetUri).Filename()
+	require.True(t, strings.HasSuffix(target, "google/protobuf/timestamp.proto"), target)
+	require.FileExists(t, target)
+}
+
+func TestJumpDefineScoping(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"acme/common.proto": `syntax = "proto3";
+package acme.common;
+
+message Status {}
+`,
+		"acme/reexport.proto": `syntax = "proto3";
+package acme.reexport;
+
+import public "acme/common.proto";
+`,
+		"acme/api.proto": `syntax = "proto3";
+package acme.api;
+
+import "acme/reexport.proto";
+
+message Status {}
+
+message User {
+	message Status {}
+	Status own = 1;
+	.acme.api.Status top = 2;
+	common.Status shared = 3;
+	message Inner {
+		Status inherited = 1;
+	}
+}
+`,
+	})
+
+	jump := func(line, character uint) string {
+		result, err := JumpDefine(context.Background(), &defines.DefinitionParams{
+			TextDocumentPositionParams: defines.TextDocumentPositionParams{
+				TextDocument: defines.TextDocumentIdentifier{Uri: uris["acme/api.proto"]},
+				Position:     defines.Position{Line: line, Character: character},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, *result, 1)
+		link := (*result)[0]
+		return fmt.Sprintf("%s:%d", filepath.Base(uri.URI(link.TargetUri).Filename()), link.TargetSelectionRange.Start.Line)
+	}
+	// the nested message hides the top level one, also in nested scopes
+	require.Equal(t, "api.proto:8", jump(9, 2))
+	require.Equal(t, "api.proto:8", jump(13, 3))
+	// a leading dot is fully qualified
+	require.Equal(t, "api.proto:5", jump(10, 12))
+	// a partial package qualifier through a public import
+	require.Equal(t, "common.proto:3", jump(11, 10))
+}
+
+func TestJumpDefineRanges(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"api.proto": `syntax = "proto3";
+package api;
+
+message Message { Message Message = 1; }
+
+message
+	User
+{
+	Message message = 1;
+}
+`,
+	})
+	jump := func(line, character uint) []defines.LocationLink {
+		result, err := JumpDefine(context.Background(), &defines.DefinitionParams{
+			TextDocumentPositionParams: defines.TextDocumentPositionParams{
+				TextDocument: defines.TextDocumentIdentifier{Uri: uris["api.proto"]},
+				Position:     defines.Position{Line: line, Character: character},
+			},
+		})
+		require.NoError(t, err)
+		return *result
+	}
+	message := defines.LocationLink{
+		TargetUri: uris["api.proto"],
+		TargetRange: defines.Range{
+			Start: defines.Position{Line: 3, Character: 0},
+			End:   defines.Position{Line: 3, Character: 40},
+		},
+		TargetSelectionRange: defines.Range{
+			Start: defines.Position{Line: 3, Character: 8},
+			End:   defines.Position{Line: 3, Character: 15},
+		},
+	}
+	// the declaration, the type and the field named like the message
+	require.Equal(t, []defines.LocationLink{message}, jump(3, 10))
+	require.Equal(t, []defines.LocationLink{message}, jump(3, 20))
+	require.Empty(t, jump(3, 30))
+	require.Equal(t, []defines.LocationLink{message}, jump(8, 3))
+
+	// the name of a declaration spanning several lines
+	result := jump(6, 2)
+	require.Len(t, result, 1)
+	require.Equal(t, defines.Range{
+		Start: defines.Position{Line: 6, Character: 1},
+		End:   defines.Position{Line: 6, Character: 5},
+	}, result[0].TargetSelectionRange)
+	require.Equal(t, uint(5), result[0].TargetRange.Start.Line)
+	require.Equal(t, uint(9), result[0].TargetRange.End.Line)
+}
@@ -0,0 +1,40 @@
+package components
+
+import (
+	"text/scanner"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/parser"
+	"github.com/walteh/protobuf-language-server/proto/view"
+)
+
+// Lint reports the lint problems of a proto file as warnings, the code of a
+// diagnostic is the ID of the violated rule.
+func Lint(proto_file view.ProtoFile) []defines.Diagnostic {
+	res := []defines.Diagnostic{}
+	for _, problem := range proto_file.View().Lint(proto_file) {
+		severity := defines.DiagnosticSeverityWarning
+		res = append(res, defines.Diagnostic{
+			Range:    problemRange(proto_file, problem.Position, problem.Anchor, problem.Text),
+			Severity: &severity,
+			Code:     problem.Rule,
+			Message:  problem.Message,
+		})
+	}
+	return res
+}
+
+// problemRange returns the range of text, which is searched after anchor
+// starting at pos, see locateWord. Without text, or if it is not found, the
+// rest of the line is marked.
+func problemRange(proto_file view.ProtoFile, pos scanner.Position, anchor, text string) defines.Range {
+	if text != "" {
+		if rng, ok := locateWord(proto_file, pos.Line-1, pos.Column-1, anchor, text); ok {
+			return rng
+		}
+	}
+	start := positionOf(proto_file, pos)
+	line_str := proto_file.ReadLine(pos.Line - 1)
+	end := defines.Position{Line: start.Line, Character: max(parser.UTF16Character(line_str, len(line_str)), start.Character)}
+	return defines.Range{Start: start, End: end}
+}
@@ -0,0 +1,47 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/view"
+)
+
+func TestLint(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"buf.yaml": "version: v2\n",
+		"acme/v1/user_api.proto": `syntax = "proto3";
+package acme.v1;
+
+message user {
+	string userName = 1;
+}
+`,
+		"Other.proto": "syntax = \"proto3\";\npackage other.v1;\n",
+	})
+
+	type diagnostic struct {
+		Line, Start, End uint
+		Code             interface{}
+	}
+	lint := func(name string) (got []diagnostic) {
+		proto_file, err := view.FromContext(context.Background()).GetFile(uris[name])
+		require.NoError(t, err)
+		for _, d := range Lint(proto_file) {
+			got = append(got, diagnostic{d.Range.Start.Line, d.Range.Start.Character, d.Range.End.Character, d.Code})
+			require.Equal(t, defines.DiagnosticSeverityWarning, *d.Severity)
+		}
+		return got
+	}
+	require.Equal(t, []diagnostic{
+		{3, 8, 12, "MESSAGE_PASCAL_CASE"},
+		{4, 8, 16, "FIELD_LOWER_SNAKE_CASE"},
+	}, lint("acme/v1/user_api.proto"))
+	require.Equal(t, []diagnostic{
+		{0, 0, 18, "FILE_LOWER_SNAKE_CASE"},
+		{1, 8, 16, "PACKAGE_DIRECTORY_MATCH"},
+	}, lint("Other.proto"))
+}
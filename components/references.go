@@ -0,0 +1,339 @@
+package components
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/walteh/protobuf-language-server/proto/parser"
+	"github.com/walteh/protobuf-language-server/proto/types"
+	"github.com/walteh/protobuf-language-server/proto/view"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+// maxStatementLines is how many lines a single declaration is searched over
+// when locating a name, e.g. an rpc whose returns clause is on the next line.
+const maxStatementLines = 5
+
+// References finds every usage of the message or enum under the cursor in
+// field types, map values, rpc request/response types and extend blocks of the
+// loaded files. Fields, enum values and rpcs are only referenced by their
+// declaration.
+func References(ctx context.Context, req *defines.ReferenceParams) (result *[]defines.Location, err error) {
+	if !view.IsProtoFile(req.TextDocument.Uri) {
+		return nil, nil
+	}
+	symbols, err := findSymbolDefinition(ctx, &req.TextDocumentPositionParams)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []defines.Location{}
+	if len(symbols) == 0 {
+		if !req.Context.IncludeDeclaration {
+			return &res, nil
+		}
+		if member, ok := findMemberDefinition(ctx, &req.TextDocumentPositionParams); ok {
+			res = append(res, member.Location)
+		}
+		return &res, nil
+	}
+
+	target := symbols[0]
+	if target.Type == DefinitionTypeImport {
+		return &res, nil
+	}
+	if req.Context.IncludeDeclaration {
+		res = append(res, symbolDeclarationLocation(target))
+	}
+	for _, file := range view.FromContext(ctx).WorkspaceFiles() {
+		select {
+		case <-ctx.Done():
+			return &res, nil
+		default:
+		}
+		refs := typeReferencesInFile(file, func(symbol SymbolDefinition) bool {
+			return sameSymbol(symbol, target)
+		})
+		for _, ref := range refs {
+			res = append(res, ref.Location)
+		}
+	}
+
+	res = uniqueLocations(res)
+	return &res, nil
+}
+
+// typeReference is a message or enum type name written in a file.
+type typeReference struct {
+	// Text is the type name as written, possibly qualified.
+	Text     string
+	Location defines.Location
+	Symbol   SymbolDefinition
+}
+
+// typeReferencesInFile returns every type reference in file that resolves to a
+// symbol accepted by match.
+func typeReferencesInFile(file view.ProtoFile, match func(SymbolDefinition) bool) (res []typeReference) {
+	visitTypeReferences(file, func(typ string, pos scanner.Position, anchor string) {
+		for _, symbol := range resolveType(file, typ, pos.Line) {
+			if !match(symbol) {
+				continue
+			}
+			if rng, ok := locateWord(file, pos.Line-1, pos.Column-1, anchor, typ); ok {
+				res = append(res, typeReference{
+					Text:     typ,
+					Location: defines.Location{Uri: file.URI(), Range: rng},
+					Symbol:   symbol,
+				})
+			}
+			return
+		}
+	})
+	return res
+}
+
+// visitTypeReferences calls visit for every message or enum type name used in
+// file, together with the position of the declaration using it and the anchor
+// after which the name is written, see locateWord.
+func visitTypeReferences(file view.ProtoFile, visit func(typ string, pos scanner.Position, anchor string)) {
+	visitType := func(typ string, pos scanner.Position, anchor string) {
+		if typ == "" || isBuildInType(typ) {
+			return
+		}
+		visit(typ, pos, anchor)
+	}
+
+	var visitMessages func(messages []parser.Message)
+	visitMessages = func(messages []parser.Message) {
+		for _, message := range messages {
+			if message.Protobuf().IsExtend {
+				visitType(message.Protobuf().Name, message.Protobuf().Position, "")
+			}
+			for _, f := range message.Fields() {
+				visitType(f.ProtoField.Type, f.ProtoField.Position, "")
+			}
+			for _, f := range message.MapFields() {
+				visitType(f.ProtoMapField.Type, f.ProtoMapField.Position, ",")
+			}
+			for _, o := range message.Oneofs() {
+				for _, e := range o.Protobuf().Elements {
+					if f, ok := e.(*protobuf.OneOfField); ok {
+						visitType(f.Type, f.Position, "")
+					}
+				}
+			}
+			visitMessages(message.NestedMessages())
+		}
+	}
+	visitMessages(file.Proto().Messages())
+
+	for _, service := range file.Proto().Services() {
+		for _, rpc := range service.RPCs() {
+			visitType(rpcType(rpc.ProtoRPC.RequestType), rpc.ProtoRPC.Position, "(")
+			visitType(rpcType(rpc.ProtoRPC.ReturnsType), rpc.ProtoRPC.Position, "returns")
+		}
+	}
+}
+
+// rpcType returns the message type of an rpc request or response. The parser
+// reads a stream of a fully qualified type, e.g. stream .foo.Bar, as the
+// single name stream.foo.Bar.
+func rpcType(typ string) string {
+	if strings.HasPrefix(typ, "stream.") {
+		return strings.TrimPrefix(typ, "stream")
+	}
+	return typ
+}
+
+// memberDefinition is a field, enum value or rpc declaration.
+type memberDefinition struct {
+	Type     string
+	Name     string
+	Location defines.Location
+	// Parent is the message, enum or service the member is declared in.
+	Parent protobuf.Visitee
+	// Element is the declared field, enum value or rpc.
+	Element protobuf.Visitee
+}
+
+// findMemberDefinition returns the field, enum value or rpc that is declared
+// under the cursor.
+func findMemberDefinition(ctx context.Context, position *defines.TextDocumentPositionParams) (member memberDefinition, ok bool) {
+	proto_file, err := view.FromContext(ctx).GetFile(position.TextDocument.Uri)
+	if err != nil || proto_file.Proto() == nil {
+		return member, false
+	}
+	at := defines.Range{Start: position.Position, End: position.Position}
+
+	var found *defines.Range
+	match := func(typ, name string, e protobuf.Visitee, pos scanner.Position, parent protobuf.Visitee) {
+		if found != nil {
+			return
+		}
+		if name_range, _ := declarationRanges(proto_file, e, pos, name); rangeContains(name_range, at) {
+			found = &name_range
+			member = memberDefinition{Type: typ, Name: name, Parent: parent, Element: e}
+		}
+	}
+	matchEnums := func(enums []parser.Enum) {
+		for _, enum := range enums {
+			for _, e := range enum.Protobuf().Elements {
+				if f, ok := e.(*protobuf.EnumField); ok {
+					match(DefinitionTypeEnumValue, f.Name, f, f.Position, enum.Protobuf())
+				}
+			}
+		}
+	}
+	var matchMessages func(messages []parser.Message)
+	matchMessages = func(messages []parser.Message) {
+		for _, message := range messages {
+			for _, f := range message.Fields() {
+				match(DefinitionTypeField, f.ProtoField.Name, f.ProtoField, f.ProtoField.Position, message.Protobuf())
+			}
+			for _, f := range message.MapFields() {
+				match(DefinitionTypeField, f.ProtoMapField.Name, f.ProtoMapField, f.ProtoMapField.Position, message.Protobuf())
+			}
+			for _, o := range message.Oneofs() {
+				for _, e := range o.Protobuf().Elements {
+					if f, ok := e.(*protobuf.OneOfField); ok {
+						match(DefinitionTypeField, f.Name, f, f.Position, message.Protobuf())
+					}
+				}
+			}
+			matchEnums(message.NestedEnums())
+			matchMessages(message.NestedMessages())
+		}
+	}
+	matchEnums(proto_file.Proto().Enums())
+	matchMessages(proto_file.Proto().Messages())
+	for _, service := range proto_file.Proto().Services() {
+		for _, rpc := range service.RPCs() {
+			match(DefinitionTypeRPC, rpc.ProtoRPC.Name, rpc.ProtoRPC, rpc.ProtoRPC.Position, service.Protobuf())
+		}
+	}
+
+	if found == nil {
+		return member, false
+	}
+	member.Location = defines.Location{Uri: proto_file.URI(), Range: *found}
+	return member, true
+}
+
+func symbolDeclarationLocation(symbol SymbolDefinition) defines.Location {
+	return defines.Location{
+		Uri:   defines.DocumentUri(symbol.Filename),
+		Range: symbol.NameRange,
+	}
+}
+
+// symbolKey identifies a message or enum declaration across separate lookups.
+type symbolKey struct {
+	Type     string
+	Filename string
+	Position defines.Position
+}
+
+func keyOfSymbol(symbol SymbolDefinition) symbolKey {
+	return symbolKey{Type: symbol.Type, Filename: symbol.Filename, Position: symbol.Position}
+}
+
+func sameSymbol(a, b SymbolDefinition) bool {
+	return keyOfSymbol(a) == keyOfSymbol(b)
+}
+
+// locateWord finds text in file starting at the provided 0-based line and
+// column, which counts runes like the columns of the parser. If anchor is not
+// empty, text is only searched after the first occurrence of anchor. The
+// search continues on the following lines so declarations spanning multiple
+// lines are supported. Only whole identifiers match.
+func locateWord(file view.ProtoFile, line, column int, anchor, text string) (defines.Range, bool) {
+	if line < 0 {
+		return defines.Range{}, false
+	}
+	for i := 0; i < maxStatementLines; i++ {
+		line_str := file.ReadLine(line + i)
+		from := 0
+		if i == 0 {
+			from = parser.ColumnOffset(line_str, column)
+		}
+		if anchor != "" {
+			idx := strings.Index(line_str[from:], anchor)
+			if idx < 0 {
+				continue
+			}
+			from += idx + len(anchor)
+			anchor = ""
+		}
+		if idx := indexWord(line_str, from, text); idx >= 0 {
+			return defines.Range{
+				Start: defines.Position{Line: uint(line + i), Character: parser.UTF16Character(line_str, idx)},
+				End:   defines.Position{Line: uint(line + i), Character: parser.UTF16Character(line_str, idx+len(text))},
+			}, true
+		}
+	}
+	return defines.Range{}, false
+}
+
+// positionOf converts pos of the parser, whose column counts runes, to an LSP
+// position.
+func positionOf(file view.ProtoFile, pos scanner.Position) defines.Position {
+	if pos.Line < 1 {
+		return defines.Position{}
+	}
+	line_str := file.ReadLine(pos.Line - 1)
+	return defines.Position{Line: uint(pos.Line - 1), Character: parser.UTF16Character(line_str, parser.ColumnOffset(line_str, pos.Column-1))}
+}
+
+// indexWord returns the index of the first occurrence of text in s at or after
+// from that is not part of a longer (possibly qualified) identifier.
+func indexWord(s string, from int, text string) int {
+	isNameChar := func(ch byte) bool {
+		return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') || ch == '_' || ch == '.'
+	}
+	for from <= len(s) {
+		idx := strings.Index(s[from:], text)
+		if idx < 0 {
+			return -1
+		}
+		start, end := from+idx, from+idx+len(text)
+		if (start == 0 || !isNameChar(s[start-1])) && (end == len(s) || !isNameChar(s[end])) {
+			return start
+		}
+		from = start + 1
+	}
+	return -1
+}
+
+func isBuildInType(typ string) bool {
+	for _, t := range types.BuildInProtoTypes {
+		if string(t) == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueLocations(locations []defines.Location) []defines.Location {
+	sort.SliceStable(locations, func(i, j int) bool {
+		a, b := locations[i], locations[j]
+		if a.Uri != b.Uri {
+			return a.Uri < b.Uri
+		}
+		if a.Range.Start.Line != b.Range.Start.Line {
+			return a.Range.Start.Line < b.Range.Start.Line
+		}
+		return a.Range.Start.Character < b.Range.Start.Character
+	})
+	res := locations[:0]
+	for _, loc := range locations {
+		if len(res) > 0 && loc == res[len(res)-1] {
+			continue
+		}
+		res = append(res, loc)
+	}
+	return res
+}
@@ -0,0 +1,251 @@
+package components
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.lsp.dev/uri"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/view"
+)
+
+// setupWorkspace writes files into a temporary directory, loads them into a
+// fresh view and returns the document uri of every file by its name.
+func setupWorkspace(t *testing.T, files map[string]string) map[string]defines.DocumentUri {
+	t.Helper()
+	_, uris := writeWorkspace(t, files)
+	for _, document_uri := range uris {
+		_, err := view.FromContext(context.Background()).GetFile(document_uri)
+		require.NoError(t, err)
+	}
+	return uris
+}
+
+// setupIndexedWorkspace writes files into a temporary directory and indexes
+// it in a fresh view, like the client opening the workspace, but only loads
+// the files named in open.
+func setupIndexedWorkspace(t *testing.T, files map[string]string, open ...string) map[string]defines.DocumentUri {
+	t.Helper()
+	dir, uris := writeWorkspace(t, files)
+	v := view.FromContext(context.Background())
+	v.IndexWorkspace(context.Background(), []string{dir})
+	for _, name := range open {
+		_, err := v.GetFile(uris[name])
+		require.NoError(t, err)
+	}
+	return uris
+}
+
+// writeWorkspace writes files into a temporary directory for a fresh view and
+// returns the directory and the document uri of every file by its name.
+func writeWorkspace(t *testing.T, files map[string]string) (string, map[string]defines.DocumentUri) {
+	t.Helper()
+	logs.Init(nil)
+	view.Init(lsp.NewServer(&lsp.Options{}))
+
+	dir := t.TempDir()
+	uris := make(map[string]defines.DocumentUri)
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		uris[name] = defines.DocumentUri(uri.File(path))
+	}
+	return dir, uris
+}
+
+func TestReferences(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"common.proto": `syntax = "proto3";
+package common;
+
+message User {
+	string name = 1;
+}
+`,
+		"reexport.proto": `syntax = "proto3";
+package reexport;
+
+import public "common.proto";
+`,
+		"api.proto": `syntax = "proto3";
+package api;
+
+import "reexport.proto";
+
+message GetUserResponse {
+	common.User user = 1;
+	map<string, common.User> by_name = 2;
+	oneof result {
+		common.User found = 3;
+	}
+}
+
+service Users {
+	rpc GetUser(common.User)
+		returns (GetUserResponse);
+}
+`,
+	})
+
+	references := func(document_uri defines.DocumentUri, line, character uint, includeDeclaration bool) []defines.Location {
+		res, err := References(context.Background(), &defines.ReferenceParams{
+			TextDocumentPositionParams: defines.TextDocumentPositionParams{
+				TextDocument: defines.TextDocumentIdentifier{Uri: document_uri},
+				Position:     defines.Position{Line: line, Character: character},
+			},
+			Context: defines.ReferenceContext{IncludeDeclaration: includeDeclaration},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		return *res
+	}
+	location := func(document_uri defines.DocumentUri, line, start, end uint) defines.Location {
+		return defines.Location{
+			Uri: document_uri,
+			Range: defines.Range{
+				Start: defines.Position{Line: line, Character: start},
+				End:   defines.Position{Line: line, Character: end},
+			},
+		}
+	}
+
+	want := []defines.Location{
+		location(uris["api.proto"], 6, 1, 12),
+		location(uris["api.proto"], 7, 13, 24),
+		location(uris["api.proto"], 9, 2, 13),
+		location(uris["api.proto"], 14, 13, 24),
+	}
+
+	t.Run("from declaration", func(t *testing.T) {
+		got := references(uris["common.proto"], 3, 9, true)
+		require.Equal(t, append(want, location(uris["common.proto"], 3, 8, 12)), got)
+	})
+
+	t.Run("from usage", func(t *testing.T) {
+		require.Equal(t, want, references(uris["api.proto"], 7, 20, false))
+	})
+
+	t.Run("rpc declaration", func(t *testing.T) {
+		require.Equal(t, []defines.Location{location(uris["api.proto"], 14, 5, 12)}, references(uris["api.proto"], 14, 6, true))
+	})
+}
+
+func TestReferencesInUnopenedFiles(t *testing.T) {
+	uris := setupIndexedWorkspace(t, map[string]string{
+		"common.proto": `syntax = "proto3";
+package common;
+
+message User {}
+`,
+		"api.proto": `syntax = "proto3";
+package api;
+
+import "common.proto";
+
+message GetUserResponse {
+	common.User user = 1;
+}
+`,
+	}, "common.proto")
+
+	// api.proto was never opened, it is found through the workspace index
+	res, err := References(context.Background(), &defines.ReferenceParams{
+		TextDocumentPositionParams: defines.TextDocumentPositionParams{
+			TextDocument: defines.TextDocumentIdentifier{Uri: uris["common.proto"]},
+			Position:     defines.Position{Line: 3, Character: 9},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []defines.Location{{
+		Uri: uris["api.proto"],
+		Range: defines.Range{
+			Start: defines.Position{Line: 6, Character: 1},
+			End:   defines.Position{Line: 6, Character: 12},
+		},
+	}}, *res)
+}
+
+// TestUTF16Columns checks that columns count UTF-16 code units when a line
+// has multi-byte characters before a name, "日本" and "🙂" are 6 and 4 bytes,
+// 2 runes and 2 UTF-16 code units each.
+func TestUTF16Columns(t *testing.T) {
+	api := `syntax = "proto2";
+package api;
+
+/* 日本 */ message User {}
+message Group {
+	optional string note = 1 [default = "🙂"]; optional User owner = 2;
+}
+`
+	uris := setupWorkspace(t, map[string]string{"api.proto": api})
+	rng := func(line, start, end uint) defines.Range {
+		return defines.Range{
+			Start: defines.Position{Line: line, Character: start},
+			End:   defines.Position{Line: line, Character: end},
+		}
+	}
+	position := defines.TextDocumentPositionParams{
+		TextDocument: defines.TextDocumentIdentifier{Uri: uris["api.proto"]},
+		Position:     defines.Position{Line: 5, Character: 55},
+	}
+
+	t.Run("definition", func(t *testing.T) {
+		res, err := JumpDefine(context.Background(), &defines.DefinitionParams{TextDocumentPositionParams: position})
+		require.NoError(t, err)
+		require.Len(t, *res, 1)
+		require.Equal(t, rng(3, 17, 21), (*res)[0].TargetSelectionRange)
+	})
+
+	t.Run("references", func(t *testing.T) {
+		res, err := References(context.Background(), &defines.ReferenceParams{
+			TextDocumentPositionParams: position,
+			Context:                    defines.ReferenceContext{IncludeDeclaration: true},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []defines.Location{
+			{Uri: uris["api.proto"], Range: rng(3, 17, 21)},
+			{Uri: uris["api.proto"], Range: rng(5, 53, 57)},
+		}, *res)
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		prepared, err := PrepareRename(context.Background(), &defines.PrepareRenameParams{TextDocumentPositionParams: position})
+		require.NoError(t, err)
+		require.Equal(t, rng(5, 53, 57), *prepared)
+
+		res, err := Rename(context.Background(), &defines.RenameParams{
+			TextDocument: position.TextDocument,
+			Position:     position.Position,
+			NewName:      "Account",
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[string][]defines.TextEdit{
+			string(uris["api.proto"]): {
+				{Range: rng(3, 17, 21), NewText: "Account"},
+				{Range: rng(5, 53, 57), NewText: "Account"},
+			},
+		}, *res.Changes)
+	})
+
+	t.Run("semantic tokens", func(t *testing.T) {
+		res, err := SemanticTokensFull(context.Background(), &defines.SemanticTokensParams{TextDocument: position.TextDocument})
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"1:8 api namespace declaration",
+			"3:17 User class declaration",
+			"4:8 Group class declaration",
+			"5:10 string type defaultLibrary",
+			"5:17 note property declaration",
+			"5:27 default decorator",
+			"5:53 User class",
+			"5:58 owner property declaration",
+		}, decodeSemanticTokens(t, api, res.Data))
+	})
+}
@@ -0,0 +1,344 @@
+package components
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/walteh/protobuf-language-server/proto/parser"
+	"github.com/walteh/protobuf-language-server/proto/view"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/jsonrpc"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// renameTarget is the declaration a rename applies to. Exactly one of Symbol
+// and Member is set.
+type renameTarget struct {
+	Name     string
+	Location defines.Location
+	// Symbol is set for messages and enums.
+	Symbol *SymbolDefinition
+	// Member is set for enum values and rpcs.
+	Member *memberDefinition
+}
+
+// PrepareRename checks that the symbol under the cursor can be renamed and
+// returns the range of its name.
+func PrepareRename(ctx context.Context, req *defines.PrepareRenameParams) (result *defines.Range, err error) {
+	if !view.IsProtoFile(req.TextDocument.Uri) {
+		return nil, nil
+	}
+	target, err := findRenameTarget(ctx, &req.TextDocumentPositionParams)
+	if err != nil {
+		return nil, err
+	}
+
+	proto_file, err := view.FromContext(ctx).GetFile(req.TextDocument.Uri)
+	if err != nil {
+		return nil, err
+	}
+	line := int(req.Position.Line)
+	line_str := proto_file.ReadLine(line)
+	start, end := getWordRange(line_str, parser.UTF16Offset(line_str, req.Position.Character), false)
+	if line_str[start:end] != target.Name {
+		return nil, renameError("only the name of %s can be renamed", target.Name)
+	}
+	return &defines.Range{
+		Start: defines.Position{Line: uint(line), Character: parser.UTF16Character(line_str, start)},
+		End:   defines.Position{Line: uint(line), Character: parser.UTF16Character(line_str, end)},
+	}, nil
+}
+
+// Rename renames the message, enum, enum value or rpc under the cursor and
+// updates every qualified and unqualified reference to it.
+func Rename(ctx context.Context, req *defines.RenameParams) (result *defines.WorkspaceEdit, err error) {
+	if !view.IsProtoFile(req.TextDocument.Uri) {
+		return nil, nil
+	}
+	target, err := findRenameTarget(ctx, &defines.TextDocumentPositionParams{
+		TextDocument: req.TextDocument,
+		Position:     req.Position,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !identifierRegexp.MatchString(req.NewName) {
+		return nil, renameError("%q is not a valid identifier", req.NewName)
+	}
+
+	v := view.FromContext(ctx)
+	changes := make(map[string][]defines.TextEdit)
+	if req.NewName == target.Name {
+		return &defines.WorkspaceEdit{Changes: &changes}, nil
+	}
+	if err := checkRenameCollision(v, target, req.NewName); err != nil {
+		return nil, err
+	}
+
+	addEdit := func(location defines.Location) {
+		changes[string(location.Uri)] = append(changes[string(location.Uri)], defines.TextEdit{
+			Range:   location.Range,
+			NewText: req.NewName,
+		})
+	}
+	addEdit(target.Location)
+	if target.Symbol != nil {
+		for _, location := range renameTypeReferences(v, *target.Symbol, target.Name) {
+			addEdit(location)
+		}
+	}
+	if target.Member != nil && target.Member.Type == DefinitionTypeEnumValue {
+		for _, location := range enumValueReferences(v, *target.Member) {
+			addEdit(location)
+		}
+	}
+
+	for uri, edits := range changes {
+		sort.Slice(edits, func(i, j int) bool {
+			a, b := edits[i].Range.Start, edits[j].Range.Start
+			return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+		})
+		unique := edits[:0]
+		for _, edit := range edits {
+			if len(unique) > 0 && unique[len(unique)-1].Range == edit.Range {
+				continue
+			}
+			unique = append(unique, edit)
+		}
+		changes[uri] = unique
+	}
+	return &defines.WorkspaceEdit{Changes: &changes}, nil
+}
+
+func findRenameTarget(ctx context.Context, position *defines.TextDocumentPositionParams) (*renameTarget, error) {
+	symbols, err := findSymbolDefinition(ctx, position)
+	if err != nil {
+		return nil, err
+	}
+	if len(symbols) > 0 {
+		symbol := symbols[0]
+		switch symbol.Type {
+		case DefinitionTypeMessage:
+			return &renameTarget{
+				Name:     symbol.Message.Protobuf().Name,
+				Location: symbolDeclarationLocation(symbol),
+				Symbol:   &symbol,
+			}, nil
+		case DefinitionTypeEnum:
+			return &renameTarget{
+				Name:     symbol.Enum.Protobuf().Name,
+				Location: symbolDeclarationLocation(symbol),
+				Symbol:   &symbol,
+			}, nil
+		}
+	}
+
+	member, ok := findMemberDefinition(ctx, position)
+	if ok && (member.Type == DefinitionTypeEnumValue || member.Type == DefinitionTypeRPC) {
+		return &renameTarget{
+			Name:     member.Name,
+			Location: member.Location,
+			Member:   &member,
+		}, nil
+	}
+	return nil, renameError("only messages, enums, enum values and rpcs can be renamed")
+}
+
+// checkRenameCollision fails if newName is already declared in the scope of
+// target.
+func checkRenameCollision(v *view.View, target *renameTarget, newName string) error {
+	var parent protobuf.Visitee
+	switch {
+	case target.Symbol != nil && target.Symbol.Type == DefinitionTypeMessage:
+		parent = target.Symbol.Message.Protobuf().Parent
+	case target.Symbol != nil && target.Symbol.Type == DefinitionTypeEnum:
+		parent = target.Symbol.Enum.Protobuf().Parent
+	case target.Member.Type == DefinitionTypeEnumValue:
+		// enum values are siblings of their enum, not children of it
+		parent = target.Member.Parent.(*protobuf.Enum).Parent
+	default:
+		parent = target.Member.Parent
+	}
+
+	var names []string
+	scope := ""
+	switch p := parent.(type) {
+	case *protobuf.Proto:
+		file, err := v.GetFile(target.Location.Uri)
+		if err != nil {
+			return err
+		}
+		pkg := packageName(file)
+		for _, f := range v.WorkspaceFiles() {
+			if packageName(f) == pkg {
+				names = append(names, declaredNames(f.Proto().Protobuf().Elements)...)
+			}
+		}
+		scope = fmt.Sprintf("package %q", pkg)
+	case *protobuf.Message:
+		names = declaredNames(p.Elements)
+		scope = fmt.Sprintf("message %s", p.Name)
+	case *protobuf.Service:
+		for _, e := range p.Elements {
+			if rpc, ok := e.(*protobuf.RPC); ok {
+				names = append(names, rpc.Name)
+			}
+		}
+		scope = fmt.Sprintf("service %s", p.Name)
+	}
+
+	for _, name := range names {
+		if name == newName {
+			return renameError("%s is already declared in %s", newName, scope)
+		}
+	}
+	return nil
+}
+
+// declaredNames returns the names that elements declare in their scope.
+func declaredNames(elements []protobuf.Visitee) (names []string) {
+	for _, e := range elements {
+		switch v := e.(type) {
+		case *protobuf.Message:
+			if !v.IsExtend {
+				names = append(names, v.Name)
+			}
+		case *protobuf.Enum:
+			names = append(names, v.Name)
+			for _, item := range v.Elements {
+				if f, ok := item.(*protobuf.EnumField); ok {
+					names = append(names, f.Name)
+				}
+			}
+		case *protobuf.Service:
+			names = append(names, v.Name)
+		case *protobuf.NormalField:
+			names = append(names, v.Name)
+		case *protobuf.MapField:
+			names = append(names, v.Name)
+		case *protobuf.Oneof:
+			names = append(names, v.Name)
+			names = append(names, declaredNames(v.Elements)...)
+		case *protobuf.OneOfField:
+			names = append(names, v.Name)
+		case *protobuf.Group:
+			names = append(names, v.Name)
+		}
+	}
+	return names
+}
+
+// renameTypeReferences returns the ranges of every occurrence of name in type
+// references to symbol. References to types nested in symbol are included when
+// they are qualified with its name, e.g. Outer.Inner.
+func renameTypeReferences(v *view.View, symbol SymbolDefinition, name string) (res []defines.Location) {
+	depths := map[symbolKey]int{keyOfSymbol(symbol): 0}
+	if symbol.Type == DefinitionTypeMessage {
+		file, err := v.GetFile(defines.DocumentUri(symbol.Filename))
+		if err == nil {
+			var addNested func(message parser.Message, depth int)
+			addNested = func(message parser.Message, depth int) {
+				for _, enum := range message.NestedEnums() {
+					depths[keyOfSymbol(enumSymbolDefinition(file, enum))] = depth
+				}
+				for _, nested := range message.NestedMessages() {
+					depths[keyOfSymbol(messageSymbolDefinition(file, nested))] = depth
+					addNested(nested, depth+1)
+				}
+			}
+			addNested(symbol.Message, 1)
+		}
+	}
+
+	for _, file := range v.WorkspaceFiles() {
+		refs := typeReferencesInFile(file, func(s SymbolDefinition) bool {
+			_, ok := depths[keyOfSymbol(s)]
+			return ok
+		})
+		for _, ref := range refs {
+			segments := strings.Split(ref.Text, ".")
+			idx := len(segments) - 1 - depths[keyOfSymbol(ref.Symbol)]
+			if idx < 0 || segments[idx] != name {
+				// nested type referenced without the renamed qualifier
+				continue
+			}
+			offset := uint(len(strings.Join(segments[:idx], ".")))
+			if idx > 0 {
+				offset++
+			}
+			start := ref.Location.Range.Start
+			start.Character += offset
+			res = append(res, defines.Location{
+				Uri: ref.Location.Uri,
+				Range: defines.Range{
+					Start: start,
+					End:   defines.Position{Line: start.Line, Character: start.Character + uint(len(name))},
+				},
+			})
+		}
+	}
+	return res
+}
+
+// enumValueReferences returns the ranges of default values that use the enum
+// value.
+func enumValueReferences(v *view.View, value memberDefinition) (res []defines.Location) {
+	enum, ok := value.Parent.(*protobuf.Enum)
+	if !ok {
+		return nil
+	}
+	for _, file := range v.WorkspaceFiles() {
+		var visitMessages func(messages []parser.Message)
+		visitMessages = func(messages []parser.Message) {
+			for _, message := range messages {
+				for _, f := range message.Fields() {
+					if !hasOptionValue(f.ProtoField.Options, "default", value.Name) {
+						continue
+					}
+					for _, symbol := range resolveType(file, f.ProtoField.Type, f.ProtoField.Position.Line) {
+						if symbol.Type != DefinitionTypeEnum || symbol.Enum.Protobuf() != enum {
+							continue
+						}
+						pos := f.ProtoField.Position
+						if rng, ok := locateWord(file, pos.Line-1, pos.Column-1, "default", value.Name); ok {
+							res = append(res, defines.Location{Uri: file.URI(), Range: rng})
+						}
+						break
+					}
+				}
+				visitMessages(message.NestedMessages())
+			}
+		}
+		visitMessages(file.Proto().Messages())
+	}
+	return res
+}
+
+func hasOptionValue(options []*protobuf.Option, name, value string) bool {
+	for _, option := range options {
+		if option.Name == name && option.Constant.Source == value {
+			return true
+		}
+	}
+	return false
+}
+
+func packageName(proto_file view.ProtoFile) string {
+	if len(proto_file.Proto().Packages()) == 0 {
+		return ""
+	}
+	return proto_file.Proto().Packages()[0].ProtoPackage.Name
+}
+
+func renameError(format string, a ...interface{}) error {
+	return jsonrpc.ResponseError{
+		Code:    jsonrpc.RequestFailedCode,
+		Message: fmt.Sprintf(format, a...),
+	}
+}
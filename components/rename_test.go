@@ -0,0 +1,168 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/jsonrpc"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+func TestRename(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"common.proto": `syntax = "proto2";
+package common;
+
+message User {
+	optional string name = 1;
+	optional Status status = 2 [default = ACTIVE];
+	enum Status {
+		ACTIVE = 0;
+		BANNED = 1;
+	}
+}
+
+message Group {}
+`,
+		"api.proto": `syntax = "proto2";
+package api;
+
+import "common.proto";
+
+message GetUserResponse {
+	optional common.User user = 1;
+	optional common.User.Status status = 2 [default = ACTIVE];
+}
+
+service Users {
+	rpc GetUser(common.User) returns (GetUserResponse);
+}
+`,
+	})
+
+	rename := func(document_uri defines.DocumentUri, line, character uint, name string) (*defines.WorkspaceEdit, error) {
+		return Rename(context.Background(), &defines.RenameParams{
+			TextDocument: defines.TextDocumentIdentifier{Uri: document_uri},
+			Position:     defines.Position{Line: line, Character: character},
+			NewName:      name,
+		})
+	}
+	edit := func(line, start, end uint, text string) defines.TextEdit {
+		return defines.TextEdit{
+			Range: defines.Range{
+				Start: defines.Position{Line: line, Character: start},
+				End:   defines.Position{Line: line, Character: end},
+			},
+			NewText: text,
+		}
+	}
+
+	t.Run("message", func(t *testing.T) {
+		res, err := rename(uris["api.proto"], 6, 18, "Account")
+		require.NoError(t, err)
+		require.Equal(t, map[string][]defines.TextEdit{
+			string(uris["common.proto"]): {edit(3, 8, 12, "Account")},
+			string(uris["api.proto"]): {
+				edit(6, 17, 21, "Account"),
+				edit(7, 17, 21, "Account"),
+				edit(11, 20, 24, "Account"),
+			},
+		}, *res.Changes)
+	})
+
+	t.Run("enum value", func(t *testing.T) {
+		res, err := rename(uris["common.proto"], 7, 3, "ENABLED")
+		require.NoError(t, err)
+		require.Equal(t, map[string][]defines.TextEdit{
+			string(uris["common.proto"]): {
+				edit(5, 39, 45, "ENABLED"),
+				edit(7, 2, 8, "ENABLED"),
+			},
+			string(uris["api.proto"]): {edit(7, 51, 57, "ENABLED")},
+		}, *res.Changes)
+	})
+
+	t.Run("collision", func(t *testing.T) {
+		_, err := rename(uris["common.proto"], 3, 9, "Group")
+		var responseErr jsonrpc.ResponseError
+		require.ErrorAs(t, err, &responseErr)
+		require.Equal(t, jsonrpc.RequestFailedCode, responseErr.Code)
+	})
+
+	t.Run("prepare field", func(t *testing.T) {
+		_, err := PrepareRename(context.Background(), &defines.PrepareRenameParams{
+			TextDocumentPositionParams: defines.TextDocumentPositionParams{
+				TextDocument: defines.TextDocumentIdentifier{Uri: uris["common.proto"]},
+				Position:     defines.Position{Line: 4, Character: 18},
+			},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestRenameUnopenedFiles(t *testing.T) {
+	uris := setupIndexedWorkspace(t, map[string]string{
+		"common.proto": `syntax = "proto2";
+package common;
+
+message User {}
+enum Status {
+	ACTIVE = 0;
+}
+`,
+		"account.proto": `syntax = "proto2";
+package common;
+
+message Account {}
+`,
+		"api.proto": `syntax = "proto2";
+package api;
+
+import "common.proto";
+
+message GetUserResponse {
+	optional common.User user = 1;
+	optional common.Status status = 2 [default = ACTIVE];
+}
+`,
+	}, "common.proto")
+
+	rename := func(line, character uint, name string) (*defines.WorkspaceEdit, error) {
+		return Rename(context.Background(), &defines.RenameParams{
+			TextDocument: defines.TextDocumentIdentifier{Uri: uris["common.proto"]},
+			Position:     defines.Position{Line: line, Character: character},
+			NewName:      name,
+		})
+	}
+	edit := func(line, start, end uint, text string) defines.TextEdit {
+		return defines.TextEdit{
+			Range: defines.Range{
+				Start: defines.Position{Line: line, Character: start},
+				End:   defines.Position{Line: line, Character: end},
+			},
+			NewText: text,
+		}
+	}
+
+	// the files of the workspace are searched although only common.proto is
+	// open
+	res, err := rename(3, 9, "Member")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]defines.TextEdit{
+		string(uris["common.proto"]): {edit(3, 8, 12, "Member")},
+		string(uris["api.proto"]):    {edit(6, 17, 21, "Member")},
+	}, *res.Changes)
+
+	res, err = rename(5, 2, "ENABLED")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]defines.TextEdit{
+		string(uris["common.proto"]): {edit(5, 1, 7, "ENABLED")},
+		string(uris["api.proto"]):    {edit(7, 46, 52, "ENABLED")},
+	}, *res.Changes)
+
+	_, err = rename(3, 9, "Account")
+	var responseErr jsonrpc.ResponseError
+	require.ErrorAs(t, err, &responseErr)
+}
@@ -0,0 +1,151 @@
+package components
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/walteh/protobuf-language-server/proto/parser"
+	"github.com/walteh/protobuf-language-server/proto/view"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+// SelectionRanges expands a selection from the identifier at a position to
+// its qualified name, the statement around it, the blocks it is nested in
+// from the innermost one outwards, e.g. a oneof and its messages, and at last
+// to the whole file.
+func SelectionRanges(ctx context.Context, req *defines.SelectionRangeParams) (result *[]defines.SelectionRange, err error) {
+	if !view.IsProtoFile(req.TextDocument.Uri) {
+		return nil, nil
+	}
+	proto_file, err := view.FromContext(ctx).GetFile(req.TextDocument.Uri)
+	if err != nil {
+		return nil, err
+	}
+	data, _, err := proto_file.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	file_range := defines.Range{End: defines.Position{Line: uint(len(lines) - 1), Character: parser.UTF16Character(lines[len(lines)-1], len(lines[len(lines)-1]))}}
+
+	res := []defines.SelectionRange{}
+	for _, pos := range req.Positions {
+		ranges := []defines.Range{file_range}
+		if proto_file.Proto() != nil {
+			ranges = append(ranges, statementRanges(proto_file, pos)...)
+		}
+		line_str := proto_file.ReadLine(int(pos.Line))
+		for _, include_dot := range []bool{true, false} {
+			if l, r := getWordRange(line_str, parser.UTF16Offset(line_str, pos.Character), include_dot); l < r {
+				ranges = append(ranges, defines.Range{
+					Start: defines.Position{Line: pos.Line, Character: parser.UTF16Character(line_str, l)},
+					End:   defines.Position{Line: pos.Line, Character: parser.UTF16Character(line_str, r)},
+				})
+			}
+		}
+		res = append(res, selectionRange(pos, ranges))
+	}
+	return &res, nil
+}
+
+// selectionRange chains the ranges around pos from the smallest to the
+// largest. Ranges not containing the smaller ones are dropped.
+func selectionRange(pos defines.Position, ranges []defines.Range) defines.SelectionRange {
+	sort.SliceStable(ranges, func(i, j int) bool {
+		a, b := ranges[i], ranges[j]
+		if a.Start != b.Start {
+			return !positionBefore(a.Start, b.Start)
+		}
+		return positionBefore(a.End, b.End)
+	})
+	var parent *defines.SelectionRange
+	for i := len(ranges) - 1; i >= 0; i-- {
+		if parent != nil && (parent.Range == ranges[i] || !rangeContains(parent.Range, ranges[i])) {
+			continue
+		}
+		parent = &defines.SelectionRange{Range: ranges[i], Parent: parent}
+	}
+	if parent == nil {
+		return defines.SelectionRange{Range: defines.Range{Start: pos, End: pos}}
+	}
+	return *parent
+}
+
+// statementRanges returns the ranges of the statements and blocks around pos.
+// Blocks end after their closing brace, other statements after their
+// semicolon.
+func statementRanges(proto_file view.ProtoFile, pos defines.Position) (res []defines.Range) {
+	proto := proto_file.Proto()
+	walkElements(proto.Protobuf().Elements, func(e protobuf.Visitee) {
+		start, ok := elementPosition(e)
+		if !ok {
+			return
+		}
+		rng := defines.Range{Start: positionOf(proto_file, start)}
+		end, is_block := proto.BlockEnd(e)
+		if option, ok := e.(*protobuf.Option); ok && option.IsEmbedded {
+			if !is_block {
+				return
+			}
+			// inline options start at the bracket or comma before them
+			line_str := proto_file.ReadLine(start.Line - 1)
+			character := parser.ColumnOffset(line_str, start.Column)
+			character += len(line_str[character:]) - len(strings.TrimLeft(line_str[character:], " \t"))
+			rng.Start.Character = parser.UTF16Character(line_str, character)
+		}
+		if is_block {
+			rng.End = afterBlock(proto_file, end)
+		} else {
+			var ok bool
+			if rng.End, ok = statementEnd(proto_file, e, rng.Start); !ok {
+				return
+			}
+		}
+		if rangeContains(rng, defines.Range{Start: pos, End: pos}) {
+			res = append(res, rng)
+		}
+	})
+	return res
+}
+
+// statementEnd returns the position after the semicolon ending the statement
+// e that starts at start, which follows the blocks of its inline options.
+func statementEnd(proto_file view.ProtoFile, e protobuf.Visitee, start defines.Position) (defines.Position, bool) {
+	walkElements([]protobuf.Visitee{e}, func(o protobuf.Visitee) {
+		if end, ok := proto_file.Proto().BlockEnd(o); ok && o != e {
+			start = afterBlock(proto_file, end)
+		}
+	})
+	for line := start.Line; line < start.Line+100; line++ {
+		line_str := proto_file.ReadLine(int(line))
+		character := 0
+		if line == start.Line {
+			character = parser.UTF16Offset(line_str, start.Character)
+		}
+		code, _, _ := strings.Cut(line_str[character:], "//")
+		if idx := strings.Index(code, ";"); idx >= 0 {
+			return defines.Position{Line: line, Character: parser.UTF16Character(line_str, character+idx+1)}, true
+		}
+	}
+	return defines.Position{}, false
+}
+
+// afterBlock returns the position after the closing brace at end, see
+// parser.Proto.BlockEnd.
+func afterBlock(proto_file view.ProtoFile, end scanner.Position) defines.Position {
+	end.Column++
+	return positionOf(proto_file, end)
+}
+
+// rangeContains reports whether inner is inside outer.
+func rangeContains(outer, inner defines.Range) bool {
+	return !positionBefore(inner.Start, outer.Start) && !positionBefore(outer.End, inner.End)
+}
+
+func positionBefore(x, y defines.Position) bool {
+	return x.Line < y.Line || (x.Line == y.Line && x.Character < y.Character)
+}
@@ -0,0 +1,56 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+func TestSelectionRanges(t *testing.T) {
+	uris := setupWorkspace(t, map[string]string{
+		"api.proto": `syntax = "proto3";
+package api;
+
+message User {
+	message Contact {
+		oneof kind {
+			google.protobuf.Timestamp since = 1 [(meta) = {
+				name: "since"
+			}];
+		}
+	}
+}
+`,
+	})
+
+	res, err := SelectionRanges(context.Background(), &defines.SelectionRangeParams{
+		TextDocument: defines.TextDocumentIdentifier{Uri: uris["api.proto"]},
+		Positions:    []defines.Position{{Line: 6, Character: 20}, {Line: 1, Character: 9}},
+	})
+	require.NoError(t, err)
+	require.Len(t, *res, 2)
+
+	chain := func(s defines.SelectionRange) (res [][4]uint) {
+		for r := &s; r != nil; r = r.Parent {
+			res = append(res, [4]uint{r.Range.Start.Line, r.Range.Start.Character, r.Range.End.Line, r.Range.End.Character})
+		}
+		return res
+	}
+	require.Equal(t, [][4]uint{
+		{6, 19, 6, 28}, // Timestamp
+		{6, 3, 6, 28},  // google.protobuf.Timestamp
+		{6, 3, 8, 6},   // the field
+		{5, 2, 9, 3},   // oneof kind
+		{4, 1, 10, 2},  // message Contact
+		{3, 0, 11, 1},  // message User
+		{0, 0, 12, 0},  // the file
+	}, chain((*res)[0]))
+	require.Equal(t, [][4]uint{
+		{1, 8, 1, 11},
+		{1, 0, 1, 12},
+		{0, 0, 12, 0},
+	}, chain((*res)[1]))
+}
@@ -0,0 +1,281 @@
+package components
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/walteh/protobuf-language-server/proto/view"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+// The semantic token types, their value is the index in the legend.
+const (
+	tokenPackage = iota
+	tokenMessage
+	tokenEnum
+	tokenEnumValue
+	tokenField
+	tokenService
+	tokenRPC
+	tokenScalar
+	tokenOption
+)
+
+// The semantic token modifiers, their value is the bit in the legend.
+const (
+	modifierDeclaration = 1 << iota
+	modifierDeprecated
+	modifierDefaultLibrary
+)
+
+// SemanticTokensLegend lists the token types and modifiers SemanticTokensFull
+// and SemanticTokensRange use, in the order of their constants.
+var SemanticTokensLegend = defines.SemanticTokensLegend{
+	TokenTypes: []string{
+		string(defines.SemanticTokenTypesNamespace),
+		string(defines.SemanticTokenTypesClass),
+		string(defines.SemanticTokenTypesEnum),
+		string(defines.SemanticTokenTypesEnumMember),
+		string(defines.SemanticTokenTypesProperty),
+		string(defines.SemanticTokenTypesInterface),
+		string(defines.SemanticTokenTypesMethod),
+		string(defines.SemanticTokenTypesType),
+		string(defines.SemanticTokenTypesDecorator),
+	},
+	TokenModifiers: []string{
+		string(defines.SemanticTokenModifiersDeclaration),
+		string(defines.SemanticTokenModifiersDeprecated),
+		string(defines.SemanticTokenModifiersDefaultLibrary),
+	},
+}
+
+// SemanticTokensFull classifies the names of a proto file: packages,
+// messages, enums, enum values, fields, services, rpcs, scalar types and
+// options. Type references are resolved, so messages and enums are told
+// apart, well-known types are marked as default library and unresolved types
+// are left to the grammar of the editor. Declarations and references of
+// symbols with option deprecated = true are marked deprecated.
+func SemanticTokensFull(ctx context.Context, req *defines.SemanticTokensParams) (result *defines.SemanticTokens, err error) {
+	return semanticTokens(ctx, req.TextDocument.Uri, nil)
+}
+
+// SemanticTokensRange is SemanticTokensFull for the lines of a range.
+func SemanticTokensRange(ctx context.Context, req *defines.SemanticTokensRangeParams) (result *defines.SemanticTokens, err error) {
+	return semanticTokens(ctx, req.TextDocument.Uri, &req.Range)
+}
+
+func semanticTokens(ctx context.Context, document_uri defines.DocumentUri, rng *defines.Range) (*defines.SemanticTokens, error) {
+	if !view.IsProtoFile(document_uri) {
+		return nil, nil
+	}
+	proto_file, err := view.FromContext(ctx).GetFile(document_uri)
+	if err != nil {
+		return nil, err
+	}
+	b := &semanticTokenBuilder{file: proto_file}
+	if proto_file.Proto() != nil {
+		b.elements(proto_file.Proto().Protobuf().Elements)
+	}
+	return &defines.SemanticTokens{Data: b.encode(rng)}, nil
+}
+
+type semanticToken struct {
+	line, start, length uint
+	typ                 int
+	modifiers           int
+}
+
+type semanticTokenBuilder struct {
+	file   view.ProtoFile
+	tokens []semanticToken
+}
+
+// add classifies text, which is searched after anchor starting at pos, see
+// locateWord.
+func (b *semanticTokenBuilder) add(pos scanner.Position, anchor, text string, typ, modifiers int) {
+	if text == "" {
+		return
+	}
+	if rng, ok := locateWord(b.file, pos.Line-1, pos.Column-1, anchor, text); ok {
+		b.tokens = append(b.tokens, semanticToken{rng.Start.Line, rng.Start.Character, rng.End.Character - rng.Start.Character, typ, modifiers})
+	}
+}
+
+func (b *semanticTokenBuilder) elements(elements []protobuf.Visitee) {
+	for _, e := range elements {
+		switch v := e.(type) {
+		case *protobuf.Package:
+			b.add(v.Position, "package", v.Name, tokenPackage, modifierDeclaration)
+		case *protobuf.Message:
+			if v.IsExtend {
+				b.typeReference(v.Position, "extend", v.Name)
+			} else {
+				b.add(v.Position, "message", v.Name, tokenMessage, modifierDeclaration|deprecatedModifier(optionsOf(v.Elements)))
+			}
+			b.elements(v.Elements)
+		case *protobuf.Group:
+			b.add(v.Position, "group", v.Name, tokenMessage, modifierDeclaration|deprecatedModifier(optionsOf(v.Elements)))
+			b.elements(v.Elements)
+		case *protobuf.Enum:
+			b.add(v.Position, "enum", v.Name, tokenEnum, modifierDeclaration|deprecatedModifier(optionsOf(v.Elements)))
+			b.elements(v.Elements)
+		case *protobuf.EnumField:
+			b.add(v.Position, "", v.Name, tokenEnumValue, modifierDeclaration|deprecatedModifier(optionsOf(v.Elements)))
+			b.elements(v.Elements)
+		case *protobuf.NormalField:
+			b.typeReference(v.Position, "", v.Type)
+			b.field(v.Field, v.Type)
+		case *protobuf.OneOfField:
+			b.typeReference(v.Position, "", v.Type)
+			b.field(v.Field, v.Type)
+		case *protobuf.MapField:
+			b.typeReference(v.Position, "<", v.KeyType)
+			b.typeReference(v.Position, ",", v.Type)
+			b.field(v.Field, ">")
+		case *protobuf.Oneof:
+			b.elements(v.Elements)
+		case *protobuf.Service:
+			b.add(v.Position, "service", v.Name, tokenService, modifierDeclaration|deprecatedModifier(optionsOf(v.Elements)))
+			b.elements(v.Elements)
+		case *protobuf.RPC:
+			b.add(v.Position, "rpc", v.Name, tokenRPC, modifierDeclaration|deprecatedModifier(optionsOf(v.Elements)))
+			b.typeReference(v.Position, "(", v.RequestType)
+			b.typeReference(v.Position, "returns", v.ReturnsType)
+			b.elements(v.Elements)
+		case *protobuf.Option:
+			b.option(v)
+		}
+	}
+}
+
+// field classifies the name of a field, which is written after anchor, and
+// its options.
+func (b *semanticTokenBuilder) field(f *protobuf.Field, anchor string) {
+	b.add(f.Position, anchor, f.Name, tokenField, modifierDeclaration|deprecatedModifier(f.Options))
+	for _, o := range f.Options {
+		b.option(o)
+	}
+}
+
+// option classifies the name of an option, for custom options the extension
+// in parentheses.
+func (b *semanticTokenBuilder) option(o *protobuf.Option) {
+	name := o.Name
+	if strings.HasPrefix(name, "(") {
+		if end := strings.Index(name, ")"); end > 0 {
+			b.add(o.Position, "(", name[1:end], tokenOption, 0)
+		}
+		return
+	}
+	name, _, _ = strings.Cut(name, ".")
+	b.add(o.Position, "", name, tokenOption, 0)
+}
+
+// typeReference classifies a type written after anchor. A qualified name is
+// split into the package, the messages it is nested in and the type itself.
+func (b *semanticTokenBuilder) typeReference(pos scanner.Position, anchor, typ string) {
+	if typ == "" {
+		return
+	}
+	if isBuildInType(typ) {
+		b.add(pos, anchor, typ, tokenScalar, modifierDefaultLibrary)
+		return
+	}
+	symbols := resolveType(b.file, typ, pos.Line)
+	if len(symbols) == 0 {
+		return
+	}
+	rng, ok := locateWord(b.file, pos.Line-1, pos.Column-1, anchor, typ)
+	if !ok {
+		return
+	}
+
+	symbol := symbols[0]
+	typ_token, modifiers, parent := tokenMessage, 0, protobuf.Visitee(nil)
+	switch symbol.Type {
+	case DefinitionTypeMessage:
+		modifiers = deprecatedModifier(optionsOf(symbol.Message.Protobuf().Elements))
+		parent = symbol.Message.Protobuf().Parent
+	case DefinitionTypeEnum:
+		typ_token = tokenEnum
+		modifiers = deprecatedModifier(optionsOf(symbol.Enum.Protobuf().Elements))
+		parent = symbol.Enum.Protobuf().Parent
+	}
+	if declaring, err := b.file.View().GetFile(defines.DocumentUri(symbol.Filename)); err == nil && declaring.Proto() != nil {
+		if packages := declaring.Proto().Packages(); len(packages) > 0 && packages[0].ProtoPackage.Name == "google.protobuf" {
+			modifiers |= modifierDefaultLibrary
+		}
+	}
+	// the messages the type is nested in, innermost first
+	var nested []*protobuf.Message
+	for message, ok := parent.(*protobuf.Message); ok; message, ok = message.Parent.(*protobuf.Message) {
+		nested = append(nested, message)
+	}
+
+	start := rng.Start.Character
+	if strings.HasPrefix(typ, ".") {
+		start++
+	}
+	parts := strings.Split(strings.TrimPrefix(typ, "."), ".")
+	for i, part := range parts {
+		token := semanticToken{line: rng.Start.Line, start: start, length: uint(len(part)), typ: tokenPackage}
+		switch {
+		case i == len(parts)-1:
+			token.typ, token.modifiers = typ_token, modifiers
+		case i >= len(parts)-1-len(nested):
+			message := nested[len(parts)-2-i]
+			token.typ = tokenMessage
+			token.modifiers = modifiers&modifierDefaultLibrary | deprecatedModifier(optionsOf(message.Elements))
+		}
+		b.tokens = append(b.tokens, token)
+		start += uint(len(part)) + 1
+	}
+}
+
+// encode returns the tokens on the lines of rng, or all without one, in the
+// relative format of the protocol.
+func (b *semanticTokenBuilder) encode(rng *defines.Range) []uint {
+	sort.SliceStable(b.tokens, func(i, j int) bool {
+		a, c := b.tokens[i], b.tokens[j]
+		return a.line < c.line || (a.line == c.line && a.start < c.start)
+	})
+	data := []uint{}
+	var line, start uint
+	for i, token := range b.tokens {
+		if i > 0 && token.line == b.tokens[i-1].line && token.start < b.tokens[i-1].start+b.tokens[i-1].length {
+			// overlapping tokens are not allowed
+			continue
+		}
+		if rng != nil && (token.line < rng.Start.Line || token.line > rng.End.Line) {
+			continue
+		}
+		if token.line != line {
+			start = 0
+		}
+		data = append(data, token.line-line, token.start-start, token.length, uint(token.typ), uint(token.modifiers))
+		line, start = token.line, token.start
+	}
+	return data
+}
+
+func optionsOf(elements []protobuf.Visitee) (options []*protobuf.Option) {
+	for _, e := range elements {
+		if o, ok := e.(*protobuf.Option); ok {
+			options = append(options, o)
+		}
+	}
+	return options
+}
+
+func deprecatedModifier(options []*protobuf.Option) int {
+	for _, o := range options {
+		if o.Name == "deprecated" && o.Constant.Source == "true" {
+			return modifierDeprecated
+		}
+	}
+	return 0
+}
@@ -0,0 +1,108 @@
+package components
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/parser"
+)
+
+// decodeSemanticTokens returns the tokens as "line:column text type
+// modifiers" strings.
+func decodeSemanticTokens(t *testing.T, content string, data []uint) (res []string) {
+	t.Helper()
+	require.Zero(t, len(data)%5)
+	lines := strings.Split(content, "\n")
+	var line, start uint
+	for i := 0; i < len(data); i += 5 {
+		if data[i] > 0 {
+			start = 0
+		}
+		line, start = line+data[i], start+data[i+1]
+		var modifiers []string
+		for bit, modifier := range SemanticTokensLegend.TokenModifiers {
+			if data[i+4]&(1<<bit) != 0 {
+				modifiers = append(modifiers, modifier)
+			}
+		}
+		// the columns count UTF-16 code units
+		text := lines[line][parser.UTF16Offset(lines[line], start):parser.UTF16Offset(lines[line], start+data[i+2])]
+		res = append(res, strings.TrimSpace(fmt.Sprintf("%d:%d %s %s %s", line, start, text, SemanticTokensLegend.TokenTypes[data[i+3]], strings.Join(modifiers, ","))))
+	}
+	return res
+}
+
+func TestSemanticTokens(t *testing.T) {
+	api := `syntax = "proto3";
+package acme.api;
+
+import "google/protobuf/timestamp.proto";
+
+message User {
+	option deprecated = true;
+	message Address {}
+	string name = 1 [deprecated = true];
+	Address address = 2;
+	google.protobuf.Timestamp created = 3;
+	map<string, Status> statuses = 4;
+	Unknown unknown = 5;
+}
+
+enum Status {
+	STATUS_UNSPECIFIED = 0;
+}
+
+service Users {
+	rpc Get(User.Address) returns (.acme.api.User);
+}
+`
+	uris := setupWorkspace(t, map[string]string{"api.proto": api})
+
+	res, err := SemanticTokensFull(context.Background(), &defines.SemanticTokensParams{
+		TextDocument: defines.TextDocumentIdentifier{Uri: uris["api.proto"]},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"1:8 acme.api namespace declaration",
+		"5:8 User class declaration,deprecated",
+		"6:8 deprecated decorator",
+		"7:9 Address class declaration",
+		"8:1 string type defaultLibrary",
+		"8:8 name property declaration,deprecated",
+		"8:18 deprecated decorator",
+		"9:1 Address class",
+		"9:9 address property declaration",
+		"10:1 google namespace",
+		"10:8 protobuf namespace",
+		"10:17 Timestamp class defaultLibrary",
+		"10:27 created property declaration",
+		"11:5 string type defaultLibrary",
+		"11:13 Status enum",
+		"11:21 statuses property declaration",
+		"12:9 unknown property declaration",
+		"15:5 Status enum declaration",
+		"16:1 STATUS_UNSPECIFIED enumMember declaration",
+		"19:8 Users interface declaration",
+		"20:5 Get method declaration",
+		"20:9 User class deprecated",
+		"20:14 Address class",
+		"20:33 acme namespace",
+		"20:38 api namespace",
+		"20:42 User class deprecated",
+	}, decodeSemanticTokens(t, api, res.Data))
+
+	res, err = SemanticTokensRange(context.Background(), &defines.SemanticTokensRangeParams{
+		TextDocument: defines.TextDocumentIdentifier{Uri: uris["api.proto"]},
+		Range:        defines.Range{Start: defines.Position{Line: 15}, End: defines.Position{Line: 16, Character: 10}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"15:5 Status enum declaration",
+		"16:1 STATUS_UNSPECIFIED enumMember declaration",
+	}, decodeSemanticTokens(t, api, res.Data))
+}
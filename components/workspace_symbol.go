@@ -0,0 +1,85 @@
+package components
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/walteh/protobuf-language-server/proto/view"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+// maxWorkspaceSymbols limits the result so large monorepos stay responsive.
+const maxWorkspaceSymbols = 500
+
+// WorkspaceSymbol searches the messages, enums, enum values, fields, services
+// and rpcs of every proto file in the workspace. The query matches a symbol if
+// its characters appear in order in the name or fully qualified name, ignoring
+// case.
+func WorkspaceSymbol(ctx context.Context, req *defines.WorkspaceSymbolParams) (result *[]defines.SymbolInformation, err error) {
+	type match struct {
+		symbol view.Symbol
+		score  int
+	}
+	query := strings.ToLower(req.Query)
+	matches := []match{}
+	for _, symbol := range view.FromContext(ctx).WorkspaceSymbols() {
+		score, ok := fuzzyScore(query, symbol.Name)
+		if full_score, full_ok := fuzzyScore(query, symbol.FullName); full_ok && (!ok || full_score < score) {
+			score, ok = full_score, true
+		}
+		if ok {
+			matches = append(matches, match{symbol: symbol, score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score < matches[j].score
+	})
+	if len(matches) > maxWorkspaceSymbols {
+		matches = matches[:maxWorkspaceSymbols]
+	}
+
+	res := make([]defines.SymbolInformation, 0, len(matches))
+	for _, m := range matches {
+		info := defines.SymbolInformation{
+			Name:     m.symbol.Name,
+			Kind:     m.symbol.Kind,
+			Location: m.symbol.Location,
+		}
+		if m.symbol.ContainerName != "" {
+			container := m.symbol.ContainerName
+			info.ContainerName = &container
+		}
+		res = append(res, info)
+	}
+	return &res, nil
+}
+
+// fuzzyScore reports whether the lower case query is a subsequence of name and
+// how well it matches, lower is better: exact matches come first, then
+// prefixes, substrings and finally scattered matches ranked by the number of
+// characters skipped.
+func fuzzyScore(query, name string) (int, bool) {
+	name = strings.ToLower(name)
+	switch {
+	case query == name:
+		return 0, true
+	case strings.HasPrefix(name, query):
+		return 1, true
+	case strings.Contains(name, query):
+		return 2, true
+	}
+	gaps, i := 0, 0
+	for j := 0; j < len(name) && i < len(query); j++ {
+		if name[j] == query[i] {
+			i++
+		} else if i > 0 {
+			gaps++
+		}
+	}
+	if i < len(query) {
+		return 0, false
+	}
+	return 3 + gaps, true
+}
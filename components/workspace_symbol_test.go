@@ -0,0 +1,37 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+func TestWorkspaceSymbol(t *testing.T) {
+	setupWorkspace(t, map[string]string{
+		"user.proto": `syntax = "proto3";
+package api;
+
+message GetUserRequest {}
+message User {
+	string user_name = 1;
+}
+message Group {}
+`,
+	})
+
+	names := func(query string) (res []string) {
+		symbols, err := WorkspaceSymbol(context.Background(), &defines.WorkspaceSymbolParams{Query: query})
+		require.NoError(t, err)
+		for _, symbol := range *symbols {
+			res = append(res, *symbol.ContainerName+"."+symbol.Name)
+		}
+		return res
+	}
+
+	require.Equal(t, []string{"api.User", "api.User.user_name", "api.GetUserRequest"}, names("user"))
+	require.Equal(t, []string{"api.GetUserRequest"}, names("gur"))
+	require.Equal(t, []string{"api.User.user_name"}, names("api.user.name"))
+}
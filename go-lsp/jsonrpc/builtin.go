@@ -2,7 +2,8 @@ package jsonrpc
 
 import "context"
 
-// $/cancelRequest
+const cancelRequestMethod = "$/cancelRequest"
+
 type cancelParams struct {
 	ID interface{} `json:"id"`
 }
@@ -19,7 +20,7 @@ func cancelRequest(ctx context.Context, req interface{}) (interface{}, error) {
 
 func CancelRequest() MethodInfo {
 	return MethodInfo{
-		Name: "$/cancelRequest",
+		Name: cancelRequestMethod,
 		NewRequest: func() interface{} {
 			return &cancelParams{}
 		},
@@ -27,5 +28,4 @@ func CancelRequest() MethodInfo {
 	}
 }
 
-// $/progress
-// TODO not implemented yet
+// $/progress is only sent by the server, see lsp.Progress.
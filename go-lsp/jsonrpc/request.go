@@ -0,0 +1,106 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
+)
+
+// DefaultRequestTimeout bounds how long Request waits for the client unless
+// the server sets another timeout, see Server.SetRequestTimeout.
+const DefaultRequestTimeout = time.Minute
+
+var ErrSessionClosed = errors.New("session closed")
+
+// ErrNoSession is returned when a message is sent outside of a session.
+var ErrNoSession = errors.New("no session")
+
+// SessionFromContext returns the session a request is handled in, or nil if
+// ctx does not belong to a request.
+func SessionFromContext(ctx context.Context) *Session {
+	return getSession(ctx)
+}
+
+// Request sends a request to the client and waits for its response, whose
+// result is unmarshalled into result unless it is nil. An error response is
+// returned as ResponseError. If ctx is done or the request timeout passes
+// first, the request is cancelled with $/cancelRequest and the error of the
+// context is returned.
+//
+// The response is read by the loop that reads requests, so Request must not
+// be called from the reading goroutine. On wasip1, where handlers run on that
+// goroutine, call it from a new goroutine.
+func (s *Session) Request(ctx context.Context, method string, params interface{}, result interface{}) error {
+	if timeout := s.server.requestTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	raw, err := jsoniter.Marshal(params)
+	if err != nil {
+		return err
+	}
+	id := atomic.AddInt64(&s.nextID, 1)
+	key := fmt.Sprint(id)
+	ch := make(chan *message, 1)
+	s.pendingLock.Lock()
+	s.pending[key] = ch
+	s.pendingLock.Unlock()
+	defer func() {
+		s.pendingLock.Lock()
+		delete(s.pending, key)
+		s.pendingLock.Unlock()
+	}()
+
+	req := RequestMessage{BaseMessage: BaseMessage{Jsonrpc: "2.0"}, ID: id, Method: method, Params: raw}
+	if err := s.SendMsg(req); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return *resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return jsoniter.Unmarshal(resp.Result, result)
+	case <-ctx.Done():
+		s.Notify("$/cancelRequest", CancelParams{ID: id})
+		return fmt.Errorf("request %s: %w", method, ctx.Err())
+	case <-s.closed:
+		return fmt.Errorf("request %s: %w", method, ErrSessionClosed)
+	}
+}
+
+// Notify sends a notification to the client.
+func (s *Session) Notify(method string, params interface{}) error {
+	raw, err := jsoniter.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.SendMsg(NotificationMessage{BaseMessage: BaseMessage{Jsonrpc: "2.0"}, Method: method, Params: raw})
+}
+
+// handleResponse passes a response of the client to the Request waiting for
+// it. Responses to unknown or abandoned requests are dropped.
+func (s *Session) handleResponse(resp *message) {
+	key := fmt.Sprint(resp.ID)
+	s.pendingLock.Lock()
+	ch, ok := s.pending[key]
+	delete(s.pending, key)
+	s.pendingLock.Unlock()
+	if !ok {
+		logs.Printf("drop response to unknown request: [%v]\n", resp.ID)
+		return
+	}
+	logs.Printf("Response of client: [%v]\n", resp.ID)
+	ch <- resp
+}
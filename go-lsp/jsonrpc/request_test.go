@@ -0,0 +1,149 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
+)
+
+func TestMain(m *testing.M) {
+	logs.Init(nil)
+	os.Exit(m.Run())
+}
+
+// testClient speaks to a session over a pipe like an editor would.
+type testClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newTestClient(t *testing.T, server *Server) *testClient {
+	t.Helper()
+	client, conn := net.Pipe()
+	go server.ConnComeIn(conn)
+	t.Cleanup(func() { client.Close() })
+	return &testClient{conn: client, reader: bufio.NewReader(client)}
+}
+
+func (c *testClient) send(t *testing.T, msg string) {
+	t.Helper()
+	_, err := fmt.Fprintf(c.conn, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	require.NoError(t, err)
+}
+
+func (c *testClient) read(t *testing.T) map[string]interface{} {
+	t.Helper()
+	header, err := c.reader.ReadString('\n')
+	require.NoError(t, err)
+	length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+	require.NoError(t, err)
+	_, err = c.reader.ReadString('\n')
+	require.NoError(t, err)
+	body := make([]byte, length)
+	_, err = io.ReadFull(c.reader, body)
+	require.NoError(t, err)
+	var msg map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &msg))
+	return msg
+}
+
+// newRoundTripServer serves test/roundTrip by asking the client client/echo
+// and answering with its result or error.
+func newRoundTripServer() *Server {
+	server := NewServer()
+	server.RegisterMethod(MethodInfo{
+		Name:       "test/roundTrip",
+		NewRequest: func() interface{} { return &map[string]string{} },
+		Handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+			var result string
+			err := SessionFromContext(ctx).Request(ctx, "client/echo", req, &result)
+			if err != nil {
+				return nil, ResponseError{Code: RequestFailedCode, Message: err.Error()}
+			}
+			return result, nil
+		},
+	})
+	return server
+}
+
+func TestSessionRequest(t *testing.T) {
+	client := newTestClient(t, newRoundTripServer())
+
+	client.send(t, `{"jsonrpc":"2.0","id":"a","method":"test/roundTrip","params":{"text":"hello"}}`)
+	req := client.read(t)
+	require.Equal(t, "client/echo", req["method"])
+	require.Equal(t, map[string]interface{}{"text": "hello"}, req["params"])
+
+	client.send(t, fmt.Sprintf(`{"jsonrpc":"2.0","id":%v,"result":"hello back"}`, req["id"]))
+	resp := client.read(t)
+	require.Equal(t, "a", resp["id"])
+	require.Equal(t, "hello back", resp["result"])
+
+	// an error response of the client is returned by Request
+	client.send(t, `{"jsonrpc":"2.0","id":"b","method":"test/roundTrip","params":{}}`)
+	req = client.read(t)
+	require.NotEqual(t, float64(1), req["id"], "request ids are not reused")
+	client.send(t, fmt.Sprintf(`{"jsonrpc":"2.0","id":%v,"error":{"code":-32601,"message":"no echo"}}`, req["id"]))
+	resp = client.read(t)
+	require.Equal(t, "b", resp["id"])
+	require.Contains(t, resp["error"].(map[string]interface{})["message"], "no echo")
+}
+
+func TestServerSession(t *testing.T) {
+	server := newRoundTripServer()
+	newTestClient(t, server)
+
+	// requests outside of a request are not routed to some client
+	require.Nil(t, server.Session(context.Background()))
+	require.ErrorIs(t, server.SendMsg(context.Background(), NotificationMessage{}), ErrNoSession)
+}
+
+func TestSessionRequestTimeout(t *testing.T) {
+	server := newRoundTripServer()
+	server.SetRequestTimeout(10 * time.Millisecond)
+	client := newTestClient(t, server)
+
+	client.send(t, `{"jsonrpc":"2.0","id":1,"method":"test/roundTrip","params":{}}`)
+	req := client.read(t)
+	cancel := client.read(t)
+	require.Equal(t, "$/cancelRequest", cancel["method"])
+	require.Equal(t, req["id"], cancel["params"].(map[string]interface{})["id"])
+	resp := client.read(t)
+	require.Contains(t, resp["error"].(map[string]interface{})["message"], context.DeadlineExceeded.Error())
+
+	// a late response is dropped
+	client.send(t, fmt.Sprintf(`{"jsonrpc":"2.0","id":%v,"result":"late"}`, req["id"]))
+}
+
+func TestSessionRequestClosed(t *testing.T) {
+	server := NewServer()
+	errs := make(chan error, 1)
+	server.RegisterMethod(MethodInfo{
+		Name:       "test/wait",
+		NewRequest: func() interface{} { return &map[string]string{} },
+		Handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+			errs <- SessionFromContext(ctx).Request(context.Background(), "client/echo", nil, nil)
+			return nil, nil
+		},
+	})
+	client := newTestClient(t, server)
+
+	client.send(t, `{"jsonrpc":"2.0","method":"test/wait","params":{}}`)
+	client.read(t)
+	client.conn.Close()
+	err := <-errs
+	require.True(t, errors.Is(err, ErrSessionClosed), err)
+}
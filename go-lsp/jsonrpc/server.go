@@ -3,6 +3,7 @@ package jsonrpc
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 type MethodInfo struct {
@@ -16,12 +17,16 @@ type Server struct {
 	nowId       int
 	methods     map[string]MethodInfo
 	sessionLock sync.Mutex
+
+	timeoutLock sync.RWMutex
+	timeout     time.Duration
 }
 
 func NewServer() *Server {
 	s := &Server{}
 	s.session = make(map[int]*Session)
 	s.methods = make(map[string]MethodInfo)
+	s.timeout = DefaultRequestTimeout
 
 	// Register Builtin
 	s.RegisterMethod(CancelRequest())
@@ -54,12 +59,34 @@ func (s *Server) newSession(conn ReaderWriter) *Session {
 	return session
 }
 
-func (s *Server) SendMsg(resp interface{}) error {
-	for _, session := range s.session {
-		if session != nil {
-			session.SendMsg(resp)
-			break
-		}
+// SetRequestTimeout sets how long requests to the client wait for a response,
+// zero waits until the context of the request is done.
+func (s *Server) SetRequestTimeout(timeout time.Duration) {
+	s.timeoutLock.Lock()
+	defer s.timeoutLock.Unlock()
+	s.timeout = timeout
+}
+
+func (s *Server) requestTimeout() time.Duration {
+	s.timeoutLock.RLock()
+	defer s.timeoutLock.RUnlock()
+	return s.timeout
+}
+
+// Session returns the session a request is handled in, or nil if ctx does not
+// belong to a request. Requests to the client are only ever sent to the
+// session owning the request, never to another client.
+func (s *Server) Session(ctx context.Context) *Session {
+	return getSession(ctx)
+}
+
+// SendMsg sends msg to the client of the session ctx belongs to. It fails with
+// ErrNoSession if ctx does not belong to a request, a message is never sent to
+// another client.
+func (s *Server) SendMsg(ctx context.Context, msg interface{}) error {
+	session := s.Session(ctx)
+	if session == nil {
+		return ErrNoSession
 	}
-	return nil
+	return session.SendMsg(msg)
 }
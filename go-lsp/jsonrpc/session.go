@@ -32,12 +32,28 @@ type Session struct {
 	executorLock sync.Mutex
 	writeLock    sync.Mutex
 	cancel       chan struct{}
+
+	// requests of the server waiting for a response of the client, see Request
+	pending     map[string]chan *message
+	pendingLock sync.Mutex
+	nextID      int64
+	closed      chan struct{}
+	closeOnce   sync.Once
+
+	// state handlers keep for the connection, see Value
+	values sync.Map
+
+	// done when the last notification read is handled, see execute. Only
+	// the goroutine reading the connection uses it.
+	notified chan struct{}
 }
 
 func newSession(id int, server *Server, conn ReaderWriter) *Session {
 	s := &Session{id: id, server: server, conn: conn}
 	s.executors = make(map[interface{}]*executor)
 	s.cancel = make(chan struct{}, 1)
+	s.pending = make(map[string]chan *message)
+	s.closed = make(chan struct{})
 	return s
 }
 
@@ -63,8 +79,12 @@ func (s *Session) handle() {
 		return
 	}
 	req.Jsonrpc = "2.0"
+	if req.Method == "" {
+		s.handleResponse(&req)
+		return
+	}
 	logs.Printf("Request: [%v] [%s], content: [%v]\n", req.ID, req.Method, string(req.Params))
-	err = s.handlerRequest(req)
+	err = s.handlerRequest(req.RequestMessage)
 	if err != nil {
 		logs.Println("handlerRequest error: ", err)
 		err := s.handlerResponse(req.ID, nil, err)
@@ -102,33 +122,33 @@ func (s *Session) readSize(len int) ([]byte, error) {
 	return buf, nil
 }
 
-func (s *Session) readRequest() (RequestMessage, error) {
+func (s *Session) readRequest() (message, error) {
 	lenHeader, err := s.readSize(15)
 	if err != nil {
-		return RequestMessage{}, err
+		return message{}, err
 	}
 	if strings.ToLower(string(lenHeader)) != "content-length:" {
-		return RequestMessage{}, ParseError
+		return message{}, ParseError
 	}
 	var buf []byte
 	state := 0
 	for max := 0; max < 20; max++ {
 		b, err := s.readSize(1)
 		if err != nil {
-			return RequestMessage{}, err
+			return message{}, err
 		}
 		if state == 0 {
 			buf = append(buf, b[0])
 		} else {
 			if b[0] != '\r' && b[0] != '\n' {
-				return RequestMessage{}, ParseError
+				return message{}, ParseError
 			}
 		}
 		if b[0] == '\r' {
 			if state%2 == 0 {
 				state += 1
 			} else {
-				return RequestMessage{}, ParseError
+				return message{}, ParseError
 			}
 		}
 		if b[0] == '\n' {
@@ -138,29 +158,29 @@ func (s *Session) readRequest() (RequestMessage, error) {
 					break
 				}
 			} else {
-				return RequestMessage{}, ParseError
+				return message{}, ParseError
 			}
 		}
 	}
 	if state != 4 {
-		return RequestMessage{}, ParseError
+		return message{}, ParseError
 	}
 	contentLen, err := strconv.Atoi(strings.TrimSpace(string(buf)))
 	if err != nil {
 		e := ParseError
 		e.Data = err
-		return RequestMessage{}, e
+		return message{}, e
 	}
 	content, err := s.readSize(contentLen)
 	if err != nil {
-		return RequestMessage{}, err
+		return message{}, err
 	}
-	req := RequestMessage{}
+	req := message{}
 	err = jsoniter.Unmarshal(content, &req)
 	if err != nil {
 		e := ParseError
 		e.Data = err
-		return RequestMessage{}, e
+		return message{}, e
 	}
 	return req, nil
 }
@@ -173,6 +193,19 @@ func getSession(ctx context.Context) *Session {
 	return val.(*Session)
 }
 
+// Value returns the state stored for key on this connection, or nil.
+func (s *Session) Value(key interface{}) interface{} {
+	val, _ := s.values.Load(key)
+	return val
+}
+
+// SetValue stores state for key on this connection, it lives as long as the
+// connection does. Servers talking to several clients keep their per client
+// state this way.
+func (s *Session) SetValue(key, value interface{}) {
+	s.values.Store(key, value)
+}
+
 func (s *Session) getExecutor(id interface{}) *executor {
 	if isNil(id) {
 		return nil
@@ -228,9 +261,33 @@ func (s *Session) execute(mtdInfo MethodInfo, req RequestMessage, args interface
 		// normally stuff works alright, but in a stdio language server the main thread is blocked on waiting for input
 		// i have not really tested the repercussions of this, but it does indeed work (as in "not block") in vscode
 		wrk()
-	} else {
+		return
+	}
+	if req.Method == cancelRequestMethod {
+		// cancels requests queued or running, without waiting for them
 		go wrk()
+		return
+	}
+
+	// notifications, e.g. textDocument/didChange, are handled one after the
+	// other in the order they are read, requests only start once the
+	// notifications before them are handled and then run concurrently
+	prev := s.notified
+	if req.ID == nil {
+		done := make(chan struct{})
+		s.notified = done
+		wrk_notification := wrk
+		wrk = func() {
+			defer close(done)
+			wrk_notification()
+		}
 	}
+	go func() {
+		if prev != nil {
+			<-prev
+		}
+		wrk()
+	}()
 }
 
 func (s *Session) handlerRequest(req RequestMessage) error {
@@ -338,6 +395,7 @@ func (s *Session) handlerError(err error) {
 		case s.cancel <- struct{}{}:
 		default:
 		}
+		s.closeOnce.Do(func() { close(s.closed) })
 		s.server.removeSession(s.id)
 	}
 	logs.Println("error: ", err)
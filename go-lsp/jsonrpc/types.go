@@ -16,6 +16,14 @@ type RequestMessage struct {
 	Params json.RawMessage `json:"params"` // params, is some struct or slice
 }
 
+// message is any message read from the connection: a request, a notification
+// or a response to a request of the server, which has no method.
+type message struct {
+	RequestMessage
+	Result json.RawMessage `json:"result"`
+	Error  *ResponseError  `json:"error"`
+}
+
 type NotificationMessage struct {
 	BaseMessage
 	Method string          `json:"method"` // starts with "/$", server build-in methods.
@@ -65,6 +73,7 @@ const UnknownErrorCodeCode = -32001
 const jsonrpcReservedErrorRangeEnCode = -32000
 const serverErrorEndCode = jsonrpcReservedErrorRangeEnCode
 const lspReservedErrorRangeStartCode = -32899
+const RequestFailedCode = -32803
 const ContentModifiedCode = -32801
 const RequestCancelledCode = -32800
 const lspReservedErrorRangeEndCode = -32800
@@ -101,6 +110,10 @@ var ContentModified = BuildInError{
 	Code:    ContentModifiedCode,
 	Message: "ContentModified",
 }
+var RequestFailed = BuildInError{
+	Code:    RequestFailedCode,
+	Message: "RequestFailed",
+}
 var RequestCancelled = BuildInError{
 	Code:    RequestCancelledCode,
 	Message: "RequestCancelled",
@@ -8,7 +8,11 @@ import (
 
 func (m *Methods) builtinInitialize(ctx context.Context, req *defines.InitializeParams) (defines.InitializeResult, error) {
 	resp := defines.InitializeResult{}
-	resp.Capabilities.TextDocumentSync = defines.TextDocumentSyncKindFull
+	if m.Opt.TextDocumentSync != defines.TextDocumentSyncKindNone {
+		resp.Capabilities.TextDocumentSync = m.Opt.TextDocumentSync
+	} else {
+		resp.Capabilities.TextDocumentSync = defines.TextDocumentSyncKindFull
+	}
 	if m.Opt.CompletionProvider != nil {
 		resp.Capabilities.CompletionProvider = m.Opt.CompletionProvider
 	} else if m.onCompletion != nil {
@@ -115,7 +119,10 @@ func (m *Methods) builtinInitialize(ctx context.Context, req *defines.Initialize
 	}
 	if m.Opt.RenameProvider != nil {
 		resp.Capabilities.RenameProvider = m.Opt.RenameProvider
-	} else if m.onPrepareRename != nil {
+	} else if m.onRenameRequest != nil && m.onPrepareRename != nil {
+		t := true
+		resp.Capabilities.RenameProvider = &defines.RenameOptions{PrepareProvider: &t}
+	} else if m.onRenameRequest != nil {
 		resp.Capabilities.RenameProvider = true
 	}
 	if m.Opt.FoldingRangeProvider != nil {
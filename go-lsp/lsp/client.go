@@ -0,0 +1,55 @@
+package lsp
+
+import (
+	"context"
+	"time"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/jsonrpc"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+// Request sends a request to the client of the connection ctx belongs to and
+// waits for the response, see jsonrpc.Session.Request.
+func (s *Server) Request(ctx context.Context, method string, params interface{}, result interface{}) error {
+	session := s.rpcServer.Session(ctx)
+	if session == nil {
+		return jsonrpc.ErrSessionClosed
+	}
+	return session.Request(ctx, method, params, result)
+}
+
+// SetRequestTimeout sets how long requests to the client wait for a response,
+// zero waits until the context of the request is done.
+func (s *Server) SetRequestTimeout(timeout time.Duration) {
+	s.rpcServer.SetRequestTimeout(timeout)
+}
+
+// WorkspaceConfiguration fetches configuration settings from the client, one
+// result per item.
+func (s *Server) WorkspaceConfiguration(ctx context.Context, params *defines.ConfigurationParams) ([]interface{}, error) {
+	var res []interface{}
+	err := s.Request(ctx, "workspace/configuration", params, &res)
+	return res, err
+}
+
+// ApplyEdit asks the client to apply an edit to the workspace.
+func (s *Server) ApplyEdit(ctx context.Context, params *defines.ApplyWorkspaceEditParams) (*defines.ApplyWorkspaceEditResult, error) {
+	res := &defines.ApplyWorkspaceEditResult{}
+	if err := s.Request(ctx, "workspace/applyEdit", params, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ShowMessageRequest shows a message with actions to the user and returns the
+// selected action, or nil if the message was dismissed.
+func (s *Server) ShowMessageRequest(ctx context.Context, params *defines.ShowMessageRequestParams) (*defines.MessageActionItem, error) {
+	var res *defines.MessageActionItem
+	err := s.Request(ctx, "window/showMessageRequest", params, &res)
+	return res, err
+}
+
+// RegisterCapability registers a capability with the client dynamically.
+func (s *Server) RegisterCapability(ctx context.Context, params *defines.RegistrationParams) error {
+	return s.Request(ctx, "client/registerCapability", params, nil)
+}
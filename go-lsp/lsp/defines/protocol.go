@@ -1,10 +1,12 @@
 package defines
 
+// ClientCapabilities does not embed WorkspaceFoldersClientCapabilities,
+// ConfigurationClientCapabilities and WorkDoneProgressClientCapabilities, their
+// workspace and window fields would hide the ones of _ClientCapabilities when
+// decoding. Their capabilities are part of WorkspaceClientCapabilities and
+// WindowClientCapabilities instead.
 type ClientCapabilities struct {
 	_ClientCapabilities
-	WorkspaceFoldersClientCapabilities
-	ConfigurationClientCapabilities
-	WorkDoneProgressClientCapabilities
 }
 type ServerCapabilities struct {
 	_ServerCapabilities
@@ -209,6 +211,16 @@ type WorkspaceClientCapabilities struct {
 	// Capabilities specific to the `workspacedidChangeWatchedFiles` notification.
 	DidChangeWatchedFiles *DidChangeWatchedFilesClientCapabilities `json:"didChangeWatchedFiles,omitempty"`
 
+	// The client has support for workspace folders
+	//
+	// @since 3.6.0
+	WorkspaceFolders *bool `json:"workspaceFolders,omitempty"`
+
+	// The client supports `workspaceconfiguration` requests.
+	//
+	// @since 3.6.0
+	Configuration *bool `json:"configuration,omitempty"`
+
 	// Capabilities specific to the `workspacesymbol` request.
 	Symbol *WorkspaceSymbolClientCapabilities `json:"symbol,omitempty"`
 
@@ -763,7 +775,7 @@ type DidChangeTextDocumentParams struct {
 type TextDocumentContentChangeEvent struct {
 
 	// The range of the document that changed.
-	Range Range `json:"range,omitempty"`
+	Range *Range `json:"range,omitempty"`
 
 	// The optional length of the range that got replaced.
 	//
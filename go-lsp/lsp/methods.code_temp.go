@@ -1,5 +1,14 @@
 package lsp
 
+const importsTemp = `
+import (
+	"context"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/jsonrpc"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+`
+
 const structItemTemp = `	on%s func(ctx context.Context, req *%s) (*%s, %s)`
 
 const noRespStructItemTemp = `	on%s func(ctx context.Context, req *%s) %s`
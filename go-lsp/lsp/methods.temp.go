@@ -35,32 +35,38 @@ var methods = []method{
 		Name: "Exit",
 	},
 	{
-		Name: "DidChangeConfiguration",
-		Args: defines.DidChangeConfigurationParams{},
+		Name:         "DidChangeConfiguration",
+		RegisterName: "workspace/didChangeConfiguration",
+		Args:         defines.DidChangeConfigurationParams{},
 	},
 	{
-		Name: "DidChangeWatchedFiles",
-		Args: defines.DidChangeWatchedFilesParams{},
+		Name:         "DidChangeWatchedFiles",
+		RegisterName: "workspace/didChangeWatchedFiles",
+		Args:         defines.DidChangeWatchedFilesParams{},
 	},
 	{
-		Name: "DidOpenTextDocument",
-		Args: defines.DidOpenTextDocumentParams{},
+		Name:         "DidOpenTextDocument",
+		RegisterName: "textDocument/didOpen",
+		Args:         defines.DidOpenTextDocumentParams{},
 	},
 	{
-		Name: "DidChangeTextDocument",
-		Args: defines.DidChangeTextDocumentParams{},
+		Name:         "DidChangeTextDocument",
+		RegisterName: "textDocument/didChange",
+		Args:         defines.DidChangeTextDocumentParams{},
 	},
 	{
-		Name: "DidCloseTextDocument",
-		Args: defines.DidCloseTextDocumentParams{},
+		Name:         "DidCloseTextDocument",
+		RegisterName: "textDocument/didClose",
+		Args:         defines.DidCloseTextDocumentParams{},
 	},
 	{
 		Name: "WillSaveTextDocument",
 		Args: defines.WillSaveTextDocumentParams{},
 	},
 	{
-		Name: "DidSaveTextDocument",
-		Args: defines.DidSaveTextDocumentParams{},
+		Name:         "DidSaveTextDocument",
+		RegisterName: "textDocument/didSave",
+		Args:         defines.DidSaveTextDocumentParams{},
 	},
 	{
 		Name:          "ExecuteCommand",
@@ -202,7 +208,7 @@ var methods = []method{
 	},
 	{
 		Name:         "PrepareRename",
-		RegisterName: "textDocument/rename",
+		RegisterName: "textDocument/prepareRename",
 		Args:         defines.PrepareRenameParams{},
 		Result:       defines.Range{},
 	},
@@ -247,4 +253,18 @@ var methods = []method{
 		Result:        []defines.SelectionRange{},
 		ProgressToken: []defines.SelectionRange{},
 	},
+	{
+		Name:          "SemanticTokensFull",
+		RegisterName:  "textDocument/semanticTokens/full",
+		Args:          defines.SemanticTokensParams{},
+		Result:        defines.SemanticTokens{},
+		ProgressToken: defines.SemanticTokensPartialResult{},
+	},
+	{
+		Name:          "SemanticTokensRange",
+		RegisterName:  "textDocument/semanticTokens/range",
+		Args:          defines.SemanticTokensRangeParams{},
+		Result:        defines.SemanticTokens{},
+		ProgressToken: defines.SemanticTokensPartialResult{},
+	},
 }
@@ -51,6 +51,8 @@ type Methods struct {
 	onColorPresentation                        func(ctx context.Context, req *defines.ColorPresentationParams) (*[]defines.ColorPresentation, error)
 	onFoldingRanges                            func(ctx context.Context, req *defines.FoldingRangeParams) (*[]defines.FoldingRange, error)
 	onSelectionRanges                          func(ctx context.Context, req *defines.SelectionRangeParams) (*[]defines.SelectionRange, error)
+	onSemanticTokensFull                       func(ctx context.Context, req *defines.SemanticTokensParams) (*defines.SemanticTokens, error)
+	onSemanticTokensRange                      func(ctx context.Context, req *defines.SemanticTokensRangeParams) (*defines.SemanticTokens, error)
 }
 
 func (m *Methods) OnInitialize(f func(ctx context.Context, req *defines.InitializeParams) (result *defines.InitializeResult, err *defines.InitializeError)) {
@@ -214,7 +216,7 @@ func (m *Methods) didChangeWatchedFilesMethodInfo() *jsonrpc.MethodInfo {
 		return nil
 	}
 	return &jsonrpc.MethodInfo{
-		Name: "didChangeWatchedFiles",
+		Name: "workspace/didChangeWatchedFiles",
 		NewRequest: func() interface{} {
 			return &defines.DidChangeWatchedFilesParams{}
 		},
@@ -1026,7 +1028,7 @@ func (m *Methods) prepareRenameMethodInfo() *jsonrpc.MethodInfo {
 		return nil
 	}
 	return &jsonrpc.MethodInfo{
-		Name: "textDocument/rename",
+		Name: "textDocument/prepareRename",
 		NewRequest: func() interface{} {
 			return &defines.PrepareRenameParams{}
 		},
@@ -1202,6 +1204,62 @@ func (m *Methods) selectionRangesMethodInfo() *jsonrpc.MethodInfo {
 	}
 }
 
+func (m *Methods) OnSemanticTokensFull(f func(ctx context.Context, req *defines.SemanticTokensParams) (result *defines.SemanticTokens, err error)) {
+	m.onSemanticTokensFull = f
+}
+
+func (m *Methods) semanticTokensFull(ctx context.Context, req interface{}) (interface{}, error) {
+	params := req.(*defines.SemanticTokensParams)
+	if m.onSemanticTokensFull != nil {
+		res, err := m.onSemanticTokensFull(ctx, params)
+		e := wrapErrorToRespError(err, 0)
+		return res, e
+	}
+	return nil, nil
+}
+
+func (m *Methods) semanticTokensFullMethodInfo() *jsonrpc.MethodInfo {
+
+	if m.onSemanticTokensFull == nil {
+		return nil
+	}
+	return &jsonrpc.MethodInfo{
+		Name: "textDocument/semanticTokens/full",
+		NewRequest: func() interface{} {
+			return &defines.SemanticTokensParams{}
+		},
+		Handler: m.semanticTokensFull,
+	}
+}
+
+func (m *Methods) OnSemanticTokensRange(f func(ctx context.Context, req *defines.SemanticTokensRangeParams) (result *defines.SemanticTokens, err error)) {
+	m.onSemanticTokensRange = f
+}
+
+func (m *Methods) semanticTokensRange(ctx context.Context, req interface{}) (interface{}, error) {
+	params := req.(*defines.SemanticTokensRangeParams)
+	if m.onSemanticTokensRange != nil {
+		res, err := m.onSemanticTokensRange(ctx, params)
+		e := wrapErrorToRespError(err, 0)
+		return res, e
+	}
+	return nil, nil
+}
+
+func (m *Methods) semanticTokensRangeMethodInfo() *jsonrpc.MethodInfo {
+
+	if m.onSemanticTokensRange == nil {
+		return nil
+	}
+	return &jsonrpc.MethodInfo{
+		Name: "textDocument/semanticTokens/range",
+		NewRequest: func() interface{} {
+			return &defines.SemanticTokensRangeParams{}
+		},
+		Handler: m.semanticTokensRange,
+	}
+}
+
 func (m *Methods) GetMethods() []*jsonrpc.MethodInfo {
 	return []*jsonrpc.MethodInfo{
 		m.initializeMethodInfo(),
@@ -1245,5 +1303,7 @@ func (m *Methods) GetMethods() []*jsonrpc.MethodInfo {
 		m.colorPresentationMethodInfo(),
 		m.foldingRangesMethodInfo(),
 		m.selectionRangesMethodInfo(),
+		m.semanticTokensFullMethodInfo(),
+		m.semanticTokensRangeMethodInfo(),
 	}
 }
@@ -2,6 +2,7 @@ package lsp
 
 import (
 	"fmt"
+	"go/format"
 	"io/ioutil"
 	"reflect"
 	"strings"
@@ -118,8 +119,11 @@ func generateOneNoResp(name, regName, args, error, code string, withBuiltin bool
 }
 
 func TestMethodsGen(t *testing.T) {
-	res := generate(methods)
-	err := ioutil.WriteFile("methods_gen.go", []byte(res), 0777)
+	res, err := format.Source([]byte(generate(methods)))
+	if err != nil {
+		panic(err)
+	}
+	err = ioutil.WriteFile("methods_gen.go", res, 0777)
 	if err != nil {
 		panic(err)
 	}
@@ -220,7 +224,7 @@ func generate(items []method) string {
 			}
 		}
 	}
-	pkg := "// code gen by methods_gen_test.go, do not edit!\npackage lsp\n"
+	pkg := "// code gen by methods_gen_test.go, do not edit!\npackage lsp\n" + importsTemp
 	code1 := strings.Join(codeBlock1, "\n")
 	code2 := strings.Join(codeBlock2, "\n")
 	code3 := strings.Join(codeBlock3, "\n")
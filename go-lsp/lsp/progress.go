@@ -0,0 +1,117 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/jsonrpc"
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+var progressTokens int64
+
+// Progress reports the progress of a long running operation to the client
+// with $/progress notifications. The methods of a nil *Progress do nothing, so
+// callers need not check whether the client supports progress.
+type Progress struct {
+	session    *jsonrpc.Session
+	token      defines.ProgressToken
+	mu         sync.Mutex
+	percentage uint
+	ended      bool
+}
+
+// BeginProgress creates a token with window/workDoneProgress/create and begins
+// reporting progress titled title on it. It returns nil if the client does not
+// support server initiated progress or rejects the token.
+//
+// Creating the token is a round trip to the client, on wasip1 handlers run on
+// the goroutine that reads the response so nil is returned there.
+func (s *Server) BeginProgress(ctx context.Context, title string) *Progress {
+	session := s.rpcServer.Session(ctx)
+	if session == nil || !s.supportsWorkDoneProgress(ctx) || runtime.GOOS == "wasip1" {
+		return nil
+	}
+	token := fmt.Sprintf("go-lsp/progress/%d", atomic.AddInt64(&progressTokens, 1))
+	err := session.Request(ctx, "window/workDoneProgress/create", &defines.WorkDoneProgressCreateParams{Token: token}, nil)
+	if err != nil {
+		logs.Printf("create progress token err: %v", err)
+		return nil
+	}
+	return beginProgress(session, token, title)
+}
+
+// BeginWorkDoneProgress begins reporting progress titled title on the
+// workDoneToken of a request. It returns nil if the client sent no token.
+func (s *Server) BeginWorkDoneProgress(ctx context.Context, token *defines.ProgressToken, title string) *Progress {
+	if token == nil || *token == nil {
+		return nil
+	}
+	session := s.rpcServer.Session(ctx)
+	if session == nil {
+		return nil
+	}
+	return beginProgress(session, *token, title)
+}
+
+func (s *Server) supportsWorkDoneProgress(ctx context.Context) bool {
+	params := s.InitializeParams(ctx)
+	if params == nil || params.Capabilities.Window == nil {
+		return false
+	}
+	supported := params.Capabilities.Window.WorkDoneProgress
+	return supported != nil && *supported
+}
+
+func beginProgress(session *jsonrpc.Session, token defines.ProgressToken, title string) *Progress {
+	p := &Progress{session: session, token: token}
+	percentage := uint(0)
+	p.notify(defines.WorkDoneProgressBegin{Kind: "begin", Title: title, Percentage: &percentage})
+	return p
+}
+
+// Report updates the message and the percentage, a value in [0, 100] that may
+// not decrease.
+func (p *Progress) Report(message string, percentage uint) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ended {
+		return
+	}
+	p.percentage = max(p.percentage, min(percentage, 100))
+	percentage = p.percentage
+	p.notify(defines.WorkDoneProgressReport{Kind: "report", Message: &message, Percentage: &percentage})
+}
+
+// End ends the progress with an optional final message. Later calls of Report
+// and End are ignored.
+func (p *Progress) End(message string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ended {
+		return
+	}
+	p.ended = true
+	end := defines.WorkDoneProgressEnd{Kind: "end"}
+	if message != "" {
+		end.Message = &message
+	}
+	p.notify(end)
+}
+
+func (p *Progress) notify(value interface{}) {
+	err := p.session.Notify("$/progress", jsonrpc.ProgressParams{Token: p.token, Value: value})
+	if err != nil {
+		logs.Printf("send progress err: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/jsonrpc"
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+func readMessage(t *testing.T, reader *bufio.Reader) map[string]interface{} {
+	t.Helper()
+	header, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+	require.NoError(t, err)
+	_, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	body := make([]byte, length)
+	_, err = io.ReadFull(reader, body)
+	require.NoError(t, err)
+	var msg map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &msg))
+	return msg
+}
+
+func writeMessage(t *testing.T, conn net.Conn, msg string) {
+	t.Helper()
+	_, err := fmt.Fprintf(conn, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	require.NoError(t, err)
+}
+
+func TestProgress(t *testing.T) {
+	logs.Init(nil)
+	s := NewServer(&Options{})
+	supported := true
+	params := &defines.InitializeParams{}
+	params.Capabilities.Window = &defines.WindowClientCapabilities{WorkDoneProgress: &supported}
+	s.initializeParams = params
+	s.rpcServer.RegisterMethod(jsonrpc.MethodInfo{
+		Name:       "test/progress",
+		NewRequest: func() interface{} { return &defines.WorkDoneProgressParams{} },
+		Handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+			progress := s.BeginProgress(ctx, "Indexing")
+			progress.Report("1/2 files", 50)
+			progress.Report("2/2 files", 20)
+			progress.End("done")
+			progress.End("again")
+
+			progress = s.BeginWorkDoneProgress(ctx, req.(*defines.WorkDoneProgressParams).WorkDoneToken, "Formatting")
+			progress.End("")
+			return nil, nil
+		},
+	})
+	client, conn := net.Pipe()
+	defer client.Close()
+	go s.rpcServer.ConnComeIn(conn)
+	reader := bufio.NewReader(client)
+
+	writeMessage(t, client, `{"jsonrpc":"2.0","id":1,"method":"test/progress","params":{"workDoneToken":"request-token"}}`)
+	create := readMessage(t, reader)
+	require.Equal(t, "window/workDoneProgress/create", create["method"])
+	token := create["params"].(map[string]interface{})["token"]
+	writeMessage(t, client, fmt.Sprintf(`{"jsonrpc":"2.0","id":%v,"result":null}`, create["id"]))
+
+	type progress struct {
+		Token interface{}
+		Kind  interface{}
+		Value map[string]interface{}
+	}
+	next := func() progress {
+		msg := readMessage(t, reader)
+		require.Equal(t, "$/progress", msg["method"])
+		params := msg["params"].(map[string]interface{})
+		value := params["value"].(map[string]interface{})
+		return progress{Token: params["token"], Kind: value["kind"], Value: value}
+	}
+	begin := next()
+	require.Equal(t, token, begin.Token)
+	require.Equal(t, "begin", begin.Kind)
+	require.Equal(t, "Indexing", begin.Value["title"])
+	report := next()
+	require.Equal(t, "report", report.Kind)
+	require.Equal(t, float64(50), report.Value["percentage"])
+	report = next()
+	require.Equal(t, float64(50), report.Value["percentage"], "the percentage does not decrease")
+	end := next()
+	require.Equal(t, "end", end.Kind)
+	require.Equal(t, "done", end.Value["message"])
+
+	begin = next()
+	require.Equal(t, "request-token", begin.Token)
+	require.Equal(t, "Formatting", begin.Value["title"])
+	require.Equal(t, "end", next().Kind)
+
+	resp := readMessage(t, reader)
+	require.Equal(t, float64(1), resp["id"])
+}
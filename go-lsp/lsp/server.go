@@ -1,17 +1,23 @@
 package lsp
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"reflect"
+	"sync"
 
 	"github.com/walteh/protobuf-language-server/go-lsp/jsonrpc"
 	"github.com/walteh/protobuf-language-server/go-lsp/logs"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
 )
 
 type Server struct {
 	Methods
 	rpcServer *jsonrpc.Server
+
+	initializeMu     sync.RWMutex
+	initializeParams *defines.InitializeParams
 }
 
 func NewServer(opt *Options) *Server {
@@ -25,6 +31,9 @@ func (s *Server) Run() {
 	mtds := s.GetMethods()
 	for _, m := range mtds {
 		if m != nil {
+			if m.Name == "initialize" {
+				m.Handler = s.recordInitialize(m.Handler)
+			}
 			s.rpcServer.RegisterMethod(*m)
 		}
 	}
@@ -57,6 +66,40 @@ func (s *Server) run() {
 	}
 }
 
+type initializeParamsKey struct{}
+
+// recordInitialize keeps the params of the initialize request so they can be
+// read by handlers of later requests, see InitializeParams. Every connection
+// keeps its own.
+func (s *Server) recordInitialize(handler func(ctx context.Context, req interface{}) (interface{}, error)) func(ctx context.Context, req interface{}) (interface{}, error) {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		if params, ok := req.(*defines.InitializeParams); ok {
+			if session := jsonrpc.SessionFromContext(ctx); session != nil {
+				session.SetValue(initializeParamsKey{}, params)
+			} else {
+				s.initializeMu.Lock()
+				s.initializeParams = params
+				s.initializeMu.Unlock()
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// InitializeParams returns the params the client of the connection ctx
+// belongs to sent with the initialize request, or nil if it has not been
+// received yet.
+func (s *Server) InitializeParams(ctx context.Context) *defines.InitializeParams {
+	if session := jsonrpc.SessionFromContext(ctx); session != nil {
+		if params, ok := session.Value(initializeParamsKey{}).(*defines.InitializeParams); ok {
+			return params
+		}
+	}
+	s.initializeMu.RLock()
+	defer s.initializeMu.RUnlock()
+	return s.initializeParams
+}
+
 func wrapErrorToRespError(err interface{}, code int) error {
 	if isNil(err) {
 		return nil
@@ -81,6 +124,8 @@ func isNil(i interface{}) bool {
 	return false
 }
 
-func (s *Server) SendMsg(resp interface{}) error {
-	return s.rpcServer.SendMsg(resp)
+// SendMsg sends msg to the client of the session ctx belongs to, see
+// jsonrpc.Server.SendMsg.
+func (s *Server) SendMsg(ctx context.Context, msg interface{}) error {
+	return s.rpcServer.SendMsg(ctx, msg)
 }
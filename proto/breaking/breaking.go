@@ -0,0 +1,238 @@
+// Package breaking detects changes of proto files that break clients built
+// against an earlier version of them.
+//
+// A baseline is read from a git ref of the local repository, see Repo, or
+// from a FileDescriptorSet. Files are compared one by one, like buf breaking
+// does with its FILE rules, and the rule IDs are the ones of buf.
+package breaking
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/scanner"
+)
+
+// Problem is a breaking change.
+type Problem struct {
+	// Rule is the ID of the violated rule, e.g. FIELD_SAME_TYPE.
+	Rule string
+	// Path is the name of the file, it is set by CompareFiles.
+	Path string
+	// Position is the declaration of the current file the change is shown
+	// at, the enclosing one if the declaration was deleted. It is zero if
+	// the whole file was deleted.
+	Position scanner.Position
+	// Text is the word to mark, it is searched after Anchor starting at
+	// Position. It is empty if the whole line is meant.
+	Anchor, Text string
+	Message      string
+}
+
+// CompareFiles compares the files of current with the ones of the same name
+// in base.
+func CompareFiles(base, current map[string]*File) (problems []Problem) {
+	for _, path := range sortedKeys(base) {
+		current_file, ok := current[path]
+		if !ok {
+			problems = append(problems, Problem{Rule: "FILE_NO_DELETE", Path: path, Message: fmt.Sprintf("file %s was deleted", path)})
+			continue
+		}
+		for _, problem := range Compare(base[path], current_file) {
+			problem.Path = path
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+// Compare returns the breaking changes of current compared to base.
+func Compare(base, current *File) []Problem {
+	c := &comparer{current: current}
+	if base.Package != current.Package {
+		c.report("FILE_SAME_PACKAGE", current.Position, "package", current.Package,
+			fmt.Sprintf("package changed from %q to %q", base.Package, current.Package))
+	}
+	for _, name := range sortedKeys(base.Messages) {
+		c.message(base, base.Messages[name])
+	}
+	for _, name := range sortedKeys(base.Enums) {
+		c.enum(base, base.Enums[name])
+	}
+	for _, name := range sortedKeys(base.Services) {
+		c.service(base.Services[name])
+	}
+	sort.SliceStable(c.problems, func(i, j int) bool {
+		return c.problems[i].Position.Offset < c.problems[j].Position.Offset
+	})
+	return c.problems
+}
+
+type comparer struct {
+	current  *File
+	problems []Problem
+}
+
+func (c *comparer) report(rule string, position scanner.Position, anchor, text, message string) {
+	c.problems = append(c.problems, Problem{Rule: rule, Position: position, Anchor: anchor, Text: text, Message: message})
+}
+
+// deleted reports whether the message of base enclosing name was deleted, in
+// which case only the outermost deleted declaration is reported.
+func (c *comparer) deleted(base *File, name string) bool {
+	parent, _, ok := cutLast(name)
+	if !ok {
+		return false
+	}
+	_, in_base := base.Messages[parent]
+	_, in_current := c.current.Messages[parent]
+	return in_base && !in_current
+}
+
+// parent returns where a deleted declaration is reported, its enclosing
+// message or the package.
+func (c *comparer) parent(name string) (position scanner.Position, anchor, text string) {
+	if parent, _, ok := cutLast(name); ok {
+		if m, ok := c.current.Messages[parent]; ok {
+			return m.Position, "", lastName(m.Name)
+		}
+	}
+	return c.current.Position, "package", c.current.Package
+}
+
+func (c *comparer) message(base *File, m *Message) {
+	current, ok := c.current.Messages[m.Name]
+	if !ok {
+		if !c.deleted(base, m.Name) {
+			position, anchor, text := c.parent(m.Name)
+			c.report("MESSAGE_NO_DELETE", position, anchor, text, fmt.Sprintf("message %s was deleted", m.Name))
+		}
+		return
+	}
+
+	by_name := make(map[string]*Field)
+	for _, f := range current.Fields {
+		by_name[f.Name] = f
+	}
+	for _, number := range sortedKeys(m.Fields) {
+		f := m.Fields[number]
+		if renumbered, ok := by_name[f.Name]; ok && renumbered.Number != f.Number {
+			c.report("FIELD_SAME_NUMBER", renumbered.Position, renumbered.Anchor, renumbered.Name,
+				fmt.Sprintf("field %q of message %s changed number from %d to %d", f.Name, m.Name, f.Number, renumbered.Number))
+		}
+		cf, ok := current.Fields[number]
+		if !ok {
+			if _, renumbered := by_name[f.Name]; !renumbered && !reserved(current.ReservedNumbers, number) {
+				c.report("FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED", current.Position, "", lastName(current.Name),
+					fmt.Sprintf("field %d %q of message %s was deleted without reserving its number", number, f.Name, m.Name))
+			}
+			continue
+		}
+		if !sameType(f.Type, cf.Type) {
+			c.report("FIELD_SAME_TYPE", cf.Position, cf.Anchor, cf.Name,
+				fmt.Sprintf("field %d %q of message %s changed type from %s to %s", number, cf.Name, m.Name, f.Type, cf.Type))
+		}
+		if f.Label != cf.Label {
+			c.report("FIELD_SAME_CARDINALITY", cf.Position, cf.Anchor, cf.Name,
+				fmt.Sprintf("field %d %q of message %s changed cardinality from %s to %s", number, cf.Name, m.Name, labelName(f.Label), labelName(cf.Label)))
+		}
+		if f.Oneof != cf.Oneof {
+			c.report("FIELD_SAME_ONEOF", cf.Position, cf.Anchor, cf.Name,
+				fmt.Sprintf("field %d %q of message %s moved from %s to %s", number, cf.Name, m.Name, oneofName(f.Oneof), oneofName(cf.Oneof)))
+		}
+	}
+}
+
+func (c *comparer) enum(base *File, e *Enum) {
+	current, ok := c.current.Enums[e.Name]
+	if !ok {
+		if !c.deleted(base, e.Name) {
+			position, anchor, text := c.parent(e.Name)
+			c.report("ENUM_NO_DELETE", position, anchor, text, fmt.Sprintf("enum %s was deleted", e.Name))
+		}
+		return
+	}
+
+	by_name := make(map[string]*EnumValue)
+	for _, v := range current.Values {
+		by_name[v.Name] = v
+	}
+	for _, number := range sortedKeys(e.Values) {
+		v := e.Values[number]
+		if renumbered, ok := by_name[v.Name]; ok && renumbered.Number != v.Number {
+			c.report("ENUM_VALUE_SAME_NUMBER", renumbered.Position, "", renumbered.Name,
+				fmt.Sprintf("enum value %s of enum %s changed number from %d to %d", v.Name, e.Name, v.Number, renumbered.Number))
+			continue
+		}
+		if _, ok := current.Values[number]; !ok && !reserved(current.ReservedNumbers, number) {
+			c.report("ENUM_VALUE_NO_DELETE_UNLESS_NUMBER_RESERVED", current.Position, "enum", lastName(current.Name),
+				fmt.Sprintf("enum value %d %s of enum %s was deleted without reserving its number", number, v.Name, e.Name))
+		}
+	}
+}
+
+func (c *comparer) service(s *Service) {
+	current, ok := c.current.Services[s.Name]
+	if !ok {
+		c.report("SERVICE_NO_DELETE", c.current.Position, "package", c.current.Package, fmt.Sprintf("service %s was deleted", s.Name))
+		return
+	}
+	for _, name := range sortedKeys(s.RPCs) {
+		rpc := s.RPCs[name]
+		cr, ok := current.RPCs[name]
+		if !ok {
+			c.report("RPC_NO_DELETE", current.Position, "service", current.Name, fmt.Sprintf("rpc %s of service %s was deleted", name, s.Name))
+			continue
+		}
+		if !sameType(rpc.RequestType, cr.RequestType) {
+			c.report("RPC_SAME_REQUEST_TYPE", cr.Position, "rpc", cr.Name,
+				fmt.Sprintf("rpc %s of service %s changed request type from %s to %s", name, s.Name, rpc.RequestType, cr.RequestType))
+		}
+		if !sameType(rpc.ResponseType, cr.ResponseType) {
+			c.report("RPC_SAME_RESPONSE_TYPE", cr.Position, "rpc", cr.Name,
+				fmt.Sprintf("rpc %s of service %s changed response type from %s to %s", name, s.Name, rpc.ResponseType, cr.ResponseType))
+		}
+		if rpc.ClientStreaming != cr.ClientStreaming {
+			c.report("RPC_SAME_CLIENT_STREAMING", cr.Position, "rpc", cr.Name, fmt.Sprintf("rpc %s of service %s changed client streaming", name, s.Name))
+		}
+		if rpc.ServerStreaming != cr.ServerStreaming {
+			c.report("RPC_SAME_SERVER_STREAMING", cr.Position, "rpc", cr.Name, fmt.Sprintf("rpc %s of service %s changed server streaming", name, s.Name))
+		}
+	}
+}
+
+func reserved(ranges []Range, number int) bool {
+	for _, r := range ranges {
+		if number >= r.From && number <= r.To {
+			return true
+		}
+	}
+	return false
+}
+
+func cutLast(name string) (parent, last string, ok bool) {
+	idx := strings.LastIndex(name, ".")
+	if idx < 0 {
+		return "", name, false
+	}
+	return name[:idx], name[idx+1:], true
+}
+
+func lastName(name string) string {
+	_, last, _ := cutLast(name)
+	return last
+}
+
+func labelName(label string) string {
+	if label == "" {
+		return "singular"
+	}
+	return label
+}
+
+func oneofName(oneof string) string {
+	if oneof == "" {
+		return "no oneof"
+	}
+	return "oneof " + oneof
+}
@@ -0,0 +1,214 @@
+package breaking
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const baseProto = `syntax = "proto3";
+package acme.v1;
+
+import "google/protobuf/timestamp.proto";
+
+message User {
+  string name = 1;
+  int32 age = 2;
+  repeated string tags = 3;
+  google.protobuf.Timestamp created = 4;
+  map<string, Group> groups = 5;
+  oneof contact {
+    string email = 6;
+    string phone = 7;
+  }
+  string nickname = 8;
+  message Address {
+    string street = 1;
+  }
+  Address address = 9;
+}
+
+message Group {
+  string name = 1;
+  message Member {}
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_ACTIVE = 1;
+  STATUS_BANNED = 2;
+  STATUS_DELETED = 3;
+}
+
+service UserService {
+  rpc GetUser(User) returns (User);
+  rpc DeleteUser(User) returns (User);
+  rpc Watch(User) returns (stream User);
+}
+`
+
+func parseFile(t *testing.T, data string) *File {
+	t.Helper()
+	file, err := ParseFile([]byte(data))
+	require.NoError(t, err)
+	return file
+}
+
+// problems returns the problems as "line:rule" strings.
+func problems(res []Problem) (got []string) {
+	for _, problem := range res {
+		got = append(got, fmt.Sprintf("%d:%s", problem.Position.Line, problem.Rule))
+	}
+	return got
+}
+
+func TestCompare(t *testing.T) {
+	current := `syntax = "proto3";
+package acme.v2;
+
+import "google/protobuf/timestamp.proto";
+
+message User {
+  reserved 8;
+  string name = 1;
+  int64 age = 2;
+  string tags = 3;
+  google.protobuf.Timestamp created = 4;
+  map<string, Group> groups = 5;
+  string email = 6;
+  string phone = 10;
+  message Address {
+    string street = 1;
+  }
+  Address address = 9;
+}
+
+message Group {
+  string name = 1;
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_ACTIVE = 1;
+  STATUS_BANNED = 4;
+}
+
+service UserService {
+  rpc GetUser(Group) returns (User);
+  rpc Watch(User) returns (User);
+}
+`
+	require.Equal(t, []string{
+		"2:FILE_SAME_PACKAGE",
+		"9:FIELD_SAME_TYPE",
+		"10:FIELD_SAME_CARDINALITY",
+		"13:FIELD_SAME_ONEOF",
+		"14:FIELD_SAME_NUMBER",
+		"21:MESSAGE_NO_DELETE",
+		"25:ENUM_VALUE_NO_DELETE_UNLESS_NUMBER_RESERVED",
+		"28:ENUM_VALUE_SAME_NUMBER",
+		"31:RPC_NO_DELETE",
+		"32:RPC_SAME_REQUEST_TYPE",
+		"33:RPC_SAME_SERVER_STREAMING",
+	}, problems(Compare(parseFile(t, baseProto), parseFile(t, current))))
+
+	require.Empty(t, Compare(parseFile(t, baseProto), parseFile(t, baseProto)))
+}
+
+func TestCompareDeletedField(t *testing.T) {
+	base := "syntax = \"proto3\";\nmessage A {\n  string a = 1;\n  string b = 2;\n}\n"
+	require.Equal(t, []string{"2:FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED"},
+		problems(Compare(parseFile(t, base), parseFile(t, "syntax = \"proto3\";\nmessage A {\n  string a = 1;\n}\n"))))
+	require.Empty(t, Compare(parseFile(t, base), parseFile(t, "syntax = \"proto3\";\nmessage A {\n  reserved 2;\n  string a = 1;\n}\n")))
+	require.Empty(t, Compare(parseFile(t, base), parseFile(t, "syntax = \"proto3\";\nmessage A {\n  reserved 2 to max;\n  string a = 1;\n}\n")))
+}
+
+func TestCompareFiles(t *testing.T) {
+	base := map[string]*File{"a.proto": parseFile(t, baseProto), "b.proto": parseFile(t, "syntax = \"proto3\";\n")}
+	current := map[string]*File{"a.proto": parseFile(t, baseProto)}
+	require.Equal(t, []Problem{{Rule: "FILE_NO_DELETE", Path: "b.proto", Message: "file b.proto was deleted"}}, CompareFiles(base, current))
+}
+
+func TestFromDescriptorSet(t *testing.T) {
+	str := func(s string) *string { return &s }
+	num := func(n int32) *int32 { return &n }
+	field := func(name string, number int32, label descriptorpb.FieldDescriptorProto_Label, typ descriptorpb.FieldDescriptorProto_Type, type_name string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{Name: str(name), Number: num(number), Label: label.Enum(), Type: typ.Enum()}
+		if type_name != "" {
+			f.TypeName = str(type_name)
+		}
+		return f
+	}
+	optional, repeated := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	message := descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	stringType := descriptorpb.FieldDescriptorProto_TYPE_STRING
+
+	user := &descriptorpb.DescriptorProto{
+		Name: str("User"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			field("name", 1, optional, stringType, ""),
+			field("age", 2, optional, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+			field("tags", 3, repeated, stringType, ""),
+			field("created", 4, optional, message, ".google.protobuf.Timestamp"),
+			field("groups", 5, repeated, message, ".acme.v1.User.GroupsEntry"),
+			field("email", 6, optional, stringType, ""),
+			field("phone", 7, optional, stringType, ""),
+			field("nickname", 8, optional, stringType, ""),
+			field("address", 9, optional, message, ".acme.v1.User.Address"),
+		},
+		NestedType: []*descriptorpb.DescriptorProto{
+			{
+				Name:    str("GroupsEntry"),
+				Field:   []*descriptorpb.FieldDescriptorProto{field("key", 1, optional, stringType, ""), field("value", 2, optional, message, ".acme.v1.Group")},
+				Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+			},
+			{Name: str("Address"), Field: []*descriptorpb.FieldDescriptorProto{field("street", 1, optional, stringType, "")}},
+		},
+		OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: str("contact")}},
+	}
+	user.Field[5].OneofIndex = num(0)
+	user.Field[6].OneofIndex = num(0)
+
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    str("acme/v1/user.proto"),
+		Package: str("acme.v1"),
+		Syntax:  str("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			user,
+			{
+				Name:       str("Group"),
+				Field:      []*descriptorpb.FieldDescriptorProto{field("name", 1, optional, stringType, "")},
+				NestedType: []*descriptorpb.DescriptorProto{{Name: str("Member")}},
+			},
+		},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: str("Status"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: str("STATUS_UNSPECIFIED"), Number: num(0)},
+				{Name: str("STATUS_ACTIVE"), Number: num(1)},
+				{Name: str("STATUS_BANNED"), Number: num(2)},
+				{Name: str("STATUS_DELETED"), Number: num(3)},
+			},
+		}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: str("UserService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: str("GetUser"), InputType: str(".acme.v1.User"), OutputType: str(".acme.v1.User")},
+				{Name: str("DeleteUser"), InputType: str(".acme.v1.User"), OutputType: str(".acme.v1.User")},
+				{Name: str("Watch"), InputType: str(".acme.v1.User"), OutputType: str(".acme.v1.User"), ServerStreaming: proto.Bool(true)},
+			},
+		}},
+	}}}
+	data, err := proto.Marshal(set)
+	require.NoError(t, err)
+
+	files, err := FromDescriptorSet(data)
+	require.NoError(t, err)
+	require.Contains(t, files, "acme/v1/user.proto")
+	// the descriptors describe the same schema as the source
+	require.Empty(t, Compare(files["acme/v1/user.proto"], parseFile(t, baseProto)))
+	require.Empty(t, Compare(parseFile(t, baseProto), files["acme/v1/user.proto"]))
+}
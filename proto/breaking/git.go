@@ -0,0 +1,647 @@
+package breaking
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/walteh/protobuf-language-server/proto/view/fs"
+)
+
+// Repo reads commits of a git repository straight from its .git directory,
+// the git binary is not needed. Loose objects and pack files are supported,
+// SHA-256 repositories and alternates are not.
+type Repo struct {
+	// Root is the top directory of the work tree, it is slash separated.
+	Root string
+	fsys fs.FS
+	// gitDir holds HEAD and the refs of the work tree, commonDir the
+	// objects and the shared refs, they differ for linked work trees.
+	gitDir, commonDir string
+
+	packsMu sync.Mutex
+	packs   map[string]*pack
+}
+
+var ErrNotRepository = errors.New("not a git repository")
+
+// OpenRepo opens the repository dir is part of, it is read from fsys.
+func OpenRepo(fsys fs.FS, dir string) (*Repo, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	dir = filepath.ToSlash(dir)
+	for d := dir; ; d = path.Dir(d) {
+		dot_git := path.Join(d, ".git")
+		if info, err := fsys.Stat(dot_git); err == nil {
+			git_dir := dot_git
+			if !info.IsDir() {
+				// a linked work tree or submodule points to its git dir
+				data, err := fsys.ReadFile(dot_git)
+				if err != nil {
+					return nil, err
+				}
+				target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+				if !ok {
+					return nil, fmt.Errorf("%w: %s", ErrNotRepository, dot_git)
+				}
+				git_dir = filepath.ToSlash(strings.TrimSpace(target))
+				if !filepath.IsAbs(git_dir) {
+					git_dir = path.Join(d, git_dir)
+				}
+			}
+			common_dir := git_dir
+			if data, err := fsys.ReadFile(path.Join(git_dir, "commondir")); err == nil {
+				common_dir = filepath.ToSlash(strings.TrimSpace(string(data)))
+				if !filepath.IsAbs(common_dir) {
+					common_dir = path.Join(git_dir, common_dir)
+				}
+			}
+			return &Repo{Root: d, fsys: fsys, gitDir: git_dir, commonDir: common_dir, packs: make(map[string]*pack)}, nil
+		}
+		if path.Dir(d) == d {
+			return nil, fmt.Errorf("%w: %s", ErrNotRepository, dir)
+		}
+	}
+}
+
+// Resolve returns the commit hash of rev, which is a hash or an abbreviation
+// of one, HEAD, a branch, tag or remote ref, optionally followed by ~n and ^
+// to select ancestors.
+func (r *Repo) Resolve(rev string) (string, error) {
+	name := rev
+	var ancestors []int
+	for {
+		if idx := strings.LastIndexAny(name, "~^"); idx >= 0 {
+			n := 1
+			if suffix := name[idx+1:]; suffix != "" {
+				var err error
+				if n, err = strconv.Atoi(suffix); err != nil {
+					break
+				}
+			}
+			if name[idx] == '^' && n != 1 {
+				return "", fmt.Errorf("unsupported revision %q, only the first parent can be selected with ^", rev)
+			}
+			ancestors = append(ancestors, n)
+			name = name[:idx]
+			continue
+		}
+		break
+	}
+
+	hash, err := r.resolveName(name)
+	if err != nil {
+		return "", err
+	}
+	if hash, err = r.peel(hash); err != nil {
+		return "", err
+	}
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		for n := 0; n < ancestors[i]; n++ {
+			if hash, err = r.parent(hash); err != nil {
+				return "", fmt.Errorf("resolve %q: %w", rev, err)
+			}
+		}
+	}
+	return hash, nil
+}
+
+func (r *Repo) resolveName(name string) (string, error) {
+	if len(name) == 40 && isHex(name) {
+		return name, nil
+	}
+	candidates := []string{name, "refs/" + name, "refs/tags/" + name, "refs/heads/" + name, "refs/remotes/" + name, "refs/remotes/" + name + "/HEAD"}
+	for _, candidate := range candidates {
+		if hash, ok := r.readRef(candidate, 0); ok {
+			return hash, nil
+		}
+	}
+	if len(name) >= 4 && isHex(name) {
+		return r.expandHash(name)
+	}
+	return "", fmt.Errorf("unknown revision %q", name)
+}
+
+// readRef returns the hash ref points to, following symbolic refs.
+func (r *Repo) readRef(ref string, depth int) (string, bool) {
+	if depth > 5 {
+		return "", false
+	}
+	for _, dir := range []string{r.gitDir, r.commonDir} {
+		data, err := r.fsys.ReadFile(path.Join(dir, ref))
+		if err != nil {
+			continue
+		}
+		content := strings.TrimSpace(string(data))
+		if target, ok := strings.CutPrefix(content, "ref:"); ok {
+			return r.readRef(strings.TrimSpace(target), depth+1)
+		}
+		if len(content) == 40 && isHex(content) {
+			return content, true
+		}
+	}
+	packed_refs, err := r.fsys.ReadFile(path.Join(r.commonDir, "packed-refs"))
+	if err != nil {
+		return "", false
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(packed_refs))
+	for scanner.Scan() {
+		hash, name, ok := strings.Cut(scanner.Text(), " ")
+		if ok && name == ref && len(hash) == 40 {
+			return hash, true
+		}
+	}
+	return "", false
+}
+
+// expandHash returns the object hash starting with prefix.
+func (r *Repo) expandHash(prefix string) (string, error) {
+	var found []string
+	entries, _ := r.fsys.ReadDir(path.Join(r.commonDir, "objects", prefix[:2]))
+	for _, entry := range entries {
+		if strings.HasPrefix(prefix[:2]+entry.Name(), prefix) {
+			found = append(found, prefix[:2]+entry.Name())
+		}
+	}
+	packs, err := r.loadPacks()
+	if err != nil {
+		return "", err
+	}
+	for _, p := range packs {
+		found = append(found, p.withPrefix(prefix)...)
+	}
+	sort.Strings(found)
+	found = slices.Compact(found)
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("unknown revision %q", prefix)
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("revision %q is ambiguous", prefix)
+}
+
+// peel follows annotated tags to the commit they point to.
+func (r *Repo) peel(hash string) (string, error) {
+	for i := 0; i < 10; i++ {
+		typ, data, err := r.object(hash)
+		if err != nil {
+			return "", err
+		}
+		switch typ {
+		case "commit":
+			return hash, nil
+		case "tag":
+			object, ok := header(data, "object")
+			if !ok {
+				return "", fmt.Errorf("tag %s has no object", hash)
+			}
+			hash = object
+		default:
+			return "", fmt.Errorf("%s is a %s, not a commit", hash, typ)
+		}
+	}
+	return "", fmt.Errorf("too many nested tags at %s", hash)
+}
+
+func (r *Repo) parent(commit string) (string, error) {
+	_, data, err := r.object(commit)
+	if err != nil {
+		return "", err
+	}
+	parent, ok := header(data, "parent")
+	if !ok {
+		return "", fmt.Errorf("commit %s has no parent", commit)
+	}
+	return parent, nil
+}
+
+// header returns the value of the first header line key of a commit or tag.
+func header(data []byte, key string) (string, bool) {
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, key+" "); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// tree returns the hash of the tree at dir, a slash separated path relative
+// to the root of the work tree, in commit.
+func (r *Repo) tree(commit, dir string) (string, error) {
+	_, data, err := r.object(commit)
+	if err != nil {
+		return "", err
+	}
+	hash, ok := header(data, "tree")
+	if !ok {
+		return "", fmt.Errorf("commit %s has no tree", commit)
+	}
+	for _, name := range strings.Split(path.Clean(dir), "/") {
+		if name == "." || name == "" {
+			continue
+		}
+		entries, err := r.treeEntries(hash)
+		if err != nil {
+			return "", err
+		}
+		entry, ok := entries[name]
+		if !ok || !entry.dir {
+			return "", fmt.Errorf("%w: %s in %s", os.ErrNotExist, dir, commit)
+		}
+		hash = entry.hash
+	}
+	return hash, nil
+}
+
+type treeEntry struct {
+	hash string
+	dir  bool
+}
+
+func (r *Repo) treeEntries(hash string) (map[string]treeEntry, error) {
+	typ, data, err := r.object(hash)
+	if err != nil {
+		return nil, err
+	}
+	if typ != "tree" {
+		return nil, fmt.Errorf("%s is a %s, not a tree", hash, typ)
+	}
+	entries := make(map[string]treeEntry)
+	for len(data) > 0 {
+		// <mode> <name>\0<20 byte hash>
+		space := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if space < 0 || nul < space || len(data) < nul+21 {
+			return nil, fmt.Errorf("tree %s is corrupt", hash)
+		}
+		mode := string(data[:space])
+		entries[string(data[space+1:nul])] = treeEntry{hex.EncodeToString(data[nul+1 : nul+21]), mode == "40000"}
+		data = data[nul+21:]
+	}
+	return entries, nil
+}
+
+// ReadFile returns the content of the file at name, a slash separated path
+// relative to the root of the work tree, in commit.
+func (r *Repo) ReadFile(commit, name string) ([]byte, error) {
+	dir, base := path.Split(path.Clean(name))
+	tree, err := r.tree(commit, dir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := r.treeEntries(tree)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := entries[base]
+	if !ok || entry.dir {
+		return nil, fmt.Errorf("%w: %s in %s", os.ErrNotExist, name, commit)
+	}
+	_, data, err := r.object(entry.hash)
+	return data, err
+}
+
+// Files returns the files below dir in commit whose name ends with suffix,
+// by their path relative to dir.
+func (r *Repo) Files(commit, dir, suffix string) (map[string][]byte, error) {
+	tree, err := r.tree(commit, dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	var walk func(hash, prefix string) error
+	walk = func(hash, prefix string) error {
+		entries, err := r.treeEntries(hash)
+		if err != nil {
+			return err
+		}
+		for name, entry := range entries {
+			switch {
+			case entry.dir:
+				if err := walk(entry.hash, prefix+name+"/"); err != nil {
+					return err
+				}
+			case strings.HasSuffix(name, suffix):
+				_, data, err := r.object(entry.hash)
+				if err != nil {
+					return err
+				}
+				files[prefix+name] = data
+			}
+		}
+		return nil
+	}
+	return files, walk(tree, "")
+}
+
+// object returns the type and content of the object hash.
+func (r *Repo) object(hash string) (string, []byte, error) {
+	if len(hash) != 40 || !isHex(hash) {
+		return "", nil, fmt.Errorf("invalid object hash %q", hash)
+	}
+	if data, err := r.fsys.ReadFile(path.Join(r.commonDir, "objects", hash[:2], hash[2:])); err == nil {
+		return readLooseObject(bytes.NewReader(data))
+	}
+
+	packs, err := r.loadPacks()
+	if err != nil {
+		return "", nil, err
+	}
+	raw, _ := hex.DecodeString(hash)
+	for _, p := range packs {
+		if offset, ok := p.find(raw); ok {
+			return p.object(r, offset)
+		}
+	}
+	return "", nil, fmt.Errorf("%w: object %s", os.ErrNotExist, hash)
+}
+
+func readLooseObject(f io.Reader) (string, []byte, error) {
+	z, err := zlib.NewReader(f)
+	if err != nil {
+		return "", nil, err
+	}
+	defer z.Close()
+	data, err := io.ReadAll(z)
+	if err != nil {
+		return "", nil, err
+	}
+	// <type> <size>\0<content>
+	nul := bytes.IndexByte(data, 0)
+	typ, _, ok := strings.Cut(string(data[:max(nul, 0)]), " ")
+	if nul < 0 || !ok {
+		return "", nil, errors.New("corrupt loose object")
+	}
+	return typ, data[nul+1:], nil
+}
+
+// loadPacks returns the pack files, the indexes of new ones are read.
+func (r *Repo) loadPacks() ([]*pack, error) {
+	r.packsMu.Lock()
+	defer r.packsMu.Unlock()
+	pack_dir := path.Join(r.commonDir, "objects", "pack")
+	entries, err := r.fsys.ReadDir(pack_dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	var res []*pack
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".idx") {
+			continue
+		}
+		name := path.Join(pack_dir, entry.Name())
+		p, ok := r.packs[name]
+		if !ok {
+			if p, err = openPack(r.fsys, name); err != nil {
+				return nil, err
+			}
+			r.packs[name] = p
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+// pack is a pack file with its version 2 index.
+type pack struct {
+	filename string
+	hashes   []byte
+	offsets  []byte
+	large    []byte
+}
+
+func openPack(fsys fs.FS, idx_name string) (*pack, error) {
+	data, err := fsys.ReadFile(idx_name)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8+256*4 || !bytes.Equal(data[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(data[4:8]) != 2 {
+		return nil, fmt.Errorf("unsupported pack index %s", idx_name)
+	}
+	n := int(binary.BigEndian.Uint32(data[8+255*4:]))
+	hashes := 8 + 256*4
+	offsets := hashes + n*20 + n*4
+	large := offsets + n*4
+	if len(data) < large {
+		return nil, fmt.Errorf("pack index %s is corrupt", idx_name)
+	}
+	return &pack{
+		filename: strings.TrimSuffix(idx_name, ".idx") + ".pack",
+		hashes:   data[hashes : hashes+n*20],
+		offsets:  data[offsets:large],
+		large:    data[large:],
+	}, nil
+}
+
+func (p *pack) count() int {
+	return len(p.hashes) / 20
+}
+
+func (p *pack) find(hash []byte) (int64, bool) {
+	i := sort.Search(p.count(), func(i int) bool {
+		return bytes.Compare(p.hashes[i*20:i*20+20], hash) >= 0
+	})
+	if i == p.count() || !bytes.Equal(p.hashes[i*20:i*20+20], hash) {
+		return 0, false
+	}
+	offset := binary.BigEndian.Uint32(p.offsets[i*4:])
+	if offset&0x80000000 == 0 {
+		return int64(offset), true
+	}
+	idx := int(offset&0x7fffffff) * 8
+	if idx+8 > len(p.large) {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(p.large[idx:])), true
+}
+
+func (p *pack) withPrefix(prefix string) (res []string) {
+	for i := 0; i < p.count(); i++ {
+		if hash := hex.EncodeToString(p.hashes[i*20 : i*20+20]); strings.HasPrefix(hash, prefix) {
+			res = append(res, hash)
+		}
+	}
+	return res
+}
+
+var packTypes = map[byte]string{1: "commit", 2: "tree", 3: "blob", 4: "tag"}
+
+const (
+	packOffsetDelta = 6
+	packRefDelta    = 7
+)
+
+// object reads the object at offset, resolving deltas.
+func (p *pack) object(r *Repo, offset int64) (string, []byte, error) {
+	f, err := fs.Open(r.fsys, p.filename)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	return p.readObject(r, f, offset, 0)
+}
+
+func (p *pack) readObject(r *Repo, f io.ReaderAt, offset int64, depth int) (string, []byte, error) {
+	if depth > 64 {
+		return "", nil, errors.New("delta chain too long")
+	}
+	reader := bufio.NewReader(io.NewSectionReader(f, offset, 1<<62))
+	c, err := reader.ReadByte()
+	if err != nil {
+		return "", nil, err
+	}
+	kind := (c >> 4) & 7
+	size := int64(c & 15)
+	for shift := 4; c&0x80 != 0; shift += 7 {
+		if c, err = reader.ReadByte(); err != nil {
+			return "", nil, err
+		}
+		size |= int64(c&0x7f) << shift
+	}
+
+	var base_type string
+	var base []byte
+	switch kind {
+	case packOffsetDelta:
+		c, err := reader.ReadByte()
+		if err != nil {
+			return "", nil, err
+		}
+		distance := int64(c & 0x7f)
+		for c&0x80 != 0 {
+			if c, err = reader.ReadByte(); err != nil {
+				return "", nil, err
+			}
+			distance = ((distance + 1) << 7) | int64(c&0x7f)
+		}
+		if base_type, base, err = p.readObject(r, f, offset-distance, depth+1); err != nil {
+			return "", nil, err
+		}
+	case packRefDelta:
+		raw := make([]byte, 20)
+		if _, err := io.ReadFull(reader, raw); err != nil {
+			return "", nil, err
+		}
+		if base_type, base, err = r.object(hex.EncodeToString(raw)); err != nil {
+			return "", nil, err
+		}
+	default:
+		if _, ok := packTypes[kind]; !ok {
+			return "", nil, fmt.Errorf("unknown object type %d in %s", kind, p.filename)
+		}
+	}
+
+	z, err := zlib.NewReader(reader)
+	if err != nil {
+		return "", nil, err
+	}
+	defer z.Close()
+	data := make([]byte, size)
+	if _, err := io.ReadFull(z, data); err != nil {
+		return "", nil, err
+	}
+	if base == nil {
+		return packTypes[kind], data, nil
+	}
+	res, err := applyDelta(base, data)
+	return base_type, res, err
+}
+
+// applyDelta builds an object from its base and a git delta.
+func applyDelta(base, delta []byte) ([]byte, error) {
+	errCorrupt := errors.New("corrupt delta")
+	varint := func() (int, bool) {
+		n, shift := 0, 0
+		for len(delta) > 0 {
+			c := delta[0]
+			delta = delta[1:]
+			n |= int(c&0x7f) << shift
+			shift += 7
+			if c&0x80 == 0 {
+				return n, true
+			}
+		}
+		return 0, false
+	}
+	base_size, ok := varint()
+	if !ok || base_size != len(base) {
+		return nil, errCorrupt
+	}
+	size, ok := varint()
+	if !ok {
+		return nil, errCorrupt
+	}
+	res := make([]byte, 0, size)
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		switch {
+		case op&0x80 != 0:
+			// copy from the base, the set bits select the bytes of offset
+			// and size that follow
+			offset, length := 0, 0
+			for i := 0; i < 7; i++ {
+				if op&(1<<i) == 0 {
+					continue
+				}
+				if len(delta) == 0 {
+					return nil, errCorrupt
+				}
+				if i < 4 {
+					offset |= int(delta[0]) << (8 * i)
+				} else {
+					length |= int(delta[0]) << (8 * (i - 4))
+				}
+				delta = delta[1:]
+			}
+			if length == 0 {
+				length = 0x10000
+			}
+			if offset+length > len(base) {
+				return nil, errCorrupt
+			}
+			res = append(res, base[offset:offset+length]...)
+		case op != 0:
+			// insert the next op bytes
+			if int(op) > len(delta) {
+				return nil, errCorrupt
+			}
+			res = append(res, delta[:op]...)
+			delta = delta[op:]
+		default:
+			return nil, errCorrupt
+		}
+	}
+	if len(res) != size {
+		return nil, errCorrupt
+	}
+	return res, nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
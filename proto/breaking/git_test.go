@@ -0,0 +1,115 @@
+package breaking
+
+import (
+	iofs "io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/proto/view/fs"
+)
+
+// gitRepo creates a repository with a commit per content of file under
+// proto/acme.proto, it is skipped if git is not installed.
+func gitRepo(t *testing.T, contents ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q", "-b", "main")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "proto"), 0o755))
+	for _, content := range contents {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "proto", "acme.proto"), []byte(content), 0o644))
+		git("add", "-A")
+		git("commit", "-q", "-m", "update")
+	}
+	git("tag", "-a", "v1", "-m", "v1", "HEAD~1")
+	return dir
+}
+
+func TestRepo(t *testing.T) {
+	v1 := "syntax = \"proto3\";\nmessage A {\n  string a = 1;\n  string b = 2;\n}\n"
+	v2 := "syntax = \"proto3\";\nmessage A {\n  string a = 1;\n}\n"
+	dir := gitRepo(t, v1, v2)
+
+	for _, packed := range []bool{false, true} {
+		if packed {
+			cmd := exec.Command("git", "gc", "-q", "--aggressive")
+			cmd.Dir = dir
+			out, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(out))
+		}
+		// the repository is only read through the file system it is opened
+		// on, a copy in memory works the same
+		memory := fs.NewMapFS(nil)
+		require.NoError(t, filepath.WalkDir(dir, func(name string, entry iofs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			data, err := os.ReadFile(name)
+			memory.Set(filepath.ToSlash(name), data)
+			return err
+		}))
+
+		for _, fsys := range []fs.FS{&fs.RealFS{}, memory} {
+			repo, err := OpenRepo(fsys, filepath.Join(dir, "proto"))
+			require.NoError(t, err)
+
+			head, err := repo.Resolve("HEAD")
+			require.NoError(t, err)
+			main, err := repo.Resolve("main")
+			require.NoError(t, err)
+			require.Equal(t, head, main)
+			short, err := repo.Resolve(head[:7])
+			require.NoError(t, err)
+			require.Equal(t, head, short)
+
+			data, err := repo.ReadFile(head, "proto/acme.proto")
+			require.NoError(t, err)
+			require.Equal(t, v2, string(data))
+
+			for _, rev := range []string{"HEAD~1", "main^", "v1"} {
+				commit, err := repo.Resolve(rev)
+				require.NoError(t, err, rev)
+				data, err := repo.ReadFile(commit, "proto/acme.proto")
+				require.NoError(t, err)
+				require.Equal(t, v1, string(data), rev)
+			}
+
+			_, err = repo.Resolve("HEAD~2")
+			require.Error(t, err)
+			_, err = repo.ReadFile(head, "proto/missing.proto")
+			require.ErrorIs(t, err, os.ErrNotExist)
+		}
+	}
+
+	base, err := LoadGit(filepath.Join(dir, "proto"), "HEAD~1")
+	require.NoError(t, err)
+	current, err := LoadDir(filepath.Join(dir, "proto"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"2:FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED"}, problems(CompareFiles(base, current)))
+}
+
+func TestApplyDelta(t *testing.T) {
+	base := []byte("hello world")
+	// base size 11, result size 16, copy 6 bytes at 0, insert "there", copy 5 at 6
+	delta := []byte{11, 16, 0x90, 6, 5, 't', 'h', 'e', 'r', 'e', 0x91, 6, 5}
+	res, err := applyDelta(base, delta)
+	require.NoError(t, err)
+	require.Equal(t, "hello thereworld", string(res))
+
+	_, err = applyDelta(base, []byte{12, 1, 1, 'x'})
+	require.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package breaking
+
+import (
+	"bytes"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+
+	"github.com/walteh/protobuf-language-server/proto/view/fs"
+)
+
+// ParseFile returns the schema of the proto file data.
+func ParseFile(data []byte) (*File, error) {
+	proto, err := protobuf.NewParser(bytes.NewReader(data)).Parse()
+	if err != nil {
+		return nil, err
+	}
+	return FromProto(proto), nil
+}
+
+// parseFiles returns the schemas of files by name.
+func parseFiles(files map[string][]byte) (map[string]*File, error) {
+	res := make(map[string]*File)
+	for name, data := range files {
+		file, err := ParseFile(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", name, err)
+		}
+		res[name] = file
+	}
+	return res, nil
+}
+
+// LoadDir returns the schemas of the proto files below dir by their slash
+// separated path relative to dir. Hidden directories are skipped.
+func LoadDir(dir string) (map[string]*File, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(name string, entry iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if name != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".proto") {
+			return nil
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseFiles(files)
+}
+
+// LoadGit returns the schemas of the proto files below dir as of the git
+// revision rev, see Repo.Resolve, by their path relative to dir.
+func LoadGit(dir, rev string) (map[string]*File, error) {
+	repo, err := OpenRepo(&fs.RealFS{}, dir)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.Resolve(rev)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(filepath.FromSlash(repo.Root), abs)
+	if err != nil {
+		return nil, err
+	}
+	files, err := repo.Files(commit, filepath.ToSlash(rel), ".proto")
+	if err != nil {
+		return nil, err
+	}
+	return parseFiles(files)
+}
+
+// LoadDescriptorSet returns the schemas of the files of the FileDescriptorSet
+// stored in filename, see FromDescriptorSet.
+func LoadDescriptorSet(filename string) (map[string]*File, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return FromDescriptorSet(data)
+}
@@ -0,0 +1,412 @@
+package breaking
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// File is the schema of a proto file, the declarations breaking changes are
+// checked on. Names of declarations are relative to the package, e.g.
+// Outer.Inner, so that a renamed package is reported once. Positions are only
+// known for files read from source.
+type File struct {
+	Package  string
+	Messages map[string]*Message
+	Enums    map[string]*Enum
+	Services map[string]*Service
+	// Position is the package declaration, or the start of the file if there
+	// is none.
+	Position scanner.Position
+}
+
+type Message struct {
+	Name            string
+	Fields          map[int]*Field
+	ReservedNumbers []Range
+	ReservedNames   []string
+	Position        scanner.Position
+}
+
+type Field struct {
+	Name   string
+	Number int
+	// Type is the name of a scalar type, a type relative to the package if
+	// it is declared in the file, a map<key, value> or a type as written.
+	Type string
+	// Label is repeated, required, optional for explicit presence, or empty.
+	Label string
+	// Oneof is the name of the oneof the field is part of.
+	Oneof    string
+	Position scanner.Position
+	// Anchor is the text in front of the name.
+	Anchor string
+}
+
+type Enum struct {
+	Name string
+	// Values are by number, the first of aliased values is kept.
+	Values          map[int]*EnumValue
+	ReservedNumbers []Range
+	ReservedNames   []string
+	Position        scanner.Position
+}
+
+type EnumValue struct {
+	Name     string
+	Number   int
+	Position scanner.Position
+}
+
+type Service struct {
+	Name     string
+	RPCs     map[string]*RPC
+	Position scanner.Position
+}
+
+type RPC struct {
+	Name                             string
+	RequestType, ResponseType        string
+	ClientStreaming, ServerStreaming bool
+	Position                         scanner.Position
+}
+
+// Range is an inclusive range of numbers.
+type Range struct {
+	From, To int
+}
+
+const (
+	maxFieldNumber = 536870911
+	maxEnumNumber  = 2147483647
+)
+
+func newFile() *File {
+	return &File{
+		Messages: make(map[string]*Message),
+		Enums:    make(map[string]*Enum),
+		Services: make(map[string]*Service),
+		Position: scanner.Position{Line: 1, Column: 1},
+	}
+}
+
+// FromProto returns the schema of a parsed proto file.
+func FromProto(p *protobuf.Proto) *File {
+	f := newFile()
+	for _, e := range p.Elements {
+		if pkg, ok := e.(*protobuf.Package); ok {
+			f.Package, f.Position = pkg.Name, pkg.Position
+		}
+	}
+	declared := make(map[string]bool)
+	declare(p.Elements, "", declared)
+	b := &builder{file: f, declared: declared}
+	b.elements(p.Elements, nil)
+	return f
+}
+
+// declare collects the names of the messages and enums of elements.
+func declare(elements []protobuf.Visitee, prefix string, declared map[string]bool) {
+	for _, e := range elements {
+		switch v := e.(type) {
+		case *protobuf.Message:
+			if !v.IsExtend {
+				declared[prefix+v.Name] = true
+				declare(v.Elements, prefix+v.Name+".", declared)
+			}
+		case *protobuf.Group:
+			declared[prefix+v.Name] = true
+			declare(v.Elements, prefix+v.Name+".", declared)
+		case *protobuf.Enum:
+			declared[prefix+v.Name] = true
+		}
+	}
+}
+
+type builder struct {
+	file     *File
+	declared map[string]bool
+}
+
+func (b *builder) elements(elements []protobuf.Visitee, scope []string) {
+	for _, e := range elements {
+		switch v := e.(type) {
+		case *protobuf.Message:
+			if !v.IsExtend {
+				b.message(v.Name, v.Position, v.Elements, scope)
+			}
+		case *protobuf.Group:
+			b.message(v.Name, v.Position, v.Elements, scope)
+		case *protobuf.Enum:
+			b.enum(v, scope)
+		case *protobuf.Service:
+			b.service(v)
+		}
+	}
+}
+
+func (b *builder) message(name string, position scanner.Position, elements []protobuf.Visitee, scope []string) {
+	scope = append(scope[:len(scope):len(scope)], name)
+	m := &Message{Name: strings.Join(scope, "."), Fields: make(map[int]*Field), Position: position}
+	b.file.Messages[m.Name] = m
+	b.fields(m, elements, scope, "")
+	b.elements(elements, scope)
+}
+
+func (b *builder) fields(m *Message, elements []protobuf.Visitee, scope []string, oneof string) {
+	add := func(f *Field) {
+		f.Oneof = oneof
+		m.Fields[f.Number] = f
+	}
+	for _, e := range elements {
+		switch v := e.(type) {
+		case *protobuf.NormalField:
+			add(&Field{Name: v.Name, Number: v.Sequence, Type: b.resolve(v.Type, scope), Label: label(v.Repeated, v.Required, v.Optional), Position: v.Position, Anchor: v.Type})
+		case *protobuf.MapField:
+			add(&Field{Name: v.Name, Number: v.Sequence, Type: mapType(v.KeyType, b.resolve(v.Type, scope)), Position: v.Position, Anchor: ">"})
+		case *protobuf.OneOfField:
+			add(&Field{Name: v.Name, Number: v.Sequence, Type: b.resolve(v.Type, scope), Position: v.Position, Anchor: v.Type})
+		case *protobuf.Group:
+			add(&Field{Name: strings.ToLower(v.Name), Number: v.Sequence, Type: b.resolve(v.Name, scope), Label: label(v.Repeated, v.Required, v.Optional), Position: v.Position, Anchor: "group"})
+		case *protobuf.Oneof:
+			b.fields(m, v.Elements, scope, v.Name)
+		case *protobuf.Reserved:
+			m.ReservedNumbers = append(m.ReservedNumbers, reservedRanges(v.Ranges, maxFieldNumber)...)
+			m.ReservedNames = append(m.ReservedNames, v.FieldNames...)
+		}
+	}
+}
+
+func label(repeated, required, optional bool) string {
+	switch {
+	case repeated:
+		return "repeated"
+	case required:
+		return "required"
+	case optional:
+		return "optional"
+	}
+	return ""
+}
+
+// reservedRanges returns the reserved ranges, max stands for the largest
+// number.
+func reservedRanges(ranges []protobuf.Range, max int) (res []Range) {
+	for _, r := range ranges {
+		to := r.To
+		if r.Max {
+			to = max
+		}
+		res = append(res, Range{r.From, to})
+	}
+	return res
+}
+
+func (b *builder) enum(enum *protobuf.Enum, scope []string) {
+	e := &Enum{Name: strings.Join(append(scope[:len(scope):len(scope)], enum.Name), "."), Values: make(map[int]*EnumValue), Position: enum.Position}
+	b.file.Enums[e.Name] = e
+	for _, element := range enum.Elements {
+		switch v := element.(type) {
+		case *protobuf.EnumField:
+			if _, ok := e.Values[v.Integer]; !ok {
+				e.Values[v.Integer] = &EnumValue{Name: v.Name, Number: v.Integer, Position: v.Position}
+			}
+		case *protobuf.Reserved:
+			e.ReservedNumbers = append(e.ReservedNumbers, reservedRanges(v.Ranges, maxEnumNumber)...)
+			e.ReservedNames = append(e.ReservedNames, v.FieldNames...)
+		}
+	}
+}
+
+func (b *builder) service(service *protobuf.Service) {
+	s := &Service{Name: service.Name, RPCs: make(map[string]*RPC), Position: service.Position}
+	b.file.Services[s.Name] = s
+	for _, e := range service.Elements {
+		if rpc, ok := e.(*protobuf.RPC); ok {
+			s.RPCs[rpc.Name] = &RPC{
+				Name:            rpc.Name,
+				RequestType:     b.resolve(rpc.RequestType, nil),
+				ResponseType:    b.resolve(rpc.ReturnsType, nil),
+				ClientStreaming: rpc.StreamsRequest,
+				ServerStreaming: rpc.StreamsReturns,
+				Position:        rpc.Position,
+			}
+		}
+	}
+}
+
+// resolve returns typ relative to the package if it names a message or enum
+// of the file seen from scope, and typ as written otherwise.
+func (b *builder) resolve(typ string, scope []string) string {
+	if strings.HasPrefix(typ, ".") {
+		return b.file.relative(typ)
+	}
+	for i := len(scope); i >= 0; i-- {
+		candidate := strings.Join(append(scope[:i:i], typ), ".")
+		if b.declared[candidate] {
+			return candidate
+		}
+	}
+	if relative := b.file.relative("." + typ); b.declared[relative] {
+		return relative
+	}
+	return typ
+}
+
+// relative returns the fully qualified name relative to the package of the
+// file if it is in it, and without the leading dot otherwise.
+func (f *File) relative(full_name string) string {
+	name := strings.TrimPrefix(full_name, ".")
+	if f.Package != "" && strings.HasPrefix(name, f.Package+".") {
+		return strings.TrimPrefix(name, f.Package+".")
+	}
+	return name
+}
+
+func mapType(key, value string) string {
+	return fmt.Sprintf("map<%s, %s>", key, value)
+}
+
+// FromDescriptorSet returns the schemas of the files of a serialized
+// FileDescriptorSet, e.g. written by protoc --descriptor_set_out or buf
+// build, by file name.
+func FromDescriptorSet(data []byte) (map[string]*File, error) {
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	files := make(map[string]*File)
+	for _, fd := range set.GetFile() {
+		files[fd.GetName()] = fromFileDescriptor(fd)
+	}
+	return files, nil
+}
+
+func fromFileDescriptor(fd *descriptorpb.FileDescriptorProto) *File {
+	f := newFile()
+	f.Package = fd.GetPackage()
+	// editions mark presence with features instead of the optional label
+	proto3 := fd.GetSyntax() == "proto3" || fd.GetSyntax() == "editions"
+	for _, md := range fd.GetMessageType() {
+		f.addMessageDescriptor(md, "", proto3)
+	}
+	for _, ed := range fd.GetEnumType() {
+		f.addEnumDescriptor(ed, "")
+	}
+	for _, sd := range fd.GetService() {
+		s := &Service{Name: sd.GetName(), RPCs: make(map[string]*RPC)}
+		for _, md := range sd.GetMethod() {
+			s.RPCs[md.GetName()] = &RPC{
+				Name:            md.GetName(),
+				RequestType:     f.relative(md.GetInputType()),
+				ResponseType:    f.relative(md.GetOutputType()),
+				ClientStreaming: md.GetClientStreaming(),
+				ServerStreaming: md.GetServerStreaming(),
+			}
+		}
+		f.Services[s.Name] = s
+	}
+	return f
+}
+
+func (f *File) addMessageDescriptor(md *descriptorpb.DescriptorProto, prefix string, proto3 bool) {
+	m := &Message{Name: prefix + md.GetName(), Fields: make(map[int]*Field), ReservedNames: md.GetReservedName()}
+	f.Messages[m.Name] = m
+	for _, r := range md.GetReservedRange() {
+		// the end of message ranges is exclusive
+		m.ReservedNumbers = append(m.ReservedNumbers, Range{int(r.GetStart()), int(r.GetEnd()) - 1})
+	}
+
+	// map entries are part of the type of their field, not messages
+	entries := make(map[string]*descriptorpb.DescriptorProto)
+	for _, nested := range md.GetNestedType() {
+		if nested.GetOptions().GetMapEntry() {
+			entries[m.Name+"."+nested.GetName()] = nested
+			continue
+		}
+		f.addMessageDescriptor(nested, m.Name+".", proto3)
+	}
+	for _, ed := range md.GetEnumType() {
+		f.addEnumDescriptor(ed, m.Name+".")
+	}
+
+	for _, fd := range md.GetField() {
+		field := &Field{Name: fd.GetName(), Number: int(fd.GetNumber()), Type: f.fieldType(fd)}
+		if fd.OneofIndex != nil && !fd.GetProto3Optional() {
+			field.Oneof = md.GetOneofDecl()[fd.GetOneofIndex()].GetName()
+		}
+		switch fd.GetLabel() {
+		case descriptorpb.FieldDescriptorProto_LABEL_REPEATED:
+			field.Label = "repeated"
+			if entry, ok := entries[field.Type]; ok && len(entry.GetField()) == 2 {
+				field.Label = ""
+				field.Type = mapType(f.fieldType(entry.GetField()[0]), f.fieldType(entry.GetField()[1]))
+			}
+		case descriptorpb.FieldDescriptorProto_LABEL_REQUIRED:
+			field.Label = "required"
+		default:
+			if field.Oneof == "" && (!proto3 || fd.GetProto3Optional()) {
+				field.Label = "optional"
+			}
+		}
+		m.Fields[field.Number] = field
+	}
+}
+
+func (f *File) addEnumDescriptor(ed *descriptorpb.EnumDescriptorProto, prefix string) {
+	e := &Enum{Name: prefix + ed.GetName(), Values: make(map[int]*EnumValue), ReservedNames: ed.GetReservedName()}
+	f.Enums[e.Name] = e
+	for _, r := range ed.GetReservedRange() {
+		// the end of enum ranges is inclusive
+		e.ReservedNumbers = append(e.ReservedNumbers, Range{int(r.GetStart()), int(r.GetEnd())})
+	}
+	for _, vd := range ed.GetValue() {
+		if _, ok := e.Values[int(vd.GetNumber())]; !ok {
+			e.Values[int(vd.GetNumber())] = &EnumValue{Name: vd.GetName(), Number: int(vd.GetNumber())}
+		}
+	}
+}
+
+// fieldType returns the type of a field as it is written in a proto file.
+func (f *File) fieldType(fd *descriptorpb.FieldDescriptorProto) string {
+	if fd.GetTypeName() != "" {
+		return f.relative(fd.GetTypeName())
+	}
+	return strings.TrimPrefix(strings.ToLower(fd.GetType().String()), "type_")
+}
+
+// sameType reports whether a and b are the same type. Types declared in other
+// files are known as written on one side, so a qualified name matches a less
+// qualified one.
+func sameType(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if key_a, value_a, ok := splitMap(a); ok {
+		key_b, value_b, ok := splitMap(b)
+		return ok && key_a == key_b && sameType(value_a, value_b)
+	}
+	return strings.HasSuffix(a, "."+b) || strings.HasSuffix(b, "."+a)
+}
+
+func splitMap(typ string) (key, value string, ok bool) {
+	if !strings.HasPrefix(typ, "map<") || !strings.HasSuffix(typ, ">") {
+		return "", "", false
+	}
+	key, value, ok = strings.Cut(typ[len("map<"):len(typ)-1], ", ")
+	return key, value, ok
+}
+
+func sortedKeys[K int | string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
@@ -0,0 +1,67 @@
+package format
+
+import (
+	"bytes"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/editorconfig/editorconfig-core-go/v2"
+
+	"github.com/walteh/protobuf-language-server/proto/view/fs"
+)
+
+// EditorConfig applies the indent_style, indent_size and tab_width of the
+// .editorconfig files that match filename to options, they are read from
+// fsys. The project settings win over the ones of the editor.
+func EditorConfig(fsys fs.FS, filename string, options Options) Options {
+	config := &editorconfig.Config{Parser: fsParser{fsys: fsys}}
+	definition, err := config.Load(filename)
+	if err != nil {
+		return options
+	}
+
+	size, err := strconv.Atoi(definition.IndentSize)
+	if err != nil || definition.IndentSize == "tab" {
+		size = definition.TabWidth
+	}
+	style := definition.IndentStyle
+	if style == "" && size > 0 && !strings.Contains(options.Indent, "\t") {
+		style = "space"
+	}
+	switch style {
+	case "tab":
+		options.Indent = "\t"
+	case "space":
+		if size > 0 {
+			options.Indent = strings.Repeat(" ", size)
+		}
+	}
+	return options
+}
+
+// fsParser reads the .editorconfig files from a file system instead of the
+// disk, so that unsaved buffers apply.
+type fsParser struct {
+	fsys fs.FS
+}
+
+func (p fsParser) ParseIni(filename string) (*editorconfig.Editorconfig, error) {
+	ec, warning, err := p.ParseIniGraceful(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ec, warning
+}
+
+func (p fsParser) ParseIniGraceful(filename string) (*editorconfig.Editorconfig, error, error) {
+	data, err := p.fsys.ReadFile(filepath.ToSlash(filename))
+	if err != nil {
+		return nil, nil, err
+	}
+	return editorconfig.ParseGraceful(bytes.NewReader(data))
+}
+
+func (p fsParser) FnmatchCase(pattern string, filename string) (bool, error) {
+	return editorconfig.FnmatchCase(pattern, filename)
+}
@@ -0,0 +1,653 @@
+// Package format prints proto files in a canonical layout.
+//
+// The file is parsed and printed back from its syntax tree. Comments are
+// copied from the source, a blank line between two declarations is kept, and
+// everything else, indentation, spacing and line breaks, is decided by the
+// printer.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/scanner"
+	"unicode/utf8"
+
+	protobuf "github.com/emicklei/proto"
+)
+
+// Options controls the layout of a formatted file.
+type Options struct {
+	// Indent is one level of indentation, two spaces if empty.
+	Indent string
+}
+
+// Source formats the proto file data. It fails if data does not parse, or if
+// the syntax tree cannot represent all of it, e.g. concatenated strings or
+// hexadecimal enum values, so that formatting never loses anything.
+func Source(data []byte, options Options) ([]byte, error) {
+	proto, err := protobuf.NewParser(bytes.NewReader(data)).Parse()
+	if err != nil {
+		return nil, err
+	}
+	indent := options.Indent
+	if indent == "" {
+		indent = "  "
+	}
+	p := &printer{src: string(data), lines: strings.Split(string(data), "\n"), indent: indent}
+	p.elements(proto.Elements)
+	res := []byte(p.out.String())
+	if err := sameTokens(data, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+type printer struct {
+	out strings.Builder
+	// the source, comments and option values are copied from it
+	src    string
+	lines  []string
+	indent string
+	depth  int
+}
+
+// println writes text on a line of its own at the current depth, followed by
+// the inline comment if there is one. The lines a line comment goes on with
+// below are aligned with it, see continuedComment.
+func (p *printer) println(text string, inline *protobuf.Comment) {
+	p.out.WriteString(strings.Repeat(p.indent, p.depth))
+	p.out.WriteString(text)
+	if inline != nil {
+		separator := "\n"
+		if !inline.Cstyle {
+			separator += strings.Repeat(p.indent, p.depth) + strings.Repeat(" ", utf8.RuneCountInString(text)+1)
+		}
+		p.out.WriteString(" ")
+		p.out.WriteString(strings.Join(p.commentLines(inline), separator))
+	}
+	p.out.WriteString("\n")
+}
+
+func (p *printer) elements(elements []protobuf.Visitee) {
+	skip := false
+	for i, element := range elements {
+		if skip {
+			skip = false
+			continue
+		}
+		if i > 0 && p.blankBefore(startLine(element)) {
+			p.out.WriteString("\n")
+		}
+		skip = i+1 < len(elements) && p.continuedComment(element, elements[i+1])
+		p.element(element)
+	}
+}
+
+// continuedComment moves the lines right below the inline line comment of
+// element that start in the same column to it, e.g. the second line of
+//
+//	SPEED = 1;  // Generate complete code for parsing, serialization,
+//	            // etc.
+//
+// The parser makes them the comment of next, or next itself. It reports
+// whether next is a comment that was moved entirely.
+func (p *printer) continuedComment(element, next protobuf.Visitee) bool {
+	inline := inlineComment(element)
+	if inline == nil || inline.Cstyle {
+		return false
+	}
+	c, ok := next.(*protobuf.Comment)
+	if !ok {
+		c = doc(next)
+	}
+	if c == nil || c.Cstyle || c.Position.Line != inline.Position.Line+len(inline.Lines) {
+		return false
+	}
+	n := 0
+	for n < len(c.Lines) && p.commentAt(c.Position.Line+n, inline.Position.Column) {
+		n++
+	}
+	if n == 0 {
+		return false
+	}
+
+	inline.Lines = append(inline.Lines, c.Lines[:n]...)
+	c.Lines = c.Lines[n:]
+	c.Position.Line += n
+	c.Position.Column = 1
+	if c.Position.Line <= len(p.lines) {
+		next := p.lines[c.Position.Line-1]
+		c.Position.Column += len(next) - len(strings.TrimLeft(next, " \t"))
+	}
+	return ok && len(c.Lines) == 0
+}
+
+// commentAt reports whether a line comment starts in column of line and
+// nothing but white space is before it.
+func (p *printer) commentAt(line, column int) bool {
+	if line < 1 || line > len(p.lines) {
+		return false
+	}
+	source := p.lines[line-1]
+	start := byteOffset(source, column-1)
+	return strings.TrimSpace(source[:start]) == "" && strings.HasPrefix(source[start:], "//")
+}
+
+// blankBefore reports whether the source has a blank line right above line.
+func (p *printer) blankBefore(line int) bool {
+	idx := line - 2
+	return idx >= 0 && idx < len(p.lines) && strings.TrimSpace(p.lines[idx]) == ""
+}
+
+// block prints a declaration with a body, an empty body stays on one line.
+func (p *printer) block(header string, elements []protobuf.Visitee) {
+	if len(elements) == 0 {
+		p.println(header+" {}", nil)
+		return
+	}
+	inline, elements := p.headerComment(elements)
+	p.println(header+" {", inline)
+	p.depth++
+	p.elements(elements)
+	p.depth--
+	p.println("}", nil)
+}
+
+// headerComment splits the line comment following the opening brace of a
+// block off the first of its elements. The parser merges it with the comments
+// on the lines below, which belong to the body.
+func (p *printer) headerComment(elements []protobuf.Visitee) (*protobuf.Comment, []protobuf.Visitee) {
+	c, ok := elements[0].(*protobuf.Comment)
+	if !ok {
+		c = doc(elements[0])
+	}
+	if c == nil || c.Position.Line < 1 || c.Position.Line > len(p.lines) {
+		return nil, elements
+	}
+	source := p.lines[c.Position.Line-1]
+	start := byteOffset(source, c.Position.Column-1)
+	if !strings.HasSuffix(strings.TrimSpace(source[:start]), "{") || !strings.HasPrefix(source[start:], "//") {
+		return nil, elements
+	}
+
+	inline := *c
+	inline.Lines = c.Lines[:1]
+	inline.Cstyle = false
+	if len(c.Lines) == 1 && ok {
+		return &inline, elements[1:]
+	}
+	// the comment goes on with the line below
+	c.Lines = c.Lines[1:]
+	c.Position.Line++
+	c.Position.Column = 1
+	if c.Position.Line <= len(p.lines) {
+		next := p.lines[c.Position.Line-1]
+		c.Position.Column += len(next) - len(strings.TrimLeft(next, " \t"))
+	}
+	return &inline, elements
+}
+
+func (p *printer) element(element protobuf.Visitee) {
+	if c := doc(element); c != nil {
+		p.comment(c)
+	}
+
+	switch e := element.(type) {
+	case *protobuf.Comment:
+		p.comment(e)
+	case *protobuf.Syntax:
+		p.println(fmt.Sprintf("syntax = %s;", quote(e.Value)), e.InlineComment)
+	case *protobuf.Edition:
+		p.println(fmt.Sprintf("edition = %s;", quote(e.Value)), e.InlineComment)
+	case *protobuf.Package:
+		p.println(fmt.Sprintf("package %s;", e.Name), e.InlineComment)
+	case *protobuf.Import:
+		kind := ""
+		if e.Kind != "" {
+			kind = e.Kind + " "
+		}
+		p.println(fmt.Sprintf("import %s%s;", kind, quote(e.Filename)), e.InlineComment)
+	case *protobuf.Option:
+		p.println(fmt.Sprintf("option %s = %s;", e.Name, p.optionValue(e)), e.InlineComment)
+	case *protobuf.Message:
+		keyword := "message"
+		if e.IsExtend {
+			keyword = "extend"
+		}
+		p.block(keyword+" "+e.Name, e.Elements)
+	case *protobuf.Enum:
+		p.block("enum "+e.Name, e.Elements)
+	case *protobuf.Service:
+		p.block("service "+e.Name, e.Elements)
+	case *protobuf.Oneof:
+		p.block("oneof "+e.Name, e.Elements)
+	case *protobuf.Group:
+		p.block(fmt.Sprintf("%sgroup %s = %d", label(e.Required, e.Optional, e.Repeated), e.Name, e.Sequence), e.Elements)
+	case *protobuf.NormalField:
+		p.println(fmt.Sprintf("%s%s %s = %s%s;", label(e.Required, e.Optional, e.Repeated), e.Type, e.Name, p.number(e.Position, e.Sequence), p.compactOptions(e.Position, e.Options)), e.InlineComment)
+	case *protobuf.MapField:
+		p.println(fmt.Sprintf("map<%s, %s> %s = %s%s;", e.KeyType, e.Type, e.Name, p.number(e.Position, e.Sequence), p.compactOptions(e.Position, e.Options)), e.InlineComment)
+	case *protobuf.OneOfField:
+		p.println(fmt.Sprintf("%s %s = %s%s;", e.Type, e.Name, p.number(e.Position, e.Sequence), p.compactOptions(e.Position, e.Options)), e.InlineComment)
+	case *protobuf.EnumField:
+		var options []*protobuf.Option
+		for _, each := range e.Elements {
+			if option, ok := each.(*protobuf.Option); ok {
+				options = append(options, option)
+			}
+		}
+		p.println(fmt.Sprintf("%s = %s%s;", e.Name, p.number(e.Position, e.Integer), p.compactOptions(e.Position, options)), e.InlineComment)
+	case *protobuf.RPC:
+		header := fmt.Sprintf("rpc %s(%s) returns (%s)", e.Name, streamType(e.StreamsRequest, e.RequestType), streamType(e.StreamsReturns, e.ReturnsType))
+		if len(e.Elements) == 0 {
+			p.println(header+";", e.InlineComment)
+			return
+		}
+		p.block(header, e.Elements)
+	case *protobuf.Reserved:
+		var values []string
+		for _, r := range e.Ranges {
+			values = append(values, r.SourceRepresentation())
+		}
+		for _, name := range e.FieldNames {
+			values = append(values, quote(name))
+		}
+		p.println(fmt.Sprintf("reserved %s;", strings.Join(values, ", ")), e.InlineComment)
+	case *protobuf.Extensions:
+		var values []string
+		for _, r := range e.Ranges {
+			values = append(values, r.SourceRepresentation())
+		}
+		p.println(fmt.Sprintf("extensions %s;", strings.Join(values, ", ")), e.InlineComment)
+	}
+}
+
+func (p *printer) comment(c *protobuf.Comment) {
+	for _, line := range p.commentLines(c) {
+		p.println(line, nil)
+	}
+}
+
+// commentLines returns the lines of a comment as written in the source. The
+// continuation lines of a block comment keep their indentation relative to
+// its first line, or are kept as they are if it follows code.
+func (p *printer) commentLines(c *protobuf.Comment) []string {
+	first := c.Position.Line - 1
+	last := first + len(c.Lines) - 1
+	if first < 0 || last >= len(p.lines) {
+		return fallbackCommentLines(c)
+	}
+	source := p.lines[first]
+	start := byteOffset(source, c.Position.Column-1)
+	if !strings.HasPrefix(source[start:], "/") {
+		return fallbackCommentLines(c)
+	}
+	margin := source[:start]
+	if strings.TrimSpace(margin) != "" {
+		margin = ""
+	}
+
+	var lines []string
+	for i := first; i <= last; i++ {
+		line := p.lines[i]
+		switch {
+		case i == first:
+			line = line[start:]
+		case c.Cstyle:
+			line = strings.TrimPrefix(line, margin)
+		default:
+			line = strings.TrimLeft(line, " \t")
+		}
+		if i == last && c.Cstyle {
+			end := strings.LastIndex(line, "*/")
+			if end < 0 {
+				return fallbackCommentLines(c)
+			}
+			line = line[:end+2]
+		}
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+	return lines
+}
+
+func fallbackCommentLines(c *protobuf.Comment) []string {
+	if c.Cstyle {
+		return strings.Split("/*"+strings.Join(c.Lines, "\n")+"*/", "\n")
+	}
+	prefix := "//"
+	if c.ExtraSlash {
+		prefix = "///"
+	}
+	lines := make([]string, len(c.Lines))
+	for i, line := range c.Lines {
+		lines[i] = strings.TrimRight(prefix+line, " \t\r")
+	}
+	return lines
+}
+
+// optionValue prints the value of an option statement. Messages are copied
+// from the source, they may contain comments the syntax tree does not keep.
+func (p *printer) optionValue(option *protobuf.Option) string {
+	start := p.skipSpace(p.valueStart(option.Position.Offset))
+	if start < len(p.src) && p.src[start] == '{' {
+		if end := p.matching(start); end > 0 {
+			return p.reindent(p.src[start:end], option.Position.Line)
+		}
+	}
+	return p.literal(&option.Constant, p.depth)
+}
+
+// compactOptions prints the options of a field or enum value declared at pos,
+// e.g. [deprecated = true]. Options spanning several lines are copied from the
+// source.
+func (p *printer) compactOptions(pos scanner.Position, options []*protobuf.Option) string {
+	if len(options) == 0 {
+		return ""
+	}
+	start := p.skipSpace(p.valueStart(pos.Offset))
+	start = p.skipSpace(start + len(p.numberAt(start)))
+	if start < len(p.src) && p.src[start] == '[' {
+		if end := p.matching(start); end > 0 && strings.Contains(p.src[start:end], "\n") {
+			return " " + p.reindent(p.src[start:end], pos.Line)
+		}
+	}
+	values := make([]string, len(options))
+	for i, option := range options {
+		values[i] = fmt.Sprintf("%s = %s", option.Name, p.literal(&option.Constant, -1))
+	}
+	return " [" + strings.Join(values, ", ") + "]"
+}
+
+// literal prints an option value. Messages are printed on several lines
+// indented from depth, or on one line if depth is negative.
+func (p *printer) literal(l *protobuf.Literal, depth int) string {
+	switch {
+	case l.Array != nil:
+		values := make([]string, len(l.Array))
+		for i, each := range l.Array {
+			values[i] = p.literal(each, -1)
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case !l.IsString && l.Source == "":
+		if len(l.OrderedMap) == 0 {
+			return "{}"
+		}
+		values := make([]string, len(l.OrderedMap))
+		for i, each := range l.OrderedMap {
+			separator := " "
+			if each.PrintsColon {
+				separator = ": "
+			}
+			nested := -1
+			if depth >= 0 {
+				nested = depth + 1
+			}
+			values[i] = each.Name + separator + p.literal(each.Literal, nested)
+		}
+		if depth < 0 {
+			return "{" + strings.Join(values, ", ") + "}"
+		}
+		inner := strings.Repeat(p.indent, depth+1)
+		return "{\n" + inner + strings.Join(values, "\n"+inner) + "\n" + strings.Repeat(p.indent, depth) + "}"
+	default:
+		return l.SourceRepresentation()
+	}
+}
+
+// number prints the number assigned by the declaration at pos as written,
+// e.g. in hexadecimal.
+func (p *printer) number(pos scanner.Position, value int) string {
+	source := p.numberAt(p.skipSpace(p.valueStart(pos.Offset)))
+	if source == "" {
+		return strconv.Itoa(value)
+	}
+	return source
+}
+
+// numberAt returns the number starting at offset i of the source.
+func (p *printer) numberAt(i int) string {
+	end := i
+	if end < len(p.src) && p.src[end] == '-' {
+		end++
+	}
+	for end < len(p.src) && isWordByte(p.src[end]) {
+		end++
+	}
+	return p.src[i:end]
+}
+
+// reindent moves the lines after the first of text, source of the statement
+// on line, from the indentation of that line to the current depth.
+func (p *printer) reindent(text string, line int) string {
+	lines := strings.Split(text, "\n")
+	margin := ""
+	if line-1 < len(p.lines) {
+		source := p.lines[line-1]
+		margin = source[:len(source)-len(strings.TrimLeft(source, " \t"))]
+	}
+	indent := strings.Repeat(p.indent, p.depth)
+	for i, l := range lines {
+		l = strings.TrimRight(l, " \t\r")
+		if i > 0 {
+			if strings.HasPrefix(l, margin) {
+				l = l[len(margin):]
+			} else {
+				l = strings.TrimLeft(l, " \t")
+			}
+			if l != "" {
+				l = indent + l
+			}
+		}
+		lines[i] = l
+	}
+	return strings.Join(lines, "\n")
+}
+
+// valueStart returns the offset after the first '=' following offset.
+func (p *printer) valueStart(offset int) int {
+	for i := offset; i < len(p.src); i = p.next(i) {
+		if p.src[i] == '=' {
+			return i + 1
+		}
+	}
+	return len(p.src)
+}
+
+// skipSpace returns the offset of the first token at or after i.
+func (p *printer) skipSpace(i int) int {
+	for i < len(p.src) {
+		switch {
+		case strings.IndexByte(" \t\r\n", p.src[i]) >= 0:
+			i++
+		case strings.HasPrefix(p.src[i:], "//") || strings.HasPrefix(p.src[i:], "/*"):
+			i = p.next(i)
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// matching returns the offset after the bracket that closes the one at open,
+// or -1 if there is none.
+func (p *printer) matching(open int) int {
+	depth := 0
+	for i := open; i < len(p.src); i = p.next(i) {
+		switch p.src[i] {
+		case '{', '[', '(', '<':
+			depth++
+		case '}', ']', ')', '>':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// next returns the offset after the character at i, skipping over the string
+// or comment starting there.
+func (p *printer) next(i int) int {
+	switch {
+	case p.src[i] == '"' || p.src[i] == '\'':
+		for j := i + 1; j < len(p.src); j++ {
+			switch p.src[j] {
+			case '\\':
+				j++
+			case p.src[i], '\n':
+				return j + 1
+			}
+		}
+		return len(p.src)
+	case strings.HasPrefix(p.src[i:], "//"):
+		if end := strings.IndexByte(p.src[i:], '\n'); end >= 0 {
+			return i + end
+		}
+		return len(p.src)
+	case strings.HasPrefix(p.src[i:], "/*"):
+		if end := strings.Index(p.src[i+2:], "*/"); end >= 0 {
+			return i + 2 + end + 2
+		}
+		return len(p.src)
+	}
+	return i + 1
+}
+
+func label(required, optional, repeated bool) string {
+	switch {
+	case required:
+		return "required "
+	case optional:
+		return "optional "
+	case repeated:
+		return "repeated "
+	}
+	return ""
+}
+
+func streamType(stream bool, typ string) string {
+	if stream {
+		return "stream " + typ
+	}
+	return typ
+}
+
+func quote(s string) string {
+	return `"` + s + `"`
+}
+
+// startLine returns the first line of an element including its comment.
+func startLine(element protobuf.Visitee) int {
+	if c := doc(element); c != nil {
+		return c.Position.Line
+	}
+	return position(element).Line
+}
+
+// doc returns the comment right above an element.
+func doc(element protobuf.Visitee) *protobuf.Comment {
+	var c *protobuf.Comment
+	switch e := element.(type) {
+	case *protobuf.Comment:
+		return nil
+	case *protobuf.Reserved:
+		c = e.Comment
+	case *protobuf.Extensions:
+		c = e.Comment
+	case protobuf.Documented:
+		c = e.Doc()
+	}
+	if c == nil || len(c.Lines) == 0 {
+		// all of it is printed after the header of the block, see headerComment
+		return nil
+	}
+	return c
+}
+
+// inlineComment returns the comment printed after an element on its line.
+func inlineComment(element protobuf.Visitee) *protobuf.Comment {
+	switch e := element.(type) {
+	case *protobuf.Syntax:
+		return e.InlineComment
+	case *protobuf.Edition:
+		return e.InlineComment
+	case *protobuf.Package:
+		return e.InlineComment
+	case *protobuf.Import:
+		return e.InlineComment
+	case *protobuf.Option:
+		return e.InlineComment
+	case *protobuf.NormalField:
+		return e.InlineComment
+	case *protobuf.MapField:
+		return e.InlineComment
+	case *protobuf.OneOfField:
+		return e.InlineComment
+	case *protobuf.EnumField:
+		return e.InlineComment
+	case *protobuf.RPC:
+		if len(e.Elements) == 0 {
+			return e.InlineComment
+		}
+	case *protobuf.Reserved:
+		return e.InlineComment
+	case *protobuf.Extensions:
+		return e.InlineComment
+	}
+	return nil
+}
+
+func position(element protobuf.Visitee) scanner.Position {
+	switch e := element.(type) {
+	case *protobuf.Comment:
+		return e.Position
+	case *protobuf.Syntax:
+		return e.Position
+	case *protobuf.Edition:
+		return e.Position
+	case *protobuf.Package:
+		return e.Position
+	case *protobuf.Import:
+		return e.Position
+	case *protobuf.Option:
+		return e.Position
+	case *protobuf.Message:
+		return e.Position
+	case *protobuf.Enum:
+		return e.Position
+	case *protobuf.Service:
+		return e.Position
+	case *protobuf.Oneof:
+		return e.Position
+	case *protobuf.Group:
+		return e.Position
+	case *protobuf.NormalField:
+		return e.Position
+	case *protobuf.MapField:
+		return e.Position
+	case *protobuf.OneOfField:
+		return e.Position
+	case *protobuf.EnumField:
+		return e.Position
+	case *protobuf.RPC:
+		return e.Position
+	case *protobuf.Reserved:
+		return e.Position
+	case *protobuf.Extensions:
+		return e.Position
+	}
+	return scanner.Position{}
+}
+
+// byteOffset converts a column counted in characters to a byte offset in line.
+func byteOffset(line string, column int) int {
+	offset := 0
+	for i := 0; i < column && offset < len(line); i++ {
+		_, size := utf8.DecodeRuneInString(line[offset:])
+		offset += size
+	}
+	return offset
+}
@@ -0,0 +1,245 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/proto/view/fs"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "declarations are indented and spaced",
+			data: `syntax="proto3";
+package   foo.bar ;
+import public 'b.proto';
+message A {
+    int32 a=1 [deprecated=true];
+  map<string,int32> m = 2;
+    oneof choice { string c = 3; int64 d = 4; }
+  message Inner {}
+  enum E { E_UNSPECIFIED = 0; E_ONE = 0x1; }
+  reserved 10 to 20, 30 to max;
+}
+service S {
+rpc Get(A) returns (stream A);
+  rpc Put(A) returns (A) { option idempotency_level = IDEMPOTENT; }
+}
+`,
+			want: `syntax = "proto3";
+package foo.bar;
+import public "b.proto";
+message A {
+  int32 a = 1 [deprecated = true];
+  map<string, int32> m = 2;
+  oneof choice {
+    string c = 3;
+    int64 d = 4;
+  }
+  message Inner {}
+  enum E {
+    E_UNSPECIFIED = 0;
+    E_ONE = 0x1;
+  }
+  reserved 10 to 20, 30 to max;
+}
+service S {
+  rpc Get(A) returns (stream A);
+  rpc Put(A) returns (A) {
+    option idempotency_level = IDEMPOTENT;
+  }
+}
+`,
+		},
+		{
+			name: "comments and single blank lines are kept",
+			data: `// Copyright
+////////////
+
+
+syntax = "proto3";
+
+/**
+ * A is a message.
+ */
+message A {
+        // a is a field
+        string a = 1;   // inline
+
+
+        /* detached
+           comment */
+        string b = 2;
+}
+`,
+			want: `// Copyright
+////////////
+
+syntax = "proto3";
+
+/**
+ * A is a message.
+ */
+message A {
+  // a is a field
+  string a = 1; // inline
+
+  /* detached
+     comment */
+  string b = 2;
+}
+`,
+		},
+		{
+			name: "comments after the brace of a block stay on its line",
+			data: `message A { // header
+    /* block
+       comment */
+    string a = 1;
+}
+enum E { // header
+    // doc
+    E_UNSPECIFIED = 0;
+}
+service S { // header
+
+    // detached
+    rpc Get(A) returns (A);
+}
+message B { // header
+}
+`,
+			want: `message A { // header
+  /* block
+     comment */
+  string a = 1;
+}
+enum E { // header
+  // doc
+  E_UNSPECIFIED = 0;
+}
+service S { // header
+  // detached
+  rpc Get(A) returns (A);
+}
+message B { // header
+}
+`,
+		},
+		{
+			name: "trailing comments going on below stay aligned",
+			data: `syntax = "proto2";
+message FileOptions {
+  // Generated classes can be optimized for speed or code size.
+  enum OptimizeMode {
+    SPEED = 1;         // Generate complete code for parsing, serialization,
+                       // etc.
+    CODE_SIZE = 2;     // Use ReflectionOps to implement these methods.
+    LITE_RUNTIME = 3;  // Generate code using MessageLite and the lite runtime.
+  }
+  optional OptimizeMode optimize_for = 9 [default = SPEED];  // trailing
+                                                             // and more
+  // doc
+  optional string go_package = 11;
+}
+`,
+			want: `syntax = "proto2";
+message FileOptions {
+  // Generated classes can be optimized for speed or code size.
+  enum OptimizeMode {
+    SPEED = 1; // Generate complete code for parsing, serialization,
+               // etc.
+    CODE_SIZE = 2; // Use ReflectionOps to implement these methods.
+    LITE_RUNTIME = 3; // Generate code using MessageLite and the lite runtime.
+  }
+  optional OptimizeMode optimize_for = 9 [default = SPEED]; // trailing
+                                                            // and more
+  // doc
+  optional string go_package = 11;
+}
+`,
+		},
+		{
+			name: "option messages are reindented as written",
+			data: `syntax = "proto3";
+message A {
+    string a = 1 [(validate).cel = {
+        id: "a"
+        // comments survive
+        expression: "this != ''"
+    }];
+}
+    option (foo) = {
+      a: 1
+    };
+`,
+			want: `syntax = "proto3";
+message A {
+  string a = 1 [(validate).cel = {
+      id: "a"
+      // comments survive
+      expression: "this != ''"
+  }];
+}
+option (foo) = {
+  a: 1
+};
+`,
+		},
+		{
+			name:    "indent",
+			options: Options{Indent: "\t"},
+			data:    "message A {\n  string a = 1;\n}\n",
+			want:    "message A {\n\tstring a = 1;\n}\n",
+		},
+		{
+			name:    "syntax errors are not formatted",
+			data:    "message A {\n",
+			wantErr: true,
+		},
+		{
+			name:    "what the syntax tree loses is not formatted",
+			data:    "message A {\n  string a = 1 [default = \"a\" \"b\"];\n}\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Source([]byte(tt.data), tt.options)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(got))
+
+			again, err := Source(got, tt.options)
+			require.NoError(t, err)
+			require.Equal(t, string(got), string(again))
+		})
+	}
+}
+
+func TestEditorConfig(t *testing.T) {
+	fsys := fs.NewMapFS(map[string]string{
+		"/repo/.editorconfig":     "root = true\n\n[*.proto]\nindent_style = space\nindent_size = 4\n",
+		"/repo/api/.editorconfig": "[b.proto]\nindent_style = tab\n",
+	})
+
+	options := EditorConfig(fsys, "/repo/api/a.proto", Options{Indent: "\t"})
+	require.Equal(t, "    ", options.Indent)
+
+	options = EditorConfig(fsys, "/repo/api/b.proto", Options{Indent: "  "})
+	require.Equal(t, "\t", options.Indent)
+
+	options = EditorConfig(fsys, "/repo/a.txt", Options{Indent: "\t"})
+	require.Equal(t, "\t", options.Indent)
+}
@@ -0,0 +1,77 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sameTokens fails if formatting changed more than the layout of data, the
+// identifiers, numbers, strings and comment words of both must be equal and
+// in the same order. Punctuation is ignored, the printer may drop optional
+// separators.
+func sameTokens(before, after []byte) error {
+	want, got := tokens(string(before)), tokens(string(after))
+	for i := 0; i < len(want) || i < len(got); i++ {
+		if i >= len(want) || i >= len(got) || want[i] != got[i] {
+			var missing string
+			if i < len(want) {
+				missing = want[i]
+			}
+			return fmt.Errorf("formatting would change %q, the file is left as is", missing)
+		}
+	}
+	return nil
+}
+
+func tokens(s string) (res []string) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case strings.HasPrefix(s[i:], "//"):
+			end := strings.IndexByte(s[i:], '\n')
+			if end < 0 {
+				end = len(s) - i
+			}
+			res = append(res, strings.Fields(s[i:i+end])...)
+			i += end
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				end = len(s) - i - 2
+			}
+			res = append(res, strings.Fields(s[i:i+2+end])...)
+			i += 2 + end
+		case c == '"' || c == '\'':
+			// the quote may change, the content may not
+			j := i + 1
+			for j < len(s) && s[j] != c && s[j] != '\n' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j > len(s) {
+				j = len(s)
+			}
+			res = append(res, "string:"+s[i+1:j])
+			i = j + 1
+		case isWordByte(c):
+			j := i
+			for j < len(s) && isWordByte(s[j]) {
+				j++
+			}
+			res = append(res, s[i:j])
+			i = j
+		case c == '-' || c == '+':
+			res = append(res, string(c))
+			i++
+		default:
+			i++
+		}
+	}
+	return res
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,30 @@
+package include
+
+import (
+	"bytes"
+	iofs "io/fs"
+	"path"
+
+	"github.com/walteh/protobuf-language-server/proto/view/fs"
+)
+
+// Extract writes the embedded protos below dir of fsys so editors can open
+// them, for example when jumping to the definition of
+// google.protobuf.Timestamp. Files that are already up to date are left
+// untouched, outdated ones, e.g. of an older version, are replaced.
+func Extract(fsys fs.WriteFS, dir string) error {
+	return iofs.WalkDir(FS, ".", func(name string, d iofs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := FS.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		target := path.Join(dir, name)
+		if existing, err := fsys.ReadFile(target); err == nil && bytes.Equal(existing, data) {
+			return nil
+		}
+		return fsys.WriteFile(target, data)
+	})
+}
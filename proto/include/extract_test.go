@@ -0,0 +1,46 @@
+package include
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/proto/view/fs"
+)
+
+func TestExtract(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "google", "protobuf", "empty.proto")
+	require.NoError(t, os.MkdirAll(filepath.Dir(target), 0o755))
+	// a read-only copy of an older version
+	require.NoError(t, os.WriteFile(target, []byte("outdated"), 0o444))
+
+	require.NoError(t, Extract(&fs.RealFS{}, filepath.ToSlash(dir)))
+
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	want, err := FS.ReadFile("google/protobuf/empty.proto")
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(data))
+	info, err := os.Stat(target)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+	entries, err := os.ReadDir(filepath.Dir(target))
+	require.NoError(t, err)
+	for _, entry := range entries {
+		require.NotContains(t, entry.Name(), ".empty.proto.", "temporary files are removed")
+	}
+}
+
+func TestExtractFS(t *testing.T) {
+	fsys := fs.NewMapFS(nil)
+	require.NoError(t, Extract(fsys, "/include"))
+
+	data, err := fsys.ReadFile("/include/google/protobuf/empty.proto")
+	require.NoError(t, err)
+	want, err := FS.ReadFile("google/protobuf/empty.proto")
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(data))
+}
@@ -0,0 +1,11 @@
+// Package include embeds the well-known types and the common googleapis
+// protos, so imports of them resolve without any file on disk.
+package include
+
+import "embed"
+
+// FS holds the embedded protos by their import path, e.g.
+// google/protobuf/timestamp.proto.
+//
+//go:embed google
+var FS embed.FS
@@ -0,0 +1,28 @@
+package include
+
+import (
+	iofs "io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/parser"
+)
+
+func TestEmbeddedProtosParse(t *testing.T) {
+	count := 0
+	err := iofs.WalkDir(FS, ".", func(name string, d iofs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := FS.ReadFile(name)
+		require.NoError(t, err)
+		_, errs := parser.ParseProtoWithErrors(defines.DocumentUri("file:///"+name), data)
+		require.Empty(t, errs, name)
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Greater(t, count, 0)
+}
@@ -0,0 +1,200 @@
+// Package lint checks proto files against style rules.
+//
+// The rules and their IDs are the ones of buf lint, a Config selects them the
+// way the lint section of a buf.yaml does. Rules are registered with Register,
+// the built-in ones cover the MINIMAL, BASIC, STANDARD, COMMENTS and UNARY_RPC
+// categories as far as they can be checked one file at a time.
+package lint
+
+import (
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+)
+
+// Config selects the rules that are checked, it mirrors the lint section of a
+// buf.yaml.
+type Config struct {
+	// Use lists rule IDs and categories, STANDARD if empty.
+	Use []string `yaml:"use" json:"use"`
+	// Except lists rule IDs and categories that are removed from Use.
+	Except []string `yaml:"except" json:"except"`
+	// Ignore lists files and directories that are not checked, see File.Path
+	// for what relative paths are matched against.
+	Ignore []string `yaml:"ignore" json:"ignore"`
+	// IgnoreOnly lists files and directories per rule ID or category that
+	// are not checked by those rules.
+	IgnoreOnly map[string][]string `yaml:"ignore_only" json:"ignore_only"`
+
+	// EnumZeroValueSuffix is the suffix of zero enum values, _UNSPECIFIED if
+	// empty.
+	EnumZeroValueSuffix string `yaml:"enum_zero_value_suffix" json:"enum_zero_value_suffix"`
+	// ServiceSuffix is the suffix of service names, Service if empty.
+	ServiceSuffix                        string `yaml:"service_suffix" json:"service_suffix"`
+	RPCAllowSameRequestResponse          bool   `yaml:"rpc_allow_same_request_response" json:"rpc_allow_same_request_response"`
+	RPCAllowGoogleProtobufEmptyRequests  bool   `yaml:"rpc_allow_google_protobuf_empty_requests" json:"rpc_allow_google_protobuf_empty_requests"`
+	RPCAllowGoogleProtobufEmptyResponses bool   `yaml:"rpc_allow_google_protobuf_empty_responses" json:"rpc_allow_google_protobuf_empty_responses"`
+	// AllowCommentIgnores enables "// buf:lint:ignore RULE_ID" comments in
+	// front of a declaration.
+	AllowCommentIgnores bool `yaml:"-" json:"allow_comment_ignores"`
+}
+
+// File is a parsed file to check.
+type File struct {
+	Proto *protobuf.Proto
+	// Filename is the absolute path of the file.
+	Filename string
+	// Path is the path of the file relative to its import root, e.g.
+	// acme/v1/user.proto, empty if it has none. Relative Ignore paths are
+	// matched against it, absolute ones against Filename.
+	Path string
+}
+
+// Problem is a rule violation.
+type Problem struct {
+	// Rule is the ID of the violated rule.
+	Rule string
+	// Position is where the offending declaration starts.
+	Position scanner.Position
+	// Text is the offending word, it is searched after Anchor starting at
+	// Position. It is empty for problems of the file as a whole.
+	Anchor, Text string
+	Message      string
+	// Comment is the leading comment of the declaration, it may disable the
+	// rule.
+	Comment *protobuf.Comment
+}
+
+// Rule is a lint check.
+type Rule struct {
+	// ID is the name used in Config and ignore comments, e.g.
+	// FIELD_LOWER_SNAKE_CASE.
+	ID string
+	// Categories are the groups the rule is part of, e.g. BASIC.
+	Categories []string
+	// Check reports the problems of file, the Rule of the problems is set by
+	// the caller.
+	Check func(file *File, config *Config, report func(Problem))
+}
+
+var (
+	rulesMu sync.RWMutex
+	rules   []Rule
+)
+
+// Register adds a rule, it replaces a rule with the same ID.
+func Register(rule Rule) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	for i := range rules {
+		if rules[i].ID == rule.ID {
+			rules[i] = rule
+			return
+		}
+	}
+	rules = append(rules, rule)
+}
+
+// Rules returns the registered rules sorted by ID.
+func Rules() []Rule {
+	rulesMu.RLock()
+	res := slices.Clone(rules)
+	rulesMu.RUnlock()
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+// categoryAliases are the names of older buf versions for the categories.
+var categoryAliases = map[string]string{
+	"DEFAULT": "STANDARD",
+}
+
+// Lint checks file with the rules config selects.
+func Lint(file *File, config Config) (problems []Problem) {
+	if file.Proto == nil || ignored(file, config.Ignore) {
+		return nil
+	}
+	for _, rule := range Rules() {
+		if !config.enabled(rule) || ignored(file, config.ignoreOnly(rule)) {
+			continue
+		}
+		rule.Check(file, &config, func(problem Problem) {
+			problem.Rule = rule.ID
+			if config.AllowCommentIgnores && ignoredByComment(problem.Comment, rule.ID) {
+				return
+			}
+			problems = append(problems, problem)
+		})
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Position.Offset < problems[j].Position.Offset
+	})
+	return problems
+}
+
+// enabled reports whether rule is selected by Use and not by Except.
+func (c *Config) enabled(rule Rule) bool {
+	use := c.Use
+	if len(use) == 0 {
+		use = []string{"STANDARD"}
+	}
+	return matchesRule(use, rule) && !matchesRule(c.Except, rule)
+}
+
+func (c *Config) ignoreOnly(rule Rule) (paths []string) {
+	for id, id_paths := range c.IgnoreOnly {
+		if matchesRule([]string{id}, rule) {
+			paths = append(paths, id_paths...)
+		}
+	}
+	return paths
+}
+
+// matchesRule reports whether ids names rule or one of its categories.
+func matchesRule(ids []string, rule Rule) bool {
+	for _, id := range ids {
+		if alias, ok := categoryAliases[id]; ok {
+			id = alias
+		}
+		if id == rule.ID || slices.Contains(rule.Categories, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignored reports whether file is one of paths or inside of one.
+func ignored(file *File, paths []string) bool {
+	for _, p := range paths {
+		name := file.Path
+		if path.IsAbs(p) {
+			name = file.Filename
+		}
+		p = path.Clean(p)
+		if name != "" && (name == p || p == "." || strings.HasPrefix(name, strings.TrimSuffix(p, "/")+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+const ignorePrefix = "buf:lint:ignore"
+
+// ignoredByComment reports whether comment has a "buf:lint:ignore id" line.
+func ignoredByComment(comment *protobuf.Comment, id string) bool {
+	if comment == nil {
+		return false
+	}
+	for _, line := range comment.Lines {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == ignorePrefix && fields[1] == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, data string) *protobuf.Proto {
+	t.Helper()
+	proto, err := protobuf.NewParser(strings.NewReader(data)).Parse()
+	require.NoError(t, err)
+	return proto
+}
+
+// lint returns the problems as "line:rule" strings.
+func lint(t *testing.T, path, data string, config Config) (res []string) {
+	t.Helper()
+	file := &File{Proto: parse(t, data), Filename: "/repo/" + path, Path: path}
+	for _, problem := range Lint(file, config) {
+		res = append(res, fmt.Sprintf("%d:%s", problem.Position.Line, problem.Rule))
+	}
+	return res
+}
+
+func TestLint(t *testing.T) {
+	data := `syntax = "proto3";
+package acme.user;
+
+import public "other.proto";
+
+message user_info {
+  string userName = 1;
+  oneof Choice {
+    string a = 2;
+  }
+}
+
+enum Status {
+  ACTIVE = 0;
+  Status_BANNED = 1;
+}
+
+service Users {
+  rpc getUser(GetUserRequest) returns (user_info);
+  rpc List(user_info) returns (ListResponse);
+}
+`
+	require.Equal(t, []string{
+		"2:PACKAGE_DIRECTORY_MATCH",
+		"2:PACKAGE_VERSION_SUFFIX",
+		"4:IMPORT_NO_PUBLIC",
+		"6:MESSAGE_PASCAL_CASE",
+		"7:FIELD_LOWER_SNAKE_CASE",
+		"8:ONEOF_LOWER_SNAKE_CASE",
+		"14:ENUM_VALUE_PREFIX",
+		"14:ENUM_ZERO_VALUE_SUFFIX",
+		"15:ENUM_VALUE_PREFIX",
+		"15:ENUM_VALUE_UPPER_SNAKE_CASE",
+		"18:SERVICE_SUFFIX",
+		"19:RPC_PASCAL_CASE",
+		"19:RPC_REQUEST_STANDARD_NAME",
+		"19:RPC_RESPONSE_STANDARD_NAME",
+		"20:RPC_REQUEST_RESPONSE_UNIQUE",
+		"20:RPC_REQUEST_STANDARD_NAME",
+	}, lint(t, "acme/api.proto", data, Config{}))
+
+	require.Equal(t, []string{
+		"4:IMPORT_NO_PUBLIC",
+		"6:MESSAGE_PASCAL_CASE",
+		"7:FIELD_LOWER_SNAKE_CASE",
+		"8:ONEOF_LOWER_SNAKE_CASE",
+		"15:ENUM_VALUE_UPPER_SNAKE_CASE",
+		"19:RPC_PASCAL_CASE",
+	}, lint(t, "acme/user/api.proto", data, Config{Use: []string{"BASIC"}}))
+
+	require.Equal(t, []string{
+		"2:PACKAGE_DIRECTORY_MATCH",
+		"6:MESSAGE_PASCAL_CASE",
+	}, lint(t, "acme/api.proto", data, Config{Use: []string{"MINIMAL", "MESSAGE_PASCAL_CASE"}}))
+
+	require.Equal(t, []string{
+		"6:MESSAGE_PASCAL_CASE",
+	}, lint(t, "acme/api.proto", data, Config{
+		Use:        []string{"MINIMAL", "MESSAGE_PASCAL_CASE"},
+		IgnoreOnly: map[string][]string{"MINIMAL": {"acme"}},
+	}))
+
+	require.Empty(t, lint(t, "acme/api.proto", data, Config{Ignore: []string{"acme/api.proto"}}))
+	require.Empty(t, lint(t, "acme/api.proto", data, Config{Ignore: []string{"/repo/acme"}}))
+}
+
+func TestLintConfig(t *testing.T) {
+	data := `syntax = "proto3";
+package acme.v1;
+
+import "google/protobuf/empty.proto";
+
+// buf:lint:ignore ENUM_ZERO_VALUE_SUFFIX
+enum Kind {
+  KIND_NONE = 0;
+}
+
+// Users manages users.
+service UsersAPI {
+  // Get gets a user.
+  rpc Get(google.protobuf.Empty) returns (google.protobuf.Empty);
+  rpc Delete(DeleteRequest) returns (google.protobuf.Empty);
+}
+`
+	require.Equal(t, []string{
+		"8:ENUM_ZERO_VALUE_SUFFIX",
+		"12:SERVICE_SUFFIX",
+		"14:RPC_REQUEST_RESPONSE_UNIQUE",
+		"14:RPC_REQUEST_STANDARD_NAME",
+		"14:RPC_RESPONSE_STANDARD_NAME",
+		"15:RPC_REQUEST_RESPONSE_UNIQUE",
+		"15:RPC_RESPONSE_STANDARD_NAME",
+	}, lint(t, "acme/v1/api.proto", data, Config{}))
+
+	require.Equal(t, []string{
+		"7:COMMENT_ENUM",
+		"8:COMMENT_ENUM_VALUE",
+		"15:COMMENT_RPC",
+	}, lint(t, "acme/v1/api.proto", data, Config{
+		Use:                                  []string{"DEFAULT", "COMMENTS"},
+		EnumZeroValueSuffix:                  "_NONE",
+		ServiceSuffix:                        "API",
+		RPCAllowGoogleProtobufEmptyRequests:  true,
+		RPCAllowGoogleProtobufEmptyResponses: true,
+		AllowCommentIgnores:                  true,
+	}))
+}
+
+func TestRegister(t *testing.T) {
+	Register(Rule{
+		ID:         "TEST_NO_FOO",
+		Categories: []string{"TEST"},
+		Check: func(file *File, config *Config, report func(Problem)) {
+			each(file.Proto.Elements, func(e protobuf.Visitee) {
+				if m, ok := e.(*protobuf.Message); ok && m.Name == "Foo" {
+					report(Problem{Position: m.Position, Text: m.Name, Message: "no Foo"})
+				}
+			})
+		},
+	})
+	require.Equal(t, []string{"2:TEST_NO_FOO"}, lint(t, "a.proto", "syntax = \"proto3\";\nmessage Foo {}\n", Config{Use: []string{"TEST"}}))
+}
+
+func TestCase(t *testing.T) {
+	tests := []struct {
+		in, pascal, lowerSnake, upperSnake string
+	}{
+		{"FooBar", "FooBar", "foo_bar", "FOO_BAR"},
+		{"foo_bar", "FooBar", "foo_bar", "FOO_BAR"},
+		{"fooBar2", "FooBar2", "foo_bar2", "FOO_BAR2"},
+		{"HTTPServer", "HTTPServer", "http_server", "HTTP_SERVER"},
+		{"FOO__BAR", "FOOBAR", "foo_bar", "FOO_BAR"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.pascal, toPascalCase(tt.in), tt.in)
+		require.Equal(t, tt.lowerSnake, toLowerSnakeCase(tt.in), tt.in)
+		require.Equal(t, tt.upperSnake, toUpperSnakeCase(tt.in), tt.in)
+	}
+}
@@ -0,0 +1,612 @@
+package lint
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/scanner"
+	"unicode"
+
+	protobuf "github.com/emicklei/proto"
+
+	"github.com/walteh/protobuf-language-server/proto/parser"
+)
+
+var (
+	minimal  = []string{"MINIMAL", "BASIC", "STANDARD"}
+	basic    = []string{"BASIC", "STANDARD"}
+	standard = []string{"STANDARD"}
+	comments = []string{"COMMENTS"}
+	unaryRPC = []string{"UNARY_RPC"}
+)
+
+const googleProtobufEmpty = "google.protobuf.Empty"
+
+func init() {
+	for _, rule := range []Rule{
+		{ID: "PACKAGE_DEFINED", Categories: minimal, Check: checkPackageDefined},
+		{ID: "PACKAGE_DIRECTORY_MATCH", Categories: minimal, Check: checkPackageDirectoryMatch},
+
+		{ID: "ENUM_FIRST_VALUE_ZERO", Categories: basic, Check: checkEnumFirstValueZero},
+		{ID: "ENUM_NO_ALLOW_ALIAS", Categories: basic, Check: checkEnumNoAllowAlias},
+		{ID: "ENUM_PASCAL_CASE", Categories: basic, Check: checkEnumPascalCase},
+		{ID: "ENUM_VALUE_UPPER_SNAKE_CASE", Categories: basic, Check: checkEnumValueUpperSnakeCase},
+		{ID: "FIELD_LOWER_SNAKE_CASE", Categories: basic, Check: checkFieldLowerSnakeCase},
+		{ID: "FIELD_NOT_REQUIRED", Categories: basic, Check: checkFieldNotRequired},
+		{ID: "IMPORT_NO_PUBLIC", Categories: basic, Check: checkImportKind("public")},
+		{ID: "IMPORT_NO_WEAK", Categories: basic, Check: checkImportKind("weak")},
+		{ID: "MESSAGE_PASCAL_CASE", Categories: basic, Check: checkMessagePascalCase},
+		{ID: "ONEOF_LOWER_SNAKE_CASE", Categories: basic, Check: checkOneofLowerSnakeCase},
+		{ID: "PACKAGE_LOWER_SNAKE_CASE", Categories: basic, Check: checkPackageLowerSnakeCase},
+		{ID: "RPC_PASCAL_CASE", Categories: basic, Check: checkRPCPascalCase},
+		{ID: "SERVICE_PASCAL_CASE", Categories: basic, Check: checkServicePascalCase},
+		{ID: "SYNTAX_SPECIFIED", Categories: basic, Check: checkSyntaxSpecified},
+
+		{ID: "ENUM_VALUE_PREFIX", Categories: standard, Check: checkEnumValuePrefix},
+		{ID: "ENUM_ZERO_VALUE_SUFFIX", Categories: standard, Check: checkEnumZeroValueSuffix},
+		{ID: "FILE_LOWER_SNAKE_CASE", Categories: standard, Check: checkFileLowerSnakeCase},
+		{ID: "PACKAGE_VERSION_SUFFIX", Categories: standard, Check: checkPackageVersionSuffix},
+		{ID: "RPC_REQUEST_RESPONSE_UNIQUE", Categories: standard, Check: checkRPCRequestResponseUnique},
+		{ID: "RPC_REQUEST_STANDARD_NAME", Categories: standard, Check: checkRPCStandardName("request", "Request")},
+		{ID: "RPC_RESPONSE_STANDARD_NAME", Categories: standard, Check: checkRPCStandardName("response", "Response")},
+		{ID: "SERVICE_SUFFIX", Categories: standard, Check: checkServiceSuffix},
+
+		{ID: "COMMENT_ENUM", Categories: comments, Check: checkComment("enum")},
+		{ID: "COMMENT_ENUM_VALUE", Categories: comments, Check: checkComment("enum value")},
+		{ID: "COMMENT_FIELD", Categories: comments, Check: checkComment("field")},
+		{ID: "COMMENT_MESSAGE", Categories: comments, Check: checkComment("message")},
+		{ID: "COMMENT_ONEOF", Categories: comments, Check: checkComment("oneof")},
+		{ID: "COMMENT_RPC", Categories: comments, Check: checkComment("rpc")},
+		{ID: "COMMENT_SERVICE", Categories: comments, Check: checkComment("service")},
+
+		{ID: "RPC_NO_CLIENT_STREAMING", Categories: unaryRPC, Check: checkRPCNoStreaming("client", "(")},
+		{ID: "RPC_NO_SERVER_STREAMING", Categories: unaryRPC, Check: checkRPCNoStreaming("server", "returns")},
+	} {
+		Register(rule)
+	}
+}
+
+// each calls fn for every declaration of elements, nested ones included.
+func each(elements []protobuf.Visitee, fn func(protobuf.Visitee)) {
+	for _, e := range elements {
+		fn(e)
+		switch v := e.(type) {
+		case *protobuf.Message:
+			each(v.Elements, fn)
+		case *protobuf.Group:
+			each(v.Elements, fn)
+		case *protobuf.Oneof:
+			each(v.Elements, fn)
+		case *protobuf.Enum:
+			each(v.Elements, fn)
+		case *protobuf.Service:
+			each(v.Elements, fn)
+		}
+	}
+}
+
+func packageOf(file *File) *protobuf.Package {
+	for _, e := range file.Proto.Elements {
+		if p, ok := e.(*protobuf.Package); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// fileProblem reports a problem of the file as a whole, it is shown at the
+// syntax declaration and ignored by its comment.
+func fileProblem(file *File, message string) Problem {
+	problem := Problem{Position: scanner.Position{Line: 1, Column: 1}, Message: message}
+	for _, e := range file.Proto.Elements {
+		switch v := e.(type) {
+		case *protobuf.Syntax:
+			problem.Position, problem.Comment = v.Position, v.Comment
+		case *protobuf.Edition:
+			problem.Position, problem.Comment = v.Position, v.Comment
+		}
+	}
+	return problem
+}
+
+func checkPackageDefined(file *File, config *Config, report func(Problem)) {
+	if packageOf(file) == nil {
+		report(fileProblem(file, "files must have a package declaration"))
+	}
+}
+
+func checkPackageDirectoryMatch(file *File, config *Config, report func(Problem)) {
+	pkg := packageOf(file)
+	if pkg == nil || file.Path == "" {
+		return
+	}
+	want := strings.ReplaceAll(pkg.Name, ".", "/")
+	if dir := path.Dir(file.Path); dir != want {
+		report(Problem{
+			Position: pkg.Position, Anchor: "package", Text: pkg.Name, Comment: pkg.Comment,
+			Message: fmt.Sprintf("files with package %q must be within a directory %q relative to the root, not %q", pkg.Name, want, dir),
+		})
+	}
+}
+
+func checkPackageLowerSnakeCase(file *File, config *Config, report func(Problem)) {
+	pkg := packageOf(file)
+	if pkg == nil {
+		return
+	}
+	components := strings.Split(pkg.Name, ".")
+	for i, component := range components {
+		components[i] = toLowerSnakeCase(component)
+	}
+	if want := strings.Join(components, "."); pkg.Name != want {
+		report(Problem{
+			Position: pkg.Position, Anchor: "package", Text: pkg.Name, Comment: pkg.Comment,
+			Message: fmt.Sprintf("package name %q should be lower_snake.case, such as %q", pkg.Name, want),
+		})
+	}
+}
+
+// versionSuffix matches the last package component of a versioned package,
+// e.g. v1, v1beta2 or v2p1alpha1.
+var versionSuffix = regexp.MustCompile(`^v\d+((alpha|beta)\d*)?(p\d+((alpha|beta)\d*)?)?(test.*)?$`)
+
+func checkPackageVersionSuffix(file *File, config *Config, report func(Problem)) {
+	pkg := packageOf(file)
+	if pkg == nil {
+		return
+	}
+	if !versionSuffix.MatchString(pkg.Name[strings.LastIndex(pkg.Name, ".")+1:]) {
+		report(Problem{
+			Position: pkg.Position, Anchor: "package", Text: pkg.Name, Comment: pkg.Comment,
+			Message: fmt.Sprintf("package name %q should be suffixed with a correctly formed version, such as %q", pkg.Name, pkg.Name+".v1"),
+		})
+	}
+}
+
+func checkSyntaxSpecified(file *File, config *Config, report func(Problem)) {
+	for _, e := range file.Proto.Elements {
+		switch e.(type) {
+		case *protobuf.Syntax, *protobuf.Edition:
+			return
+		}
+	}
+	report(fileProblem(file, `files must have a syntax explicitly specified, such as syntax = "proto3";`))
+}
+
+func checkFileLowerSnakeCase(file *File, config *Config, report func(Problem)) {
+	base := strings.TrimSuffix(path.Base(file.Filename), ".proto")
+	if want := toLowerSnakeCase(base); base != want {
+		report(fileProblem(file, fmt.Sprintf("file name should be lower_snake_case.proto, such as %q", want+".proto")))
+	}
+}
+
+func checkImportKind(kind string) func(file *File, config *Config, report func(Problem)) {
+	return func(file *File, config *Config, report func(Problem)) {
+		for _, e := range file.Proto.Elements {
+			if i, ok := e.(*protobuf.Import); ok && i.Kind == kind {
+				report(Problem{
+					Position: i.Position, Anchor: "import", Text: kind, Comment: i.Comment,
+					Message: fmt.Sprintf("import %q must not be %s", i.Filename, kind),
+				})
+			}
+		}
+	}
+}
+
+func checkMessagePascalCase(file *File, config *Config, report func(Problem)) {
+	each(file.Proto.Elements, func(e protobuf.Visitee) {
+		if m, ok := e.(*protobuf.Message); ok && !m.IsExtend {
+			if want := toPascalCase(m.Name); m.Name != want {
+				report(Problem{
+					Position: m.Position, Anchor: "message", Text: m.Name, Comment: m.Comment,
+					Message: fmt.Sprintf("message name %q should be PascalCase, such as %q", m.Name, want),
+				})
+			}
+		}
+	})
+}
+
+// fields calls fn for every field declared in elements, with the anchor its
+// name follows.
+func fields(elements []protobuf.Visitee, fn func(field *protobuf.Field, anchor string)) {
+	each(elements, func(e protobuf.Visitee) {
+		switch v := e.(type) {
+		case *protobuf.NormalField:
+			fn(v.Field, v.Type)
+		case *protobuf.MapField:
+			fn(v.Field, ">")
+		case *protobuf.OneOfField:
+			fn(v.Field, v.Type)
+		}
+	})
+}
+
+func checkFieldLowerSnakeCase(file *File, config *Config, report func(Problem)) {
+	fields(file.Proto.Elements, func(field *protobuf.Field, anchor string) {
+		if want := toLowerSnakeCase(field.Name); field.Name != want {
+			report(Problem{
+				Position: field.Position, Anchor: anchor, Text: field.Name, Comment: field.Comment,
+				Message: fmt.Sprintf("field name %q should be lower_snake_case, such as %q", field.Name, want),
+			})
+		}
+	})
+}
+
+func checkFieldNotRequired(file *File, config *Config, report func(Problem)) {
+	each(file.Proto.Elements, func(e protobuf.Visitee) {
+		if f, ok := e.(*protobuf.NormalField); ok && f.Required {
+			report(Problem{
+				Position: f.Position, Text: "required", Comment: f.Comment,
+				Message: fmt.Sprintf("field %q must not be required", f.Name),
+			})
+		}
+	})
+}
+
+func checkOneofLowerSnakeCase(file *File, config *Config, report func(Problem)) {
+	each(file.Proto.Elements, func(e protobuf.Visitee) {
+		if o, ok := e.(*protobuf.Oneof); ok {
+			if want := toLowerSnakeCase(o.Name); o.Name != want {
+				report(Problem{
+					Position: o.Position, Anchor: "oneof", Text: o.Name, Comment: o.Comment,
+					Message: fmt.Sprintf("oneof name %q should be lower_snake_case, such as %q", o.Name, want),
+				})
+			}
+		}
+	})
+}
+
+// enums calls fn for every enum declared in file with its values.
+func enums(file *File, fn func(enum *protobuf.Enum, values []*protobuf.EnumField)) {
+	each(file.Proto.Elements, func(e protobuf.Visitee) {
+		enum, ok := e.(*protobuf.Enum)
+		if !ok {
+			return
+		}
+		var values []*protobuf.EnumField
+		for _, e := range enum.Elements {
+			if v, ok := e.(*protobuf.EnumField); ok {
+				values = append(values, v)
+			}
+		}
+		fn(enum, values)
+	})
+}
+
+func checkEnumPascalCase(file *File, config *Config, report func(Problem)) {
+	enums(file, func(enum *protobuf.Enum, values []*protobuf.EnumField) {
+		if want := toPascalCase(enum.Name); enum.Name != want {
+			report(Problem{
+				Position: enum.Position, Anchor: "enum", Text: enum.Name, Comment: enum.Comment,
+				Message: fmt.Sprintf("enum name %q should be PascalCase, such as %q", enum.Name, want),
+			})
+		}
+	})
+}
+
+func checkEnumFirstValueZero(file *File, config *Config, report func(Problem)) {
+	features := parser.ResolveFeatures(file.Proto)
+	enums(file, func(enum *protobuf.Enum, values []*protobuf.EnumField) {
+		if features[enum].Values["enum_type"] == "OPEN" {
+			// an error for open enums, e.g. in proto3, it is reported as such
+			return
+		}
+		if len(values) > 0 && values[0].Integer != 0 {
+			report(Problem{
+				Position: values[0].Position, Anchor: "=", Text: strconv.Itoa(values[0].Integer), Comment: values[0].Comment,
+				Message: fmt.Sprintf("first value of enum %q should be zero", enum.Name),
+			})
+		}
+	})
+}
+
+func checkEnumNoAllowAlias(file *File, config *Config, report func(Problem)) {
+	each(file.Proto.Elements, func(e protobuf.Visitee) {
+		if o, ok := e.(*protobuf.Option); ok && o.Name == "allow_alias" && o.Constant.Source == "true" {
+			report(Problem{
+				Position: o.Position, Anchor: "option", Text: "allow_alias", Comment: o.Comment,
+				Message: "enums must not allow aliases",
+			})
+		}
+	})
+}
+
+func checkEnumValueUpperSnakeCase(file *File, config *Config, report func(Problem)) {
+	enums(file, func(enum *protobuf.Enum, values []*protobuf.EnumField) {
+		for _, v := range values {
+			if want := toUpperSnakeCase(v.Name); v.Name != want {
+				report(Problem{
+					Position: v.Position, Text: v.Name, Comment: v.Comment,
+					Message: fmt.Sprintf("enum value name %q should be UPPER_SNAKE_CASE, such as %q", v.Name, want),
+				})
+			}
+		}
+	})
+}
+
+func checkEnumValuePrefix(file *File, config *Config, report func(Problem)) {
+	enums(file, func(enum *protobuf.Enum, values []*protobuf.EnumField) {
+		prefix := toUpperSnakeCase(enum.Name) + "_"
+		for _, v := range values {
+			if !strings.HasPrefix(v.Name, prefix) {
+				report(Problem{
+					Position: v.Position, Text: v.Name, Comment: v.Comment,
+					Message: fmt.Sprintf("enum value name %q should be prefixed with %q", v.Name, prefix),
+				})
+			}
+		}
+	})
+}
+
+func checkEnumZeroValueSuffix(file *File, config *Config, report func(Problem)) {
+	suffix := config.EnumZeroValueSuffix
+	if suffix == "" {
+		suffix = "_UNSPECIFIED"
+	}
+	enums(file, func(enum *protobuf.Enum, values []*protobuf.EnumField) {
+		for _, v := range values {
+			if v.Integer == 0 && !strings.HasSuffix(v.Name, suffix) {
+				report(Problem{
+					Position: v.Position, Text: v.Name, Comment: v.Comment,
+					Message: fmt.Sprintf("enum zero value name %q should be suffixed with %q", v.Name, suffix),
+				})
+			}
+		}
+	})
+}
+
+// services calls fn for every service of file with its rpcs.
+func services(file *File, fn func(service *protobuf.Service, rpcs []*protobuf.RPC)) {
+	for _, e := range file.Proto.Elements {
+		service, ok := e.(*protobuf.Service)
+		if !ok {
+			continue
+		}
+		var rpcs []*protobuf.RPC
+		for _, e := range service.Elements {
+			if rpc, ok := e.(*protobuf.RPC); ok {
+				rpcs = append(rpcs, rpc)
+			}
+		}
+		fn(service, rpcs)
+	}
+}
+
+func checkServicePascalCase(file *File, config *Config, report func(Problem)) {
+	services(file, func(service *protobuf.Service, rpcs []*protobuf.RPC) {
+		if want := toPascalCase(service.Name); service.Name != want {
+			report(Problem{
+				Position: service.Position, Anchor: "service", Text: service.Name, Comment: service.Comment,
+				Message: fmt.Sprintf("service name %q should be PascalCase, such as %q", service.Name, want),
+			})
+		}
+	})
+}
+
+func serviceSuffix(config *Config) string {
+	if config.ServiceSuffix == "" {
+		return "Service"
+	}
+	return config.ServiceSuffix
+}
+
+func checkServiceSuffix(file *File, config *Config, report func(Problem)) {
+	suffix := serviceSuffix(config)
+	services(file, func(service *protobuf.Service, rpcs []*protobuf.RPC) {
+		if !strings.HasSuffix(service.Name, suffix) {
+			report(Problem{
+				Position: service.Position, Anchor: "service", Text: service.Name, Comment: service.Comment,
+				Message: fmt.Sprintf("service name %q should be suffixed with %q", service.Name, suffix),
+			})
+		}
+	})
+}
+
+func checkRPCPascalCase(file *File, config *Config, report func(Problem)) {
+	services(file, func(service *protobuf.Service, rpcs []*protobuf.RPC) {
+		for _, rpc := range rpcs {
+			if want := toPascalCase(rpc.Name); rpc.Name != want {
+				report(Problem{
+					Position: rpc.Position, Anchor: "rpc", Text: rpc.Name, Comment: rpc.Comment,
+					Message: fmt.Sprintf("rpc name %q should be PascalCase, such as %q", rpc.Name, want),
+				})
+			}
+		}
+	})
+}
+
+// isEmpty reports whether typ refers to google.protobuf.Empty.
+func isEmpty(typ string) bool {
+	return strings.TrimPrefix(typ, ".") == googleProtobufEmpty
+}
+
+// checkRPCStandardName checks that the request or response types are named
+// after the rpc, e.g. GetUserRequest for rpc GetUser.
+func checkRPCStandardName(kind, suffix string) func(file *File, config *Config, report func(Problem)) {
+	return func(file *File, config *Config, report func(Problem)) {
+		allow_empty := config.RPCAllowGoogleProtobufEmptyRequests
+		if kind == "response" {
+			allow_empty = config.RPCAllowGoogleProtobufEmptyResponses
+		}
+		services(file, func(service *protobuf.Service, rpcs []*protobuf.RPC) {
+			trimmed := strings.TrimSuffix(service.Name, serviceSuffix(config))
+			for _, rpc := range rpcs {
+				typ, anchor := rpc.RequestType, "("
+				if kind == "response" {
+					typ, anchor = rpc.ReturnsType, "returns"
+				}
+				if allow_empty && isEmpty(typ) {
+					continue
+				}
+				name := typ[strings.LastIndex(typ, ".")+1:]
+				if name != rpc.Name+suffix && name != service.Name+rpc.Name+suffix && name != trimmed+rpc.Name+suffix {
+					report(Problem{
+						Position: rpc.Position, Anchor: anchor, Text: typ, Comment: rpc.Comment,
+						Message: fmt.Sprintf("rpc %s %q should be named %q or %q", kind, typ, rpc.Name+suffix, service.Name+rpc.Name+suffix),
+					})
+				}
+			}
+		})
+	}
+}
+
+func checkRPCRequestResponseUnique(file *File, config *Config, report func(Problem)) {
+	used := make(map[string]string)
+	use := func(rpc *protobuf.RPC, typ, anchor string) {
+		name := strings.TrimPrefix(typ, ".")
+		if other, ok := used[name]; ok {
+			report(Problem{
+				Position: rpc.Position, Anchor: anchor, Text: typ, Comment: rpc.Comment,
+				Message: fmt.Sprintf("%q is used as request or response type by both rpc %s and rpc %s", typ, other, rpc.Name),
+			})
+			return
+		}
+		used[name] = rpc.Name
+	}
+	services(file, func(service *protobuf.Service, rpcs []*protobuf.RPC) {
+		for _, rpc := range rpcs {
+			if !(config.RPCAllowGoogleProtobufEmptyRequests && isEmpty(rpc.RequestType)) {
+				use(rpc, rpc.RequestType, "(")
+			}
+			if config.RPCAllowSameRequestResponse && rpc.RequestType == rpc.ReturnsType {
+				continue
+			}
+			if !(config.RPCAllowGoogleProtobufEmptyResponses && isEmpty(rpc.ReturnsType)) {
+				use(rpc, rpc.ReturnsType, "returns")
+			}
+		}
+	})
+}
+
+func checkRPCNoStreaming(side, anchor string) func(file *File, config *Config, report func(Problem)) {
+	return func(file *File, config *Config, report func(Problem)) {
+		services(file, func(service *protobuf.Service, rpcs []*protobuf.RPC) {
+			for _, rpc := range rpcs {
+				if (side == "client" && rpc.StreamsRequest) || (side == "server" && rpc.StreamsReturns) {
+					report(Problem{
+						Position: rpc.Position, Anchor: anchor, Text: "stream", Comment: rpc.Comment,
+						Message: fmt.Sprintf("rpc %s must not use %s streaming", rpc.Name, side),
+					})
+				}
+			}
+		})
+	}
+}
+
+// checkComment reports declarations of kind without a leading comment.
+func checkComment(kind string) func(file *File, config *Config, report func(Problem)) {
+	return func(file *File, config *Config, report func(Problem)) {
+		check := func(position scanner.Position, anchor, name string, comment *protobuf.Comment) {
+			if !hasComment(comment) {
+				report(Problem{
+					Position: position, Anchor: anchor, Text: name, Comment: comment,
+					Message: fmt.Sprintf("%s %q should have a non-empty comment for documentation", kind, name),
+				})
+			}
+		}
+		if kind == "field" {
+			fields(file.Proto.Elements, func(field *protobuf.Field, anchor string) {
+				check(field.Position, anchor, field.Name, field.Comment)
+			})
+			return
+		}
+		each(file.Proto.Elements, func(e protobuf.Visitee) {
+			switch v := e.(type) {
+			case *protobuf.Message:
+				if kind == "message" && !v.IsExtend {
+					check(v.Position, "message", v.Name, v.Comment)
+				}
+			case *protobuf.Enum:
+				if kind == "enum" {
+					check(v.Position, "enum", v.Name, v.Comment)
+				}
+			case *protobuf.EnumField:
+				if kind == "enum value" {
+					check(v.Position, "", v.Name, v.Comment)
+				}
+			case *protobuf.Oneof:
+				if kind == "oneof" {
+					check(v.Position, "oneof", v.Name, v.Comment)
+				}
+			case *protobuf.Service:
+				if kind == "service" {
+					check(v.Position, "service", v.Name, v.Comment)
+				}
+			case *protobuf.RPC:
+				if kind == "rpc" {
+					check(v.Position, "rpc", v.Name, v.Comment)
+				}
+			}
+		})
+	}
+}
+
+// hasComment reports whether comment has text besides ignore directives.
+func hasComment(comment *protobuf.Comment) bool {
+	if comment == nil {
+		return false
+	}
+	for _, line := range comment.Lines {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, ignorePrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDelimiter(r rune) bool {
+	return r == '.' || r == '-' || r == '_' || unicode.IsSpace(r)
+}
+
+// toPascalCase upper cases the first letter of every word and drops the
+// delimiters, letters that are already upper case are kept.
+func toPascalCase(s string) string {
+	var res strings.Builder
+	previous := '_'
+	for _, r := range strings.TrimSpace(s) {
+		if !isDelimiter(r) {
+			if isDelimiter(previous) || unicode.IsUpper(r) {
+				res.WriteRune(unicode.ToUpper(r))
+			} else {
+				res.WriteRune(unicode.ToLower(r))
+			}
+		}
+		previous = r
+	}
+	return res.String()
+}
+
+func toLowerSnakeCase(s string) string {
+	return strings.ToLower(toSnakeCase(s))
+}
+
+func toUpperSnakeCase(s string) string {
+	return strings.ToUpper(toSnakeCase(s))
+}
+
+// toSnakeCase separates the words of s by single underscores. A new word
+// starts at an upper case letter that follows a lower case one or precedes
+// one, so HTTPServer becomes HTTP_Server.
+func toSnakeCase(s string) string {
+	runes := []rune(strings.TrimFunc(s, isDelimiter))
+	var res []rune
+	for i, r := range runes {
+		if isDelimiter(r) {
+			r = '_'
+		}
+		switch {
+		case i == 0:
+			res = append(res, r)
+		case unicode.IsUpper(r) && res[len(res)-1] != '_' &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && !unicode.IsUpper(runes[i+1]) && !isDelimiter(runes[i+1]))):
+			res = append(res, '_', r)
+		case r == '_' && res[len(res)-1] == '_':
+		default:
+			res = append(res, r)
+		}
+	}
+	return string(res)
+}
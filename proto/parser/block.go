@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+)
+
+// blockEnds maps every element of p whose body is a block to the position of
+// its closing brace: messages, extend blocks, groups, enums, oneofs,
+// services, rpcs with options and options, also inline ones of fields, with
+// an aggregate value. Only the Line and Column of the positions are set.
+func blockEnds(s *source, p *protobuf.Proto) map[protobuf.Visitee]scanner.Position {
+	ends := make(map[protobuf.Visitee]scanner.Position)
+	var visit func(elements []protobuf.Visitee)
+	visit = func(elements []protobuf.Visitee) {
+		for _, e := range elements {
+			var pos scanner.Position
+			var children []protobuf.Visitee
+			switch v := e.(type) {
+			case *protobuf.Message:
+				pos, children = v.Position, v.Elements
+			case *protobuf.Group:
+				pos, children = v.Position, v.Elements
+			case *protobuf.Enum:
+				pos, children = v.Position, v.Elements
+			case *protobuf.Oneof:
+				pos, children = v.Position, v.Elements
+			case *protobuf.Service:
+				pos, children = v.Position, v.Elements
+			case *protobuf.RPC:
+				pos, children = v.Position, v.Elements
+			case *protobuf.Option:
+				pos = v.Position
+			case *protobuf.EnumField:
+				visit(v.Elements)
+				continue
+			case *protobuf.NormalField:
+				visit(fieldOptions(v.Options))
+				continue
+			case *protobuf.MapField:
+				visit(fieldOptions(v.Options))
+				continue
+			case *protobuf.OneOfField:
+				visit(fieldOptions(v.Options))
+				continue
+			default:
+				continue
+			}
+
+			// the block starts at the first brace of the statement, inline
+			// options start at the bracket or comma before them
+			for i := s.offset(pos) + 1; i > 0 && i < len(s.src); i++ {
+				if !s.code[i] {
+					continue
+				}
+				if r := s.src[i]; r == ';' || r == '}' || r == ',' || r == ']' {
+					break
+				}
+				if s.src[i] == '{' {
+					if end := matchingBrace(s.src, s.code, i); end <= len(s.src) && s.src[end-1] == '}' {
+						ends[e] = s.position(end - 1)
+					}
+					break
+				}
+			}
+			visit(children)
+		}
+	}
+	visit(p.Elements)
+	return ends
+}
+
+func fieldOptions(options []*protobuf.Option) []protobuf.Visitee {
+	res := make([]protobuf.Visitee, len(options))
+	for i, o := range options {
+		res[i] = o
+	}
+	return res
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockEnd(t *testing.T) {
+	proto, err := ParseProto("file:///test.proto", strings.NewReader(`syntax = "proto3";
+
+// a brace } in a comment
+message User {
+	option (meta) = { name: "}" };
+	message Address {}
+	oneof contact {
+		string email = 1 [deprecated = true, (meta) = {
+			name: "email"
+		}];
+	}
+}
+
+enum Status { ACTIVE = 0; }
+
+service Users {
+	rpc Get(User) returns (User);
+	rpc List(User) returns (stream User) {
+		option deprecated = true;
+	}
+}
+`))
+	require.NoError(t, err)
+
+	end := func(v protobuf.Visitee) [2]int {
+		pos, ok := proto.BlockEnd(v)
+		require.True(t, ok)
+		return [2]int{pos.Line, pos.Column}
+	}
+	user := proto.Messages()[0].Protobuf()
+	require.Equal(t, [2]int{12, 1}, end(user))
+	require.Equal(t, [2]int{5, 30}, end(user.Elements[0]))
+	require.Equal(t, [2]int{6, 19}, end(user.Elements[1]))
+	require.Equal(t, [2]int{11, 2}, end(user.Elements[2]))
+	email := user.Elements[2].(*protobuf.Oneof).Elements[0].(*protobuf.OneOfField)
+	_, ok := proto.BlockEnd(email.Options[0])
+	require.False(t, ok)
+	require.Equal(t, [2]int{10, 3}, end(email.Options[1]))
+	require.Equal(t, [2]int{14, 27}, end(proto.Enums()[0].Protobuf()))
+
+	service := proto.Services()[0].Protobuf()
+	require.Equal(t, [2]int{21, 1}, end(service))
+	_, ok = proto.BlockEnd(service.Elements[0])
+	require.False(t, ok)
+	require.Equal(t, [2]int{20, 2}, end(service.Elements[1]))
+}
@@ -9,6 +9,12 @@ import (
 // Enum is a registry for protobuf enum.
 type Enum interface {
 	Protobuf() *protobuf.Enum
+	// FullyQualifiedName returns the name including the package and the
+	// messages it is nested in, e.g. common.User.Status.
+	FullyQualifiedName() string
+	// Features returns the resolved features of the enum, they are empty
+	// for extend blocks.
+	Features() FeatureSet
 
 	GetFieldByName(name string) (*EnumField, bool)
 
@@ -19,6 +25,7 @@ type enum struct {
 	protoEnum *protobuf.Enum
 
 	fullyQualifiedName string
+	features           FeatureSet
 
 	fieldNameToValue map[string]*EnumField
 
@@ -39,6 +46,8 @@ func NewEnum(protoEnum *protobuf.Enum) Enum {
 		fieldNameToValue: make(map[string]*EnumField),
 
 		lineToEnumField: make(map[int]*EnumField),
+
+		mu: &sync.RWMutex{},
 	}
 
 	for _, e := range protoEnum.Elements {
@@ -59,6 +68,41 @@ func (e *enum) Protobuf() *protobuf.Enum {
 	return e.protoEnum
 }
 
+// FullyQualifiedName returns the fully qualified name of the enum.
+func (e *enum) FullyQualifiedName() (name string) {
+	e.mu.RLock()
+	name = e.fullyQualifiedName
+	e.mu.RUnlock()
+	return
+}
+
+// Features returns the resolved features of the enum.
+func (e *enum) Features() (features FeatureSet) {
+	e.mu.RLock()
+	features = e.features
+	e.mu.RUnlock()
+	return
+}
+
+func (e *enum) setFeatures(features FeatureSet) {
+	e.mu.Lock()
+	e.features = features
+	e.mu.Unlock()
+}
+
+// setFullyQualifiedName names e and its values. scope is the fully qualified
+// name of the package or message e is declared in, which is the scope of the
+// values too.
+func (e *enum) setFullyQualifiedName(scope string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.fullyQualifiedName = qualifiedName(scope, e.protoEnum.Name)
+	for name, f := range e.fieldNameToValue {
+		f.FullyQualifiedName = qualifiedName(scope, name)
+	}
+}
+
 // GetFieldByName gets EnumField by provided name.
 // This ensures thread safety.
 func (e *enum) GetFieldByName(name string) (f *EnumField, ok bool) {
@@ -80,6 +124,9 @@ func (e *enum) GetFieldByLine(line int) (f *EnumField, ok bool) {
 // EnumField is a registry for protobuf enum field.
 type EnumField struct {
 	ProtoEnumField *protobuf.EnumField
+	// FullyQualifiedName is the name of the value in the scope of its enum,
+	// e.g. common.ACTIVE for a value of common.Status.
+	FullyQualifiedName string
 }
 
 // NewEnumField returns EnumField initialized by provided *protobuf.EnumField.
@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"sync"
+
+	protobuf "github.com/emicklei/proto"
+)
+
+// Extend is a registry for protobuf extend block.
+type Extend interface {
+	Protobuf() *protobuf.Message
+	// Extendee returns the name of the extended message as written, e.g.
+	// google.protobuf.FieldOptions.
+	Extendee() string
+	// Scope returns the fully qualified name of the package or message the
+	// extend block is declared in. Its extendee and field types resolve in
+	// this scope and its fields are named in it.
+	Scope() string
+
+	Fields() []*MessageField
+	GetFieldByName(name string) (*MessageField, bool)
+}
+
+type extend struct {
+	message Message
+	scope   string
+	mu      *sync.RWMutex
+}
+
+var _ Extend = (*extend)(nil)
+
+// newExtend returns the Extend of m, which is an extend block.
+func newExtend(m Message) Extend {
+	return &extend{message: m, mu: &sync.RWMutex{}}
+}
+
+// Protobuf returns *protobuf.Message.
+func (e *extend) Protobuf() *protobuf.Message {
+	return e.message.Protobuf()
+}
+
+// Extendee returns the name of the extended message.
+func (e *extend) Extendee() string {
+	return e.message.Protobuf().Name
+}
+
+// Scope returns the fully qualified name of the scope of the extend block.
+func (e *extend) Scope() (scope string) {
+	e.mu.RLock()
+	scope = e.scope
+	e.mu.RUnlock()
+	return
+}
+
+func (e *extend) setScope(scope string) {
+	e.mu.Lock()
+	e.scope = scope
+	e.mu.Unlock()
+}
+
+// Fields returns slice of MessageField, the extensions of the block.
+func (e *extend) Fields() []*MessageField {
+	return e.message.Fields()
+}
+
+// GetFieldByName gets MessageField by provided name.
+// This ensures thread safety.
+func (e *extend) GetFieldByName(name string) (*MessageField, bool) {
+	return e.message.GetFieldByName(name)
+}
+
+// Extension is a field declared in an extend block, e.g. a custom option.
+type Extension struct {
+	Extend Extend
+	Field  *MessageField
+}
+
+// Extensions is a registry for protobuf extension ranges of a message.
+type Extensions struct {
+	ProtoExtensions *protobuf.Extensions
+}
+
+// NewExtensions returns Extensions initialized by provided *protobuf.Extensions.
+func NewExtensions(protoExtensions *protobuf.Extensions) *Extensions {
+	return &Extensions{
+		ProtoExtensions: protoExtensions,
+	}
+}
+
+// Contains reports whether the field number is in one of the ranges.
+func (e *Extensions) Contains(number int) bool {
+	for _, r := range e.ProtoExtensions.Ranges {
+		if number >= r.From && (r.Max || number <= r.To) {
+			return true
+		}
+	}
+	return false
+}
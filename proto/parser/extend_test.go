@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtends(t *testing.T) {
+	rules := parse(t, `syntax = "proto2";
+package validate;
+
+import "google/protobuf/descriptor.proto";
+
+extend google.protobuf.FieldOptions {
+	optional FieldRules rules = 1071;
+}
+
+message FieldRules {
+	extensions 100 to 199, 500 to max;
+	extensions 42;
+
+	extend google.protobuf.MessageOptions {
+		optional bool disabled = 1072;
+	}
+}
+`)
+	api := parse(t, `syntax = "proto3";
+package acme.api;
+
+extend google.protobuf.FileOptions {
+	string owner = 5000;
+}
+`)
+
+	extends := rules.Extends()
+	require.Len(t, extends, 2)
+	require.Equal(t, "google.protobuf.FieldOptions", extends[0].Extendee())
+	require.Equal(t, "validate", extends[0].Scope())
+	require.Equal(t, "google.protobuf.MessageOptions", extends[1].Extendee())
+	require.Equal(t, "validate.FieldRules", extends[1].Scope())
+
+	extension, ok := rules.GetExtensionByFullyQualifiedName("validate.FieldRules.disabled")
+	require.True(t, ok)
+	require.Equal(t, extends[1], extension.Extend)
+	require.Equal(t, 1072, extension.Field.ProtoField.Sequence)
+
+	field_rules, ok := rules.GetMessageByName("FieldRules")
+	require.True(t, ok)
+	require.Equal(t, extends[1:], field_rules.Extends())
+	contains := func(number int) bool {
+		for _, e := range field_rules.Extensions() {
+			if e.Contains(number) {
+				return true
+			}
+		}
+		return false
+	}
+	require.True(t, contains(100))
+	require.True(t, contains(199))
+	require.False(t, contains(200))
+	require.True(t, contains(42))
+	require.True(t, contains(536870911))
+
+	resolve := func(name, scope string) string {
+		t.Helper()
+		typ, ok := NewResolver(api, rules).ResolveExtension(name, scope)
+		if !ok {
+			return ""
+		}
+		require.NotNil(t, typ.Extension)
+		return typ.FullyQualifiedName()
+	}
+	require.Equal(t, "validate.rules", resolve("validate.rules", "acme.api"))
+	require.Equal(t, "validate.rules", resolve(".validate.rules", "acme.api"))
+	require.Equal(t, "validate.FieldRules.disabled", resolve("validate.FieldRules.disabled", "acme.api"))
+	require.Equal(t, "acme.api.owner", resolve("owner", "acme.api.User"))
+	require.Equal(t, "acme.api.owner", resolve("api.owner", "acme.api"))
+	require.Equal(t, "", resolve("rules", "acme.api"))
+	// messages are not extensions
+	require.Equal(t, "", resolve("validate.FieldRules", "acme.api"))
+}
@@ -0,0 +1,321 @@
+package parser
+
+import (
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+)
+
+// FeatureTarget is a kind of element a feature can be set on.
+type FeatureTarget string
+
+const (
+	FeatureTargetFile      FeatureTarget = "file"
+	FeatureTargetMessage   FeatureTarget = "message"
+	FeatureTargetField     FeatureTarget = "field"
+	FeatureTargetOneof     FeatureTarget = "oneof"
+	FeatureTargetEnum      FeatureTarget = "enum"
+	FeatureTargetEnumValue FeatureTarget = "enum value"
+	FeatureTargetService   FeatureTarget = "service"
+	FeatureTargetMethod    FeatureTarget = "rpc"
+)
+
+// Feature is a field of google.protobuf.FeatureSet, set with options such as
+// option features.field_presence = IMPLICIT; in files using editions.
+type Feature struct {
+	Name   string
+	Values []string
+	// Targets are the kinds of elements the feature can be set on.
+	Targets []FeatureTarget
+	// Since is the first edition the feature can be set in.
+	Since string
+	// Defaults are the values of the feature by proto2 or proto3 syntax and
+	// by the edition they take effect in.
+	Defaults map[string]string
+	// Doc describes the feature.
+	Doc string
+}
+
+// AppliesTo reports whether the feature can be set on target.
+func (f Feature) AppliesTo(target FeatureTarget) bool {
+	for _, t := range f.Targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Available reports whether the feature can be set in edition.
+func (f Feature) Available(edition string) bool {
+	return edition >= f.Since
+}
+
+// Features are the features of google.protobuf.FeatureSet known to the
+// parser. Language specific features, e.g. features.(pb.cpp), are not
+// resolved.
+var Features = []Feature{
+	{
+		Name:    "field_presence",
+		Values:  []string{"EXPLICIT", "IMPLICIT", "LEGACY_REQUIRED"},
+		Targets: []FeatureTarget{FeatureTargetFile, FeatureTargetField},
+		Since:   "2023",
+		Defaults: map[string]string{
+			"proto2": "EXPLICIT", "proto3": "IMPLICIT", "2023": "EXPLICIT",
+		},
+		Doc: "whether singular fields track if they are set",
+	},
+	{
+		Name:    "enum_type",
+		Values:  []string{"OPEN", "CLOSED"},
+		Targets: []FeatureTarget{FeatureTargetFile, FeatureTargetEnum},
+		Since:   "2023",
+		Defaults: map[string]string{
+			"proto2": "CLOSED", "proto3": "OPEN", "2023": "OPEN",
+		},
+		Doc: "whether unknown values are kept in enum fields",
+	},
+	{
+		Name:    "repeated_field_encoding",
+		Values:  []string{"PACKED", "EXPANDED"},
+		Targets: []FeatureTarget{FeatureTargetFile, FeatureTargetField},
+		Since:   "2023",
+		Defaults: map[string]string{
+			"proto2": "EXPANDED", "proto3": "PACKED", "2023": "PACKED",
+		},
+		Doc: "how repeated scalar fields are encoded",
+	},
+	{
+		Name:    "utf8_validation",
+		Values:  []string{"VERIFY", "NONE"},
+		Targets: []FeatureTarget{FeatureTargetFile, FeatureTargetField},
+		Since:   "2023",
+		Defaults: map[string]string{
+			"proto2": "NONE", "proto3": "VERIFY", "2023": "VERIFY",
+		},
+		Doc: "whether string fields are checked to be valid UTF-8",
+	},
+	{
+		Name:    "message_encoding",
+		Values:  []string{"LENGTH_PREFIXED", "DELIMITED"},
+		Targets: []FeatureTarget{FeatureTargetFile, FeatureTargetField},
+		Since:   "2023",
+		Defaults: map[string]string{
+			"proto2": "LENGTH_PREFIXED", "proto3": "LENGTH_PREFIXED", "2023": "LENGTH_PREFIXED",
+		},
+		Doc: "how message fields are encoded, DELIMITED like groups",
+	},
+	{
+		Name:    "json_format",
+		Values:  []string{"ALLOW", "LEGACY_BEST_EFFORT"},
+		Targets: []FeatureTarget{FeatureTargetFile, FeatureTargetMessage, FeatureTargetEnum},
+		Since:   "2023",
+		Defaults: map[string]string{
+			"proto2": "LEGACY_BEST_EFFORT", "proto3": "ALLOW", "2023": "ALLOW",
+		},
+		Doc: "whether names must be valid for the JSON mapping",
+	},
+	{
+		Name:   "enforce_naming_style",
+		Values: []string{"STYLE2024", "STYLE_LEGACY"},
+		Targets: []FeatureTarget{
+			FeatureTargetFile, FeatureTargetMessage, FeatureTargetField, FeatureTargetOneof,
+			FeatureTargetEnum, FeatureTargetEnumValue, FeatureTargetService, FeatureTargetMethod,
+		},
+		Since: "2024",
+		Defaults: map[string]string{
+			"proto2": "STYLE_LEGACY", "proto3": "STYLE_LEGACY", "2023": "STYLE_LEGACY", "2024": "STYLE2024",
+		},
+		Doc: "whether names must follow the style guide",
+	},
+	{
+		Name:    "default_symbol_visibility",
+		Values:  []string{"EXPORT_ALL", "EXPORT_TOP_LEVEL", "LOCAL_ALL", "STRICT"},
+		Targets: []FeatureTarget{FeatureTargetFile},
+		Since:   "2024",
+		Defaults: map[string]string{
+			"proto2": "EXPORT_ALL", "proto3": "EXPORT_ALL", "2023": "EXPORT_ALL", "2024": "EXPORT_TOP_LEVEL",
+		},
+		Doc: "which messages and enums other files can use",
+	},
+}
+
+// GetFeatureByName gets Feature by provided name, e.g. field_presence.
+func GetFeatureByName(name string) (Feature, bool) {
+	for _, f := range Features {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Feature{}, false
+}
+
+// FeatureSet is the resolved value of every feature of an element.
+type FeatureSet struct {
+	// Edition is the edition of the file, empty for proto2 and proto3 files
+	// whose features are implied by their syntax.
+	Edition string
+	Values  map[string]string
+}
+
+// DefaultFeatures returns the features of a file with the provided syntax,
+// proto2, proto3 or editions, and edition before any feature is set.
+func DefaultFeatures(syntax, edition string) FeatureSet {
+	set := FeatureSet{Values: make(map[string]string)}
+	if syntax == "editions" {
+		set.Edition = edition
+	}
+	for _, f := range Features {
+		if syntax != "editions" {
+			set.Values[f.Name] = f.Defaults[syntax]
+			continue
+		}
+		// the defaults of the latest edition up to this one
+		latest := ""
+		for since, value := range f.Defaults {
+			if !strings.HasPrefix(since, "proto") && since <= edition && since > latest {
+				latest = since
+				set.Values[f.Name] = value
+			}
+		}
+	}
+	return set
+}
+
+// with returns a copy of set with the features set by options.
+func (set FeatureSet) with(options []*protobuf.Option) FeatureSet {
+	res := set
+	copied := false
+	for _, o := range options {
+		for _, value := range FeatureOptionValues(o) {
+			if _, ok := GetFeatureByName(value.Name); !ok {
+				continue
+			}
+			if !copied {
+				res.Values = make(map[string]string, len(set.Values))
+				for k, v := range set.Values {
+					res.Values[k] = v
+				}
+				copied = true
+			}
+			res.Values[value.Name] = value.Value
+		}
+	}
+	return res
+}
+
+// FeatureValue is the value a feature option sets.
+type FeatureValue struct {
+	Name  string
+	Value string
+}
+
+// FeatureOptionValues returns the features set by o, both for option
+// features.enum_type = CLOSED; and option features = { enum_type: CLOSED };.
+// Language specific features are left out.
+func FeatureOptionValues(o *protobuf.Option) (res []FeatureValue) {
+	if name, ok := strings.CutPrefix(o.Name, "features."); ok {
+		if !strings.ContainsAny(name, "().") {
+			res = append(res, FeatureValue{Name: name, Value: o.Constant.Source})
+		}
+	} else if o.Name == "features" {
+		for _, named := range o.Constant.OrderedMap {
+			if named.Literal != nil {
+				res = append(res, FeatureValue{Name: named.Name, Value: named.Literal.Source})
+			}
+		}
+	}
+	return res
+}
+
+// IsFeatureOption reports whether o sets features.
+func IsFeatureOption(o *protobuf.Option) bool {
+	return o.Name == "features" || strings.HasPrefix(o.Name, "features.")
+}
+
+// ResolveFeatures returns the resolved features of p and of every element of
+// it that features can be set on, see Proto.Features.
+func ResolveFeatures(p *protobuf.Proto) map[protobuf.Visitee]FeatureSet {
+	return resolveFeatures(p, DefaultFeatures(syntaxOf(p), editionOf(p)))
+}
+
+// syntaxOf returns proto2, proto3 or editions, files without a syntax are
+// proto2.
+func syntaxOf(p *protobuf.Proto) string {
+	for _, e := range p.Elements {
+		switch v := e.(type) {
+		case *protobuf.Syntax:
+			return v.Value
+		case *protobuf.Edition:
+			return "editions"
+		}
+	}
+	return "proto2"
+}
+
+// editionOf returns the declared edition of p, or an empty string.
+func editionOf(p *protobuf.Proto) string {
+	for _, e := range p.Elements {
+		if v, ok := e.(*protobuf.Edition); ok {
+			return v.Value
+		}
+	}
+	return ""
+}
+
+// resolveFeatures returns the resolved features of p and of every element
+// of it that features can be set on. Elements inherit the features of the
+// element they are declared in, fields of extend blocks the ones of the
+// scope of the block.
+func resolveFeatures(p *protobuf.Proto, defaults FeatureSet) map[protobuf.Visitee]FeatureSet {
+	res := make(map[protobuf.Visitee]FeatureSet)
+	var visit func(elements []protobuf.Visitee, parent FeatureSet)
+	visit = func(elements []protobuf.Visitee, parent FeatureSet) {
+		for _, e := range elements {
+			switch v := e.(type) {
+			case *protobuf.Message:
+				if v.IsExtend {
+					visit(v.Elements, parent)
+					continue
+				}
+				res[v] = parent.with(elementOptions(v.Elements))
+				visit(v.Elements, res[v])
+			case *protobuf.Group:
+				res[v] = parent.with(elementOptions(v.Elements))
+				visit(v.Elements, res[v])
+			case *protobuf.Oneof:
+				res[v] = parent.with(elementOptions(v.Elements))
+				visit(v.Elements, res[v])
+			case *protobuf.Enum:
+				res[v] = parent.with(elementOptions(v.Elements))
+				visit(v.Elements, res[v])
+			case *protobuf.EnumField:
+				res[v] = parent.with(elementOptions(v.Elements))
+			case *protobuf.NormalField:
+				res[v] = parent.with(v.Options)
+			case *protobuf.MapField:
+				res[v] = parent.with(v.Options)
+			case *protobuf.OneOfField:
+				res[v] = parent.with(v.Options)
+			case *protobuf.Service:
+				res[v] = parent.with(elementOptions(v.Elements))
+				visit(v.Elements, res[v])
+			case *protobuf.RPC:
+				res[v] = parent.with(elementOptions(v.Elements))
+			}
+		}
+	}
+	res[p] = defaults.with(elementOptions(p.Elements))
+	visit(p.Elements, res[p])
+	return res
+}
+
+// elementOptions returns the options among elements.
+func elementOptions(elements []protobuf.Visitee) (res []*protobuf.Option) {
+	for _, e := range elements {
+		if o, ok := e.(*protobuf.Option); ok {
+			res = append(res, o)
+		}
+	}
+	return res
+}
@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"testing"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatures(t *testing.T) {
+	p := parse(t, `edition = "2023";
+package acme.api;
+
+option features.field_presence = IMPLICIT;
+
+message User {
+	option features = { json_format: LEGACY_BEST_EFFORT };
+
+	string name = 1;
+	int32 age = 2 [features.field_presence = EXPLICIT];
+	oneof contact {
+		string email = 3;
+	}
+	enum Status {
+		option features.enum_type = CLOSED;
+		ACTIVE = 1;
+	}
+}
+
+extend User {
+	string label = 10;
+}
+`)
+	require.Equal(t, "editions", p.Syntax())
+	require.Equal(t, "2023", p.Edition())
+
+	file, ok := p.Features(p.Protobuf())
+	require.True(t, ok)
+	require.Equal(t, "2023", file.Edition)
+	require.Equal(t, "IMPLICIT", file.Values["field_presence"])
+	require.Equal(t, "OPEN", file.Values["enum_type"])
+	require.Equal(t, "ALLOW", file.Values["json_format"])
+	require.Equal(t, "STYLE_LEGACY", file.Values["enforce_naming_style"])
+
+	user, _ := p.GetMessageByName("User")
+	require.Equal(t, "LEGACY_BEST_EFFORT", user.Features().Values["json_format"])
+	require.Equal(t, "IMPLICIT", user.Features().Values["field_presence"])
+
+	field := func(v protobuf.Visitee) FeatureSet {
+		t.Helper()
+		set, ok := p.Features(v)
+		require.True(t, ok)
+		return set
+	}
+	name, _ := user.GetFieldByName("name")
+	require.Equal(t, "IMPLICIT", field(name.ProtoField).Values["field_presence"])
+	require.Equal(t, "LEGACY_BEST_EFFORT", field(name.ProtoField).Values["json_format"])
+	age, _ := user.GetFieldByName("age")
+	require.Equal(t, "EXPLICIT", field(age.ProtoField).Values["field_presence"])
+	email, _ := user.Oneofs()[0].GetFieldByName("email")
+	require.Equal(t, "IMPLICIT", field(email.ProtoOneOfField).Values["field_presence"])
+	status, _ := user.GetNestedEnumByName("Status")
+	require.Equal(t, "CLOSED", status.Features().Values["enum_type"])
+	label, _ := p.Extends()[0].GetFieldByName("label")
+	require.Equal(t, "IMPLICIT", field(label.ProtoField).Values["field_presence"])
+	// the file set is not changed by the overrides
+	require.Equal(t, "ALLOW", file.Values["json_format"])
+
+	proto3 := parse(t, `syntax = "proto3";`)
+	set, _ := proto3.Features(proto3.Protobuf())
+	require.Equal(t, "", set.Edition)
+	require.Equal(t, "IMPLICIT", set.Values["field_presence"])
+	require.Equal(t, "proto2", parse(t, `package a;`).Syntax())
+
+	edition2024 := DefaultFeatures("editions", "2024")
+	require.Equal(t, "STYLE2024", edition2024.Values["enforce_naming_style"])
+	require.Equal(t, "EXPLICIT", edition2024.Values["field_presence"])
+}
@@ -5,6 +5,9 @@ import protobuf "github.com/emicklei/proto"
 // MapField is a registry for protobuf enum field.
 type MapField struct {
 	ProtoMapField *protobuf.MapField
+	// FullyQualifiedName is the name including the message the field is
+	// declared in.
+	FullyQualifiedName string
 }
 
 // NewMapField returns MapField initialized by provided *protobuf.MapField.
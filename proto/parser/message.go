@@ -9,12 +9,24 @@ import (
 // Message is a registry for protobuf message.
 type Message interface {
 	Protobuf() *protobuf.Message
+	// FullyQualifiedName returns the name including the package and the
+	// messages it is nested in, e.g. common.User.Address. It is empty for
+	// extend blocks.
+	FullyQualifiedName() string
+	// Features returns the resolved features of the message, they are empty
+	// for extend blocks.
+	Features() FeatureSet
 
 	NestedMessages() []Message
 	NestedEnums() []Enum
 	Fields() []*MessageField
 	Oneofs() []Oneof
 	MapFields() []*MapField
+	// Extends returns the extend blocks declared in the message, they are
+	// also part of NestedMessages.
+	Extends() []Extend
+	// Extensions returns the extension ranges of the message.
+	Extensions() []*Extensions
 
 	GetNestedMessageByName(name string) (Message, bool)
 	GetNestedEnumByName(name string) (Enum, bool)
@@ -35,12 +47,15 @@ type message struct {
 	protoMessage *protobuf.Message
 
 	fullyQualifiedName string
+	features           FeatureSet
 
 	nestedMessages []Message
 	nestedEnums    []Enum
 	fields         []*MessageField
 	oneofs         []Oneof
 	mapFields      []*MapField
+	extends        []Extend
+	extensions     []*Extensions
 
 	nestedEnumNameToEnum       map[string]Enum
 	nestedMessageNameToMessage map[string]Message
@@ -102,6 +117,11 @@ func NewMessage(protoMessage *protobuf.Message) Message {
 			f := NewMessage(v)
 			f.SetParentMessage(m)
 			m.nestedMessages = append(m.nestedMessages, f)
+			if v.IsExtend {
+				m.extends = append(m.extends, newExtend(f))
+			}
+		case *protobuf.Extensions:
+			m.extensions = append(m.extensions, NewExtensions(v))
 		default:
 		}
 	}
@@ -125,7 +145,10 @@ func NewMessage(protoMessage *protobuf.Message) Message {
 		m.nestedEnumNameToEnum[f.Protobuf().Name] = f
 	}
 
-	for _, f := range m.nestedMessageNameToMessage {
+	for _, f := range m.nestedMessages {
+		if f.Protobuf().IsExtend {
+			continue
+		}
 		m.nestedMessageNameToMessage[f.Protobuf().Name] = f
 	}
 	return m
@@ -136,6 +159,66 @@ func (m *message) Protobuf() *protobuf.Message {
 	return m.protoMessage
 }
 
+// FullyQualifiedName returns the fully qualified name of the message.
+func (m *message) FullyQualifiedName() (name string) {
+	m.mu.RLock()
+	name = m.fullyQualifiedName
+	m.mu.RUnlock()
+	return
+}
+
+// Features returns the resolved features of the message.
+func (m *message) Features() (features FeatureSet) {
+	m.mu.RLock()
+	features = m.features
+	m.mu.RUnlock()
+	return
+}
+
+func (m *message) setFeatures(features FeatureSet) {
+	m.mu.Lock()
+	m.features = features
+	m.mu.Unlock()
+}
+
+// setFullyQualifiedName names m and everything declared in it. scope is the
+// fully qualified name of the package or message m is declared in. Fields of
+// an extend block are named in scope.
+func (m *message) setFullyQualifiedName(scope string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.protoMessage.IsExtend {
+		m.fullyQualifiedName = qualifiedName(scope, m.protoMessage.Name)
+		scope = m.fullyQualifiedName
+	}
+	for _, f := range m.fields {
+		f.FullyQualifiedName = qualifiedName(scope, f.ProtoField.Name)
+	}
+	for _, f := range m.mapFields {
+		f.FullyQualifiedName = qualifiedName(scope, f.ProtoMapField.Name)
+	}
+	for _, o := range m.oneofs {
+		// oneof fields belong to the enclosing message
+		for _, e := range o.Protobuf().Elements {
+			if v, ok := e.(*protobuf.OneOfField); ok {
+				if f, ok := o.GetFieldByName(v.Name); ok {
+					f.FullyQualifiedName = qualifiedName(scope, v.Name)
+				}
+			}
+		}
+	}
+	for _, e := range m.nestedEnums {
+		e.(*enum).setFullyQualifiedName(scope)
+	}
+	for _, n := range m.nestedMessages {
+		n.(*message).setFullyQualifiedName(scope)
+	}
+	for _, e := range m.extends {
+		e.(*extend).setScope(scope)
+	}
+}
+
 // NestedMessages returns slice of nested Message.
 func (m *message) NestedMessages() (msgs []Message) {
 	m.mu.RLock()
@@ -176,6 +259,22 @@ func (m *message) MapFields() (fs []*MapField) {
 	return
 }
 
+// Extends returns slice of Extend.
+func (m *message) Extends() (extends []Extend) {
+	m.mu.RLock()
+	extends = m.extends
+	m.mu.RUnlock()
+	return
+}
+
+// Extensions returns slice of Extensions.
+func (m *message) Extensions() (extensions []*Extensions) {
+	m.mu.RLock()
+	extensions = m.extensions
+	m.mu.RUnlock()
+	return
+}
+
 // GetNestedMessageByName gets Message by provided name.
 // This ensures thread safety.
 func (m *message) GetNestedMessageByName(name string) (msg Message, ok bool) {
@@ -258,6 +357,9 @@ func (m *message) SetParentMessage(p Message) {
 // MessageField is a registry for protobuf message field.
 type MessageField struct {
 	ProtoField *protobuf.NormalField
+	// FullyQualifiedName is the name including the message, or the scope of
+	// an extend block, the field is declared in.
+	FullyQualifiedName string
 }
 
 // NewMessageField returns MessageField initialized by provided *protobuf.MessageField.
@@ -35,6 +35,8 @@ func NewOneof(protoOneofField *protobuf.Oneof) Oneof {
 		fieldNameToField: make(map[string]*OneofField),
 
 		lineToField: make(map[int]*OneofField),
+
+		mu: &sync.RWMutex{},
 	}
 
 	for _, e := range protoOneofField.Elements {
@@ -76,6 +78,9 @@ func (o *oneof) GetFieldByLine(line int) (f *OneofField, ok bool) {
 // OneofField is a registry for protobuf oneof field.
 type OneofField struct {
 	ProtoOneOfField *protobuf.OneOfField
+	// FullyQualifiedName is the name including the message the oneof is
+	// declared in, oneofs do not add a scope.
+	FullyQualifiedName string
 }
 
 // NewOneofField returns OneofField initialized by provided *protobuf.OneofField.
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"bytes"
 	"io"
 
 	protobuf "github.com/emicklei/proto"
@@ -9,10 +10,14 @@ import (
 
 // ParseProtos parses protobuf files from filenames and return parser.ProtoSet.
 func ParseProto(document_uri defines.DocumentUri, r io.Reader) (Proto, error) {
-	parser := protobuf.NewParser(r)
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	parser := protobuf.NewParser(bytes.NewReader(data))
 	p, err := parser.Parse()
 	if err != nil {
 		return nil, err
 	}
-	return NewProto(document_uri, p), nil
+	return newProtoWithSource(document_uri, p, []rune(string(data))), nil
 }
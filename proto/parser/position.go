@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The characters of LSP positions count UTF-16 code units, the columns of the
+// parser count runes and Go indexes strings by bytes. The functions below
+// convert between them on the text of a single line.
+
+// UTF16Character returns the LSP character of the byte offset in line.
+func UTF16Character(line string, offset int) uint {
+	return uint(utf16Len([]rune(line[:min(max(offset, 0), len(line))])))
+}
+
+// UTF16Offset returns the byte offset in line of the LSP character. Characters
+// past the end of line are clamped to its length.
+func UTF16Offset(line string, character uint) int {
+	units := uint(0)
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += uint(utf16.RuneLen(r))
+	}
+	return len(line)
+}
+
+// ColumnOffset returns the byte offset in line of the 0-based column of the
+// parser. Columns past the end of line are clamped to its length.
+func ColumnOffset(line string, column int) int {
+	offset := 0
+	for ; column > 0 && offset < len(line); column-- {
+		_, size := utf8.DecodeRuneInString(line[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// utf16Len returns the length of runes in UTF-16 code units.
+func utf16Len(runes []rune) int {
+	n := 0
+	for _, r := range runes {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
@@ -0,0 +1,19 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUTF16Offsets(t *testing.T) {
+	line := `a 🙂 é b`
+	require.Equal(t, uint(0), UTF16Character(line, 0))
+	require.Equal(t, uint(4), UTF16Character(line, 6))
+	require.Equal(t, uint(8), UTF16Character(line, len(line)))
+	require.Equal(t, 6, UTF16Offset(line, 4))
+	require.Equal(t, 10, UTF16Offset(line, 7))
+	require.Equal(t, len(line), UTF16Offset(line, 100))
+	require.Equal(t, 6, ColumnOffset(line, 3))
+	require.Equal(t, len(line), ColumnOffset(line, 100))
+}
@@ -2,21 +2,32 @@ package parser
 
 import (
 	"sync"
+	"text/scanner"
 
 	protobuf "github.com/emicklei/proto"
-	"github.com/walteh/protobuf-language-server/go-lsp/logs"
 	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
 )
 
 // Proto is a registry for protobuf proto.
 type Proto interface {
 	Protobuf() *protobuf.Proto
+	// Syntax returns proto2, proto3 or editions, which is the syntax of files
+	// declaring an edition. Files without a syntax are proto2.
+	Syntax() string
+	// Edition returns the declared edition, e.g. 2023, or an empty string.
+	Edition() string
+	// Features returns the resolved features of the file, for the
+	// *protobuf.Proto, or of an element of it features can be set on.
+	Features(v protobuf.Visitee) (FeatureSet, bool)
 
 	Packages() []*Package
 	Messages() []Message
 	Enums() []Enum
 	Services() []Service
 	Imports() []*Import
+	// Extends returns every extend block of the file, also the ones nested
+	// in messages.
+	Extends() []Extend
 
 	GetPackageByName(name string) (*Package, bool)
 	GetMessageByName(name string) (Message, bool)
@@ -31,8 +42,22 @@ type Proto interface {
 	GetMessageFieldByLine(line int) (*MessageField, bool)
 	GetEnumFieldByLine(line int) (*EnumField, bool)
 
-	GetAllParentMessage(line int) []Message
-	GetAllParentEnum(line int) []Enum
+	GetMessageByFullyQualifiedName(name string) (Message, bool)
+	GetEnumByFullyQualifiedName(name string) (Enum, bool)
+	GetExtensionByFullyQualifiedName(name string) (*Extension, bool)
+	GetScopeByLine(line int) string
+
+	// BlockEnd returns the position of the brace closing the block of a
+	// message, extend, group, enum, oneof, service, rpc or aggregate option.
+	BlockEnd(v protobuf.Visitee) (scanner.Position, bool)
+	// NameRange returns the range of the name of an element, e.g. of a
+	// message, field, enum value, rpc or option, the package name or the
+	// quoted file name of an import.
+	NameRange(v protobuf.Visitee) (defines.Range, bool)
+	// Range returns the range of the whole element from its first token up to
+	// and including its closing brace or semicolon. Inline options range
+	// over the option without the bracket or comma around it.
+	Range(v protobuf.Visitee) (defines.Range, bool)
 }
 
 type proto struct {
@@ -43,18 +68,29 @@ type proto struct {
 	enums    []Enum
 	services []Service
 	imports  []*Import
+	extends  []Extend
 
 	packageNameToPackage map[string]*Package
 	messageNameToMessage map[string]Message
 	enumNameToEnum       map[string]Enum
 	serviceNameToService map[string]Service
 
+	fullyQualifiedNameToMessage   map[string]Message
+	fullyQualifiedNameToEnum      map[string]Enum
+	fullyQualifiedNameToExtension map[string]*Extension
+
 	lineToPackage       map[int]*Package
 	lineToMessage       map[int]Message
 	lineToEnum          map[int]Enum
 	lineToService       map[int]Service
 	lineToParentMessage map[int]Message
 
+	features map[protobuf.Visitee]FeatureSet
+
+	blockEnds  map[protobuf.Visitee]scanner.Position
+	nameRanges map[protobuf.Visitee]defines.Range
+	ranges     map[protobuf.Visitee]defines.Range
+
 	mu *sync.RWMutex
 }
 
@@ -70,6 +106,10 @@ func NewProto(document_uri defines.DocumentUri, protoProto *protobuf.Proto) Prot
 		enumNameToEnum:       make(map[string]Enum),
 		serviceNameToService: make(map[string]Service),
 
+		fullyQualifiedNameToMessage:   make(map[string]Message),
+		fullyQualifiedNameToEnum:      make(map[string]Enum),
+		fullyQualifiedNameToExtension: make(map[string]*Extension),
+
 		lineToPackage:       make(map[int]*Package),
 		lineToMessage:       make(map[int]Message),
 		lineToEnum:          make(map[int]Enum),
@@ -111,6 +151,18 @@ func NewProto(document_uri defines.DocumentUri, protoProto *protobuf.Proto) Prot
 			proto.lineToParentMessage[f.ProtoField.Position.Line] = m
 		}
 
+		for _, f := range m.MapFields() {
+			proto.lineToParentMessage[f.ProtoMapField.Position.Line] = m
+		}
+
+		for _, o := range m.Oneofs() {
+			for _, e := range o.Protobuf().Elements {
+				if f, ok := e.(*protobuf.OneOfField); ok {
+					proto.lineToParentMessage[f.Position.Line] = m
+				}
+			}
+		}
+
 		for _, m := range m.NestedMessages() {
 			mapFiledToMessage(m)
 		}
@@ -121,7 +173,9 @@ func NewProto(document_uri defines.DocumentUri, protoProto *protobuf.Proto) Prot
 	}
 
 	for _, m := range proto.messages {
-		proto.messageNameToMessage[m.Protobuf().Name] = m
+		if !m.Protobuf().IsExtend {
+			proto.messageNameToMessage[m.Protobuf().Name] = m
+		}
 		proto.lineToMessage[m.Protobuf().Position.Line] = m
 		mapFiledToMessage(m)
 	}
@@ -136,6 +190,84 @@ func NewProto(document_uri defines.DocumentUri, protoProto *protobuf.Proto) Prot
 		proto.lineToService[s.Protobuf().Position.Line] = s
 	}
 
+	proto.setFullyQualifiedNames()
+	proto.setFeatures()
+
+	return proto
+}
+
+// setFeatures resolves the features of every element and hands them to the
+// messages and enums.
+func (p *proto) setFeatures() {
+	p.features = ResolveFeatures(p.protoProto)
+	var visit func(messages []Message, enums []Enum)
+	visit = func(messages []Message, enums []Enum) {
+		for _, e := range enums {
+			e.(*enum).setFeatures(p.features[e.Protobuf()])
+		}
+		for _, m := range messages {
+			m.(*message).setFeatures(p.features[m.Protobuf()])
+			visit(m.NestedMessages(), m.NestedEnums())
+		}
+	}
+	visit(p.messages, p.enums)
+}
+
+// setFullyQualifiedNames names every message, enum, field, service and rpc
+// and indexes the messages, enums and extensions by their names.
+func (p *proto) setFullyQualifiedNames() {
+	scope := p.packageName()
+	var index func(messages []Message, enums []Enum)
+	index = func(messages []Message, enums []Enum) {
+		for _, e := range enums {
+			p.fullyQualifiedNameToEnum[e.FullyQualifiedName()] = e
+		}
+		for _, m := range messages {
+			if !m.Protobuf().IsExtend {
+				p.fullyQualifiedNameToMessage[m.FullyQualifiedName()] = m
+			}
+			p.extends = append(p.extends, m.Extends()...)
+			index(m.NestedMessages(), m.NestedEnums())
+		}
+	}
+	for _, m := range p.messages {
+		m.(*message).setFullyQualifiedName(scope)
+		if m.Protobuf().IsExtend {
+			e := newExtend(m)
+			e.(*extend).setScope(scope)
+			p.extends = append(p.extends, e)
+		}
+	}
+	for _, e := range p.enums {
+		e.(*enum).setFullyQualifiedName(scope)
+	}
+	for _, s := range p.services {
+		s.(*service).setFullyQualifiedName(scope)
+	}
+	index(p.messages, p.enums)
+
+	for _, e := range p.extends {
+		for _, f := range e.Fields() {
+			p.fullyQualifiedNameToExtension[f.FullyQualifiedName] = &Extension{Extend: e, Field: f}
+		}
+	}
+}
+
+// qualifiedName joins a scope and a name declared in it.
+func qualifiedName(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// newProtoWithSource is NewProto for a Proto parsed from src, which also
+// knows where its blocks end and the ranges of its elements.
+func newProtoWithSource(document_uri defines.DocumentUri, protoProto *protobuf.Proto, src []rune) Proto {
+	proto := NewProto(document_uri, protoProto).(*proto)
+	s := newSource(src)
+	proto.blockEnds = blockEnds(s, protoProto)
+	proto.nameRanges, proto.ranges = elementRanges(s, protoProto, proto.blockEnds)
 	return proto
 }
 
@@ -144,6 +276,25 @@ func (p *proto) Protobuf() *protobuf.Proto {
 	return p.protoProto
 }
 
+// Syntax returns the syntax of the file.
+func (p *proto) Syntax() string {
+	return syntaxOf(p.protoProto)
+}
+
+// Edition returns the edition of the file.
+func (p *proto) Edition() string {
+	return editionOf(p.protoProto)
+}
+
+// Features gets the resolved features of an element.
+// This ensures thread safety.
+func (p *proto) Features(v protobuf.Visitee) (set FeatureSet, ok bool) {
+	p.mu.RLock()
+	set, ok = p.features[v]
+	p.mu.RUnlock()
+	return
+}
+
 func (p *proto) Packages() (pkgs []*Package) {
 	p.mu.RLock()
 	pkgs = p.packages
@@ -281,31 +432,106 @@ func (p *proto) GetEnumFieldByLine(line int) (f *EnumField, ok bool) {
 	return
 }
 
-func (p *proto) GetAllParentMessage(line int) (res []Message) {
-	m, ok := p.lineToParentMessage[line]
-	if !ok {
-		return
+func (p *proto) Extends() (extends []Extend) {
+	p.mu.RLock()
+	extends = p.extends
+	p.mu.RUnlock()
+	return
+}
+
+// GetScopeByLine returns the fully qualified name of the innermost message
+// whose block spans the provided line, or the package outside of messages.
+// Names used on the line resolve in this scope, see Resolver.
+// This ensures thread safety.
+func (p *proto) GetScopeByLine(line int) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if len(p.blockEnds) == 0 {
+		// without the source only lines of fields are known
+		if m, ok := p.lineToParentMessage[line]; ok {
+			return m.FullyQualifiedName()
+		}
+		return p.packageName()
 	}
-	for m != nil {
-		for _, m_br := range m.NestedMessages() {
-			res = append(res, m_br)
+	scope := p.packageName()
+	messages := p.messages
+search:
+	for {
+		for _, m := range messages {
+			end, ok := p.blockEnds[m.Protobuf()]
+			if !ok || m.Protobuf().IsExtend || line < m.Protobuf().Position.Line || line > end.Line {
+				continue
+			}
+			scope = m.FullyQualifiedName()
+			messages = m.NestedMessages()
+			continue search
 		}
-		m = m.GetParentMessage()
+		return scope
 	}
-	logs.Printf("ret %+v", res)
+}
+
+// GetMessageByFullyQualifiedName gets Message, also nested ones, by provided
+// fully qualified name.
+// This ensures thread safety.
+func (p *proto) GetMessageByFullyQualifiedName(name string) (m Message, ok bool) {
+	p.mu.RLock()
+	m, ok = p.fullyQualifiedNameToMessage[name]
+	p.mu.RUnlock()
 	return
 }
 
-func (p *proto) GetAllParentEnum(line int) (res []Enum) {
-	m, ok := p.lineToParentMessage[line]
-	if !ok {
-		return
-	}
-	for m != nil {
-		for _, e_br := range m.NestedEnums() {
-			res = append(res, e_br)
-		}
-		m = m.GetParentMessage()
+// GetEnumByFullyQualifiedName gets Enum, also nested ones, by provided fully
+// qualified name.
+// This ensures thread safety.
+func (p *proto) GetEnumByFullyQualifiedName(name string) (e Enum, ok bool) {
+	p.mu.RLock()
+	e, ok = p.fullyQualifiedNameToEnum[name]
+	p.mu.RUnlock()
+	return
+}
+
+// GetExtensionByFullyQualifiedName gets Extension, a field of an extend
+// block, by provided fully qualified name, e.g. google.api.http.
+// This ensures thread safety.
+func (p *proto) GetExtensionByFullyQualifiedName(name string) (e *Extension, ok bool) {
+	p.mu.RLock()
+	e, ok = p.fullyQualifiedNameToExtension[name]
+	p.mu.RUnlock()
+	return
+}
+
+// packageName returns the name of the first package statement.
+func (p *proto) packageName() string {
+	if len(p.packages) == 0 {
+		return ""
 	}
+	return p.packages[0].ProtoPackage.Name
+}
+
+// BlockEnd gets the position of the closing brace of a block by its element.
+// This ensures thread safety.
+func (p *proto) BlockEnd(v protobuf.Visitee) (pos scanner.Position, ok bool) {
+	p.mu.RLock()
+	pos, ok = p.blockEnds[v]
+	p.mu.RUnlock()
+	return
+}
+
+// NameRange gets the range of the name of an element.
+// This ensures thread safety.
+func (p *proto) NameRange(v protobuf.Visitee) (rng defines.Range, ok bool) {
+	p.mu.RLock()
+	rng, ok = p.nameRanges[v]
+	p.mu.RUnlock()
+	return
+}
+
+// Range gets the full range of an element.
+// This ensures thread safety.
+func (p *proto) Range(v protobuf.Visitee) (rng defines.Range, ok bool) {
+	p.mu.RLock()
+	rng, ok = p.ranges[v]
+	p.mu.RUnlock()
 	return
 }
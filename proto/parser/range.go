@@ -0,0 +1,274 @@
+package parser
+
+import (
+	"sort"
+	"text/scanner"
+	"unicode"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+// source is the text a Proto was parsed from. It converts the positions of
+// the scanner, whose columns count runes, to offsets and back, and offsets to
+// LSP positions.
+type source struct {
+	src         []rune
+	code        []bool
+	line_starts []int
+}
+
+func newSource(src []rune) *source {
+	s := &source{src: src, code: codeMask(src), line_starts: []int{0}}
+	for i, r := range src {
+		if r == '\n' {
+			s.line_starts = append(s.line_starts, i+1)
+		}
+	}
+	return s
+}
+
+// offset returns the index of pos in the source, or -1 if it is outside.
+func (s *source) offset(pos scanner.Position) int {
+	if pos.Line < 1 || pos.Line > len(s.line_starts) {
+		return -1
+	}
+	return min(s.line_starts[pos.Line-1]+max(pos.Column-1, 0), len(s.src))
+}
+
+// position returns the position of the index i, only Line and Column are set.
+func (s *source) position(i int) scanner.Position {
+	line := sort.Search(len(s.line_starts), func(l int) bool { return s.line_starts[l] > i }) - 1
+	return scanner.Position{Line: line + 1, Column: i - s.line_starts[line] + 1}
+}
+
+// lspPosition returns the LSP position of the index i, whose character counts
+// UTF-16 code units.
+func (s *source) lspPosition(i int) defines.Position {
+	pos := s.position(i)
+	line_start := s.line_starts[pos.Line-1]
+	return defines.Position{Line: uint(pos.Line - 1), Character: uint(utf16Len(s.src[line_start:i]))}
+}
+
+func (s *source) rangeOf(start, end int) defines.Range {
+	return defines.Range{Start: s.lspPosition(start), End: s.lspPosition(end)}
+}
+
+// skipSpace returns the index of the first code that is not white space at
+// or after i.
+func (s *source) skipSpace(i int) int {
+	for i < len(s.src) && (!s.code[i] || unicode.IsSpace(s.src[i])) {
+		i++
+	}
+	return i
+}
+
+// identifierEnd returns the index after the identifier starting at i, dots
+// are part of it if qualified is set.
+func (s *source) identifierEnd(i int, qualified bool) int {
+	for i < len(s.src) && s.code[i] && isIdentifierRune(s.src[i], qualified) {
+		i++
+	}
+	return i
+}
+
+// find returns the index of the first code rune of stop at or after i that
+// is not nested in braces, brackets, parentheses or angle brackets, or -1.
+func (s *source) find(i int, stop ...rune) int {
+	depth := 0
+	for ; i < len(s.src); i++ {
+		if !s.code[i] {
+			continue
+		}
+		r := s.src[i]
+		if depth == 0 {
+			for _, c := range stop {
+				if r == c {
+					return i
+				}
+			}
+		}
+		switch r {
+		case '{', '[', '(', '<':
+			depth++
+		case '}', ']', ')', '>':
+			if depth == 0 {
+				// the statement is not terminated
+				return -1
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+func isIdentifierRune(r rune, qualified bool) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || (qualified && r == '.')
+}
+
+// How the name of an element is found.
+const (
+	noName = iota
+	// the identifier after the keyword, e.g. message
+	nameAfterKeyword
+	// the possibly qualified identifier after the keyword, e.g. package
+	qualifiedNameAfterKeyword
+	// the identifier before the equal sign, e.g. of fields
+	nameBeforeEqual
+)
+
+// elementRanges returns the range of the name and the full range of every
+// element of p: from its first keyword, or type for fields, up to and
+// including the closing brace or semicolon. Inline options of fields and
+// enum values range over the option without the separators around it.
+// Elements without a name, e.g. syntax or reserved, only get a full range.
+func elementRanges(s *source, p *protobuf.Proto, ends map[protobuf.Visitee]scanner.Position) (names, full map[protobuf.Visitee]defines.Range) {
+	names = make(map[protobuf.Visitee]defines.Range)
+	full = make(map[protobuf.Visitee]defines.Range)
+
+	// declare records the ranges of e, which starts at pos and is named as
+	// name says.
+	declare := func(e protobuf.Visitee, pos scanner.Position, name int) {
+		start := s.offset(pos)
+		if start < 0 || start >= len(s.src) {
+			return
+		}
+		end := -1
+		if block_end, ok := ends[e]; ok {
+			end = s.offset(block_end) + 1
+		} else if semicolon := s.find(start, ';'); semicolon >= 0 {
+			end = semicolon + 1
+		}
+		if end < 0 {
+			return
+		}
+		full[e] = s.rangeOf(start, end)
+
+		name_start, name_end := -1, -1
+		if name == nameAfterKeyword || name == qualifiedNameAfterKeyword {
+			name_start = s.skipSpace(s.identifierEnd(start, false))
+			name_end = s.identifierEnd(name_start, name == qualifiedNameAfterKeyword)
+		} else if equal := s.find(start, '='); name == nameBeforeEqual && equal >= 0 && equal < end {
+			name_end = equal
+			for name_end > start && (!s.code[name_end-1] || unicode.IsSpace(s.src[name_end-1])) {
+				name_end--
+			}
+			name_start = name_end
+			for name_start > start && isIdentifierRune(s.src[name_start-1], false) {
+				name_start--
+			}
+		}
+		if name_start >= 0 && name_start < name_end {
+			names[e] = s.rangeOf(name_start, name_end)
+		}
+	}
+
+	// option records the ranges of an option, inline ones start at the
+	// bracket or comma before them.
+	option := func(o *protobuf.Option) {
+		start := s.offset(o.Position)
+		if start < 0 || start >= len(s.src) {
+			return
+		}
+		if !o.IsEmbedded {
+			start = s.skipSpace(s.identifierEnd(start, false))
+		} else {
+			start = s.skipSpace(start + 1)
+		}
+		end := s.find(start, ';', ',', ']')
+		equal := s.find(start, '=')
+		if end < 0 || equal < 0 || equal > end {
+			return
+		}
+		if o.IsEmbedded {
+			for end > start && unicode.IsSpace(s.src[end-1]) {
+				end--
+			}
+			full[o] = s.rangeOf(start, end)
+		} else {
+			full[o] = s.rangeOf(s.offset(o.Position), end+1)
+		}
+		name_end := equal
+		for name_end > start && unicode.IsSpace(s.src[name_end-1]) {
+			name_end--
+		}
+		names[o] = s.rangeOf(start, name_end)
+	}
+	options := func(options []*protobuf.Option) {
+		for _, o := range options {
+			option(o)
+		}
+	}
+
+	var visit func(elements []protobuf.Visitee)
+	visit = func(elements []protobuf.Visitee) {
+		for _, e := range elements {
+			switch v := e.(type) {
+			case *protobuf.Syntax:
+				declare(v, v.Position, noName)
+			case *protobuf.Edition:
+				declare(v, v.Position, noName)
+			case *protobuf.Package:
+				declare(v, v.Position, qualifiedNameAfterKeyword)
+			case *protobuf.Import:
+				declare(v, v.Position, noName)
+				// the name is the string literal of the file name
+				for i := s.offset(v.Position); i >= 0 && i < len(s.src) && !(s.code[i] && s.src[i] == ';'); i++ {
+					if s.code[i] || (s.src[i] != '"' && s.src[i] != '\'') {
+						continue
+					}
+					j := i + 1
+					for ; j < len(s.src) && s.src[j] != s.src[i] && s.src[j] != '\n'; j++ {
+						if s.src[j] == '\\' {
+							j++
+						}
+					}
+					names[v] = s.rangeOf(i, min(j+1, len(s.src)))
+					break
+				}
+			case *protobuf.Option:
+				option(v)
+			case *protobuf.Message:
+				if v.IsExtend {
+					declare(v, v.Position, qualifiedNameAfterKeyword)
+				} else {
+					declare(v, v.Position, nameAfterKeyword)
+				}
+				visit(v.Elements)
+			case *protobuf.Group:
+				declare(v, v.Position, nameBeforeEqual)
+				visit(v.Elements)
+			case *protobuf.Enum:
+				declare(v, v.Position, nameAfterKeyword)
+				visit(v.Elements)
+			case *protobuf.EnumField:
+				declare(v, v.Position, nameBeforeEqual)
+				visit(v.Elements)
+			case *protobuf.Oneof:
+				declare(v, v.Position, nameAfterKeyword)
+				visit(v.Elements)
+			case *protobuf.NormalField:
+				declare(v, v.Position, nameBeforeEqual)
+				options(v.Options)
+			case *protobuf.MapField:
+				declare(v, v.Position, nameBeforeEqual)
+				options(v.Options)
+			case *protobuf.OneOfField:
+				declare(v, v.Position, nameBeforeEqual)
+				options(v.Options)
+			case *protobuf.Service:
+				declare(v, v.Position, nameAfterKeyword)
+				visit(v.Elements)
+			case *protobuf.RPC:
+				declare(v, v.Position, nameAfterKeyword)
+				visit(v.Elements)
+			case *protobuf.Reserved:
+				declare(v, v.Position, noName)
+			case *protobuf.Extensions:
+				declare(v, v.Position, noName)
+			}
+		}
+	}
+	visit(p.Elements)
+	return names, full
+}
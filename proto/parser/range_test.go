@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRanges(t *testing.T) {
+	content := `syntax = "proto3";
+package acme.api;
+
+import public "common.proto";
+
+message User { User User = 1 [deprecated = true, (meta) = {
+		name: "user"
+	}];
+	enum Status {
+		ACTIVE = 0;
+	}
+	map<string, Status> status = 2;
+	oneof contact { string email = 3; }
+	option (note) = "a; b";
+}
+
+extend google.protobuf.FieldOptions {
+	string label = 50000;
+}
+
+service Users {
+	rpc Get(User) returns (User);
+}
+`
+	p := parse(t, content)
+	lines := strings.Split(content, "\n")
+	text := func(v protobuf.Visitee, name bool) string {
+		get := p.Range
+		if name {
+			get = p.NameRange
+		}
+		rng, ok := get(v)
+		require.True(t, ok, "%T", v)
+		var res []string
+		for line := rng.Start.Line; line <= rng.End.Line; line++ {
+			line_str := lines[line]
+			if line == rng.End.Line {
+				line_str = line_str[:rng.End.Character]
+			}
+			if line == rng.Start.Line {
+				line_str = line_str[rng.Start.Character:]
+			}
+			res = append(res, line_str)
+		}
+		return strings.Join(res, "\n")
+	}
+
+	elements := p.Protobuf().Elements
+	require.Equal(t, `syntax = "proto3";`, text(elements[0], false))
+	_, ok := p.NameRange(elements[0])
+	require.False(t, ok)
+	require.Equal(t, "acme.api", text(elements[1], true))
+	require.Equal(t, `"common.proto"`, text(elements[2], true))
+	require.Equal(t, `import public "common.proto";`, text(elements[2], false))
+
+	user := elements[3].(*protobuf.Message)
+	require.Equal(t, "User", text(user, true))
+	require.True(t, strings.HasPrefix(text(user, false), "message User {"))
+	require.True(t, strings.HasSuffix(text(user, false), "\"a; b\";\n}"))
+
+	field := user.Elements[0].(*protobuf.NormalField)
+	require.Equal(t, "User", text(field, true))
+	rng, _ := p.NameRange(field)
+	require.Equal(t, uint(20), rng.Start.Character)
+	require.Equal(t, "User User = 1 [deprecated = true, (meta) = {\n\t\tname: \"user\"\n\t}];", text(field, false))
+	require.Equal(t, "deprecated", text(field.Options[0], true))
+	require.Equal(t, "deprecated = true", text(field.Options[0], false))
+	require.Equal(t, "(meta)", text(field.Options[1], true))
+	require.Equal(t, "(meta) = {\n\t\tname: \"user\"\n\t}", text(field.Options[1], false))
+
+	enum := user.Elements[1].(*protobuf.Enum)
+	require.Equal(t, "Status", text(enum, true))
+	require.Equal(t, "ACTIVE", text(enum.Elements[0], true))
+	require.Equal(t, "ACTIVE = 0;", text(enum.Elements[0], false))
+	require.Equal(t, "status", text(user.Elements[2], true))
+
+	oneof := user.Elements[3].(*protobuf.Oneof)
+	require.Equal(t, "contact", text(oneof, true))
+	require.Equal(t, "oneof contact { string email = 3; }", text(oneof, false))
+	require.Equal(t, "email", text(oneof.Elements[0], true))
+	require.Equal(t, "(note)", text(user.Elements[4], true))
+	require.Equal(t, `option (note) = "a; b";`, text(user.Elements[4], false))
+
+	require.Equal(t, "google.protobuf.FieldOptions", text(elements[4], true))
+	rpc := elements[5].(*protobuf.Service).Elements[0]
+	require.Equal(t, "Get", text(rpc, true))
+	require.Equal(t, "rpc Get(User) returns (User);", text(rpc, false))
+}
+
+func TestRangesUTF16(t *testing.T) {
+	// "日本" and "🙂" are 2 runes and 2 UTF-16 code units each
+	p := parse(t, `syntax = "proto2";
+/* 日本 */ message User {
+	optional string note = 1 [default = "🙂"]; optional User owner = 2;
+}
+`)
+	message := p.Messages()[0].Protobuf()
+	rng, ok := p.NameRange(message)
+	require.True(t, ok)
+	require.Equal(t, uint(17), rng.Start.Character)
+	require.Equal(t, uint(21), rng.End.Character)
+
+	rng, ok = p.NameRange(message.Elements[1])
+	require.True(t, ok)
+	require.Equal(t, uint(58), rng.Start.Character)
+	require.Equal(t, uint(63), rng.End.Character)
+
+	_, errs := ParseProtoWithErrors("file:///test.proto", []byte("/* 🙂 */ message User { string = 1; }"))
+	require.Len(t, errs, 1)
+	require.Equal(t, uint(31), errs[0].Range.Start.Character)
+	require.Equal(t, uint(32), errs[0].Range.End.Character)
+}
@@ -0,0 +1,268 @@
+package parser
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+// maxRecoveries bounds how many syntax errors are skipped in a single file.
+const maxRecoveries = 100
+
+var (
+	errorPositionRegexp = regexp.MustCompile(`<input>:(\d+):(\d+):?\s*`)
+	errorFoundRegexp    = regexp.MustCompile(`found "((?:[^"\\]|\\.)*)"`)
+)
+
+// SyntaxError is a syntax error with the range of the offending token.
+type SyntaxError struct {
+	Message string
+	Range   defines.Range
+}
+
+func (e SyntaxError) Error() string {
+	return e.Message
+}
+
+// ParseProtoWithErrors parses data like ParseProto but does not stop at the
+// first syntax error. The statement containing an error is skipped, or the
+// whole block if the error is in the header of a block, and parsing is
+// retried, so the returned Proto holds every declaration that could be parsed.
+// Syntax errors are returned in the order they were found, only the first one
+// at a range is kept: recovering from the end of the source finds errors at
+// the same position again. Their ranges are within data, also for the errors
+// found in the braces recovery appends to close the blocks left open.
+func ParseProtoWithErrors(document_uri defines.DocumentUri, data []byte) (Proto, []SyntaxError) {
+	src := []rune(string(data))
+	size := len(src)
+	var errs []SyntaxError
+	for i := 0; ; i++ {
+		p, err := protobuf.NewParser(strings.NewReader(string(src))).Parse()
+		if err == nil {
+			return newProtoWithSource(document_uri, p, src), errs
+		}
+		syntax_err, offset := newSyntaxError(src, size, err)
+		if !slices.ContainsFunc(errs, func(e SyntaxError) bool { return e.Range == syntax_err.Range }) {
+			errs = append(errs, syntax_err)
+		}
+		if i == maxRecoveries {
+			return nil, errs
+		}
+
+		var ok bool
+		src, ok = skipStatement(src, size, offset)
+		if !ok {
+			return nil, errs
+		}
+	}
+}
+
+// newSyntaxError converts an error of the parser to a SyntaxError and returns
+// the offset of the offending token in src. The range is clamped to the first
+// size runes of src, the ones that were parsed from the file.
+func newSyntaxError(src []rune, size int, err error) (SyntaxError, int) {
+	message := strings.TrimSpace(err.Error())
+	res := SyntaxError{Message: message}
+
+	matches := errorPositionRegexp.FindStringSubmatchIndex(message)
+	if matches == nil {
+		return res, len(src)
+	}
+	line, _ := strconv.Atoi(message[matches[2]:matches[3]])
+	column, _ := strconv.Atoi(message[matches[4]:matches[5]])
+	res.Message = strings.TrimSpace(message[:matches[0]] + message[matches[1]:])
+	// scanner errors read "go scanner error at <position> = <message>"
+	res.Message = strings.Replace(res.Message, " at = ", ": ", 1)
+
+	offset := offsetOf(src, max(line-1, 0), max(column-1, 0))
+	start := min(offset, size)
+	end := start
+	if found := errorFoundRegexp.FindStringSubmatch(message); found != nil {
+		if lit, err := strconv.Unquote(`"` + found[1] + `"`); err == nil && !strings.Contains(lit, "\n") {
+			end = min(start+len([]rune(lit)), size)
+		}
+	}
+	s := newSource(src[:size])
+	res.Range = s.rangeOf(start, end)
+	return res, offset
+}
+
+// offsetOf returns the index in src of the 0-based line and character.
+func offsetOf(src []rune, line, character int) int {
+	offset := 0
+	for line > 0 && offset < len(src) {
+		if src[offset] == '\n' {
+			line--
+		}
+		offset++
+	}
+	return min(offset+character, len(src))
+}
+
+// skipStatement blanks the statement around offset, keeping line breaks so
+// positions of the remaining source do not change. If the source ends inside
+// a block, the block is closed instead, src is the first size runes parsed
+// from the file followed by the braces closed so far. It returns false if no
+// progress can be made.
+func skipStatement(src []rune, size, offset int) ([]rune, bool) {
+	code := codeMask(src)
+	ends := statementEnds(src, code)
+
+	start := offset
+	for start > 0 && !ends[start-1] {
+		start--
+	}
+	end := offset
+	for end < len(src) && !ends[end] {
+		end++
+	}
+
+	switch {
+	case end < len(src) && src[end] == ';':
+		end++
+	case end < len(src) && src[end] == '{':
+		// the header of a block is broken, skip the block
+		end = matchingBrace(src, code, end)
+	case end == len(src) && strings.TrimSpace(string(src[start:end])) == "":
+		// the source ended inside a block, every block open in the file is
+		// closed once
+		closed := (len(src) - size) / 2
+		if braceDepth(src[:size], code[:size]) <= closed {
+			return src, false
+		}
+		return append(src, '\n', '}'), true
+	}
+
+	blanked := false
+	for i := start; i < end; i++ {
+		if src[i] != '\n' && src[i] != ' ' {
+			src[i] = ' '
+			blanked = true
+		}
+	}
+	if !blanked {
+		// an unexpected brace, skip it
+		if offset >= len(src) || !isStatementEnd(src[offset]) {
+			return src, false
+		}
+		src[offset] = ' '
+	}
+	return src, true
+}
+
+func isStatementEnd(r rune) bool {
+	return r == ';' || r == '{' || r == '}'
+}
+
+// statementEnds reports for every rune of src whether it ends a statement.
+// The brackets of inline options are part of the statement, semicolons and
+// braces of aggregate values in them end nothing, also if the bracket is not
+// closed before the block around it.
+func statementEnds(src []rune, code []bool) []bool {
+	ends := make([]bool, len(src))
+	var open []rune
+	in_bracket := func() bool {
+		return len(open) > 0 && open[len(open)-1] == '['
+	}
+	for i, r := range src {
+		if !code[i] {
+			continue
+		}
+		switch r {
+		case '[':
+			open = append(open, r)
+		case ']':
+			if in_bracket() {
+				open = open[:len(open)-1]
+			}
+		case '{':
+			ends[i] = !in_bracket()
+			open = append(open, r)
+		case '}':
+			// brackets left open end with the block
+			for len(open) > 0 && open[len(open)-1] == '[' {
+				open = open[:len(open)-1]
+			}
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			ends[i] = !in_bracket()
+		case ';':
+			ends[i] = !in_bracket()
+		}
+	}
+	return ends
+}
+
+// matchingBrace returns the index after the brace closing the one at open, or
+// len(src) if it is not closed.
+func matchingBrace(src []rune, code []bool, open int) int {
+	depth := 0
+	for i := open; i < len(src); i++ {
+		if !code[i] {
+			continue
+		}
+		switch src[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(src)
+}
+
+// braceDepth returns how many blocks are still open at the end of src.
+func braceDepth(src []rune, code []bool) (depth int) {
+	for i, r := range src {
+		if !code[i] {
+			continue
+		}
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+// codeMask reports for every rune of src whether it is outside of comments and
+// string literals.
+func codeMask(src []rune) []bool {
+	code := make([]bool, len(src))
+	for i := 0; i < len(src); i++ {
+		switch {
+		case src[i] == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			if i < len(src) {
+				code[i] = true
+			}
+		case src[i] == '/' && i+1 < len(src) && src[i+1] == '*':
+			i += 2
+			for i < len(src) && !(src[i-1] == '*' && src[i] == '/') {
+				i++
+			}
+		case src[i] == '"' || src[i] == '\'':
+			quote := src[i]
+			for i++; i < len(src) && src[i] != quote && src[i] != '\n'; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+		default:
+			code[i] = true
+		}
+	}
+	return code
+}
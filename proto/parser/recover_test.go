@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+func TestParseProtoWithErrors(t *testing.T) {
+	proto, errs := ParseProtoWithErrors("file:///test.proto", []byte(`syntax = "proto3";
+package api;
+
+message User {
+	string name = ;
+	int32 age = 2;
+}
+
+message Broken name {
+	string ignored = 1;
+}
+
+enum Status {
+	ACTIVE = 0;
+}
+
+message Typing {
+	string na`))
+
+	require.NotNil(t, proto)
+	var names []string
+	for _, message := range proto.Messages() {
+		names = append(names, message.Protobuf().Name)
+	}
+	require.Equal(t, []string{"User", "Typing"}, names)
+	require.Len(t, proto.Enums(), 1)
+	_, ok := proto.Messages()[0].GetFieldByName("age")
+	require.True(t, ok)
+
+	type syntaxError struct {
+		Start, End defines.Position
+	}
+	var got []syntaxError
+	for _, err := range errs {
+		require.NotContains(t, err.Message, "<input>")
+		got = append(got, syntaxError{err.Range.Start, err.Range.End})
+	}
+	require.Equal(t, []syntaxError{
+		{defines.Position{Line: 4, Character: 15}, defines.Position{Line: 4, Character: 16}},
+		{defines.Position{Line: 8, Character: 15}, defines.Position{Line: 8, Character: 19}},
+		// reported once, although every recovery at the end finds it again
+		{defines.Position{Line: 17, Character: 10}, defines.Position{Line: 17, Character: 10}},
+	}, got)
+}
+
+func TestParseProtoWithErrorsInOptions(t *testing.T) {
+	content := `message A {
+  string name = 1 [deprecated = ;
+  int32 y = 2;
+}
+enum E {
+  E_UNSPECIFIED = 0;
+}
+`
+	proto, errs := ParseProtoWithErrors("file:///test.proto", []byte(content))
+
+	// the statement with the broken option is skipped, up to the end of its
+	// block as the bracket is not closed
+	require.NotNil(t, proto)
+	require.Len(t, proto.Messages(), 1)
+	require.Len(t, proto.Enums(), 1)
+	require.Len(t, errs, 1)
+	require.Equal(t, defines.Range{
+		Start: defines.Position{Line: 2, Character: 2},
+		End:   defines.Position{Line: 2, Character: 7},
+	}, errs[0].Range)
+}
+
+func TestParseProtoWithErrorsAtEnd(t *testing.T) {
+	// the braces closing the blocks are appended once, errors found in them
+	// are at the end of the file
+	_, errs := ParseProtoWithErrors("file:///test.proto", []byte("message A {\n  oneof {\n"))
+	require.Len(t, errs, 2)
+	for _, err := range errs {
+		require.LessOrEqual(t, err.Range.End.Line, uint(2))
+	}
+}
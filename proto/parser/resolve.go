@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"strings"
+)
+
+// Type is a message, an enum or an extension found by a Resolver and the file
+// declaring it.
+type Type struct {
+	Proto     Proto
+	Message   Message
+	Enum      Enum
+	Extension *Extension
+}
+
+// FullyQualifiedName returns the fully qualified name of the message, enum or
+// extension.
+func (t Type) FullyQualifiedName() string {
+	if t.Message != nil {
+		return t.Message.FullyQualifiedName()
+	}
+	if t.Enum != nil {
+		return t.Enum.FullyQualifiedName()
+	}
+	if t.Extension != nil {
+		return t.Extension.Field.FullyQualifiedName
+	}
+	return ""
+}
+
+// Resolver resolves the names of messages, enums and extensions like protoc
+// does, against the declarations of a set of files.
+type Resolver struct {
+	protos []Proto
+}
+
+// NewResolver returns a Resolver for the types of protos, which are usually a
+// file, the files it imports and the files those import publicly.
+func NewResolver(protos ...Proto) *Resolver {
+	return &Resolver{protos: protos}
+}
+
+// Lookup finds the message or enum with the fully qualified name, without a
+// leading dot.
+func (r *Resolver) Lookup(name string) (Type, bool) {
+	for _, p := range r.protos {
+		if p == nil {
+			continue
+		}
+		if m, ok := p.GetMessageByFullyQualifiedName(name); ok {
+			return Type{Proto: p, Message: m}, true
+		}
+		if e, ok := p.GetEnumByFullyQualifiedName(name); ok {
+			return Type{Proto: p, Enum: e}, true
+		}
+	}
+	return Type{}, false
+}
+
+// LookupExtension finds the extension with the fully qualified name, without
+// a leading dot.
+func (r *Resolver) LookupExtension(name string) (Type, bool) {
+	for _, p := range r.protos {
+		if p == nil {
+			continue
+		}
+		if e, ok := p.GetExtensionByFullyQualifiedName(name); ok {
+			return Type{Proto: p, Extension: e}, true
+		}
+	}
+	return Type{}, false
+}
+
+// Resolve resolves name as it is written in scope, the fully qualified name
+// of the message or package it is used in, see Proto.GetScopeByLine.
+//
+// A name with a leading dot is fully qualified. Otherwise the first component
+// of the name is searched in scope and then in its parents, innermost first.
+// The first message or package found that way must contain the rest of the
+// name, e.g. in scope a.b.Outer the name b.User is a.b.User, not b.User, and
+// Inner.Status is a.b.Outer.Inner.Status if Outer declares Inner.
+func (r *Resolver) Resolve(name, scope string) (Type, bool) {
+	return r.resolve(name, scope, r.Lookup)
+}
+
+// ResolveExtension resolves the name of an extension, e.g. of a custom option
+// written in parentheses, as it is written in scope. The scoping rules are
+// the ones of Resolve.
+func (r *Resolver) ResolveExtension(name, scope string) (Type, bool) {
+	return r.resolve(name, scope, r.LookupExtension)
+}
+
+// ResolvePackage resolves name, the package qualifier of a type name as it
+// is written in scope, to the fully qualified name of the package, e.g. api
+// in scope acme.api.User is acme.api. The scoping rules are the ones of
+// Resolve, a message of the same name in a closer scope hides the package.
+func (r *Resolver) ResolvePackage(name, scope string) (string, bool) {
+	if full_name, ok := strings.CutPrefix(name, "."); ok {
+		return full_name, r.isPackage(full_name)
+	}
+	first, rest, qualified := strings.Cut(name, ".")
+	for {
+		candidate := qualifiedName(scope, first)
+		if t, ok := r.Lookup(candidate); ok && t.Message != nil {
+			return "", false
+		}
+		if r.isPackage(candidate) {
+			if qualified {
+				candidate += "." + rest
+			}
+			return candidate, r.isPackage(candidate)
+		}
+		if scope == "" {
+			return "", false
+		}
+		scope = parentScope(scope)
+	}
+}
+
+// resolve resolves name in scope, lookup finds the candidates by their fully
+// qualified names.
+func (r *Resolver) resolve(name, scope string, lookup func(string) (Type, bool)) (Type, bool) {
+	if full_name, ok := strings.CutPrefix(name, "."); ok {
+		return lookup(full_name)
+	}
+	first, rest, qualified := strings.Cut(name, ".")
+	for {
+		candidate := qualifiedName(scope, first)
+		if !qualified {
+			if t, ok := lookup(candidate); ok {
+				return t, true
+			}
+		} else if t, ok := r.Lookup(candidate); ok {
+			if t.Message != nil {
+				return lookup(candidate + "." + rest)
+			}
+			// an enum cannot contain the rest, keep searching
+		} else if r.isPackage(candidate) {
+			return lookup(candidate + "." + rest)
+		}
+		if scope == "" {
+			return Type{}, false
+		}
+		scope = parentScope(scope)
+	}
+}
+
+// isPackage reports whether name is the package of a file or a prefix of one.
+func (r *Resolver) isPackage(name string) bool {
+	for _, p := range r.protos {
+		if p == nil {
+			continue
+		}
+		for _, pkg := range p.Packages() {
+			if pkg.ProtoPackage.Name == name || strings.HasPrefix(pkg.ProtoPackage.Name, name+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parentScope returns the scope enclosing scope, "" for a top level one.
+func parentScope(scope string) string {
+	if i := strings.LastIndex(scope, "."); i >= 0 {
+		return scope[:i]
+	}
+	return ""
+}
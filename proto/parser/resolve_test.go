@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, content string) Proto {
+	t.Helper()
+	p, err := ParseProto("file:///test.proto", strings.NewReader(content))
+	require.NoError(t, err)
+	return p
+}
+
+func TestFullyQualifiedNames(t *testing.T) {
+	p := parse(t, `syntax = "proto3";
+package acme.api;
+
+message User {
+	message Address {
+		string street = 1;
+	}
+	enum Status {
+		ACTIVE = 0;
+	}
+	map<string, Address> addresses = 1;
+	oneof contact {
+		string email = 2;
+	}
+	extend Other {
+		string label = 3;
+	}
+}
+
+service Users {
+	rpc Get(User) returns (User);
+}
+`)
+	user, ok := p.GetMessageByName("User")
+	require.True(t, ok)
+	require.Equal(t, "acme.api.User", user.FullyQualifiedName())
+
+	address, ok := p.GetMessageByFullyQualifiedName("acme.api.User.Address")
+	require.True(t, ok)
+	require.Equal(t, "acme.api.User.Address.street", address.Fields()[0].FullyQualifiedName)
+
+	status, ok := p.GetEnumByFullyQualifiedName("acme.api.User.Status")
+	require.True(t, ok)
+	value, _ := status.GetFieldByName("ACTIVE")
+	require.Equal(t, "acme.api.User.ACTIVE", value.FullyQualifiedName)
+
+	require.Equal(t, "acme.api.User.addresses", user.MapFields()[0].FullyQualifiedName)
+	email, _ := user.Oneofs()[0].GetFieldByName("email")
+	require.Equal(t, "acme.api.User.email", email.FullyQualifiedName)
+
+	var extend Message
+	for _, m := range user.NestedMessages() {
+		if m.Protobuf().IsExtend {
+			extend = m
+		}
+	}
+	require.Empty(t, extend.FullyQualifiedName())
+	require.Equal(t, "acme.api.User.label", extend.Fields()[0].FullyQualifiedName)
+	_, ok = p.GetMessageByFullyQualifiedName("acme.api.User.Other")
+	require.False(t, ok)
+
+	service := p.Services()[0]
+	require.Equal(t, "acme.api.Users", service.FullyQualifiedName())
+	require.Equal(t, "acme.api.Users.Get", service.RPCs()[0].FullyQualifiedName)
+
+	require.Equal(t, "acme.api", p.GetScopeByLine(2))
+	require.Equal(t, "acme.api.User", p.GetScopeByLine(11))
+	require.Equal(t, "acme.api.User.Address", p.GetScopeByLine(6))
+	require.Equal(t, "acme.api.User", p.GetScopeByLine(16))
+	require.Equal(t, "acme.api", p.GetScopeByLine(21))
+}
+
+func TestResolver(t *testing.T) {
+	api := parse(t, `syntax = "proto3";
+package acme.api;
+
+message User {
+	message Status {}
+	message Inner {
+		Status status = 1;
+	}
+}
+
+message Status {}
+`)
+	common := parse(t, `syntax = "proto3";
+package acme.common;
+
+message Status {}
+enum Kind {
+	KIND_UNSPECIFIED = 0;
+}
+`)
+	other := parse(t, `syntax = "proto3";
+package api;
+
+message User {}
+`)
+	r := NewResolver(api, common, other)
+
+	resolve := func(name, scope string) string {
+		t, ok := r.Resolve(name, scope)
+		if !ok {
+			return ""
+		}
+		return t.FullyQualifiedName()
+	}
+	// innermost scope first
+	require.Equal(t, "acme.api.User.Status", resolve("Status", "acme.api.User.Inner"))
+	require.Equal(t, "acme.api.Status", resolve("Status", "acme.api"))
+	require.Equal(t, "acme.api.Status", resolve(".acme.api.Status", "acme.api.User.Inner"))
+	// partial package qualifiers
+	require.Equal(t, "acme.common.Status", resolve("common.Status", "acme.api.User"))
+	require.Equal(t, "acme.common.Kind", resolve("acme.common.Kind", "acme.api"))
+	// api resolves to the package acme.api, which hides the package api
+	require.Equal(t, "acme.api.User", resolve("api.User", "acme.api"))
+	require.Empty(t, resolve("api.Missing", "acme.api"))
+	require.Equal(t, "api.User", resolve(".api.User", "acme.api"))
+	// nested types relative to a message in scope
+	require.Equal(t, "acme.api.User.Status", resolve("User.Status", "acme.api"))
+	require.Empty(t, resolve("User.Missing", "acme.api"))
+	require.Empty(t, resolve("Missing", "acme.api"))
+	// enums cannot contain the rest of a name
+	require.Empty(t, resolve("Kind.Status", "acme.common"))
+
+	resolvePackage := func(name, scope string) string {
+		pkg, ok := r.ResolvePackage(name, scope)
+		if !ok {
+			return ""
+		}
+		return pkg
+	}
+	require.Equal(t, "acme.api", resolvePackage("api", "acme.api.User"))
+	require.Equal(t, "acme.common", resolvePackage("common", "acme.api"))
+	require.Equal(t, "acme.common", resolvePackage("acme.common", "acme.api"))
+	require.Equal(t, "api", resolvePackage(".api", "acme.api"))
+	// a prefix of packages
+	require.Equal(t, "acme", resolvePackage("acme", "acme.api"))
+	require.Empty(t, resolvePackage("acme.missing", "acme.api"))
+	// a message hides the package
+	require.Empty(t, resolvePackage("User", "acme.api"))
+
+	found, ok := r.Resolve("Kind", "acme.common")
+	require.True(t, ok)
+	require.Equal(t, common, found.Proto)
+	require.NotNil(t, found.Enum)
+}
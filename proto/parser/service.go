@@ -9,6 +9,9 @@ import (
 // Service is a registry for protobuf service.
 type Service interface {
 	Protobuf() *protobuf.Service
+	// FullyQualifiedName returns the name including the package, e.g.
+	// common.Users.
+	FullyQualifiedName() string
 
 	RPCs() []*RPC
 
@@ -20,6 +23,8 @@ type Service interface {
 type service struct {
 	protoService *protobuf.Service
 
+	fullyQualifiedName string
+
 	rpcs []*RPC
 
 	rpcNameToRPC map[string]*RPC
@@ -65,6 +70,25 @@ func (s *service) Protobuf() *protobuf.Service {
 	return s.protoService
 }
 
+// FullyQualifiedName returns the fully qualified name of the service.
+func (s *service) FullyQualifiedName() (name string) {
+	s.mu.RLock()
+	name = s.fullyQualifiedName
+	s.mu.RUnlock()
+	return
+}
+
+// setFullyQualifiedName names s and its rpcs, scope is the package.
+func (s *service) setFullyQualifiedName(scope string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fullyQualifiedName = qualifiedName(scope, s.protoService.Name)
+	for _, r := range s.rpcs {
+		r.FullyQualifiedName = qualifiedName(s.fullyQualifiedName, r.ProtoRPC.Name)
+	}
+}
+
 // RPCs returns slice of RPC.
 func (s *service) RPCs() (rpcs []*RPC) {
 	s.mu.RLock()
@@ -94,6 +118,9 @@ func (s *service) GetRPCByLine(line int) (r *RPC, ok bool) {
 // RPC is a registry for protobuf rpc.
 type RPC struct {
 	ProtoRPC *protobuf.RPC
+	// FullyQualifiedName is the name including the service, e.g.
+	// common.Users.Get.
+	FullyQualifiedName string
 }
 
 // NewRPC returns RPC initialized by provided *protobuf.RPC.
@@ -38,3 +38,92 @@ var BuildInProtoTypes = []ProtoType{
 	String,
 	Bytes,
 }
+
+// ScalarType documents how a scalar type is encoded and the types it maps to
+// in generated code.
+type ScalarType struct {
+	// WireType is the wire type of the encoded value, ZigZag encoded for
+	// sint32 and sint64.
+	WireType string
+	// Range are the values the type can hold.
+	Range string
+	// Notes are hints on when to use the type.
+	Notes string
+
+	Go     string
+	Java   string
+	Cpp    string
+	Python string
+}
+
+// https://protobuf.dev/programming-guides/proto3/#scalar
+var ScalarTypes = map[ProtoType]ScalarType{
+	Double: {
+		WireType: "I64", Range: "64-bit IEEE 754 floating point",
+		Go: "float64", Java: "double", Cpp: "double", Python: "float",
+	},
+	Float: {
+		WireType: "I32", Range: "32-bit IEEE 754 floating point",
+		Go: "float32", Java: "float", Cpp: "float", Python: "float",
+	},
+	Int32: {
+		WireType: "VARINT", Range: "-2^31 to 2^31-1",
+		Notes: "Negative numbers always take 10 bytes, use sint32 if the field is likely to have negative values.",
+		Go:    "int32", Java: "int", Cpp: "int32_t", Python: "int",
+	},
+	Int64: {
+		WireType: "VARINT", Range: "-2^63 to 2^63-1",
+		Notes: "Negative numbers always take 10 bytes, use sint64 if the field is likely to have negative values.",
+		Go:    "int64", Java: "long", Cpp: "int64_t", Python: "int",
+	},
+	Uint32: {
+		WireType: "VARINT", Range: "0 to 2^32-1",
+		Go: "uint32", Java: "int", Cpp: "uint32_t", Python: "int",
+	},
+	Uint64: {
+		WireType: "VARINT", Range: "0 to 2^64-1",
+		Go: "uint64", Java: "long", Cpp: "uint64_t", Python: "int",
+	},
+	Sint32: {
+		WireType: "VARINT (ZigZag)", Range: "-2^31 to 2^31-1",
+		Notes: "Encodes negative numbers more efficiently than int32.",
+		Go:    "int32", Java: "int", Cpp: "int32_t", Python: "int",
+	},
+	Sint64: {
+		WireType: "VARINT (ZigZag)", Range: "-2^63 to 2^63-1",
+		Notes: "Encodes negative numbers more efficiently than int64.",
+		Go:    "int64", Java: "long", Cpp: "int64_t", Python: "int",
+	},
+	Fixed32: {
+		WireType: "I32", Range: "0 to 2^32-1",
+		Notes: "Always four bytes, more efficient than uint32 if values are often greater than 2^28.",
+		Go:    "uint32", Java: "int", Cpp: "uint32_t", Python: "int",
+	},
+	Fixed64: {
+		WireType: "I64", Range: "0 to 2^64-1",
+		Notes: "Always eight bytes, more efficient than uint64 if values are often greater than 2^56.",
+		Go:    "uint64", Java: "long", Cpp: "uint64_t", Python: "int",
+	},
+	Sfixed32: {
+		WireType: "I32", Range: "-2^31 to 2^31-1",
+		Notes: "Always four bytes.",
+		Go:    "int32", Java: "int", Cpp: "int32_t", Python: "int",
+	},
+	Sfixed64: {
+		WireType: "I64", Range: "-2^63 to 2^63-1",
+		Notes: "Always eight bytes.",
+		Go:    "int64", Java: "long", Cpp: "int64_t", Python: "int",
+	},
+	Bool: {
+		WireType: "VARINT", Range: "true or false",
+		Go: "bool", Java: "boolean", Cpp: "bool", Python: "bool",
+	},
+	String: {
+		WireType: "LEN", Range: "UTF-8 encoded or 7-bit ASCII text, at most 2^32 bytes",
+		Go: "string", Java: "String", Cpp: "std::string", Python: "str",
+	},
+	Bytes: {
+		WireType: "LEN", Range: "any sequence of bytes, at most 2^32 bytes",
+		Go: "[]byte", Java: "ByteString", Cpp: "std::string", Python: "bytes",
+	},
+}
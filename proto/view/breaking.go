@@ -0,0 +1,137 @@
+package view
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.lsp.dev/uri"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
+	"github.com/walteh/protobuf-language-server/proto/breaking"
+)
+
+// Breaking compares proto_file with its version in the baseline of the
+// settings, see BreakingSettings. Files that are not in the baseline are new
+// and break nothing.
+func (v *View) Breaking(proto_file ProtoFile) []breaking.Problem {
+	settings := v.loadSettings().Breaking
+	if settings == nil || proto_file.Proto() == nil {
+		return nil
+	}
+	filename := uri.URI(proto_file.URI()).Filename()
+	base, err := v.breakingBaseline(filename, *settings)
+	if err != nil {
+		logs.Printf("read breaking baseline of %s err: %v", filename, err)
+		return nil
+	}
+	if base == nil {
+		return nil
+	}
+	return breaking.Compare(base, breaking.FromProto(proto_file.Proto().Protobuf()))
+}
+
+// breakingBaseline returns the schema of filename in the baseline, nil if it
+// is not part of it.
+func (v *View) breakingBaseline(filename string, settings BreakingSettings) (*breaking.File, error) {
+	repo, repo_err := v.gitRepo(filepath.Dir(filename))
+	switch {
+	case settings.AgainstDescriptorSet != "":
+		set_name := settings.AgainstDescriptorSet
+		if !filepath.IsAbs(set_name) && repo != nil {
+			set_name = filepath.Join(repo.Root, set_name)
+		}
+		files, err := v.descriptorSet(set_name)
+		if err != nil {
+			return nil, err
+		}
+		// the files of the set are named relative to their import root, the
+		// longest name that filename ends with is the one
+		best := ""
+		for name := range files {
+			if (filename == name || strings.HasSuffix(filename, "/"+name)) && len(name) > len(best) {
+				best = name
+			}
+		}
+		return files[best], nil
+
+	case settings.AgainstGitRef != "":
+		if repo_err != nil {
+			return nil, repo_err
+		}
+		commit, err := repo.Resolve(settings.AgainstGitRef)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(repo.Root, filename)
+		if err != nil {
+			return nil, err
+		}
+		key := commit + ":" + filepath.ToSlash(rel)
+		if base, ok := v.breakingBaselines.Load(key); ok {
+			return base.(*breaking.File), nil
+		}
+		var base *breaking.File
+		data, err := repo.ReadFile(commit, filepath.ToSlash(rel))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, err
+		default:
+			if base, err = breaking.ParseFile(data); err != nil {
+				return nil, err
+			}
+		}
+		v.breakingBaselines.Store(key, base)
+		return base, nil
+	}
+	return nil, nil
+}
+
+// gitRepo returns the git repository dir is part of, the repositories are
+// cached to keep their pack indexes.
+func (v *View) gitRepo(dir string) (*breaking.Repo, error) {
+	if repo, ok := v.gitRepos.Load(dir); ok {
+		return repo.(*breaking.Repo), nil
+	}
+	repo, err := breaking.OpenRepo(v.fs, dir)
+	if err != nil {
+		return nil, err
+	}
+	if cached, loaded := v.gitRepos.LoadOrStore(repo.Root, repo); loaded {
+		repo = cached.(*breaking.Repo)
+	}
+	v.gitRepos.Store(dir, repo)
+	return repo, nil
+}
+
+type descriptorSet struct {
+	modTime time.Time
+	size    int64
+	files   map[string]*breaking.File
+}
+
+// descriptorSet returns the schemas of the descriptor set filename, it is
+// read from the file system of the view again when it changes.
+func (v *View) descriptorSet(filename string) (map[string]*breaking.File, error) {
+	filename = filepath.ToSlash(filename)
+	info, err := v.fs.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if set, ok := v.descriptorSets.Load(filename); ok && set.(descriptorSet).modTime.Equal(info.ModTime()) && set.(descriptorSet).size == info.Size() {
+		return set.(descriptorSet).files, nil
+	}
+	data, err := v.fs.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	files, err := breaking.FromDescriptorSet(data)
+	if err != nil {
+		return nil, err
+	}
+	v.descriptorSets.Store(filename, descriptorSet{info.ModTime(), info.Size(), files})
+	return files, nil
+}
@@ -0,0 +1,90 @@
+package view
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.lsp.dev/uri"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/view/fs"
+)
+
+func Test_view_Breaking(t *testing.T) {
+	logs.Init(nil)
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	filename := filepath.Join(dir, "proto", "acme", "user.proto")
+	require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0o755))
+	require.NoError(t, os.WriteFile(filename, []byte("syntax = \"proto3\";\npackage acme;\nmessage User {\n  string name = 1;\n}\n"), 0o644))
+	git("init", "-q", "-b", "main")
+	git("add", "-A")
+	git("commit", "-q", "-m", "init")
+
+	set, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    proto.String("acme/user.proto"),
+		Package: proto.String("acme"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("User"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:   proto.String("name"),
+				Number: proto.Int32(1),
+				Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:   descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum(),
+			}},
+		}},
+	}}})
+	require.NoError(t, err)
+	// the descriptor set and the repository are read from the file system of
+	// the view
+	disk := fs.LayeredFS{fs.NewMapFS(map[string]string{filepath.ToSlash(filepath.Join(dir, "image.binpb")): string(set)}), &fs.RealFS{}}
+
+	data := []byte("syntax = \"proto3\";\npackage acme;\nmessage User {\n  repeated string name = 1;\n}\n")
+	document_uri := defines.DocumentUri(uri.File(filename))
+	parsed, errs := parseProto(document_uri, data)
+	require.Empty(t, errs)
+	proto_file := &protoFile{File: &file{document_uri: document_uri, data: data}, proto: parsed}
+
+	tests := []struct {
+		name     string
+		settings BreakingSettings
+		want     []string
+	}{
+		{"no baseline", BreakingSettings{}, nil},
+		{"git ref", BreakingSettings{AgainstGitRef: "main"}, []string{"FIELD_SAME_CARDINALITY"}},
+		{"descriptor set", BreakingSettings{AgainstDescriptorSet: "image.binpb"}, []string{"FIELD_SAME_TYPE", "FIELD_SAME_CARDINALITY"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &View{fs: disk}
+			v.storeSettings(Settings{Breaking: &tt.settings})
+			var got []string
+			for _, problem := range v.Breaking(proto_file) {
+				got = append(got, problem.Rule)
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	// files that are new have no baseline
+	new_uri := defines.DocumentUri(uri.File(filepath.Join(dir, "proto", "acme", "new.proto")))
+	new_file := &protoFile{File: &file{document_uri: new_uri, data: data}, proto: parsed}
+	v := &View{fs: fs.NewMapFS(nil)}
+	v.storeSettings(Settings{Breaking: &BreakingSettings{AgainstGitRef: "main"}})
+	require.Empty(t, v.Breaking(new_file))
+}
@@ -0,0 +1,323 @@
+package view
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v3"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/include"
+	"github.com/walteh/protobuf-language-server/proto/lint"
+	"github.com/walteh/protobuf-language-server/proto/parser"
+)
+
+const (
+	bufYAMLName     = "buf.yaml"
+	bufWorkYAMLName = "buf.work.yaml"
+)
+
+// bufYAML is a buf.yaml of any version. v1beta1 declares import roots in
+// build.roots, v1 uses the directory of the file and v2 lists modules.
+type bufYAML struct {
+	Version string `yaml:"version"`
+	Build   struct {
+		Roots []string `yaml:"roots"`
+	} `yaml:"build"`
+	Modules []struct {
+		Path string       `yaml:"path"`
+		Lint *bufLintYAML `yaml:"lint"`
+	} `yaml:"modules"`
+	Lint *bufLintYAML `yaml:"lint"`
+}
+
+// bufLintYAML is the lint section of a buf.yaml. Comment ignores are opt-in
+// before v2 and opt-out since.
+type bufLintYAML struct {
+	lint.Config            `yaml:",inline"`
+	AllowCommentIgnores    bool `yaml:"allow_comment_ignores"`
+	DisallowCommentIgnores bool `yaml:"disallow_comment_ignores"`
+}
+
+// bufWorkYAML is a buf.work.yaml listing the module directories of a v1
+// workspace.
+type bufWorkYAML struct {
+	Version     string   `yaml:"version"`
+	Directories []string `yaml:"directories"`
+}
+
+// bufImportRoots returns the import roots of the buf module or workspace dir
+// belongs to, or nil if dir is not part of one. The result is cached per
+// directory, see resetBufConfig.
+func (v *View) bufImportRoots(dir string) []string {
+	dir = path.Clean(dir)
+	if roots, ok := v.bufRoots.Load(dir); ok {
+		return roots.([]string)
+	}
+	roots := v.findBufImportRoots(dir)
+	if !within(dir, roots) {
+		// dir is not part of any module of the workspace
+		roots = nil
+	}
+	v.bufRoots.Store(dir, roots)
+	return roots
+}
+
+// resetBufConfig drops the cached buf configuration, it must be called when a
+// buf.yaml or buf.work.yaml changes.
+func (v *View) resetBufConfig() {
+	for _, cache := range []*sync.Map{&v.bufRoots, &v.bufLint} {
+		cache.Range(func(key, _ interface{}) bool {
+			cache.Delete(key)
+			return true
+		})
+	}
+}
+
+func (v *View) findBufImportRoots(dir string) []string {
+	// the roots of the nearest v1 module, used unless a workspace includes it
+	var module_roots []string
+	for d := dir; ; d = path.Dir(d) {
+		if data, err := v.fs.ReadFile(path.Join(d, bufWorkYAMLName)); err == nil {
+			var work bufWorkYAML
+			if err := yaml.Unmarshal(data, &work); err != nil {
+				logs.Printf("parse %s err: %v", path.Join(d, bufWorkYAMLName), err)
+			} else {
+				var roots []string
+				for _, directory := range work.Directories {
+					module_dir := path.Join(d, directory)
+					config, _ := v.readBufYAML(module_dir)
+					roots = append(roots, config.roots(module_dir)...)
+				}
+				return roots
+			}
+		}
+		if config, ok := v.readBufYAML(d); ok {
+			if config.Version == "v2" {
+				// v2 configuration is always at the root of the workspace
+				return config.roots(d)
+			}
+			if module_roots == nil {
+				module_roots = config.roots(d)
+			}
+		}
+		if d == "/" || d == "." {
+			return module_roots
+		}
+	}
+}
+
+func (v *View) readBufYAML(dir string) (config bufYAML, ok bool) {
+	filename := path.Join(dir, bufYAMLName)
+	data, err := v.fs.ReadFile(filename)
+	if err != nil {
+		return config, false
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		logs.Printf("parse %s err: %v", filename, err)
+		return config, false
+	}
+	return config, true
+}
+
+// roots returns the import roots of the module configured in dir.
+func (c bufYAML) roots(dir string) (roots []string) {
+	switch {
+	case c.Version == "v2" && len(c.Modules) > 0:
+		for _, module := range c.Modules {
+			roots = append(roots, path.Join(dir, module.Path))
+		}
+	case len(c.Build.Roots) > 0:
+		for _, root := range c.Build.Roots {
+			roots = append(roots, path.Join(dir, root))
+		}
+	default:
+		roots = append(roots, dir)
+	}
+	return roots
+}
+
+// bufLint is the lint configuration of a buf module and its import root.
+type bufLint struct {
+	config lint.Config
+	root   string
+	ok     bool
+}
+
+// bufLintConfig returns the lint configuration of the buf module dir belongs
+// to and the import root of the module, ok is false if dir is not part of a
+// module. The result is cached per directory, see resetBufConfig.
+func (v *View) bufLintConfig(dir string) (config lint.Config, root string, ok bool) {
+	dir = path.Clean(dir)
+	if res, ok := v.bufLint.Load(dir); ok {
+		res := res.(bufLint)
+		return res.config, res.root, res.ok
+	}
+	res := v.findBufLintConfig(dir)
+	v.bufLint.Store(dir, res)
+	return res.config, res.root, res.ok
+}
+
+func (v *View) findBufLintConfig(dir string) bufLint {
+	for d := dir; ; d = path.Dir(d) {
+		if config, ok := v.readBufYAML(d); ok {
+			switch {
+			case config.Version == "v2" && len(config.Modules) > 0:
+				// paths of a v2 configuration are relative to its directory
+				for _, module := range config.Modules {
+					root := path.Join(d, module.Path)
+					if within(dir, []string{root}) {
+						section := config.Lint
+						if module.Lint != nil {
+							section = module.Lint
+						}
+						return bufLint{section.config(config.Version, d), root, true}
+					}
+				}
+			default:
+				for _, root := range config.roots(d) {
+					if within(dir, []string{root}) {
+						return bufLint{config.Lint.config(config.Version, root), root, true}
+					}
+				}
+			}
+			return bufLint{}
+		}
+		if data, err := v.fs.ReadFile(path.Join(d, bufWorkYAMLName)); err == nil {
+			// a workspace directory without buf.yaml is a module with the
+			// default configuration
+			var work bufWorkYAML
+			if err := yaml.Unmarshal(data, &work); err == nil {
+				for _, directory := range work.Directories {
+					root := path.Join(d, directory)
+					if within(dir, []string{root}) {
+						return bufLint{(*bufLintYAML)(nil).config(work.Version, root), root, true}
+					}
+				}
+			}
+			return bufLint{}
+		}
+		if d == "/" || d == "." {
+			return bufLint{}
+		}
+	}
+}
+
+// config returns the lint configuration of the section, whose relative
+// ignore paths are relative to dir.
+func (c *bufLintYAML) config(version, dir string) lint.Config {
+	var config lint.Config
+	if c != nil {
+		config = c.Config
+	}
+	if version == "v2" {
+		config.AllowCommentIgnores = c == nil || !c.DisallowCommentIgnores
+	} else {
+		config.AllowCommentIgnores = c != nil && c.AllowCommentIgnores
+	}
+
+	abs := func(paths []string) (res []string) {
+		for _, p := range paths {
+			res = append(res, path.Join(dir, p))
+		}
+		return res
+	}
+	config.Ignore = abs(config.Ignore)
+	ignore_only := make(map[string][]string)
+	for id, paths := range config.IgnoreOnly {
+		ignore_only[id] = abs(paths)
+	}
+	config.IgnoreOnly = ignore_only
+	return config
+}
+
+// within reports whether dir is one of roots or inside of one.
+func within(dir string, roots []string) bool {
+	for _, root := range roots {
+		if dir == root || strings.HasPrefix(dir, strings.TrimSuffix(root, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// importDiagnostics reports imports of proto_file that resolve to files in
+// several buf import roots, and imports whose resolution hides a file that
+// would be found next to the importing file or in an additional proto dir.
+func (v *View) importDiagnostics(proto_file ProtoFile) (res []defines.Diagnostic) {
+	for _, i := range proto_file.Proto().Imports() {
+		candidates, shadowed := v.importCandidates(proto_file.URI(), i.ProtoImport.Filename)
+		message := ""
+		severity := defines.DiagnosticSeverityWarning
+		switch {
+		case len(candidates) > 1:
+			severity = defines.DiagnosticSeverityError
+			message = fmt.Sprintf("import %q is ambiguous, it is found in %s", i.ProtoImport.Filename, strings.Join(candidates, ", "))
+		case shadowed != "":
+			message = fmt.Sprintf("import %q resolves to %s, which shadows %s", i.ProtoImport.Filename, candidates[0], shadowed)
+		default:
+			continue
+		}
+
+		pos := i.ProtoImport.Position
+		line_str := proto_file.ReadLine(pos.Line - 1)
+		start := parser.ColumnOffset(line_str, pos.Column-1)
+		end := len(line_str)
+		quoted := fmt.Sprintf("%q", i.ProtoImport.Filename)
+		if idx := strings.Index(line_str, quoted); idx >= 0 {
+			start, end = idx, idx+len(quoted)
+		}
+		res = append(res, defines.Diagnostic{
+			Range: defines.Range{
+				Start: defines.Position{Line: uint(pos.Line - 1), Character: parser.UTF16Character(line_str, start)},
+				End:   defines.Position{Line: uint(pos.Line - 1), Character: parser.UTF16Character(line_str, end)},
+			},
+			Severity: &severity,
+			Message:  message,
+		})
+	}
+	return res
+}
+
+// importCandidates returns the files import_name may refer to from cwd, the
+// first one is used. Inside a buf module only its import roots are searched
+// and shadowed is the file the directory based lookup would have used instead.
+// The embedded protos are used if nothing else is found.
+func (v *View) importCandidates(cwd defines.DocumentUri, import_name string) (candidates []string, shadowed string) {
+	dir := path.Dir(uri.URI(cwd).Filename())
+	fallback := v.searchParentDirs(dir, import_name)
+
+	roots := v.bufImportRoots(dir)
+	if roots == nil {
+		if fallback != "" {
+			candidates = append(candidates, fallback)
+		}
+	} else {
+		for _, root := range roots {
+			abs_name := path.Join(root, import_name)
+			if v.fs.FileExists(abs_name) {
+				candidates = append(candidates, abs_name)
+			}
+		}
+		if len(candidates) > 0 && fallback != "" && fallback != candidates[0] {
+			shadowed = fallback
+		}
+	}
+
+	if len(candidates) == 0 && v.includeRoot != "" {
+		abs_name := path.Join(v.includeRoot, import_name)
+		if v.fs.FileExists(abs_name) {
+			v.extractIncludes.Do(func() {
+				if err := include.Extract(v.disk, v.includeRoot); err != nil {
+					logs.Printf("extract embedded protos err: %v", err)
+				}
+			})
+			candidates = append(candidates, abs_name)
+		}
+	}
+	return candidates, shadowed
+}
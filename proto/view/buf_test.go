@@ -0,0 +1,214 @@
+package view
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/view/fs"
+)
+
+func Test_view_GetDocumentUriFromImportPath_buf(t *testing.T) {
+	logs.Init(nil)
+	tests := []struct {
+		name        string
+		contents    map[string]string
+		cwd         defines.DocumentUri
+		import_name string
+		want        defines.DocumentUri
+		wantErr     error
+	}{
+		{
+			name: "buf.yaml v1 uses the module directory as root",
+			contents: map[string]string{
+				"/repo/proto/buf.yaml":           "version: v1\n",
+				"/repo/proto/acme/v1/user.proto": "",
+				"/repo/proto/acme/v1/api.proto":  "",
+			},
+			cwd:         "file:///repo/proto/acme/v1/api.proto",
+			import_name: "acme/v1/user.proto",
+			want:        "file:///repo/proto/acme/v1/user.proto",
+		},
+		{
+			name: "buf.yaml v1beta1 uses build roots",
+			contents: map[string]string{
+				"/repo/buf.yaml":                "version: v1beta1\nbuild:\n  roots:\n    - src\n",
+				"/repo/src/acme/v1/user.proto":  "",
+				"/repo/acme/v1/user.proto":      "",
+				"/repo/src/acme/v1/api.proto":   "",
+				"/repo/src/acme/v1/other.proto": "",
+			},
+			cwd:         "file:///repo/src/acme/v1/api.proto",
+			import_name: "acme/v1/user.proto",
+			want:        "file:///repo/src/acme/v1/user.proto",
+		},
+		{
+			name: "buf.yaml v2 resolves across modules",
+			contents: map[string]string{
+				"/repo/buf.yaml":                    "version: v2\nmodules:\n  - path: proto\n  - path: vendor/googleapis\n",
+				"/repo/proto/acme/api.proto":        "",
+				"/repo/vendor/googleapis/a/b.proto": "",
+			},
+			cwd:         "file:///repo/proto/acme/api.proto",
+			import_name: "a/b.proto",
+			want:        "file:///repo/vendor/googleapis/a/b.proto",
+		},
+		{
+			name: "buf.work.yaml resolves across directories",
+			contents: map[string]string{
+				"/repo/buf.work.yaml":          "version: v1\ndirectories:\n  - proto\n  - third_party\n",
+				"/repo/proto/buf.yaml":         "version: v1\n",
+				"/repo/proto/acme/api.proto":   "",
+				"/repo/third_party/x/y.proto":  "",
+				"/repo/proto/x/not_root.proto": "",
+			},
+			cwd:         "file:///repo/proto/acme/api.proto",
+			import_name: "x/y.proto",
+			want:        "file:///repo/third_party/x/y.proto",
+		},
+		{
+			name: "parent directories are not searched inside a module",
+			contents: map[string]string{
+				"/repo/proto/buf.yaml":       "version: v1\n",
+				"/repo/proto/acme/api.proto": "",
+				"/repo/common/c.proto":       "",
+			},
+			cwd:         "file:///repo/proto/acme/api.proto",
+			import_name: "common/c.proto",
+			wantErr:     ErrNotFound,
+		},
+		{
+			name: "files outside of the workspace modules use parent directories",
+			contents: map[string]string{
+				"/repo/buf.work.yaml":      "version: v1\ndirectories:\n  - proto\n",
+				"/repo/scratch/api.proto":  "",
+				"/repo/scratch/c/c.proto":  "",
+				"/repo/proto/c/c.proto":    "",
+				"/repo/proto/acme/a.proto": "",
+			},
+			cwd:         "file:///repo/scratch/api.proto",
+			import_name: "c/c.proto",
+			want:        "file:///repo/scratch/c/c.proto",
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			v := &View{fs: fs.NewMapFS(tt.contents)}
+
+			got, err := v.GetDocumentUriFromImportPath(tt.cwd, tt.import_name)
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_view_importDiagnostics(t *testing.T) {
+	logs.Init(nil)
+	data := []byte(`syntax = "proto3";
+import "x/y.proto";
+import "acme/user.proto";
+import "acme/group.proto";
+`)
+	v := &View{fs: fs.NewMapFS(map[string]string{
+		"/repo/buf.yaml":                   "version: v2\nmodules:\n  - path: proto\n  - path: vendor\n",
+		"/repo/proto/x/y.proto":            "",
+		"/repo/vendor/x/y.proto":           "",
+		"/repo/proto/acme/user.proto":      "",
+		"/repo/proto/acme/acme/user.proto": "",
+		"/repo/proto/acme/group.proto":     "",
+	})}
+	proto, errs := parseProto("file:///repo/proto/acme/api.proto", data)
+	require.Empty(t, errs)
+	proto_file := &protoFile{
+		File:  &file{document_uri: "file:///repo/proto/acme/api.proto", data: data},
+		proto: proto,
+	}
+
+	diagnostics := v.importDiagnostics(proto_file)
+	require.Len(t, diagnostics, 2)
+
+	require.Equal(t, defines.DiagnosticSeverityError, *diagnostics[0].Severity)
+	require.Equal(t, defines.Range{
+		Start: defines.Position{Line: 1, Character: 7},
+		End:   defines.Position{Line: 1, Character: 18},
+	}, diagnostics[0].Range)
+	require.Contains(t, diagnostics[0].Message, "ambiguous")
+
+	require.Equal(t, defines.DiagnosticSeverityWarning, *diagnostics[1].Severity)
+	require.Equal(t, uint(2), diagnostics[1].Range.Start.Line)
+	require.Contains(t, diagnostics[1].Message, "shadows /repo/proto/acme/acme/user.proto")
+}
+
+func Test_view_Lint(t *testing.T) {
+	logs.Init(nil)
+	data := []byte("syntax = \"proto3\";\npackage acme.v1;\n\n// buf:lint:ignore MESSAGE_PASCAL_CASE\nmessage user {}\n")
+	tests := []struct {
+		name     string
+		contents map[string]string
+		settings interface{}
+		want     []string
+	}{
+		{
+			name:     "files outside of buf modules are not linted by default",
+			contents: map[string]string{},
+			want:     nil,
+		},
+		{
+			name:     "settings enable lint outside of buf modules",
+			contents: map[string]string{},
+			settings: map[string]interface{}{"lint": map[string]interface{}{"enabled": true, "use": []interface{}{"BASIC"}, "allow_comment_ignores": false}},
+			want:     []string{"MESSAGE_PASCAL_CASE"},
+		},
+		{
+			name:     "buf.yaml v1 checks the package directory against the module root",
+			contents: map[string]string{"/repo/proto/buf.yaml": "version: v1\n"},
+			want:     []string{"PACKAGE_DIRECTORY_MATCH", "MESSAGE_PASCAL_CASE"},
+		},
+		{
+			name:     "buf.yaml v1 lint section",
+			contents: map[string]string{"/repo/proto/buf.yaml": "version: v1\nlint:\n  use: [DEFAULT]\n  except: [PACKAGE_DIRECTORY_MATCH]\n  allow_comment_ignores: true\n"},
+			want:     nil,
+		},
+		{
+			name:     "buf.yaml v2 module lint section wins over the workspace one",
+			contents: map[string]string{"/repo/buf.yaml": "version: v2\nmodules:\n  - path: proto\n    lint:\n      use: [MINIMAL]\nlint:\n  use: [STANDARD]\n"},
+			want:     []string{"PACKAGE_DIRECTORY_MATCH"},
+		},
+		{
+			name:     "buf.yaml v2 ignores are relative to the workspace",
+			contents: map[string]string{"/repo/buf.yaml": "version: v2\nmodules:\n  - path: proto\nlint:\n  ignore_only:\n    PACKAGE_DIRECTORY_MATCH: [proto/acme]\n"},
+			want:     nil,
+		},
+		{
+			name:     "settings can disable lint",
+			contents: map[string]string{"/repo/proto/buf.yaml": "version: v1\n"},
+			settings: map[string]interface{}{"lint": map[string]interface{}{"enabled": false}},
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &View{fs: fs.NewMapFS(tt.contents)}
+			if tt.settings != nil {
+				settings, err := SettingsFromInterface(tt.settings)
+				require.NoError(t, err)
+				v.storeSettings(*settings)
+			}
+			proto, errs := parseProto("file:///repo/proto/acme/api.proto", data)
+			require.Empty(t, errs)
+			proto_file := &protoFile{
+				File:  &file{document_uri: "file:///repo/proto/acme/api.proto", data: data},
+				proto: proto,
+			}
+
+			var got []string
+			for _, problem := range v.Lint(proto_file) {
+				got = append(got, problem.Rule)
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
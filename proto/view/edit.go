@@ -0,0 +1,69 @@
+package view
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/parser"
+)
+
+var ErrInvalidContentChange = errors.New("invalid content change")
+
+// applyContentChanges applies the changes of a didChange notification to data
+// in order. A change without a range replaces the whole content.
+func applyContentChanges(data []byte, changes []defines.TextDocumentContentChangeEvent) ([]byte, error) {
+	for _, change := range changes {
+		text, ok := change.Text.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: text should be a string", ErrInvalidContentChange)
+		}
+		if change.Range == nil {
+			data = []byte(text)
+			continue
+		}
+		start, err := offsetOfPosition(data, change.Range.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := offsetOfPosition(data, change.Range.End)
+		if err != nil {
+			return nil, err
+		}
+		if end < start {
+			return nil, fmt.Errorf("%w: range end %v is before start %v", ErrInvalidContentChange, change.Range.End, change.Range.Start)
+		}
+		res := make([]byte, 0, len(data)-(end-start)+len(text))
+		res = append(res, data[:start]...)
+		res = append(res, text...)
+		res = append(res, data[end:]...)
+		data = res
+	}
+	return data, nil
+}
+
+// offsetOfPosition converts a position, whose character counts UTF-16 code
+// units, to a byte offset in data. Characters past the end of a line are
+// clamped to the line end.
+func offsetOfPosition(data []byte, pos defines.Position) (int, error) {
+	offset := 0
+	for line := uint(0); line < pos.Line; line++ {
+		idx := bytes.IndexByte(data[offset:], '\n')
+		if idx < 0 {
+			if line+1 == pos.Line {
+				// the position is at the end of a file without trailing newline
+				return len(data), nil
+			}
+			return 0, fmt.Errorf("%w: line %d is out of range", ErrInvalidContentChange, pos.Line)
+		}
+		offset += idx + 1
+	}
+
+	line := data[offset:]
+	if end := bytes.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	return offset + parser.UTF16Offset(string(line), pos.Character), nil
+}
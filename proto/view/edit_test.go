@@ -0,0 +1,100 @@
+package view
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+func Test_applyContentChanges(t *testing.T) {
+	change := func(startLine, startChar, endLine, endChar uint, text string) defines.TextDocumentContentChangeEvent {
+		return defines.TextDocumentContentChangeEvent{
+			Range: &defines.Range{
+				Start: defines.Position{Line: startLine, Character: startChar},
+				End:   defines.Position{Line: endLine, Character: endChar},
+			},
+			Text: text,
+		}
+	}
+	tests := []struct {
+		name    string
+		data    string
+		changes []defines.TextDocumentContentChangeEvent
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "full content",
+			data:    "message A {}\n",
+			changes: []defines.TextDocumentContentChangeEvent{{Text: "message B {}\n"}},
+			want:    "message B {}\n",
+		},
+		{
+			name: "changes are applied in order",
+			data: "message A {\n}\n",
+			changes: []defines.TextDocumentContentChangeEvent{
+				change(0, 8, 0, 9, "User"),
+				change(0, 14, 0, 14, "\n  string name = 1;"),
+			},
+			want: "message User {\n  string name = 1;\n}\n",
+		},
+		{
+			name:    "characters are utf-16 code units",
+			data:    "// 😀 é x\nmessage A {}",
+			changes: []defines.TextDocumentContentChangeEvent{change(0, 8, 0, 9, "y")},
+			want:    "// 😀 é y\nmessage A {}",
+		},
+		{
+			name:    "character past the line end is clamped",
+			data:    "a\r\nb",
+			changes: []defines.TextDocumentContentChangeEvent{change(0, 10, 1, 0, "")},
+			want:    "ab",
+		},
+		{
+			name:    "insert at the end of the file",
+			data:    "a\n",
+			changes: []defines.TextDocumentContentChangeEvent{change(1, 0, 1, 0, "b")},
+			want:    "a\nb",
+		},
+		{
+			name:    "line out of range",
+			data:    "a",
+			changes: []defines.TextDocumentContentChangeEvent{change(3, 0, 3, 0, "b")},
+			wantErr: ErrInvalidContentChange,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyContentChanges([]byte(tt.data), tt.changes)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func Test_view_didChange(t *testing.T) {
+	logs.Init(nil)
+	v := newView()
+	document_uri := defines.DocumentUri("file:///api.proto")
+	v.openFiles[document_uri] = &document{version: 1, data: []byte("message A {}")}
+
+	data, err := v.didChange(document_uri, 2, []defines.TextDocumentContentChangeEvent{{
+		Range: &defines.Range{Start: defines.Position{Character: 8}, End: defines.Position{Character: 9}},
+		Text:  "B",
+	}})
+	require.NoError(t, err)
+	require.Equal(t, "message B {}", string(data))
+
+	// an outdated change is ignored
+	data, err = v.didChange(document_uri, 2, []defines.TextDocumentContentChangeEvent{{Text: "message C {}"}})
+	require.NoError(t, err)
+	require.Nil(t, data)
+	require.Equal(t, "message B {}", string(v.openFiles[document_uri].data))
+}
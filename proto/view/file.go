@@ -24,6 +24,9 @@ type ProtoFile interface {
 	File
 	Proto() parser.Proto
 	SetProto(proto parser.Proto)
+	// View returns the view the file was loaded in, imports of the file
+	// resolve against it.
+	View() *View
 }
 
 // file is a file for changed files.
@@ -41,6 +44,7 @@ var _ File = (*file)(nil)
 type protoFile struct {
 	File
 	proto parser.Proto
+	view  *View
 }
 
 var _ ProtoFile = (*protoFile)(nil)
@@ -74,6 +78,10 @@ func (p *protoFile) Proto() parser.Proto {
 	return p.proto
 }
 
+func (p *protoFile) View() *View {
+	return p.view
+}
+
 func (p *protoFile) SetProto(proto parser.Proto) {
 	p.proto = proto
 }
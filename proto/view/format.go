@@ -0,0 +1,15 @@
+package view
+
+import (
+	"go.lsp.dev/uri"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/format"
+)
+
+// EditorConfig applies the .editorconfig files of the document to options,
+// see format.EditorConfig. They are read like the proto files, the open ones
+// with their unsaved changes.
+func (v *View) EditorConfig(document_uri defines.DocumentUri, options format.Options) format.Options {
+	return format.EditorConfig(v.fs, uri.URI(document_uri).Filename(), options)
+}
@@ -0,0 +1,57 @@
+package fs
+
+import (
+	iofs "io/fs"
+	"path"
+	"strings"
+)
+
+// EmbedFS serves the files of an io/fs.FS, e.g. an embed.FS, as if they were
+// stored below Root. It is read-only.
+type EmbedFS struct {
+	Root string
+	FS   iofs.FS
+}
+
+func (e *EmbedFS) FileExists(name string) bool {
+	info, err := e.Stat(name)
+	return err == nil && !info.IsDir()
+}
+
+func (e *EmbedFS) ReadFile(name string) ([]byte, error) {
+	rel, ok := e.rel(name)
+	if !ok {
+		return nil, &iofs.PathError{Op: "read", Path: name, Err: iofs.ErrNotExist}
+	}
+	return iofs.ReadFile(e.FS, rel)
+}
+
+func (e *EmbedFS) ReadDir(name string) ([]iofs.DirEntry, error) {
+	rel, ok := e.rel(name)
+	if !ok {
+		return nil, &iofs.PathError{Op: "readdir", Path: name, Err: iofs.ErrNotExist}
+	}
+	return iofs.ReadDir(e.FS, rel)
+}
+
+func (e *EmbedFS) Stat(name string) (iofs.FileInfo, error) {
+	rel, ok := e.rel(name)
+	if !ok {
+		return nil, &iofs.PathError{Op: "stat", Path: name, Err: iofs.ErrNotExist}
+	}
+	return iofs.Stat(e.FS, rel)
+}
+
+// rel returns the path of name inside FS.
+func (e *EmbedFS) rel(name string) (string, bool) {
+	root := path.Clean(e.Root)
+	name = path.Clean(name)
+	if name == root {
+		return ".", true
+	}
+	if !strings.HasPrefix(name, dirPrefix(root)) {
+		return "", false
+	}
+	rel := strings.TrimPrefix(name, dirPrefix(root))
+	return rel, iofs.ValidPath(rel)
+}
@@ -1,5 +1,103 @@
 package fs
 
+import (
+	"bytes"
+	"io"
+	iofs "io/fs"
+	"path"
+)
+
+// FS is the file system the view reads proto files and buf configuration
+// from. Paths are absolute and slash separated.
 type FS interface {
 	FileExists(path string) bool
+	ReadFile(path string) ([]byte, error)
+	// ReadDir returns the entries of the directory sorted by name.
+	ReadDir(path string) ([]iofs.DirEntry, error)
+	Stat(path string) (iofs.FileInfo, error)
+}
+
+// WriteFS is a file system files can be written to.
+type WriteFS interface {
+	FS
+	// WriteFile creates or replaces the file at path and the directories
+	// above it. Readers never see a partly written file.
+	WriteFile(path string, data []byte) error
+}
+
+// File is an open file that is read at any offset, see Open.
+type File interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// OpenFS is implemented by file systems that read parts of a file without
+// loading all of it.
+type OpenFS interface {
+	Open(path string) (File, error)
+}
+
+// Open opens the file at path of fsys to read parts of it, e.g. of a large git
+// pack file. File systems that do not implement OpenFS read all of it.
+func Open(fsys FS, path string) (File, error) {
+	if o, ok := fsys.(OpenFS); ok {
+		return o.Open(path)
+	}
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return bytesFile{bytes.NewReader(data)}, nil
+}
+
+type bytesFile struct {
+	*bytes.Reader
+}
+
+func (bytesFile) Close() error {
+	return nil
+}
+
+// WalkDir walks the file tree rooted at root like io/fs.WalkDir, calling fn
+// for every file and directory in lexical order.
+func WalkDir(fsys FS, root string, fn iofs.WalkDirFunc) error {
+	info, err := fsys.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(fsys, root, iofs.FileInfoToDirEntry(info), fn)
+	}
+	if err == iofs.SkipDir || err == iofs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkDir(fsys FS, name string, d iofs.DirEntry, fn iofs.WalkDirFunc) error {
+	if err := fn(name, d, nil); err != nil || !d.IsDir() {
+		if err == iofs.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := fsys.ReadDir(name)
+	if err != nil {
+		// let fn decide whether the error stops the walk
+		if err = fn(name, d, err); err != nil {
+			if err == iofs.SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, entry := range entries {
+		if err := walkDir(fsys, path.Join(name, entry.Name()), entry, fn); err != nil {
+			if err == iofs.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,125 @@
+package fs
+
+import (
+	iofs "io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func entryNames(entries []iofs.DirEntry) (names []string) {
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestMapFS(t *testing.T) {
+	m := NewMapFS(map[string]string{
+		"/repo/buf.yaml":        "version: v2\n",
+		"/repo/api/a.proto":     "syntax = \"proto3\";",
+		"/repo/api/v1/b.proto":  "",
+		"/other/vendor/c.proto": "",
+	})
+
+	require.True(t, m.FileExists("/repo/api/a.proto"))
+	require.False(t, m.FileExists("/repo/api"))
+
+	data, err := m.ReadFile("/repo/api/a.proto")
+	require.NoError(t, err)
+	require.Equal(t, "syntax = \"proto3\";", string(data))
+	_, err = m.ReadFile("/repo/missing.proto")
+	require.ErrorIs(t, err, iofs.ErrNotExist)
+
+	entries, err := m.ReadDir("/repo/api")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.proto", "v1"}, entryNames(entries))
+	require.True(t, entries[1].IsDir())
+	entries, err = m.ReadDir("/")
+	require.NoError(t, err)
+	require.Equal(t, []string{"other", "repo"}, entryNames(entries))
+
+	info, err := m.Stat("/repo/api")
+	require.NoError(t, err)
+	require.True(t, info.IsDir())
+	info, err = m.Stat("/repo/buf.yaml")
+	require.NoError(t, err)
+	require.Equal(t, int64(len("version: v2\n")), info.Size())
+
+	m.Remove("/repo/api/v1/b.proto")
+	_, err = m.Stat("/repo/api/v1")
+	require.ErrorIs(t, err, iofs.ErrNotExist)
+}
+
+func TestLayeredFS(t *testing.T) {
+	overlay := NewMapFS(map[string]string{
+		"/repo/a.proto":   "unsaved",
+		"/repo/new.proto": "",
+	})
+	disk := NewMapFS(map[string]string{
+		"/repo/a.proto": "saved",
+		"/repo/b.proto": "",
+	})
+	embedded := &EmbedFS{Root: "/include", FS: fstest.MapFS{
+		"google/protobuf/empty.proto": &fstest.MapFile{Data: []byte("package google.protobuf;")},
+	}}
+	l := LayeredFS{overlay, disk, embedded}
+
+	data, err := l.ReadFile("/repo/a.proto")
+	require.NoError(t, err)
+	require.Equal(t, "unsaved", string(data))
+	overlay.Remove("/repo/a.proto")
+	data, err = l.ReadFile("/repo/a.proto")
+	require.NoError(t, err)
+	require.Equal(t, "saved", string(data))
+
+	entries, err := l.ReadDir("/repo")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.proto", "b.proto", "new.proto"}, entryNames(entries))
+
+	f, err := Open(l, "/repo/a.proto")
+	require.NoError(t, err)
+	part := make([]byte, 4)
+	_, err = f.ReadAt(part, 1)
+	require.NoError(t, err)
+	require.Equal(t, "aved", string(part))
+	require.NoError(t, f.Close())
+	_, err = Open(l, "/missing")
+	require.ErrorIs(t, err, iofs.ErrNotExist)
+
+	require.True(t, l.FileExists("/include/google/protobuf/empty.proto"))
+	info, err := l.Stat("/include/google")
+	require.NoError(t, err)
+	require.True(t, info.IsDir())
+	_, err = l.Stat("/missing")
+	require.ErrorIs(t, err, iofs.ErrNotExist)
+}
+
+func TestWalkDir(t *testing.T) {
+	m := NewMapFS(map[string]string{
+		"/repo/a.proto":          "",
+		"/repo/api/b.proto":      "",
+		"/repo/.git/HEAD":        "",
+		"/repo/vendor/c/c.proto": "",
+	})
+	var visited []string
+	err := WalkDir(m, "/repo", func(path string, d iofs.DirEntry, err error) error {
+		require.NoError(t, err)
+		if d.IsDir() && d.Name() == ".git" {
+			return iofs.SkipDir
+		}
+		visited = append(visited, path)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"/repo",
+		"/repo/a.proto",
+		"/repo/api",
+		"/repo/api/b.proto",
+		"/repo/vendor",
+		"/repo/vendor/c",
+		"/repo/vendor/c/c.proto",
+	}, visited)
+}
@@ -0,0 +1,75 @@
+package fs
+
+import (
+	iofs "io/fs"
+)
+
+// LayeredFS looks files up in each layer in order, the first layer that has a
+// file wins. With a MapFS of the editor buffers as first layer unsaved changes
+// shadow the files on disk.
+type LayeredFS []FS
+
+func (l LayeredFS) FileExists(path string) bool {
+	for _, layer := range l {
+		if layer.FileExists(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l LayeredFS) ReadFile(path string) ([]byte, error) {
+	for _, layer := range l {
+		if data, err := layer.ReadFile(path); err == nil {
+			return data, nil
+		}
+	}
+	return nil, &iofs.PathError{Op: "read", Path: path, Err: iofs.ErrNotExist}
+}
+
+// ReadDir merges the entries of the directory in every layer, an entry of an
+// earlier layer hides one of the same name in later layers.
+func (l LayeredFS) ReadDir(path string) ([]iofs.DirEntry, error) {
+	children := make(map[string]iofs.FileInfo)
+	found := false
+	for _, layer := range l {
+		entries, err := layer.ReadDir(path)
+		if err != nil {
+			continue
+		}
+		found = true
+		for _, entry := range entries {
+			if _, ok := children[entry.Name()]; ok {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			children[entry.Name()] = info
+		}
+	}
+	if !found {
+		return nil, &iofs.PathError{Op: "readdir", Path: path, Err: iofs.ErrNotExist}
+	}
+	return sortedEntries(children), nil
+}
+
+func (l LayeredFS) Stat(path string) (iofs.FileInfo, error) {
+	for _, layer := range l {
+		if info, err := layer.Stat(path); err == nil {
+			return info, nil
+		}
+	}
+	return nil, &iofs.PathError{Op: "stat", Path: path, Err: iofs.ErrNotExist}
+}
+
+// Open opens the file of the first layer that has it, see fs.Open.
+func (l LayeredFS) Open(path string) (File, error) {
+	for _, layer := range l {
+		if layer.FileExists(path) {
+			return Open(layer, path)
+		}
+	}
+	return nil, &iofs.PathError{Op: "open", Path: path, Err: iofs.ErrNotExist}
+}
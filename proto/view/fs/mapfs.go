@@ -0,0 +1,144 @@
+package fs
+
+import (
+	iofs "io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MapFS is an in-memory file system that is safe for concurrent use.
+// Directories are implied by the paths of the files. It holds the buffers of
+// the editor, see LayeredFS, and the files of tests.
+type MapFS struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+// NewMapFS returns a MapFS holding files, a map from path to content.
+func NewMapFS(files map[string]string) *MapFS {
+	m := &MapFS{files: make(map[string][]byte, len(files))}
+	for name, content := range files {
+		m.files[path.Clean(name)] = []byte(content)
+	}
+	return m
+}
+
+// Set creates or replaces the file at name.
+func (m *MapFS) Set(name string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.files == nil {
+		m.files = make(map[string][]byte)
+	}
+	m.files[path.Clean(name)] = data
+}
+
+func (m *MapFS) WriteFile(name string, data []byte) error {
+	m.Set(name, data)
+	return nil
+}
+
+// Remove deletes the file at name if it exists.
+func (m *MapFS) Remove(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, path.Clean(name))
+}
+
+func (m *MapFS) FileExists(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.files[path.Clean(name)]
+	return ok
+}
+
+func (m *MapFS) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.files[path.Clean(name)]
+	if !ok {
+		return nil, &iofs.PathError{Op: "read", Path: name, Err: iofs.ErrNotExist}
+	}
+	return data, nil
+}
+
+func (m *MapFS) ReadDir(name string) ([]iofs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	prefix := dirPrefix(name)
+	children := make(map[string]iofs.FileInfo)
+	for file, data := range m.files {
+		if !strings.HasPrefix(file, prefix) {
+			continue
+		}
+		child, _, is_dir := strings.Cut(strings.TrimPrefix(file, prefix), "/")
+		if is_dir {
+			children[child] = dirInfo(child)
+		} else {
+			children[child] = fileInfo{name: child, size: int64(len(data))}
+		}
+	}
+	if len(children) == 0 {
+		return nil, &iofs.PathError{Op: "readdir", Path: name, Err: iofs.ErrNotExist}
+	}
+	return sortedEntries(children), nil
+}
+
+func (m *MapFS) Stat(name string) (iofs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	name = path.Clean(name)
+	if data, ok := m.files[name]; ok {
+		return fileInfo{name: path.Base(name), size: int64(len(data))}, nil
+	}
+	prefix := dirPrefix(name)
+	for file := range m.files {
+		if strings.HasPrefix(file, prefix) {
+			return dirInfo(path.Base(name)), nil
+		}
+	}
+	return nil, &iofs.PathError{Op: "stat", Path: name, Err: iofs.ErrNotExist}
+}
+
+// dirPrefix returns the prefix every path inside the directory name has.
+func dirPrefix(name string) string {
+	return strings.TrimSuffix(path.Clean(name), "/") + "/"
+}
+
+func sortedEntries(children map[string]iofs.FileInfo) []iofs.DirEntry {
+	entries := make([]iofs.DirEntry, 0, len(children))
+	for _, info := range children {
+		entries = append(entries, iofs.FileInfoToDirEntry(info))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries
+}
+
+// fileInfo describes a file or directory of a MapFS.
+type fileInfo struct {
+	name  string
+	size  int64
+	isDir bool
+}
+
+func dirInfo(name string) fileInfo {
+	return fileInfo{name: name, isDir: true}
+}
+
+func (f fileInfo) Name() string       { return f.name }
+func (f fileInfo) Size() int64        { return f.size }
+func (f fileInfo) ModTime() time.Time { return time.Time{} }
+func (f fileInfo) IsDir() bool        { return f.isDir }
+func (f fileInfo) Sys() interface{}   { return nil }
+
+func (f fileInfo) Mode() iofs.FileMode {
+	if f.isDir {
+		return iofs.ModeDir | 0o555
+	}
+	return 0o444
+}
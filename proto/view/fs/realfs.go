@@ -1,7 +1,9 @@
 package fs
 
 import (
+	iofs "io/fs"
 	"os"
+	"path/filepath"
 )
 
 // Wrapps OS file methods in FS interface
@@ -11,3 +13,48 @@ func (r *RealFS) FileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
 }
+
+func (r *RealFS) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (r *RealFS) ReadDir(path string) ([]iofs.DirEntry, error) {
+	return os.ReadDir(path)
+}
+
+func (r *RealFS) Stat(path string) (iofs.FileInfo, error) {
+	return os.Stat(path)
+}
+
+func (r *RealFS) Open(path string) (File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// WriteFile replaces the file at path through a temporary file, so readers
+// never see a partly written file.
+func (r *RealFS) WriteFile(path string, data []byte) error {
+	target := filepath.FromSlash(path)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
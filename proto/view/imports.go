@@ -0,0 +1,71 @@
+package view
+
+import (
+	iofs "io/fs"
+	"path"
+	"strings"
+
+	"go.lsp.dev/uri"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/include"
+	"github.com/walteh/protobuf-language-server/proto/parser"
+)
+
+// ImportPath returns the path the file cwd has to import target by. It is
+// the shortest one that resolves to target, see GetDocumentUriFromImportPath,
+// and false if target can not be imported from cwd.
+func (v *View) ImportPath(cwd, target defines.DocumentUri) (string, bool) {
+	target_path := path.Clean(uri.URI(target).Filename())
+	dir := path.Dir(uri.URI(cwd).Filename())
+
+	roots := v.bufImportRoots(dir)
+	if roots == nil {
+		additional_dirs := v.loadSettings().AdditionalProtoDirs
+		// the directories searchParentDirs looks in, nearest first
+		for pos := path.Clean(dir); pos != "/" && pos != "."; pos = path.Dir(pos) {
+			roots = append(roots, pos)
+			for _, additionalProtoDir := range additional_dirs {
+				roots = append(roots, path.Join(pos, additionalProtoDir))
+			}
+		}
+	}
+	if v.includeRoot != "" {
+		roots = append(roots, v.includeRoot)
+	}
+
+	import_name := ""
+	for _, root := range roots {
+		rel, ok := strings.CutPrefix(target_path, path.Clean(root)+"/")
+		if !ok || (import_name != "" && len(import_name) <= len(rel)) {
+			continue
+		}
+		resolved, err := v.GetDocumentUriFromImportPath(cwd, rel)
+		if err == nil && path.Clean(uri.URI(resolved).Filename()) == target_path {
+			import_name = rel
+		}
+	}
+	return import_name, import_name != ""
+}
+
+// IncludeSymbols returns the symbols of the embedded protos, see
+// include.FS, located below the include root. They are indexed on first use.
+func (v *View) IncludeSymbols() []Symbol {
+	v.indexIncludes.Do(func() {
+		_ = iofs.WalkDir(include.FS, ".", func(name string, d iofs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !IsProtoFile(defines.DocumentUri(name)) {
+				return err
+			}
+			data, err := include.FS.ReadFile(name)
+			if err != nil {
+				return nil
+			}
+			document_uri := defines.DocumentUri(uri.New(path.Join(v.includeRoot, name)))
+			if proto, _ := parser.ParseProtoWithErrors(document_uri, data); proto != nil {
+				v.includeSymbols = append(v.includeSymbols, protoSymbols(document_uri, data, proto)...)
+			}
+			return nil
+		})
+	})
+	return v.includeSymbols
+}
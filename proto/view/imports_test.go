@@ -0,0 +1,86 @@
+package view
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/include"
+	"github.com/walteh/protobuf-language-server/proto/view/fs"
+)
+
+func Test_view_ImportPath(t *testing.T) {
+	logs.Init(nil)
+	tests := []struct {
+		contents map[string]string
+		cwd      defines.DocumentUri
+		target   defines.DocumentUri
+		want     string
+	}{
+		{
+			contents: map[string]string{
+				"/repo/acme/api.proto":     "",
+				"/repo/acme/v1/user.proto": "",
+			},
+			cwd:    "file:///repo/acme/api.proto",
+			target: "file:///repo/acme/v1/user.proto",
+			want:   "v1/user.proto",
+		},
+		{
+			contents: map[string]string{
+				"/repo/buf.yaml":              "version: v2\nmodules:\n  - path: proto\n",
+				"/repo/proto/acme/api.proto":  "",
+				"/repo/proto/acme/user.proto": "",
+			},
+			cwd:    "file:///repo/proto/acme/api.proto",
+			target: "file:///repo/proto/acme/user.proto",
+			want:   "acme/user.proto",
+		},
+		{
+			contents: map[string]string{
+				"/repo/buf.yaml":             "version: v2\nmodules:\n  - path: proto\n",
+				"/repo/proto/acme/api.proto": "",
+				"/repo/other/user.proto":     "",
+			},
+			cwd:    "file:///repo/proto/acme/api.proto",
+			target: "file:///repo/other/user.proto",
+		},
+		{
+			contents: map[string]string{"/repo/api.proto": ""},
+			cwd:      "file:///repo/api.proto",
+			target:   "file:///include/google/protobuf/duration.proto",
+			want:     "google/protobuf/duration.proto",
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			// the embedded protos are extracted once an import resolves to them
+			disk := fs.NewMapFS(nil)
+			v := &View{
+				fs:          fs.LayeredFS{fs.NewMapFS(tt.contents), &fs.EmbedFS{Root: "/include", FS: include.FS}},
+				disk:        disk,
+				includeRoot: "/include",
+			}
+
+			got, ok := v.ImportPath(tt.cwd, tt.target)
+			require.Equal(t, tt.want != "", ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_view_IncludeSymbols(t *testing.T) {
+	v := &View{includeRoot: "/include"}
+	var found *Symbol
+	for _, symbol := range v.IncludeSymbols() {
+		if symbol.FullName == "google.protobuf.Duration" {
+			found = &symbol
+		}
+	}
+	require.NotNil(t, found)
+	require.Equal(t, "google.protobuf", found.Package)
+	require.Equal(t, defines.DocumentUri("file:///include/google/protobuf/duration.proto"), found.Location.Uri)
+}
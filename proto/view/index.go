@@ -0,0 +1,300 @@
+package view
+
+import (
+	"context"
+	"fmt"
+	iofs "io/fs"
+	"sort"
+	"strings"
+	"sync"
+	"text/scanner"
+	"unicode/utf8"
+
+	protobuf "github.com/emicklei/proto"
+	"go.lsp.dev/uri"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/parser"
+	"github.com/walteh/protobuf-language-server/proto/view/fs"
+)
+
+// Symbol is a declaration in a proto file of the workspace.
+type Symbol struct {
+	// Name is the declared name, e.g. Status.
+	Name string
+	// FullName is the fully qualified name, e.g. common.User.Status.
+	FullName string
+	// ContainerName is the fully qualified name of the package, message,
+	// enum or service the symbol is declared in.
+	ContainerName string
+	// Package is the package of the file declaring the symbol.
+	Package  string
+	Kind     defines.SymbolKind
+	Location defines.Location
+}
+
+// symbolIndex holds the symbols of every proto file below the workspace roots,
+// including files that were never opened.
+type symbolIndex struct {
+	mu      *sync.RWMutex
+	symbols map[defines.DocumentUri][]Symbol
+}
+
+func newSymbolIndex() *symbolIndex {
+	return &symbolIndex{
+		mu:      &sync.RWMutex{},
+		symbols: make(map[defines.DocumentUri][]Symbol),
+	}
+}
+
+func (i *symbolIndex) set(document_uri defines.DocumentUri, symbols []Symbol) {
+	i.mu.Lock()
+	i.symbols[document_uri] = symbols
+	i.mu.Unlock()
+}
+
+func (i *symbolIndex) remove(document_uri defines.DocumentUri) {
+	i.mu.Lock()
+	delete(i.symbols, document_uri)
+	i.mu.Unlock()
+}
+
+// uris returns the indexed files.
+func (i *symbolIndex) uris() []defines.DocumentUri {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	res := make([]defines.DocumentUri, 0, len(i.symbols))
+	for document_uri := range i.symbols {
+		res = append(res, document_uri)
+	}
+	return res
+}
+
+func (i *symbolIndex) all() []Symbol {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	var res []Symbol
+	for _, symbols := range i.symbols {
+		res = append(res, symbols...)
+	}
+	return res
+}
+
+// WorkspaceSymbols returns the symbols of every indexed proto file sorted by
+// their fully qualified name.
+func (v *View) WorkspaceSymbols() []Symbol {
+	res := v.index.all()
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].FullName != res[j].FullName {
+			return res[i].FullName < res[j].FullName
+		}
+		return res[i].Location.Uri < res[j].Location.Uri
+	})
+	return res
+}
+
+// indexFile refreshes the symbols of document_uri after it was parsed.
+func (v *View) indexFile(document_uri defines.DocumentUri, data []byte, proto parser.Proto) {
+	v.index.set(document_uri, protoSymbols(document_uri, data, proto))
+}
+
+// IndexWorkspace parses every proto file below roots so symbols of files that
+// are not open can be searched, it runs in the background once the client is
+// initialized. Files that are already loaded are indexed from their current
+// content.
+func (v *View) IndexWorkspace(ctx context.Context, roots []string) {
+	progress := v.beginProgress(ctx, "Indexing proto files")
+	var paths []string
+	for _, root := range roots {
+		err := fs.WalkDir(v.fs, root, func(path string, d iofs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+			if d.IsDir() {
+				if path != root && skipIndexDir(d.Name()) {
+					return iofs.SkipDir
+				}
+				return nil
+			}
+			if IsProtoFile(defines.DocumentUri(path)) {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			logs.Printf("index workspace %s err: %v", root, err)
+			progress.End("")
+			return
+		}
+	}
+
+	for i, path := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		progress.Report(fmt.Sprintf("%d/%d files", i+1, len(paths)), uint(i*100/len(paths)))
+		document_uri := defines.DocumentUri(uri.File(path))
+		if _, loaded := v.loadedFile(document_uri); loaded {
+			continue
+		}
+		data, err := v.fs.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if !utf8.Valid(data) {
+			data = toUtf8(data)
+		}
+		proto, _ := parser.ParseProtoWithErrors(document_uri, data)
+		if proto == nil {
+			continue
+		}
+		v.indexFile(document_uri, data, proto)
+	}
+	progress.End(fmt.Sprintf("%d files", len(paths)))
+}
+
+// skipIndexDir reports whether a directory never contains sources worth
+// indexing, e.g. version control metadata or dependencies.
+func skipIndexDir(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "node_modules" || name == "bazel-out"
+}
+
+// workspaceRoots returns the directories of the workspace folders the client
+// opened, falling back to the deprecated root uri and root path.
+func workspaceRoots(params *defines.InitializeParams) (roots []string) {
+	if params == nil {
+		return nil
+	}
+	if folders, ok := params.WorkspaceFolders.([]interface{}); ok {
+		for _, folder := range folders {
+			m, ok := folder.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if folder_uri, ok := m["uri"].(string); ok && folder_uri != "" {
+				roots = append(roots, uri.URI(folder_uri).Filename())
+			}
+		}
+	}
+	if len(roots) > 0 {
+		return roots
+	}
+	if root_uri, ok := params.RootUri.(string); ok && root_uri != "" {
+		return []string{uri.URI(root_uri).Filename()}
+	}
+	if root_path, ok := params.RootPath.(string); ok && root_path != "" {
+		return []string{root_path}
+	}
+	return nil
+}
+
+// protoSymbols returns every message, enum, enum value, field,
+// service and rpc declared in proto.
+func protoSymbols(document_uri defines.DocumentUri, data []byte, proto parser.Proto) (res []Symbol) {
+	lines := strings.Split(string(data), "\n")
+	pkg := ""
+	if len(proto.Packages()) > 0 {
+		pkg = proto.Packages()[0].ProtoPackage.Name
+	}
+
+	add := func(e protobuf.Visitee, name string, kind defines.SymbolKind, container string, pos scanner.Position) {
+		rng, ok := proto.NameRange(e)
+		if !ok {
+			rng = nameRange(lines, pos, name)
+		}
+		full_name := name
+		if container != "" {
+			full_name = container + "." + name
+		}
+		res = append(res, Symbol{
+			Name:          name,
+			FullName:      full_name,
+			ContainerName: container,
+			Package:       pkg,
+			Kind:          kind,
+			Location: defines.Location{
+				Uri:   document_uri,
+				Range: rng,
+			},
+		})
+	}
+
+	var visit func(elements []protobuf.Visitee, container string)
+	visit = func(elements []protobuf.Visitee, container string) {
+		qualify := func(name string) string {
+			if container == "" {
+				return name
+			}
+			return container + "." + name
+		}
+		for _, e := range elements {
+			switch v := e.(type) {
+			case *protobuf.Message:
+				if v.IsExtend {
+					continue
+				}
+				add(v, v.Name, defines.SymbolKindClass, container, v.Position)
+				visit(v.Elements, qualify(v.Name))
+			case *protobuf.Enum:
+				add(v, v.Name, defines.SymbolKindEnum, container, v.Position)
+				visit(v.Elements, qualify(v.Name))
+			case *protobuf.EnumField:
+				add(v, v.Name, defines.SymbolKindEnumMember, container, v.Position)
+			case *protobuf.NormalField:
+				add(v, v.Name, defines.SymbolKindField, container, v.Position)
+			case *protobuf.MapField:
+				add(v, v.Name, defines.SymbolKindField, container, v.Position)
+			case *protobuf.Oneof:
+				// oneof fields belong to the enclosing message
+				visit(v.Elements, container)
+			case *protobuf.OneOfField:
+				add(v, v.Name, defines.SymbolKindField, container, v.Position)
+			case *protobuf.Service:
+				add(v, v.Name, defines.SymbolKindInterface, container, v.Position)
+				visit(v.Elements, qualify(v.Name))
+			case *protobuf.RPC:
+				add(v, v.Name, defines.SymbolKindMethod, container, v.Position)
+			}
+		}
+	}
+	visit(proto.Protobuf().Elements, pkg)
+	return res
+}
+
+// nameRange returns the range of name in the declaration starting at pos. If
+// name is not on the same line the range is empty and starts at pos.
+func nameRange(lines []string, pos scanner.Position, name string) defines.Range {
+	line := pos.Line - 1
+	rng := defines.Range{Start: defines.Position{Line: uint(max(line, 0))}}
+	if line < 0 || line >= len(lines) || pos.Column < 1 {
+		return rng
+	}
+	line_str := lines[line]
+	column := parser.ColumnOffset(line_str, pos.Column-1)
+	rng.Start.Character = parser.UTF16Character(line_str, column)
+	rng.End = rng.Start
+	for from := column; from <= len(line_str); {
+		idx := strings.Index(line_str[from:], name)
+		if idx < 0 {
+			break
+		}
+		start, end := from+idx, from+idx+len(name)
+		if (start == 0 || !isIdentChar(line_str[start-1])) && (end == len(line_str) || !isIdentChar(line_str[end])) {
+			rng.Start.Character = parser.UTF16Character(line_str, start)
+			rng.End.Character = parser.UTF16Character(line_str, end)
+			return rng
+		}
+		from = start + 1
+	}
+	return rng
+}
+
+func isIdentChar(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') || ch == '_' || ch == '.'
+}
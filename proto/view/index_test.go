@@ -0,0 +1,68 @@
+package view
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+)
+
+func Test_view_IndexWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"api/user.proto": `syntax = "proto3";
+package api;
+
+message User {
+	enum Status {
+		ACTIVE = 0;
+	}
+	oneof id {
+		string email = 1;
+	}
+}
+
+service Users {
+	rpc GetUser(User) returns (User);
+}
+`,
+		".git/ignored.proto": `message Ignored {}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	v := newView()
+	roots := workspaceRoots(&defines.InitializeParams{
+		WorkspaceFoldersInitializeParams: defines.WorkspaceFoldersInitializeParams{
+			WorkspaceFolders: []interface{}{map[string]interface{}{"uri": "file://" + dir, "name": "test"}},
+		},
+	})
+	require.Equal(t, []string{dir}, roots)
+	v.IndexWorkspace(context.Background(), roots)
+
+	type symbol struct {
+		FullName string
+		Kind     defines.SymbolKind
+		Line     uint
+		Start    uint
+	}
+	var got []symbol
+	for _, s := range v.WorkspaceSymbols() {
+		got = append(got, symbol{s.FullName, s.Kind, s.Location.Range.Start.Line, s.Location.Range.Start.Character})
+	}
+	require.Equal(t, []symbol{
+		{"api.User", defines.SymbolKindClass, 3, 8},
+		{"api.User.Status", defines.SymbolKindEnum, 4, 6},
+		{"api.User.Status.ACTIVE", defines.SymbolKindEnumMember, 5, 2},
+		{"api.User.email", defines.SymbolKindField, 8, 9},
+		{"api.Users", defines.SymbolKindInterface, 12, 8},
+		{"api.Users.GetUser", defines.SymbolKindMethod, 13, 5},
+	}, got)
+}
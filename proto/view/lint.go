@@ -0,0 +1,35 @@
+package view
+
+import (
+	"path"
+	"strings"
+
+	"go.lsp.dev/uri"
+
+	"github.com/walteh/protobuf-language-server/proto/lint"
+)
+
+// Lint checks proto_file with the lint configuration of its buf module, or of
+// the settings if it is not part of one, see LintSettings.
+func (v *View) Lint(proto_file ProtoFile) []lint.Problem {
+	if proto_file.Proto() == nil {
+		return nil
+	}
+	settings := v.loadSettings().Lint
+	if settings != nil && settings.Enabled != nil && !*settings.Enabled {
+		return nil
+	}
+
+	filename := uri.URI(proto_file.URI()).Filename()
+	file := &lint.File{Proto: proto_file.Proto().Protobuf(), Filename: filename}
+	config, root, ok := v.bufLintConfig(path.Dir(filename))
+	switch {
+	case ok:
+		file.Path = strings.TrimPrefix(filename, strings.TrimSuffix(root, "/")+"/")
+	case settings != nil && settings.Enabled != nil:
+		config = settings.Config
+	default:
+		return nil
+	}
+	return lint.Lint(file, config)
+}
@@ -1,16 +1,43 @@
 package view
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+
+	"github.com/walteh/protobuf-language-server/proto/lint"
 )
 
 const (
 	additionalProtoDirsKey = "additional-proto-dirs"
+	lintKey                = "lint"
+	breakingKey            = "breaking"
 )
 
 type Settings struct {
 	AdditionalProtoDirs []string
+	// Lint configures linting, nil if not set.
+	Lint *LintSettings
+	// Breaking configures breaking change detection, nil if not set.
+	Breaking *BreakingSettings
+}
+
+// BreakingSettings selects the baseline files are compared with to detect
+// breaking changes, a git revision or a FileDescriptorSet, e.g. built by buf
+// build -o. A relative descriptor set path is relative to the root of the git
+// work tree of the file.
+type BreakingSettings struct {
+	AgainstGitRef        string `json:"against_git_ref"`
+	AgainstDescriptorSet string `json:"against_descriptor_set"`
+}
+
+// LintSettings configures the lint of files that are not part of a buf
+// module, the lint section of the buf.yaml is used for the others.
+type LintSettings struct {
+	// Enabled lints files outside of buf modules if true, and no file at all
+	// if false. By default only files of buf modules are linted.
+	Enabled *bool `json:"enabled"`
+	lint.Config
 }
 
 var (
@@ -33,6 +60,22 @@ func SettingsFromInterface(in interface{}) (*Settings, error) {
 		settings.AdditionalProtoDirs = protoDirs
 	}
 
+	if value, ok := settingsMap[lintKey]; ok {
+		lintSettings, err := LintSettingsFromInterface(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: key = %s", ErrRepackingSettings, err.Error(), lintKey)
+		}
+		settings.Lint = lintSettings
+	}
+
+	if value, ok := settingsMap[breakingKey]; ok {
+		var breakingSettings BreakingSettings
+		if err := structFromInterface(value, &breakingSettings); err != nil {
+			return nil, fmt.Errorf("%w: %s: key = %s", ErrRepackingSettings, err.Error(), breakingKey)
+		}
+		settings.Breaking = &breakingSettings
+	}
+
 	return &settings, nil
 }
 
@@ -54,3 +97,27 @@ func StringsSliceFromInterface(in interface{}) ([]string, error) {
 
 	return result, nil
 }
+
+// LintSettingsFromInterface reads the lint settings, whose keys are the ones
+// of the lint section of a buf.yaml plus enabled. Comment ignores are allowed
+// unless allow_comment_ignores is false.
+func LintSettingsFromInterface(in interface{}) (*LintSettings, error) {
+	result := LintSettings{Config: lint.Config{AllowCommentIgnores: true}}
+	if err := structFromInterface(in, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// structFromInterface fills the struct out from the map in by the json names
+// of its fields.
+func structFromInterface(in interface{}, out interface{}) error {
+	if _, ok := in.(map[string]interface{}); !ok {
+		return errors.New("field should have a map[string]interface{} type")
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
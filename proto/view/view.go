@@ -1,7 +1,6 @@
 package view
 
 import (
-	"bytes"
 	"context"
 	"crypto/sha1"
 	"errors"
@@ -9,23 +8,28 @@ import (
 	"os"
 	"path"
 	"path/filepath"
-	"regexp"
-	"strconv"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"unicode/utf8"
 
+	"github.com/walteh/protobuf-language-server/go-lsp/jsonrpc"
 	"github.com/walteh/protobuf-language-server/go-lsp/logs"
 	"github.com/walteh/protobuf-language-server/go-lsp/lsp"
 	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
 	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/uri"
 
+	"github.com/walteh/protobuf-language-server/proto/include"
 	"github.com/walteh/protobuf-language-server/proto/parser"
 	"github.com/walteh/protobuf-language-server/proto/view/fs"
 )
 
-type view struct {
+// View is the state of the workspace of one client: the open files, the
+// loaded protos, the symbol index and the settings. Every connection to the
+// server has its own, see FromContext.
+type View struct {
 
 	// keep track of files by document_uri and by basename, a single file may be mapped
 	// to multiple document_uris, and the same basename may map to multiple files
@@ -33,19 +37,60 @@ type view struct {
 	filesByBase map[string][]ProtoFile
 	fileMu      *sync.RWMutex
 
-	openFiles  map[defines.DocumentUri]bool
+	openFiles  map[defines.DocumentUri]*document
 	openFileMu *sync.RWMutex
+	// overlay holds the content of the open files, it shadows the disk in fs.
+	overlay *fs.MapFS
 
 	pbHeaders map[defines.DocumentUri][]string
+	index     *symbolIndex
 	Server    *lsp.Server
-	settings  Settings
-	fs        fs.FS
+	// session is the connection of the client, notifications are sent to it.
+	session *jsonrpc.Session
+	// settings are replaced by the client while requests read them, see
+	// loadSettings.
+	settings atomic.Pointer[Settings]
+	fs       fs.FS
+	// disk is the file system below the overlay and the embedded protos in
+	// fs, the embedded protos are extracted to it.
+	disk fs.WriteFS
+
+	// bufRoots caches the buf import roots by directory.
+	bufRoots sync.Map
+	// bufLint caches the buf lint configuration by directory.
+	bufLint sync.Map
+	// gitRepos caches the git repositories by directory, breakingBaselines
+	// the parsed files of their commits by commit and path and
+	// descriptorSets the parsed descriptor sets by file name.
+	gitRepos          sync.Map
+	breakingBaselines sync.Map
+	descriptorSets    sync.Map
+	// includeRoot is the directory the embedded protos are served from, it is
+	// the last place imports are searched in.
+	includeRoot     string
+	extractIncludes sync.Once
+	// includeSymbols are the symbols of the embedded protos, see
+	// IncludeSymbols.
+	includeSymbols []Symbol
+	indexIncludes  sync.Once
 }
 
 var ErrNotFound = errors.New("not found")
 
-func (v *view) GetFile(document_uri defines.DocumentUri) (ProtoFile, error) {
-	if f, ok := v.filesByURI[document_uri]; ok {
+// loadSettings returns the settings the client configured last.
+func (v *View) loadSettings() Settings {
+	if settings := v.settings.Load(); settings != nil {
+		return *settings
+	}
+	return Settings{}
+}
+
+func (v *View) storeSettings(settings Settings) {
+	v.settings.Store(&settings)
+}
+
+func (v *View) GetFile(document_uri defines.DocumentUri) (ProtoFile, error) {
+	if f, ok := v.loadedFile(document_uri); ok {
 		return f, nil
 	}
 	// no file load try again
@@ -53,70 +98,127 @@ func (v *view) GetFile(document_uri defines.DocumentUri) (ProtoFile, error) {
 	if err != nil {
 		return nil, err
 	}
-	if f, ok := v.filesByURI[document_uri]; ok {
+	if f, ok := v.loadedFile(document_uri); ok {
 		return f, nil
 	}
 
 	return nil, fmt.Errorf("%v not found", document_uri)
 }
 
+// loadedFile returns the file loaded at document_uri. The watcher and the
+// indexing of the workspace load files while requests are handled, so it
+// reads under fileMu.
+func (v *View) loadedFile(document_uri defines.DocumentUri) (ProtoFile, bool) {
+	v.fileMu.RLock()
+	defer v.fileMu.RUnlock()
+	f, ok := v.filesByURI[document_uri]
+	return f, ok
+}
+
+// WorkspaceFiles returns every loaded proto file and every file of the
+// workspace index, see IndexWorkspace, loading the ones that are not loaded
+// yet. Searches that must not miss files that were never opened, e.g. for
+// references, use it instead of GetFiles.
+func (v *View) WorkspaceFiles() []ProtoFile {
+	for _, document_uri := range v.index.uris() {
+		if _, loaded := v.loadedFile(document_uri); loaded {
+			continue
+		}
+		if _, err := v.GetFile(document_uri); err != nil {
+			logs.Printf("load %v err:%v", document_uri, err)
+		}
+	}
+	return v.GetFiles()
+}
+
+// GetFiles returns every proto file that is currently loaded, either because it
+// is open in the editor or because it was pulled in as an import.
+func (v *View) GetFiles() []ProtoFile {
+	v.fileMu.RLock()
+	files := make([]ProtoFile, 0, len(v.filesByURI))
+	for _, f := range v.filesByURI {
+		if f.Proto() != nil {
+			files = append(files, f)
+		}
+	}
+	v.fileMu.RUnlock()
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].URI() < files[j].URI()
+	})
+	return files
+}
+
 type Diagnositcs struct {
 	Method string                           `json:"method"`
 	Params defines.PublishDiagnosticsParams `json:"params"`
 }
 
 // setContent sets the file contents for a file.
-func (v *view) setContent(ctx context.Context, document_uri defines.DocumentUri, data []byte) {
-
-	v.fileMu.Lock()
-	defer v.fileMu.Unlock()
+func (v *View) setContent(ctx context.Context, document_uri defines.DocumentUri, data []byte) {
 	if data == nil {
+		v.fileMu.Lock()
 		delete(v.filesByURI, document_uri)
+		v.fileMu.Unlock()
 		return
 	}
 
 	pf := &protoFile{
+		view: v,
 		File: &file{
 			document_uri: document_uri,
 			data:         data,
 			hash:         hashContent(data),
 		},
 	}
-	if pre, ok := v.filesByURI[document_uri]; ok {
-		pf.proto = pre.Proto()
-	}
-	v.filesByURI[document_uri] = pf
 	// TODO:
 	//  Control times of parse of proto.
 	//  Currently it parses every time of file change.
-	proto, err := parseProto(document_uri, data)
+	proto, errs := parseProto(document_uri, data)
 
-	defer v.sendDiagnose(document_uri, err)
-	if err != nil {
-		return
+	v.fileMu.Lock()
+	if proto == nil {
+		if pre, ok := v.filesByURI[document_uri]; ok {
+			pf.proto = pre.Proto()
+		}
+	} else {
+		pf.proto = proto
 	}
-	pf.proto = proto
+	v.filesByURI[document_uri] = pf
+	v.fileMu.Unlock()
+
+	if proto != nil {
+		v.indexFile(document_uri, data, proto)
+	}
+	v.sendDiagnose(pf, errs)
 }
 
-func (v *view) shutdown(ctx context.Context) error {
+func (v *View) shutdown(ctx context.Context) error {
 	// return ViewManagerInstance.RemoveView(ctx, v)
 	return nil
 }
 
-func (v *view) didOpen(document_uri defines.DocumentUri, text []byte) {
+// document is the content of a file that is open in the editor.
+type document struct {
+	version int
+	data    []byte
+}
+
+func (v *View) didOpen(ctx context.Context, document_uri defines.DocumentUri, version int, text []byte) {
 	v.openFileMu.Lock()
-	v.openFiles[document_uri] = true
+	v.openFiles[document_uri] = &document{version: version, data: text}
+	v.overlay.Set(uri.URI(document_uri).Filename(), text)
 	v.openFileMu.Unlock()
 	v.openFile(document_uri, text)
 	// not like include
-	v.parseImportProto(document_uri)
+	v.parseImportProto(ctx, document_uri)
 }
 
-func (v *view) didOpenPbHeader(document_uri defines.DocumentUri, text string) {
+func (v *View) didOpenPbHeader(document_uri defines.DocumentUri, text string) {
 	v.pbHeaders[document_uri] = strings.Split(text, "\n")
 }
 
-func (v *view) GetPbHeaderLine(document_uri defines.DocumentUri, line int) string {
+func (v *View) GetPbHeaderLine(document_uri defines.DocumentUri, line int) string {
 	lines, ok := v.pbHeaders[document_uri]
 	if !ok || len(lines) <= line {
 		return ""
@@ -124,7 +226,7 @@ func (v *view) GetPbHeaderLine(document_uri defines.DocumentUri, line int) strin
 
 	return lines[line]
 }
-func (v *view) didSave(document_uri defines.DocumentUri) {
+func (v *View) didSave(document_uri defines.DocumentUri) {
 	v.fileMu.Lock()
 	if file, ok := v.filesByURI[document_uri]; ok {
 		file.SetSaved(true)
@@ -132,80 +234,106 @@ func (v *view) didSave(document_uri defines.DocumentUri) {
 	v.fileMu.Unlock()
 }
 
-func (v *view) didClose(document_uri defines.DocumentUri) {
+func (v *View) didClose(document_uri defines.DocumentUri) {
 	v.openFileMu.Lock()
 	delete(v.openFiles, document_uri)
+	v.overlay.Remove(uri.URI(document_uri).Filename())
 	v.openFileMu.Unlock()
 }
 
-func (v *view) isOpen(document_uri defines.DocumentUri) bool {
+func (v *View) isOpen(document_uri defines.DocumentUri) bool {
 	v.openFileMu.RLock()
 	defer v.openFileMu.RUnlock()
 
-	open, ok := v.openFiles[document_uri]
+	_, ok := v.openFiles[document_uri]
+	return ok
+}
+
+// didChange applies the content changes to an open document and returns its
+// new content. Changes for a version that is not newer than the current one
+// are ignored and nil is returned.
+func (v *View) didChange(document_uri defines.DocumentUri, version int, changes []defines.TextDocumentContentChangeEvent) ([]byte, error) {
+	v.openFileMu.Lock()
+	defer v.openFileMu.Unlock()
+
+	doc, ok := v.openFiles[document_uri]
 	if !ok {
-		return false
+		if changes[0].Range != nil {
+			return nil, fmt.Errorf("%w: %s is not open", ErrInvalidContentChange, document_uri)
+		}
+		doc = &document{}
+		v.openFiles[document_uri] = doc
+	} else if version != 0 && version <= doc.version {
+		logs.Printf("ignore change of %s: version %d is not newer than %d", document_uri, version, doc.version)
+		return nil, nil
 	}
-	return open
+
+	data, err := applyContentChanges(doc.data, changes)
+	if err != nil {
+		return nil, err
+	}
+	doc.version = version
+	doc.data = data
+	v.overlay.Set(uri.URI(document_uri).Filename(), data)
+	return data, nil
+}
+
+// DiagnosticsProvider reports semantic problems of a file that was parsed
+// without syntax errors.
+type DiagnosticsProvider func(proto_file ProtoFile) []defines.Diagnostic
+
+var diagnosticsProviders []DiagnosticsProvider
+
+// OnDiagnostics registers a provider whose diagnostics are published together
+// with the syntax errors whenever a file is parsed. Providers are shared by
+// the views of all connections and must be registered before serving.
+func OnDiagnostics(provider DiagnosticsProvider) {
+	diagnosticsProviders = append(diagnosticsProviders, provider)
 }
 
-func (v *view) sendDiagnose(document_uri defines.DocumentUri, err error) {
+func (v *View) sendDiagnose(proto_file ProtoFile, errs []parser.SyntaxError) {
 	res := Diagnositcs{
 		Method: "textDocument/publishDiagnostics",
 		Params: defines.PublishDiagnosticsParams{
-			Uri:         document_uri,
+			Uri:         proto_file.URI(),
 			Diagnostics: []defines.Diagnostic{},
 		},
 	}
-	defer func() {
-		ViewManager.Server.SendMsg(res)
-	}()
-	if err == nil {
-		return
-	}
-	input := err.Error()
-	re := regexp.MustCompile(`<input>:(\d+):(\d+)`)
-	matches := re.FindStringSubmatch(input)
-
-	line, row := 0, 0
-	if len(matches) == 3 {
-		line, err = strconv.Atoi(matches[1])
-		if err != nil {
-			logs.Printf("Error:%v\n", err)
-			return
-		}
-		row, err = strconv.Atoi(matches[2])
-		if err != nil {
-			logs.Printf("Error:%v\n", err)
-			return
+	for _, err := range errs {
+		severity := defines.DiagnosticSeverityError
+		res.Params.Diagnostics = append(res.Params.Diagnostics, defines.Diagnostic{
+			Message:  err.Message,
+			Severity: &severity,
+			Range:    err.Range,
+		})
+	}
+	if proto_file.Proto() != nil {
+		res.Params.Diagnostics = append(res.Params.Diagnostics, v.importDiagnostics(proto_file)...)
+	}
+	// semantic checks on a partially parsed file would mostly report the
+	// declarations that were skipped
+	if len(errs) == 0 {
+		for _, provider := range diagnosticsProviders {
+			res.Params.Diagnostics = append(res.Params.Diagnostics, provider(proto_file)...)
 		}
 	}
-	if line == 0 || row == 0 {
+	v.notify(res)
+}
+
+// notify sends a notification to the client of the view. The default view
+// belongs to no client and drops it.
+func (v *View) notify(msg interface{}) {
+	if v.session == nil {
 		return
 	}
-	logs.Println(row)
-	severity := defines.DiagnosticSeverityError
-	res.Params.Diagnostics = append(res.Params.Diagnostics, defines.Diagnostic{
-		Message:  input,
-		Severity: &severity,
-		Range: defines.Range{
-			Start: defines.Position{
-				Line:      uint(line - 1),
-				Character: uint(row - 1),
-			},
-			End: defines.Position{
-				Line:      uint(line - 1),
-				Character: uint(row),
-			},
-		},
-	})
+	if err := v.session.SendMsg(msg); err != nil {
+		logs.Printf("notify err:%v", err)
+	}
 }
 
-func (v *view) openFile(document_uri defines.DocumentUri, data []byte) {
-	v.fileMu.Lock()
-	defer v.fileMu.Unlock()
-
+func (v *View) openFile(document_uri defines.DocumentUri, data []byte) {
 	pf := &protoFile{
+		view: v,
 		File: &file{
 			document_uri: document_uri,
 			data:         data,
@@ -213,36 +341,65 @@ func (v *view) openFile(document_uri defines.DocumentUri, data []byte) {
 		},
 	}
 
-	proto, err := parseProto(document_uri, data)
-	defer v.sendDiagnose(document_uri, err)
-	if err != nil {
-		return
+	proto, errs := parseProto(document_uri, data)
+	if proto != nil {
+		pf.proto = proto
+		v.fileMu.Lock()
+		v.filesByURI[document_uri] = pf
+		v.fileMu.Unlock()
+		v.indexFile(document_uri, data, proto)
 	}
-	pf.proto = proto
-	v.filesByURI[document_uri] = pf
+	// diagnostics are computed without holding fileMu, they may load imports
+	v.sendDiagnose(pf, errs)
 }
 
-func (v *view) parseImportProto(document_uri defines.DocumentUri) {
+// parseImportProto loads the imports of document_uri that are not loaded yet.
+// They are loaded in the background, creating the progress token is a round
+// trip to the client that opening a file must not wait for.
+func (v *View) parseImportProto(ctx context.Context, document_uri defines.DocumentUri) {
 	proto_file, err := v.GetFile(document_uri)
 	if err != nil {
 		logs.Printf("parseImportProto GetFile err:%v", err)
 		return
 	}
+	var missing []defines.DocumentUri
 	for _, i := range proto_file.Proto().Imports() {
-		import_uri, err := ViewManager.GetDocumentUriFromImportPath(document_uri, i.ProtoImport.Filename)
+		import_uri, err := v.GetDocumentUriFromImportPath(document_uri, i.ProtoImport.Filename)
 		if err != nil {
 			logs.Printf("parse import err:%v", err)
 			continue
 		}
-		proto_file, err := v.GetFile(import_uri)
-		if proto_file == nil {
-			v.loadProtoFile(import_uri)
+		if _, loaded := v.loadedFile(import_uri); !loaded {
+			missing = append(missing, import_uri)
 		}
 	}
+	if len(missing) == 0 {
+		return
+	}
+
+	go func() {
+		progress := v.beginProgress(context.WithoutCancel(ctx), "Loading imports")
+		for i, import_uri := range missing {
+			progress.Report(path.Base(string(import_uri)), uint(i*100/len(missing)))
+			if err := v.loadProtoFile(import_uri); err != nil {
+				logs.Printf("load import err:%v", err)
+			}
+		}
+		progress.End("")
+	}()
 }
 
-func (v *view) loadProtoFile(document_uri defines.DocumentUri) error {
-	data, err := os.ReadFile(uri.URI(document_uri).Filename())
+// beginProgress reports the progress of a long running operation to the
+// client, see lsp.Server.BeginProgress.
+func (v *View) beginProgress(ctx context.Context, title string) *lsp.Progress {
+	if v.Server == nil {
+		return nil
+	}
+	return v.Server.BeginProgress(ctx, title)
+}
+
+func (v *View) loadProtoFile(document_uri defines.DocumentUri) error {
+	data, err := v.fs.ReadFile(uri.URI(document_uri).Filename())
 
 	if err != nil {
 		return fmt.Errorf("read file err:%v", err)
@@ -254,7 +411,7 @@ func (v *view) loadProtoFile(document_uri defines.DocumentUri) error {
 	return nil
 }
 
-func (v *view) mapFile(document_uri defines.DocumentUri, f ProtoFile) {
+func (v *View) mapFile(document_uri defines.DocumentUri, f ProtoFile) {
 	v.fileMu.Lock()
 
 	v.filesByURI[document_uri] = f
@@ -264,46 +421,110 @@ func (v *view) mapFile(document_uri defines.DocumentUri, f ProtoFile) {
 	v.fileMu.Unlock()
 }
 
-func newView() *view {
-	return &view{
+func newView() *View {
+	include_root := defaultIncludeRoot()
+	overlay := fs.NewMapFS(nil)
+	disk := &fs.RealFS{}
+	return &View{
 		filesByURI:  make(map[defines.DocumentUri]ProtoFile),
 		filesByBase: make(map[string][]ProtoFile),
 		fileMu:      &sync.RWMutex{},
-		openFiles:   make(map[defines.DocumentUri]bool),
+		openFiles:   make(map[defines.DocumentUri]*document),
 		openFileMu:  &sync.RWMutex{},
 		pbHeaders:   make(map[defines.DocumentUri][]string),
-		fs:          &fs.RealFS{},
+		index:       newSymbolIndex(),
+		overlay:     overlay,
+		// the embedded protos hide outdated copies extracted to the disk
+		fs:          fs.LayeredFS{overlay, &fs.EmbedFS{Root: include_root, FS: include.FS}, disk},
+		disk:        disk,
+		includeRoot: include_root,
 	}
 }
 
-var ViewManager *view
-
-func parseProto(document_uri defines.DocumentUri, data []byte) (proto parser.Proto, err error) {
-	buf := bytes.NewBuffer(data)
-	proto, err = parser.ParseProto(document_uri, buf)
+// defaultIncludeRoot returns the directory in the user cache the embedded
+// protos are extracted to.
+func defaultIncludeRoot() string {
+	dir, err := os.UserCacheDir()
 	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.ToSlash(filepath.Join(dir, "protobuf-language-server", "include"))
+}
+
+var (
+	server *lsp.Server
+
+	viewsMu sync.Mutex
+	// defaultView serves contexts without a connection, e.g. in tests.
+	defaultView *View
+)
+
+type viewKey struct{}
+
+// FromContext returns the view of the connection ctx belongs to, it is
+// created on first use. Editor windows sharing the server over a socket each
+// get their own view and never see each other's files or settings.
+func FromContext(ctx context.Context) *View {
+	viewsMu.Lock()
+	defer viewsMu.Unlock()
+
+	session := jsonrpc.SessionFromContext(ctx)
+	if session == nil {
+		if defaultView == nil {
+			defaultView = newView()
+			defaultView.Server = server
+		}
+		return defaultView
+	}
+	if v, ok := session.Value(viewKey{}).(*View); ok {
+		return v
+	}
+	v := newView()
+	v.Server = server
+	v.session = session
+	session.SetValue(viewKey{}, v)
+	return v
+}
+
+func parseProto(document_uri defines.DocumentUri, data []byte) (proto parser.Proto, errs []parser.SyntaxError) {
+	proto, errs = parser.ParseProtoWithErrors(document_uri, data)
+	for _, err := range errs {
 		logs.Printf("parseProto err %v", err)
 	}
-	return proto, err
+	return proto, errs
 }
 
-func (v *view) GetDocumentUriFromImportPath(cwd defines.DocumentUri, import_name string) (defines.DocumentUri, error) {
-	pos := path.Dir(uri.URI(cwd).Filename())
+// GetDocumentUriFromImportPath resolves an import of the file cwd. Files in a
+// buf module or workspace resolve imports against its import roots, others
+// search the parent directories and the additional proto dirs of each.
+func (v *View) GetDocumentUriFromImportPath(cwd defines.DocumentUri, import_name string) (defines.DocumentUri, error) {
 	var res defines.DocumentUri
+	candidates, _ := v.importCandidates(cwd, import_name)
+	if len(candidates) == 0 {
+		return res, fmt.Errorf("%w: import %s", ErrNotFound, import_name)
+	}
+	return defines.DocumentUri(uri.New(path.Clean(candidates[0]))), nil
+}
+
+// searchParentDirs returns the path of import_name relative to dir or one of
+// its parents, or empty if there is none.
+func (v *View) searchParentDirs(dir string, import_name string) string {
+	additional_dirs := v.loadSettings().AdditionalProtoDirs
+	pos := dir
 	for path.Clean(pos) != "/" {
 		abs_name := path.Join(pos, import_name)
 		if v.fs.FileExists(abs_name) {
-			return defines.DocumentUri(uri.New(path.Clean(abs_name))), nil
+			return path.Clean(abs_name)
 		}
-		for _, additionalProtoDir := range v.settings.AdditionalProtoDirs {
+		for _, additionalProtoDir := range additional_dirs {
 			abs_name := path.Join(pos, additionalProtoDir, import_name)
 			if v.fs.FileExists(abs_name) {
-				return defines.DocumentUri(uri.New(path.Clean(abs_name))), nil
+				return path.Clean(abs_name)
 			}
 		}
 		pos = path.Join(pos, "..")
 	}
-	return res, fmt.Errorf("%w: import %s", ErrNotFound, import_name)
+	return ""
 }
 
 func toUtf8(iso8859_1_buf []byte) []byte {
@@ -322,12 +543,12 @@ func didOpen(ctx context.Context, params *defines.DidOpenTextDocumentParams) err
 		document_uri := params.TextDocument.Uri
 		text := []byte(params.TextDocument.Text)
 
-		ViewManager.didOpen(document_uri, text)
+		FromContext(ctx).didOpen(ctx, document_uri, params.TextDocument.Version, text)
 		return nil
 	}
 
 	if IsPbHeader(params.TextDocument.Uri) {
-		ViewManager.didOpenPbHeader(params.TextDocument.Uri, params.TextDocument.Text)
+		FromContext(ctx).didOpenPbHeader(params.TextDocument.Uri, params.TextDocument.Text)
 	}
 	return nil
 }
@@ -341,10 +562,17 @@ func didChange(ctx context.Context, params *defines.DidChangeTextDocumentParams)
 		return jsonrpc2.NewError(jsonrpc2.InternalError, "no content changes provided")
 	}
 
+	v := FromContext(ctx)
 	document_uri := params.TextDocument.Uri
-	text := params.ContentChanges[0].Text
+	data, err := v.didChange(document_uri, params.TextDocument.Version, params.ContentChanges)
+	if err != nil {
+		return jsonrpc2.NewError(jsonrpc2.InvalidParams, err.Error())
+	}
+	if data == nil {
+		return nil
+	}
 
-	ViewManager.setContent(ctx, document_uri, []byte(text.(string)))
+	v.setContent(ctx, document_uri, data)
 	return nil
 }
 
@@ -353,53 +581,57 @@ func didClose(ctx context.Context, params *defines.DidCloseTextDocumentParams) e
 		return nil
 	}
 
+	v := FromContext(ctx)
 	document_uri := params.TextDocument.Uri
 
-	ViewManager.didClose(document_uri)
-	ViewManager.setContent(ctx, document_uri, nil)
+	v.didClose(document_uri)
+	v.setContent(ctx, document_uri, nil)
 
 	return nil
 }
 
-func didSave(_ context.Context, params *defines.DidSaveTextDocumentParams) error {
+func didSave(ctx context.Context, params *defines.DidSaveTextDocumentParams) error {
 	if !IsProtoFile(params.TextDocument.Uri) {
 		return nil
 	}
 
 	document_uri := defines.DocumentUri(params.TextDocument.Uri)
 
-	ViewManager.didSave(document_uri)
+	FromContext(ctx).didSave(document_uri)
 
 	return nil
 }
 
 func onInitialized(ctx context.Context, req *defines.InitializeParams) (err error) {
+	v := FromContext(ctx)
+	params := v.Server.InitializeParams(ctx)
+	v.registerFileWatchers(context.WithoutCancel(ctx), params)
+	go v.IndexWorkspace(context.WithoutCancel(ctx), workspaceRoots(params))
 	return nil
 }
 
 func onDidChangeConfiguration(ctx context.Context, req *defines.DidChangeConfigurationParams) (err error) {
-	if ViewManager == nil {
-		return nil
-	}
 	settings, err := SettingsFromInterface(req.Settings)
 	if err != nil {
 		return err
 	}
-	ViewManager.settings = *settings
+	FromContext(ctx).storeSettings(*settings)
 	return nil
 }
 
-func Init(server *lsp.Server) {
-
-	ViewManager = newView()
-	ViewManager.Server = server
-
-	server.OnInitialized(onInitialized)
-	server.OnDidChangeConfiguration(onDidChangeConfiguration)
-	server.OnDidOpenTextDocument(didOpen)
-	server.OnDidChangeTextDocument(didChange)
-	server.OnDidCloseTextDocument(didClose)
-	server.OnDidSaveTextDocument(didSave)
+func Init(lsp_server *lsp.Server) {
+	viewsMu.Lock()
+	server = lsp_server
+	defaultView = nil
+	viewsMu.Unlock()
+
+	lsp_server.OnInitialized(onInitialized)
+	lsp_server.OnDidChangeConfiguration(onDidChangeConfiguration)
+	lsp_server.OnDidOpenTextDocument(didOpen)
+	lsp_server.OnDidChangeTextDocument(didChange)
+	lsp_server.OnDidCloseTextDocument(didClose)
+	lsp_server.OnDidSaveTextDocument(didSave)
+	lsp_server.OnDidChangeWatchedFiles(onDidChangeWatchedFiles)
 }
 
 func IsProtoFile(document_uri defines.DocumentUri) bool {
@@ -1,11 +1,22 @@
 package view
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/walteh/protobuf-language-server/go-lsp/jsonrpc"
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
 	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/view/fs"
 )
 
 func Test_view_GetDocumentUriFromImportPath(t *testing.T) {
@@ -62,9 +73,13 @@ func Test_view_GetDocumentUriFromImportPath(t *testing.T) {
 	}
 	for i, tt := range tests {
 		t.Run(fmt.Sprint(i), func(t *testing.T) {
-			mockFS := &MockFS{ExistingFiles: tt.existingFiles}
+			mapFS := fs.NewMapFS(nil)
+			for _, name := range tt.existingFiles {
+				mapFS.Set(name, nil)
+			}
 
-			v := &view{fs: mockFS, settings: tt.settings}
+			v := &View{fs: mapFS}
+			v.storeSettings(tt.settings)
 
 			got, err := v.GetDocumentUriFromImportPath(tt.cwd, tt.import_name)
 			require.ErrorIs(t, err, tt.wantErr)
@@ -72,3 +87,227 @@ func Test_view_GetDocumentUriFromImportPath(t *testing.T) {
 		})
 	}
 }
+
+func Test_view_overlay(t *testing.T) {
+	logs.Init(nil)
+	v := newView()
+	v.fs = fs.LayeredFS{v.overlay, fs.NewMapFS(map[string]string{
+		"/repo/api.proto":    `import "common.proto";`,
+		"/repo/common.proto": "saved",
+	})}
+
+	// unsaved buffers shadow the disk and new files can be imported
+	_, err := v.didChange("file:///repo/common.proto", 1, []defines.TextDocumentContentChangeEvent{{Text: "unsaved"}})
+	require.NoError(t, err)
+	_, err = v.didChange("file:///repo/new.proto", 1, []defines.TextDocumentContentChangeEvent{{Text: ""}})
+	require.NoError(t, err)
+
+	data, err := v.fs.ReadFile("/repo/common.proto")
+	require.NoError(t, err)
+	require.Equal(t, "unsaved", string(data))
+	got, err := v.GetDocumentUriFromImportPath("file:///repo/api.proto", "new.proto")
+	require.NoError(t, err)
+	require.Equal(t, defines.DocumentUri("file:///repo/new.proto"), got)
+
+	v.didClose("file:///repo/common.proto")
+	data, err = v.fs.ReadFile("/repo/common.proto")
+	require.NoError(t, err)
+	require.Equal(t, "saved", string(data))
+}
+
+func Test_view_GetFileConcurrent(t *testing.T) {
+	logs.Init(nil)
+	v := newView()
+	disk := fs.NewMapFS(nil)
+	uris := make([]defines.DocumentUri, 20)
+	for i := range uris {
+		disk.Set(fmt.Sprintf("/repo/%d.proto", i), []byte("syntax = \"proto3\";\n"))
+		uris[i] = defines.DocumentUri(fmt.Sprintf("file:///repo/%d.proto", i))
+	}
+	v.fs = fs.LayeredFS{v.overlay, disk}
+
+	// every file is loaded while the others are looked up, like the watcher
+	// and the indexing of the workspace do while requests are handled
+	errs := make([]error, len(uris))
+	var wg sync.WaitGroup
+	for i, document_uri := range uris {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = v.GetFile(document_uri)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	require.Len(t, v.GetFiles(), len(uris))
+}
+
+func Test_view_settingsConcurrent(t *testing.T) {
+	logs.Init(nil)
+	v := newView()
+	v.fs = fs.LayeredFS{v.overlay, fs.NewMapFS(map[string]string{
+		"/repo/protos/common.proto": "syntax = \"proto3\";\n",
+	})}
+
+	// the client changes the configuration while imports are resolved
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			v.storeSettings(Settings{AdditionalProtoDirs: []string{"protos"}})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		v.GetDocumentUriFromImportPath("file:///repo/a.proto", "common.proto")
+		v.ImportPath("file:///repo/a.proto", "file:///repo/protos/common.proto")
+	}
+	wg.Wait()
+
+	document_uri, err := v.GetDocumentUriFromImportPath("file:///repo/a.proto", "common.proto")
+	require.NoError(t, err)
+	require.Equal(t, defines.DocumentUri("file:///repo/protos/common.proto"), document_uri)
+}
+
+func Test_FromContext(t *testing.T) {
+	logs.Init(nil)
+	server := jsonrpc.NewServer()
+	views := make(chan *View, 2)
+	server.RegisterMethod(jsonrpc.MethodInfo{
+		Name:       "textDocument/didOpen",
+		NewRequest: func() interface{} { return &defines.DidOpenTextDocumentParams{} },
+		Handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+			views <- FromContext(ctx)
+			return nil, didOpen(ctx, req.(*defines.DidOpenTextDocumentParams))
+		},
+	})
+
+	// every connection opens its own file and only hears about that one
+	open := func(name string) (*View, string) {
+		client := connect(t, server)
+
+		document_uri := "file:///window/" + name
+		msg := fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":%q,"languageId":"proto","version":1,"text":"syntax = \"proto3\";\nmessage A {"}}}`, document_uri)
+		_, err := fmt.Fprintf(client, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+		require.NoError(t, err)
+
+		reader := bufio.NewReader(client)
+		header, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+		require.NoError(t, err)
+		_, err = reader.ReadString('\n')
+		require.NoError(t, err)
+		body := make([]byte, length)
+		_, err = io.ReadFull(reader, body)
+		require.NoError(t, err)
+		var diagnostics Diagnositcs
+		require.NoError(t, json.Unmarshal(body, &diagnostics))
+		return <-views, string(diagnostics.Params.Uri)
+	}
+	first, first_uri := open("a.proto")
+	second, second_uri := open("b.proto")
+
+	require.Equal(t, "file:///window/a.proto", first_uri)
+	require.Equal(t, "file:///window/b.proto", second_uri)
+	require.NotSame(t, first, second)
+	require.True(t, first.isOpen("file:///window/a.proto"))
+	require.False(t, first.isOpen("file:///window/b.proto"))
+	require.True(t, second.isOpen("file:///window/b.proto"))
+
+	require.Same(t, FromContext(context.Background()), FromContext(context.Background()))
+}
+
+func Test_didChangeInOrder(t *testing.T) {
+	logs.Init(nil)
+	server := jsonrpc.NewServer()
+	server.RegisterMethod(jsonrpc.MethodInfo{
+		Name:       "textDocument/didOpen",
+		NewRequest: func() interface{} { return &defines.DidOpenTextDocumentParams{} },
+		Handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, didOpen(ctx, req.(*defines.DidOpenTextDocumentParams))
+		},
+	})
+	server.RegisterMethod(jsonrpc.MethodInfo{
+		Name:       "textDocument/didChange",
+		NewRequest: func() interface{} { return &defines.DidChangeTextDocumentParams{} },
+		Handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, didChange(ctx, req.(*defines.DidChangeTextDocumentParams))
+		},
+	})
+	server.RegisterMethod(jsonrpc.MethodInfo{
+		Name:       "test/text",
+		NewRequest: func() interface{} { return &defines.TextDocumentIdentifier{} },
+		Handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+			v := FromContext(ctx)
+			v.openFileMu.Lock()
+			defer v.openFileMu.Unlock()
+			if doc, ok := v.openFiles[req.(*defines.TextDocumentIdentifier).Uri]; ok {
+				return string(doc.data), nil
+			}
+			return "", nil
+		},
+	})
+
+	client := connect(t, server)
+
+	// diagnostics are published for every change, the response of test/text
+	// is the only message with an id
+	responses := make(chan map[string]interface{}, 1)
+	go func() {
+		reader := bufio.NewReader(client)
+		for {
+			header, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			length, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+			if _, err := reader.ReadString('\n'); err != nil {
+				return
+			}
+			body := make([]byte, length)
+			if _, err := io.ReadFull(reader, body); err != nil {
+				return
+			}
+			var msg map[string]interface{}
+			if json.Unmarshal(body, &msg) == nil && msg["id"] != nil {
+				responses <- msg
+			}
+		}
+	}()
+	send := func(msg string) {
+		_, err := fmt.Fprintf(client, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+		require.NoError(t, err)
+	}
+
+	document_uri := "file:///window/order.proto"
+	send(fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":%q,"languageId":"proto","version":1,"text":""}}}`, document_uri))
+	var want strings.Builder
+	for i := 0; i < 50; i++ {
+		send(fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":%q,"version":%d},"contentChanges":[{"range":{"start":{"line":0,"character":%d},"end":{"line":0,"character":%d}},"text":"%d"}]}}`, document_uri, i+2, i, i, i%10))
+		want.WriteString(strconv.Itoa(i % 10))
+	}
+	send(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"test/text","params":{"uri":%q}}`, document_uri))
+
+	require.Equal(t, want.String(), (<-responses)["result"])
+}
+
+// connect serves a connection of server and returns the client end of it.
+// The session ends before the test does, so it does not outlive the state,
+// e.g. the logger, the test set up.
+func connect(t *testing.T, server *jsonrpc.Server) net.Conn {
+	t.Helper()
+	client, conn := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.ConnComeIn(conn)
+	}()
+	t.Cleanup(func() {
+		client.Close()
+		<-done
+	})
+	return client
+}
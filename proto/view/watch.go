@@ -0,0 +1,164 @@
+package view
+
+import (
+	"context"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"go.lsp.dev/uri"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/parser"
+)
+
+const watchedFilesRegistrationID = "protobuf-language-server/watched-files"
+
+// watchedFilesGlobs match the files whose changes on disk affect the view.
+var watchedFilesGlobs = []string{"**/*.proto", "**/" + bufYAMLName, "**/" + bufWorkYAMLName}
+
+// registerFileWatchers asks the client to send workspace/didChangeWatchedFiles
+// for proto files and buf configuration, if it supports dynamic registration.
+func (v *View) registerFileWatchers(ctx context.Context, initialize_params *defines.InitializeParams) {
+	if !supportsWatchedFilesRegistration(initialize_params) {
+		return
+	}
+	watchers := make([]defines.FileSystemWatcher, 0, len(watchedFilesGlobs))
+	for _, glob := range watchedFilesGlobs {
+		watchers = append(watchers, defines.FileSystemWatcher{GlobPattern: glob})
+	}
+	params := &defines.RegistrationParams{
+		Registrations: []defines.Registration{{
+			Id:              watchedFilesRegistrationID,
+			Method:          "workspace/didChangeWatchedFiles",
+			RegisterOptions: defines.DidChangeWatchedFilesRegistrationOptions{Watchers: watchers},
+		}},
+	}
+	// on wasip1 handlers run on the goroutine that reads the response
+	go func() {
+		if err := v.Server.RegisterCapability(ctx, params); err != nil {
+			logs.Printf("register file watchers err: %v", err)
+		}
+	}()
+}
+
+func supportsWatchedFilesRegistration(params *defines.InitializeParams) bool {
+	if params == nil || params.Capabilities.Workspace == nil {
+		return false
+	}
+	capabilities := params.Capabilities.Workspace.DidChangeWatchedFiles
+	return capabilities != nil && capabilities.DynamicRegistration != nil && *capabilities.DynamicRegistration
+}
+
+// didChangeWatchedFiles reloads the proto files that changed on disk and
+// republishes the diagnostics of every open file importing them, directly or
+// transitively. Files open in the editor are left alone, their buffer is
+// authoritative. A change of the buf configuration affects every open file.
+func (v *View) didChangeWatchedFiles(ctx context.Context, changes []defines.FileEvent) {
+	var changed []defines.DocumentUri
+	buf_changed := false
+	for _, change := range changes {
+		switch name := path.Base(uri.URI(change.Uri).Filename()); {
+		case name == bufYAMLName || name == bufWorkYAMLName:
+			buf_changed = true
+		case IsProtoFile(change.Uri) && !v.isOpen(change.Uri):
+			changed = append(changed, change.Uri)
+		}
+	}
+	if buf_changed {
+		v.resetBufConfig()
+	}
+	for _, document_uri := range changed {
+		v.reloadFile(ctx, document_uri)
+	}
+
+	affected := v.importers(changed)
+	v.openFileMu.RLock()
+	documents := make(map[defines.DocumentUri][]byte)
+	for document_uri, doc := range v.openFiles {
+		if buf_changed || affected[uri.URI(document_uri).Filename()] {
+			documents[document_uri] = doc.data
+		}
+	}
+	v.openFileMu.RUnlock()
+	for document_uri, data := range documents {
+		v.setContent(ctx, document_uri, data)
+	}
+}
+
+// reloadFile rereads a file from disk. Loaded files are parsed again, others
+// are only indexed, and deleted files are dropped.
+func (v *View) reloadFile(ctx context.Context, document_uri defines.DocumentUri) {
+	_, loaded := v.loadedFile(document_uri)
+
+	data, err := v.fs.ReadFile(uri.URI(document_uri).Filename())
+	if err != nil {
+		// the file was deleted
+		v.index.remove(document_uri)
+		if loaded {
+			v.setContent(ctx, document_uri, nil)
+			v.notify(Diagnositcs{
+				Method: "textDocument/publishDiagnostics",
+				Params: defines.PublishDiagnosticsParams{Uri: document_uri, Diagnostics: []defines.Diagnostic{}},
+			})
+		}
+		return
+	}
+	if !utf8.Valid(data) {
+		data = toUtf8(data)
+	}
+	if loaded {
+		v.setContent(ctx, document_uri, data)
+		return
+	}
+	if proto, _ := parser.ParseProtoWithErrors(document_uri, data); proto != nil {
+		v.indexFile(document_uri, data, proto)
+	}
+}
+
+// importers returns the file names of the loaded files that import one of
+// targets, directly or transitively. An import whose path is a suffix of a
+// target counts too, a deleted file can no longer be resolved.
+func (v *View) importers(targets []defines.DocumentUri) map[string]bool {
+	target_names := make([]string, 0, len(targets))
+	for _, target := range targets {
+		target_names = append(target_names, uri.URI(target).Filename())
+	}
+
+	imported_by := make(map[string][]string)
+	for _, proto_file := range v.GetFiles() {
+		importer := uri.URI(proto_file.URI()).Filename()
+		for _, i := range proto_file.Proto().Imports() {
+			import_name := i.ProtoImport.Filename
+			if import_uri, err := v.GetDocumentUriFromImportPath(proto_file.URI(), import_name); err == nil {
+				imported := uri.URI(import_uri).Filename()
+				imported_by[imported] = append(imported_by[imported], importer)
+			}
+			for _, target := range target_names {
+				if strings.HasSuffix(target, "/"+import_name) {
+					imported_by[target] = append(imported_by[target], importer)
+				}
+			}
+		}
+	}
+
+	res := make(map[string]bool)
+	queue := target_names
+	for len(queue) > 0 {
+		imported := queue[0]
+		queue = queue[1:]
+		for _, importer := range imported_by[imported] {
+			if !res[importer] {
+				res[importer] = true
+				queue = append(queue, importer)
+			}
+		}
+	}
+	return res
+}
+
+func onDidChangeWatchedFiles(ctx context.Context, req *defines.DidChangeWatchedFilesParams) error {
+	FromContext(ctx).didChangeWatchedFiles(ctx, req.Changes)
+	return nil
+}
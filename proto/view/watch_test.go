@@ -0,0 +1,84 @@
+package view
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/walteh/protobuf-language-server/go-lsp/logs"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp"
+	"github.com/walteh/protobuf-language-server/go-lsp/lsp/defines"
+	"github.com/walteh/protobuf-language-server/proto/view/fs"
+)
+
+func Test_view_didChangeWatchedFiles(t *testing.T) {
+	logs.Init(nil)
+	v := newView()
+	v.Server = lsp.NewServer(&lsp.Options{})
+	disk := fs.NewMapFS(map[string]string{
+		"/repo/a.proto": "syntax = \"proto3\";\nimport \"b.proto\";\nmessage A { B b = 1; }\n",
+		"/repo/b.proto": "syntax = \"proto3\";\nimport \"c.proto\";\nmessage B { C c = 1; }\n",
+		"/repo/c.proto": "syntax = \"proto3\";\nmessage C {}\n",
+		"/repo/d.proto": "syntax = \"proto3\";\nmessage D {}\n",
+	})
+	v.fs = fs.LayeredFS{v.overlay, disk}
+
+	a := defines.DocumentUri("file:///repo/a.proto")
+	c := defines.DocumentUri("file:///repo/c.proto")
+	data, err := disk.ReadFile("/repo/a.proto")
+	require.NoError(t, err)
+	v.didOpen(context.Background(), a, 1, data)
+	// the imports are loaded in the background
+	require.Eventually(t, func() bool {
+		_, loaded := v.loadedFile("file:///repo/b.proto")
+		return loaded
+	}, time.Second, time.Millisecond)
+	_, err = v.GetFile(c)
+	require.NoError(t, err)
+
+	messageNames := func(document_uri defines.DocumentUri) (names []string) {
+		v.fileMu.RLock()
+		defer v.fileMu.RUnlock()
+		for _, m := range v.filesByURI[document_uri].Proto().Messages() {
+			names = append(names, m.Protobuf().Name)
+		}
+		return names
+	}
+	require.Equal(t, []string{"C"}, messageNames(c))
+	require.Equal(t, map[string]bool{"/repo/a.proto": true, "/repo/b.proto": true}, v.importers([]defines.DocumentUri{c}))
+
+	// a changed file is parsed again
+	disk.Set("/repo/c.proto", []byte("syntax = \"proto3\";\nmessage C {}\nmessage E {}\n"))
+	v.didChangeWatchedFiles(context.Background(), []defines.FileEvent{{Uri: c, Type: defines.FileChangeTypeChanged}})
+	require.Equal(t, []string{"C", "E"}, messageNames(c))
+
+	// importers of a deleted file are still found
+	disk.Remove("/repo/c.proto")
+	v.didChangeWatchedFiles(context.Background(), []defines.FileEvent{{Uri: c, Type: defines.FileChangeTypeDeleted}})
+	v.fileMu.RLock()
+	_, loaded := v.filesByURI[c]
+	v.fileMu.RUnlock()
+	require.False(t, loaded)
+	require.Equal(t, map[string]bool{"/repo/a.proto": true, "/repo/b.proto": true}, v.importers([]defines.DocumentUri{c}))
+	for _, symbol := range v.WorkspaceSymbols() {
+		require.NotEqual(t, c, symbol.Location.Uri)
+	}
+
+	// a change of the buf configuration drops the cached import roots
+	require.Nil(t, v.bufImportRoots("/repo"))
+	disk.Set("/repo/buf.yaml", []byte("version: v2\n"))
+	v.didChangeWatchedFiles(context.Background(), []defines.FileEvent{{Uri: "file:///repo/buf.yaml", Type: defines.FileChangeTypeCreated}})
+	require.Equal(t, []string{"/repo"}, v.bufImportRoots("/repo"))
+}
+
+func Test_supportsWatchedFilesRegistration(t *testing.T) {
+	require.False(t, supportsWatchedFilesRegistration(nil))
+	dynamic := true
+	params := &defines.InitializeParams{}
+	params.Capabilities.Workspace = &defines.WorkspaceClientCapabilities{
+		DidChangeWatchedFiles: &defines.DidChangeWatchedFilesClientCapabilities{DynamicRegistration: &dynamic},
+	}
+	require.True(t, supportsWatchedFilesRegistration(params))
+}